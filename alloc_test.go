@@ -0,0 +1,81 @@
+package timberjack
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hotPathConfigs are configurations whose writes that don't rotate must not
+// allocate.
+var hotPathConfigs = []struct {
+	name      string
+	configure func(l *Logger)
+}{
+	{"default", func(l *Logger) {}},
+	{"buffered", func(l *Logger) { l.BufferSize = 64 << 10 }},
+	{"interval", func(l *Logger) { l.RotationInterval = time.Hour }},
+	{"minutes", func(l *Logger) { l.RotateAtMinutes, l.LocalTime = []int{0, 30}, true }},
+	{"calendar", func(l *Logger) { l.RotationPeriod = RotationDaily }},
+	{"tail", func(l *Logger) { l.TailBufferSize = 16 }},
+	{"integrity", func(l *Logger) { l.IntegrityInterval, l.IdleFinalizeAfter = time.Hour, time.Hour }},
+	{"ratelimit", func(l *Logger) { l.MaxBytesPerSecond = 1 << 30 }},
+}
+
+func TestWrite_ZeroAllocs(t *testing.T) {
+	currentTime = time.Now
+	defer func() { currentTime = fakeTime }()
+	megabyte = 1024 * 1024
+	defer func() { megabyte = 1 }()
+
+	record := []byte(strings.Repeat("x", 127) + "\n")
+	for _, tc := range hotPathConfigs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			dir := makeTempDir("TestWrite_ZeroAllocs", t)
+			defer os.RemoveAll(dir)
+
+			l := &Logger{Filename: logFile(dir), MaxSize: 1024}
+			tc.configure(l)
+			defer l.Close()
+
+			// Warm up: open the file and fill the tail buffer.
+			for i := 0; i < 32; i++ {
+				_, err := l.Write(record)
+				isNil(err, t)
+			}
+			allocs := testing.AllocsPerRun(1000, func() {
+				_, _ = l.Write(record)
+			})
+			equals(0.0, allocs, t)
+		})
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	megabyte = 1024 * 1024
+	defer func() { megabyte = 1 }()
+
+	record := []byte(strings.Repeat("x", 127) + "\n")
+	for _, tc := range hotPathConfigs {
+		tc := tc
+		b.Run(tc.name, func(b *testing.B) {
+			dir := makeTempDir("BenchmarkWrite", b)
+			defer os.RemoveAll(dir)
+
+			l := &Logger{Filename: logFile(dir), MaxSize: 1024}
+			tc.configure(l)
+			defer l.Close()
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(record)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := l.Write(record); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package timberjack
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveSuffix is the extension of the archives made by ArchiveAfter.
+const archiveSuffix = ".tar.gz"
+
+// archivePeriod returns the effective ArchivePeriod.
+func (l *Logger) archivePeriod() RotationPeriod {
+	switch l.ArchivePeriod {
+	case RotationDaily, RotationWeekly:
+		return l.ArchivePeriod
+	}
+	return RotationMonthly
+}
+
+// archivePath returns the path of the archive a backup with timestamp t is
+// bundled into.
+func (l *Logger) archivePath(t time.Time) string {
+	t = t.In(l.location())
+	var period string
+	switch l.archivePeriod() {
+	case RotationDaily:
+		period = t.Format("2006-01-02")
+	case RotationWeekly:
+		year, week := t.ISOWeek()
+		period = fmt.Sprintf("%04d-W%02d", year, week)
+	default:
+		period = t.Format("2006-01")
+	}
+	prefix, _ := l.prefixAndExt()
+	return filepath.Join(l.backupDir(), prefix+period+archiveSuffix)
+}
+
+// archiveEnd parses the name of an archive and returns the end of the period
+// it covers, in the Logger's time zone.
+func (l *Logger) archiveEnd(name string) (time.Time, bool) {
+	prefix, _ := l.prefixAndExt()
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, archiveSuffix) {
+		return time.Time{}, false
+	}
+	period := name[len(prefix) : len(name)-len(archiveSuffix)]
+	loc := l.location()
+	if t, err := time.ParseInLocation("2006-01-02", period, loc); err == nil {
+		return t.AddDate(0, 0, 1), true
+	}
+	if t, err := time.ParseInLocation("2006-01", period, loc); err == nil {
+		return t.AddDate(0, 1, 0), true
+	}
+	var year, week int
+	if n, err := fmt.Sscanf(period, "%04d-W%02d", &year, &week); err == nil && n == 2 {
+		// January 4th is always in week 1.
+		jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+(week-1)*7)
+		return monday.AddDate(0, 0, 7), true
+	}
+	return time.Time{}, false
+}
+
+// splitArchived separates the backups due for archiving from files. A
+// backup is due once it is older than ArchiveAfter and the period of its
+// archive has ended, so that each archive is normally written once rather
+// than rewritten on every pass as the period's backups come of age.
+func (l *Logger) splitArchived(files []logInfo) (remaining, archived []logInfo) {
+	if l.ArchiveAfter <= 0 || l.NumberedBackups {
+		return files, nil
+	}
+	now := l.now()
+	cutoff := now.Add(-time.Duration(l.ArchiveAfter) * 24 * time.Hour)
+	for _, f := range files {
+		end, ok := l.archiveEnd(filepath.Base(l.archivePath(f.timestamp)))
+		if f.timestamp.Before(cutoff) && ok && !end.After(now) {
+			archived = append(archived, f)
+		} else {
+			remaining = append(remaining, f)
+		}
+	}
+	return remaining, archived
+}
+
+// archiveBackups bundles files into their archives and removes them.
+// Problems are reported on stderr; the files concerned are left for the
+// next pass.
+func (l *Logger) archiveBackups(files []logInfo) {
+	groups := make(map[string][]logInfo)
+	var order []string
+	for _, f := range files {
+		archive := l.archivePath(f.timestamp)
+		if _, ok := groups[archive]; !ok {
+			order = append(order, archive)
+		}
+		groups[archive] = append(groups[archive], f)
+	}
+	for _, archive := range order {
+		if err := l.context().Err(); err != nil {
+			return // the Logger's context ended; leave the rest for later
+		}
+		if err := l.bundle(archive, groups[archive]); err != nil {
+			l.backgroundError("archive", err, "failed to archive backups into %s", archive)
+			continue
+		}
+		for _, f := range groups[archive] {
+			if err := osRemove(filepath.Join(l.backupDir(), f.Name())); err != nil && !os.IsNotExist(err) {
+				l.backgroundError("remove", err, "failed to remove archived log file %s", f.Name())
+				continue
+			}
+			l.forgetBackup(f.Name())
+			l.removeEmptyLayoutDirs(f.Name())
+		}
+	}
+}
+
+// bundle adds files to the archive, creating it if necessary. The archive
+// is rewritten under a temporary name and renamed into place when complete,
+// so an interrupted bundle never loses data. Existing archives are only
+// rewritten for backups that turn up after their period was archived.
+func (l *Logger) bundle(archive string, files []logInfo) (err error) {
+	tmp := archive + tempSuffix
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = out.Close()
+			_ = osRemove(tmp)
+		}
+	}()
+	zw := gzip.NewWriter(out)
+	tw := tar.NewWriter(zw)
+
+	if err := copyArchive(tw, archive); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := addToArchive(tw, filepath.Join(l.backupDir(), f.Name())); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, archive)
+}
+
+// copyArchive copies the entries of an existing archive to tw.
+func copyArchive(tw *tar.Writer, archive string) error {
+	f, err := os.Open(archive)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// addToArchive writes the file at path to tw under its base name.
+func addToArchive(tw *tar.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = filepath.Base(path)
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// expiredArchives returns the archives whose whole period lies beyond MaxAge.
+func (l *Logger) expiredArchives() []os.FileInfo {
+	if l.MaxAge <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(l.backupDir())
+	if err != nil {
+		return nil
+	}
+	cutoff := l.now().Add(-time.Duration(l.MaxAge) * 24 * time.Hour)
+	var expired []os.FileInfo
+	for _, e := range entries {
+		if end, ok := l.archiveEnd(e.Name()); ok && !e.IsDir() && !end.After(cutoff) {
+			if info, err := e.Info(); err == nil {
+				expired = append(expired, info)
+			}
+		}
+	}
+	return expired
+}
+
+// expireArchives removes the archives returned by expiredArchives.
+func (l *Logger) expireArchives() {
+	removed := 0
+	for _, info := range l.expiredArchives() {
+		if err := osRemove(filepath.Join(l.backupDir(), info.Name())); err != nil && !os.IsNotExist(err) {
+			l.backgroundError("remove", err, "failed to remove expired archive %s", info.Name())
+			continue
+		}
+		l.emit(Event{Type: EventRemoval, File: filepath.Join(l.backupDir(), info.Name()), Reason: "ArchiveAfter"})
+		removed++
+	}
+	l.countRemovals(removed)
+}
@@ -0,0 +1,120 @@
+package timberjack
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestArchiveAfter(t *testing.T) {
+	now := time.Date(2025, time.June, 20, 12, 0, 0, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = fakeTime }()
+	megabyte = 1
+
+	dir := makeTempDir("TestArchiveAfter", t)
+	defer os.RemoveAll(dir)
+
+	backup := func(day time.Time) string {
+		name := filepath.Join(dir, "foobar-"+day.Format(backupTimeFormat)+"-size.log")
+		isNil(os.WriteFile(name, []byte(day.Format("Jan 2")), 0644), t)
+		return filepath.Base(name)
+	}
+	may3 := backup(time.Date(2025, time.May, 3, 0, 0, 0, 0, time.UTC))
+	may20 := backup(time.Date(2025, time.May, 20, 0, 0, 0, 0, time.UTC))
+	june10 := backup(time.Date(2025, time.June, 10, 0, 0, 0, 0, time.UTC))
+	june19 := backup(time.Date(2025, time.June, 19, 0, 0, 0, 0, time.UTC))
+
+	l := &Logger{Filename: logFile(dir), ArchiveAfter: 7, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	plan, err := l.RetentionPlan()
+	isNil(err, t)
+	equals(2, len(plan), t)
+	equals(ActionArchive, plan[0].Action, t)
+	equals("foobar-2025-05.tar.gz", plan[0].Archive, t)
+
+	isNil(l.millRunOnce(), t)
+	archive := filepath.Join(dir, "foobar-2025-05.tar.gz")
+	equals([]string{may3, may20}, archiveMembers(archive, t), t)
+	notExist(filepath.Join(dir, may3), t)
+	notExist(filepath.Join(dir, may20), t)
+	existsWithContent(filepath.Join(dir, june19), []byte("Jun 19"), t)
+	// June hasn't ended yet, so its backups wait to be archived in one go.
+	existsWithContent(filepath.Join(dir, june10), []byte("Jun 10"), t)
+	notExist(filepath.Join(dir, "foobar-2025-06.tar.gz"), t)
+
+	// A late backup is added to the existing archive.
+	may25 := backup(time.Date(2025, time.May, 25, 0, 0, 0, 0, time.UTC))
+	isNil(l.millRunOnce(), t)
+	equals([]string{may3, may20, may25}, archiveMembers(archive, t), t)
+
+	// MaxAge removes an archive once its whole period has expired.
+	now = time.Date(2025, time.July, 5, 0, 0, 0, 0, time.UTC)
+	l.MaxAge = 30
+	isNil(l.millRunOnce(), t)
+	notExist(archive, t)
+	equals([]string{june10, june19}, archiveMembers(filepath.Join(dir, "foobar-2025-06.tar.gz"), t), t)
+}
+
+func TestArchiveAfter_Location(t *testing.T) {
+	// May has ended in UTC but not yet in New York.
+	now := time.Date(2025, time.June, 1, 2, 0, 0, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = fakeTime }()
+
+	nyc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	l := &Logger{Filename: "foo.log", ArchiveAfter: 7, Location: nyc}
+	may3 := logInfo{timestamp: time.Date(2025, time.May, 3, 0, 0, 0, 0, nyc)}
+	_, archived := l.splitArchived([]logInfo{may3})
+	equals(0, len(archived), t)
+	end, ok := l.archiveEnd("foo-2025-05.tar.gz")
+	equals(true, ok, t)
+	equals(time.Date(2025, time.June, 1, 0, 0, 0, 0, nyc), end, t)
+
+	now = time.Date(2025, time.June, 1, 5, 0, 0, 0, time.UTC)
+	_, archived = l.splitArchived([]logInfo{may3})
+	equals(1, len(archived), t)
+}
+
+func TestArchivePeriods(t *testing.T) {
+	l := &Logger{Filename: "/var/log/foo.log"}
+	for _, period := range []RotationPeriod{RotationDaily, RotationWeekly, RotationMonthly} {
+		l.ArchivePeriod = period
+		for day := time.Date(2024, time.December, 20, 15, 0, 0, 0, time.UTC); day.Year() < 2025 || day.Month() < time.February; day = day.AddDate(0, 0, 1) {
+			name := filepath.Base(l.archivePath(day))
+			end, ok := l.archiveEnd(name)
+			assert(ok, t, "can't parse archive name %s", name)
+			assert(end.After(day) && end.Sub(day) <= 31*24*time.Hour, t, "%s: unexpected end %v of %s", period, end, name)
+			equals(name, filepath.Base(l.archivePath(end.Add(-time.Second))), t)
+		}
+	}
+	equals("foo-2025-W01.tar.gz", filepath.Base((&Logger{Filename: "foo.log", ArchivePeriod: RotationWeekly}).archivePath(time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC))), t)
+}
+
+// archiveMembers returns the sorted names of the files in a tar.gz archive.
+func archiveMembers(path string, t testing.TB) []string {
+	f, err := os.Open(path)
+	isNil(err, t)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	isNil(err, t)
+	tr := tar.NewReader(zr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, hdr.Name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,188 @@
+package timberjack
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// QueueFullPolicy decides what Write does when the AsyncQueueSize queue is
+// full.
+type QueueFullPolicy string
+
+const (
+	// QueueBlock makes Write wait until the queue has room. It is the
+	// default.
+	QueueBlock QueueFullPolicy = "block"
+
+	// QueueError makes Write return ErrQueueFull without queueing the
+	// record.
+	QueueError QueueFullPolicy = "error"
+
+	// QueueDrop makes Write drop the record, reporting it as written, for
+	// latency-critical services that prefer losing records to waiting.
+	// Queued records whose write fails, e.g. because the disk is full, are
+	// dropped too. Dropped records are counted in Stats.Dropped, and once
+	// records are written again, a line "timberjack: dropped N messages"
+	// is written before them.
+	QueueDrop QueueFullPolicy = "drop"
+)
+
+// ErrQueueFull is returned by Write when the AsyncQueueSize queue is full and
+// QueueFullPolicy is QueueError.
+var ErrQueueFull = errors.New("timberjack: write queue full")
+
+// ValidateQueueFullPolicy checks that QueueFullPolicy is empty, QueueBlock,
+// QueueError or QueueDrop.
+func (l *Logger) ValidateQueueFullPolicy() error {
+	switch l.QueueFullPolicy {
+	case "", QueueBlock, QueueError, QueueDrop:
+		return nil
+	}
+	return fmt.Errorf("invalid QueueFullPolicy %q: expected %q, %q or %q", l.QueueFullPolicy, QueueBlock, QueueError, QueueDrop)
+}
+
+// queuedRecord is an entry of the write queue: a record, or a marker whose
+// channel is closed once the records queued before it are written.
+type queuedRecord struct {
+	p       []byte
+	flushed chan struct{}
+}
+
+// writeQueue returns the queue of AsyncQueueSize, starting the goroutine
+// writing its records on first use.
+func (l *Logger) writeQueue() chan queuedRecord {
+	l.queueOnce.Do(func() {
+		l.queue = make(chan queuedRecord, l.AsyncQueueSize)
+		l.queueDone = make(chan struct{})
+		go l.runQueue(l.queue, l.queueDone)
+	})
+	return l.queue
+}
+
+// enqueueWrite copies p into the write queue, waiting for room or failing
+// with ErrQueueFull depending on QueueFullPolicy.
+func (l *Logger) enqueueWrite(p []byte) (int, error) {
+	if err := l.checkWriteLen(int64(len(p))); err != nil {
+		return 0, err
+	}
+	record := queuedRecord{p: append([]byte(nil), p...)}
+
+	// Close can't begin while a record is being queued, so the queue is
+	// only closed once no Write can send to it.
+	l.intake.RLock()
+	defer l.intake.RUnlock()
+	if l.isClosed() {
+		return 0, ErrClosed
+	}
+	queue := l.writeQueue()
+	switch l.QueueFullPolicy {
+	case QueueError:
+		select {
+		case queue <- record:
+			return len(p), nil
+		default:
+			return 0, ErrQueueFull
+		}
+	case QueueDrop:
+		select {
+		case queue <- record:
+		default:
+			l.drop()
+		}
+		return len(p), nil
+	}
+	queue <- record
+	return len(p), nil
+}
+
+// runQueue writes the records of queue to the file until it is closed, then
+// closes done.
+func (l *Logger) runQueue(queue chan queuedRecord, done chan struct{}) {
+	defer close(done)
+	for r := range queue {
+		l.mu.Lock()
+		l.writeDropSummary()
+		if r.flushed != nil {
+			l.mu.Unlock()
+			close(r.flushed)
+			continue
+		}
+		n, err := l.write(r.p)
+		n, err = l.handleDiskFull(r.p, n, err)
+		if _, err = l.writeFallback(r.p, n, err); err != nil {
+			if l.QueueFullPolicy == QueueDrop {
+				l.drop()
+			} else {
+				l.backgroundError("write", l.classifyError(err), "queued write failed")
+			}
+		}
+		l.mu.Unlock()
+	}
+	l.mu.Lock()
+	l.writeDropSummary()
+	l.mu.Unlock()
+}
+
+// dropCounter counts the records dropped by QueueDrop. It is allocated
+// separately so its counters are 64-bit aligned for atomic access.
+type dropCounter struct {
+	total      int64
+	unreported int64 // not yet reported in the file
+}
+
+// drops returns the Logger's drop counter, creating it on first use.
+func (l *Logger) drops() *dropCounter {
+	l.dropsOnce.Do(func() {
+		l.dropped = &dropCounter{}
+	})
+	return l.dropped
+}
+
+// drop counts a record dropped by QueueDrop.
+func (l *Logger) drop() {
+	d := l.drops()
+	atomic.AddInt64(&d.total, 1)
+	atomic.AddInt64(&d.unreported, 1)
+}
+
+// writeDropSummary writes a line reporting the records dropped since the
+// last such line, if any. It expects l.mu to be held.
+func (l *Logger) writeDropSummary() {
+	d := l.drops()
+	n := atomic.SwapInt64(&d.unreported, 0)
+	if n == 0 {
+		return
+	}
+	if _, err := l.write([]byte(fmt.Sprintf("timberjack: dropped %d messages\n", n))); err != nil {
+		atomic.AddInt64(&d.unreported, n) // report them once writes recover
+	}
+}
+
+// flushQueue waits until the records queued so far are written. It must not
+// be called with l.mu held.
+func (l *Logger) flushQueue() {
+	if l.AsyncQueueSize <= 0 {
+		return
+	}
+	flushed := make(chan struct{})
+	l.intake.RLock()
+	if l.isClosed() {
+		l.intake.RUnlock()
+		return
+	}
+	l.writeQueue() <- queuedRecord{flushed: flushed}
+	l.intake.RUnlock()
+	<-flushed
+}
+
+// closeQueue writes the remaining queued records and stops the queue's
+// goroutine. It is called by Close once no Write can queue records anymore,
+// without l.mu held.
+func (l *Logger) closeQueue() {
+	if l.AsyncQueueSize <= 0 || l.queue == nil {
+		return
+	}
+	close(l.queue)
+	<-l.queueDone
+}
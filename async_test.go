@@ -0,0 +1,119 @@
+package timberjack
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestAsyncQueue(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestAsyncQueue", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 1000, AsyncQueueSize: 4}
+	defer l.Close()
+	isNil(l.ValidateQueueFullPolicy(), t)
+
+	var want bytes.Buffer
+	for i := 0; i < 100; i++ {
+		b := []byte{'a' + byte(i%26)}
+		n, err := l.Write(b)
+		isNil(err, t)
+		equals(1, n, t)
+		want.Write(b)
+	}
+	isNil(l.Flush(), t)
+	existsWithContent(filename, want.Bytes(), t)
+
+	// Close writes what is still queued.
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Close(), t)
+	want.WriteString("boo!")
+	existsWithContent(filename, want.Bytes(), t)
+
+	_, err = l.Write([]byte("boo!"))
+	equals(ErrClosed, err, t)
+}
+
+func TestAsyncQueue_Full(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestAsyncQueue_Full", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 1000, AsyncQueueSize: 1, QueueFullPolicy: QueueError}
+	defer l.Close()
+	isNil(l.ValidateQueueFullPolicy(), t)
+
+	// Stall the queue's writer.
+	l.mu.Lock()
+	var accepted, rejected int
+	for i := 0; i < 3; i++ {
+		_, err := l.Write([]byte("boo!"))
+		switch err {
+		case nil:
+			accepted++
+		case ErrQueueFull:
+			rejected++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	l.mu.Unlock()
+	assert(rejected > 0, t, "expected writes to be rejected by a full queue")
+
+	isNil(l.Flush(), t)
+	existsWithContent(filename, bytes.Repeat([]byte("boo!"), accepted), t)
+
+	l.QueueFullPolicy = "discard"
+	notNil(l.ValidateQueueFullPolicy(), t)
+}
+
+func TestAsyncQueue_Drop(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestAsyncQueue_Drop", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 1000, AsyncQueueSize: 1, QueueFullPolicy: QueueDrop}
+	defer l.Close()
+	isNil(l.ValidateQueueFullPolicy(), t)
+
+	// Stall the queue's writer; records that don't fit are dropped, but
+	// reported as written.
+	l.mu.Lock()
+	for i := 0; i < 4; i++ {
+		n, err := l.Write([]byte("boo!"))
+		isNil(err, t)
+		equals(4, n, t)
+	}
+	l.mu.Unlock()
+	dropped := l.Stats().Dropped
+	assert(dropped > 0, t, "expected records to be dropped")
+
+	isNil(l.Flush(), t)
+	b, err := os.ReadFile(filename)
+	isNil(err, t)
+	summary := fmt.Sprintf("timberjack: dropped %d messages\n", dropped)
+	equals(1, bytes.Count(b, []byte(summary)), t)
+	equals(4-int(dropped), bytes.Count(b, []byte("boo!")), t)
+
+	// The drops are only reported once.
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Flush(), t)
+	b, err = os.ReadFile(filename)
+	isNil(err, t)
+	equals(1, bytes.Count(b, []byte("dropped")), t)
+	equals(dropped, l.Stats().Dropped, t)
+}
@@ -0,0 +1,122 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupNameFunc(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestBackupNameFunc", t)
+	defer os.RemoveAll(dir)
+
+	var gotPrefix, gotReason, gotExt string
+	l := &Logger{
+		Filename:   logFile(dir),
+		MaxSize:    10,
+		MaxBackups: 1,
+		BackupNameFunc: func(prefix string, t time.Time, reason string, ext string) string {
+			gotPrefix, gotReason, gotExt = prefix, reason, ext
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%s.host1.%d-W%02d.%d%s", prefix, year, week, t.Unix(), ext)
+		},
+		BackupNamePattern: "foobar.host1.*-W*.*.log",
+	}
+	defer l.Close()
+
+	name := func() string {
+		year, week := fakeTime().ISOWeek()
+		return filepath.Join(dir, fmt.Sprintf("foobar.host1.%d-W%02d.%d.log", year, week, fakeTime().Unix()))
+	}
+
+	_, err := l.Write([]byte("first"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	equals("foobar", gotPrefix, t)
+	equals("size", gotReason, t)
+	equals(".log", gotExt, t)
+	first := name()
+	existsWithContent(first, []byte("first"), t)
+
+	// Custom backups are subject to retention.
+	newFakeTime()
+	_, err = l.Write([]byte("second"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	past := time.Now().Add(-time.Hour)
+	isNil(os.Chtimes(first, past, past), t)
+	isNil(l.millRunOnce(), t)
+
+	notExist(first, t)
+	existsWithContent(name(), []byte("second"), t)
+	fileCount(dir, 2, t)
+}
+
+func TestBackupNameFunc_Neighbours(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestBackupNameFunc_Neighbours", t)
+	defer os.RemoveAll(dir)
+
+	// Files sharing the log's name stem and extension aren't backups.
+	neighbours := []string{
+		filepath.Join(dir, "foobar-audit.log"),
+		filepath.Join(dir, "foobarbaz.log"),
+		filepath.Join(dir, "foobar.other.log.gz"),
+	}
+	for _, name := range neighbours {
+		isNil(os.WriteFile(name, []byte("not a backup"), 0644), t)
+	}
+
+	l := &Logger{
+		Filename:   logFile(dir),
+		MaxBackups: 1,
+		BackupNameFunc: func(prefix string, t time.Time, reason string, ext string) string {
+			return fmt.Sprintf("%s.%d%s", prefix, t.UnixNano(), ext)
+		},
+		BackupNamePattern: "foobar.[0-9]*.log",
+	}
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := l.Write([]byte("data"))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+		newFakeTime()
+	}
+	isNil(l.millRunOnce(), t)
+
+	for _, name := range neighbours {
+		existsWithContent(name, []byte("not a backup"), t)
+	}
+	// The log file, one backup and the neighbours.
+	fileCount(dir, 2+len(neighbours), t)
+}
+
+func TestValidateBackupNamePattern(t *testing.T) {
+	name := func(string, time.Time, string, string) string { return "x.log" }
+	isNil((&Logger{}).ValidateBackupNamePattern(), t)
+	isNil((&Logger{BackupNameFunc: name, BackupNamePattern: "x*.log"}).ValidateBackupNamePattern(), t)
+	notNil((&Logger{BackupNameFunc: name}).ValidateBackupNamePattern(), t)
+	notNil((&Logger{BackupNameFunc: name, BackupNamePattern: "x[.log"}).ValidateBackupNamePattern(), t)
+	notNil((&Logger{Filename: "x.log", BackupNameFunc: name}).Validate(), t)
+}
+
+func TestBackupNameFunc_Invalid(t *testing.T) {
+	currentTime = fakeTime
+	l := &Logger{
+		Filename:         "/var/log/foobar.log",
+		BackupTimeFormat: backupTimeFormat,
+		BackupNameFunc:   func(string, time.Time, string, string) string { return "../escape.log" },
+	}
+	equals(backupName(l.Filename, false, "size", fakeTime(), backupTimeFormat), l.backupPath(l.Filename, "size", fakeTime()), t)
+
+	l.BackupNameFunc = func(string, time.Time, string, string) string { return "" }
+	equals(backupName(l.Filename, false, "time", fakeTime(), backupTimeFormat), l.backupPath(l.Filename, "time", fakeTime()), t)
+}
@@ -0,0 +1,74 @@
+package timberjack
+
+import (
+	"path/filepath"
+	"time"
+)
+
+// BackupInfo describes a backup file managed by a Logger.
+type BackupInfo struct {
+	// Name is the name of the backup file relative to the backup directory:
+	// its base name, prefixed with its subdirectory if BackupDirLayout is set.
+	Name string
+
+	// Timestamp is the rotation time encoded in the file name (or the
+	// modification time for adopted files).
+	Timestamp time.Time
+
+	// Reason is the rotation reason encoded in the file name ("size", "time",
+	// ...). It is available for compressed and uncompressed backups alike and
+	// is empty for adopted files.
+	Reason string
+
+	// Size is the current size of the file in bytes.
+	Size int64
+
+	// Compressed reports whether the backup has been compressed.
+	Compressed bool
+
+	// Pinned reports whether the backup is protected from retention by Pin.
+	Pinned bool
+
+	// OriginalSize and CompressionDuration describe the compression of the
+	// backup. They are only known for backups compressed by this Logger and
+	// are zero otherwise.
+	OriginalSize        int64
+	CompressionDuration time.Duration
+
+	// Link places the backup in the chain of segments rotated by the running
+	// Logger. It is zero for backups written before the Logger started.
+	Link SegmentLink
+}
+
+// Backups returns information about every backup managed by the Logger,
+// newest first.
+func (l *Logger) Backups() ([]BackupInfo, error) {
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+
+	backups := make([]BackupInfo, 0, len(files))
+	for _, f := range files {
+		b := BackupInfo{
+			Name:       f.Name(),
+			Timestamp:  f.timestamp,
+			Reason:     l.backupReason(f.Name()),
+			Size:       f.Size(),
+			Compressed: l.isCompressed(f.Name()) || l.adoptedCompressed(f.Name()),
+			Pinned:     l.pinned(filepath.Join(l.backupDir(), f.Name())),
+		}
+		if rec, ok := l.compressions[filepath.Base(f.Name())]; ok {
+			b.OriginalSize = rec.originalSize
+			b.CompressionDuration = rec.duration
+		}
+		if link, ok := l.links[l.trimCompressed(filepath.Base(f.Name()))]; ok {
+			b.Link = link
+		}
+		backups = append(backups, b)
+	}
+	return backups, nil
+}
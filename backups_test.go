@@ -0,0 +1,64 @@
+package timberjack
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackups(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestBackups", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:   logFile(dir),
+		Compress:   true,
+		MaxBackups: 1,
+	}
+	defer l.Close()
+
+	older := backupFileWithReason(dir, "size")
+	data := bytes.Repeat([]byte("x"), 1000)
+	isNil(os.WriteFile(older, data, 0644), t)
+	isNil(l.millRunOnce(), t)
+
+	newFakeTime()
+	newer := backupFileWithReason(dir, "time")
+	isNil(os.WriteFile(newer, []byte("plain"), 0644), t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+
+	equals(filepath.Base(newer), backups[0].Name, t)
+	equals("time", backups[0].Reason, t)
+	equals(false, backups[0].Compressed, t)
+	equals(int64(5), backups[0].Size, t)
+	equals(int64(0), backups[0].OriginalSize, t)
+
+	equals(filepath.Base(older)+compressSuffix, backups[1].Name, t)
+	equals(true, backups[1].Compressed, t)
+	equals("size", backups[1].Reason, t)
+	equals(int64(len(data)), backups[1].OriginalSize, t)
+	assert(backups[1].CompressionDuration > 0, t, "expected compression duration")
+	assert(backups[0].Timestamp.After(backups[1].Timestamp), t, "expected newest first")
+
+	// Pruning forgets the per-backup compression data.
+	isNil(l.millRunOnce(), t)
+	_, ok := l.compressions[filepath.Base(older)+compressSuffix]
+	equals(false, ok, t)
+}
+
+func TestBackupReason(t *testing.T) {
+	l := &Logger{Filename: "/var/log/foobar.log"}
+
+	equals("size", l.backupReason("foobar-2025-01-01T00-00-00.000-size.log"), t)
+	equals("time", l.backupReason("foobar-2025-01-01T00-00-00.000-time.log"+compressSuffix), t)
+	equals("", l.backupReason("foobar.log.1"), t)
+	equals("foobar-2025-01-01T00-00-00.000-time.log"+compressSuffix,
+		l.compressedName("foobar-2025-01-01T00-00-00.000-time.log"), t)
+}
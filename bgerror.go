@@ -0,0 +1,73 @@
+package timberjack
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxPendingErrors is the most background failures kept for Err; older ones
+// are discarded first.
+const maxPendingErrors = 32
+
+// BackgroundError is a failure of work a Logger did in the background, such
+// as compressing or removing a backup or a timer-driven rotation.
+type BackgroundError struct {
+	Op   string    // the work that failed, as passed to OnError
+	Err  error     // what went wrong
+	Time time.Time // when it happened, by the system clock
+}
+
+// Error implements error.
+func (e *BackgroundError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *BackgroundError) Unwrap() error {
+	return e.Err
+}
+
+// BackgroundErrors is the error returned by Err when more than one
+// background failure happened. Its errors are *BackgroundError values,
+// oldest first.
+type BackgroundErrors []error
+
+// Error implements error.
+func (e BackgroundErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("timberjack: %d background errors: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the individual failures, for errors.Is and errors.As.
+func (e BackgroundErrors) Unwrap() []error {
+	return e
+}
+
+// Err returns the failures of background work since the previous call to
+// Err, and forgets them: nil if there were none, a *BackgroundError if there
+// was one, and BackgroundErrors otherwise. Only the most recent 32 are kept;
+// Stats.BackgroundErrors counts them all. Polling Err, e.g. from a health
+// check, lets operators find out about failing compression, pruning or
+// rotation before the disk fills up.
+func (l *Logger) Err() error {
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+	errs := l.pendingErrors
+	l.pendingErrors = nil
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		out := make(BackgroundErrors, len(errs))
+		for i, err := range errs {
+			out[i] = err
+		}
+		return out
+	}
+}
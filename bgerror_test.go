@@ -0,0 +1,54 @@
+package timberjack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestErr(t *testing.T) {
+	dir := makeTempDir("TestErr", t)
+	defer os.RemoveAll(dir)
+
+	// A backup directory that can't be listed makes cleanup fail.
+	notADir := filepath.Join(dir, "file")
+	isNil(os.WriteFile(notADir, []byte("x"), 0644), t)
+
+	l := &Logger{
+		Filename:   logFile(dir),
+		BackupDir:  notADir,
+		MaxBackups: 1,
+	}
+	defer l.Close()
+
+	isNil(l.Err(), t)
+
+	l.millRunReported()
+	err := l.Err()
+	var bgErr *BackgroundError
+	assert(errors.As(err, &bgErr), t, "expected a *BackgroundError, got %v", err)
+	equals("cleanup", bgErr.Op, t)
+	notNil(bgErr.Err, t)
+
+	// Err forgets what it returned.
+	isNil(l.Err(), t)
+
+	l.millRunReported()
+	l.millRunReported()
+	err = l.Err()
+	errs, ok := err.(BackgroundErrors)
+	assert(ok, t, "expected BackgroundErrors, got %T", err)
+	equals(2, len(errs), t)
+	assert(errors.As(err, &bgErr), t, "expected errors.As to find a *BackgroundError")
+}
+
+func TestErr_KeepsMostRecent(t *testing.T) {
+	l := &Logger{Filename: "unused.log"}
+	for i := 0; i < maxPendingErrors+5; i++ {
+		l.backgroundError("compress", errors.New("boom"), "compression failed")
+	}
+	errs := l.Err().(BackgroundErrors)
+	equals(maxPendingErrors, len(errs), t)
+	equals(int64(maxPendingErrors+5), l.Stats().BackgroundErrors, t)
+}
@@ -0,0 +1,88 @@
+package timberjack
+
+import (
+	"os"
+	"time"
+)
+
+// pendingRotation is a rotation that exceeded RotationTimeout while waiting
+// for the filesystem.
+type pendingRotation struct {
+	probed chan error // receives the result of probeFilesystem
+	reason string
+}
+
+// rotateWithBudget performs a rotation that may block the caller for at most
+// RotationTimeout waiting for the filesystem. Creating the directories and
+// stat'ing the log file, where a hung mount blocks, run in the background;
+// the rotation itself runs under l.mu once they have returned, so the active
+// file is never renamed while writes still go to it. If the filesystem takes
+// longer than the budget, the old file stays active and the rotation is
+// performed later by finishPendingRotation. It expects l.mu to be held.
+func (l *Logger) rotateWithBudget(reason string) error {
+	if l.pendingRotation != nil {
+		// A previous attempt is still waiting; don't start another one.
+		return l.finishPendingRotation()
+	}
+
+	probed := make(chan error, 1)
+	dir, backupDir, name := l.dir(), l.backupDir(), l.filename()
+	go func() {
+		probed <- probeFilesystem(dir, backupDir, name)
+	}()
+
+	timer := time.NewTimer(l.RotationTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-probed:
+		if err != nil {
+			return err
+		}
+		return l.rotateNow(reason)
+	case <-timer.C:
+		l.pendingRotation = &pendingRotation{probed: probed, reason: reason}
+		l.emit(Event{Type: EventRotationTimeout, File: name, Reason: reason})
+		return nil
+	}
+}
+
+// probeFilesystem creates the log and backup directories and stats the log
+// file, the operations of a rotation that block on an unresponsive mount.
+func probeFilesystem(dir, backupDir, name string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+	if _, err := osStat(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// finishPendingRotation performs a rotation that exceeded RotationTimeout,
+// if the filesystem has responded in the meantime. It expects l.mu to be held.
+func (l *Logger) finishPendingRotation() error {
+	if l.pendingRotation == nil {
+		return nil
+	}
+	select {
+	case err := <-l.pendingRotation.probed:
+		reason := l.pendingRotation.reason
+		l.pendingRotation = nil
+		if err != nil {
+			return err
+		}
+		return l.rotateNow(reason)
+	default:
+		return nil // still waiting; keep writing to the old file
+	}
+}
+
+// abandonPendingRotation drops a rotation still waiting for the filesystem
+// when the Logger is closed. It expects l.mu to be held.
+func (l *Logger) abandonPendingRotation() {
+	l.pendingRotation = nil
+}
@@ -0,0 +1,142 @@
+package timberjack
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowStat makes osStat of name block until the returned release function
+// is called, once arm has been called. The returned restore function undoes
+// the mock.
+func slowStat(name string) (arm, release, restore func()) {
+	var armed int32
+	ch := make(chan struct{})
+	origStat := osStat
+	osStat = func(path string) (os.FileInfo, error) {
+		if path == name && atomic.LoadInt32(&armed) == 1 {
+			<-ch
+		}
+		return os.Stat(path)
+	}
+	return func() { atomic.StoreInt32(&armed, 1) },
+		func() { close(ch) },
+		func() { osStat = origStat }
+}
+
+func TestRotationTimeout_SlowFilesystem(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestRotationTimeout_SlowFilesystem", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	arm, release, restore := slowStat(filename)
+	defer restore()
+
+	l := &Logger{
+		Filename:        filename,
+		MaxSize:         100,
+		RotationTimeout: 10 * time.Millisecond,
+		CleanupInterval: time.Millisecond,
+		MaxBackups:      1,
+	}
+	defer l.Close()
+	events := l.Events()
+
+	_, err := l.Write([]byte("a"))
+	isNil(err, t)
+
+	// The filesystem doesn't respond, so Rotate gives up after the budget
+	// and keeps the old file in place.
+	arm()
+	equals(ErrRotationPending, l.Rotate(), t)
+	e := <-events
+	equals(EventRotationTimeout, e.Type, t)
+	equals("size", e.Reason, t)
+	notNil(l.pendingRotation, t)
+
+	_, err = l.Write([]byte("b"))
+	isNil(err, t)
+	equals(ErrRotationPending, l.Rotate(), t)
+
+	// Background cleanup passes see no backup to remove.
+	time.Sleep(20 * time.Millisecond)
+	existsWithContent(filename, []byte("ab"), t)
+	fileCount(dir, 1, t)
+
+	// Once the filesystem responds, the next write performs the rotation.
+	release()
+	probed := func() bool {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return len(l.pendingRotation.probed) > 0
+	}
+	deadline := time.Now().Add(time.Second)
+	for !probed() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	_, err = l.Write([]byte("c"))
+	isNil(err, t)
+	isNil(l.pendingRotation, t)
+	existsWithContent(filename, []byte("c"), t)
+	existsWithContent(backupFileWithReason(dir, "size"), []byte("ab"), t)
+}
+
+func TestRotationTimeout_FastRotation(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestRotationTimeout_FastRotation", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{
+		Filename:        filename,
+		MaxSize:         100,
+		RotationTimeout: time.Second,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("a"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	isNil(l.pendingRotation, t)
+
+	_, err = l.Write([]byte("b"))
+	isNil(err, t)
+	existsWithContent(filename, []byte("b"), t)
+	existsWithContent(backupFileWithReason(dir, "size"), []byte("a"), t)
+}
+
+func TestRotationTimeout_AbandonedOnClose(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestRotationTimeout_AbandonedOnClose", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	arm, release, restore := slowStat(filename)
+	defer restore()
+
+	l := &Logger{
+		Filename:        filename,
+		MaxSize:         100,
+		RotationTimeout: 10 * time.Millisecond,
+	}
+	_, err := l.Write([]byte("a"))
+	isNil(err, t)
+	arm()
+	equals(ErrRotationPending, l.Rotate(), t)
+	pending := l.pendingRotation
+	isNil(l.Close(), t)
+	isNil(l.pendingRotation, t)
+	existsWithContent(filename, []byte("a"), t)
+
+	// Let the abandoned probe finish before osStat is restored.
+	release()
+	<-pending.probed
+}
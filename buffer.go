@@ -0,0 +1,84 @@
+package timberjack
+
+import (
+	"bufio"
+	"time"
+)
+
+// writeFile writes p to the active file, through a buffer of BufferSize
+// bytes if that is set. It expects l.mu to be held and the file to be open.
+func (l *Logger) writeFile(p []byte) (int, error) {
+	if l.BufferSize <= 0 {
+		return l.file.Write(p)
+	}
+	if l.buf == nil {
+		l.buf = bufio.NewWriterSize(l.file, l.BufferSize)
+	}
+	return l.buf.Write(p)
+}
+
+// flushBuffer writes what is buffered to the active file. It expects l.mu to
+// be held.
+func (l *Logger) flushBuffer() error {
+	if l.buf == nil {
+		return nil
+	}
+	return l.buf.Flush()
+}
+
+// Flush writes records buffered by BufferSize, and those staged by
+// WriteShards, queued by AsyncQueueSize or collected by CoalesceWindow, to
+// the log file. It is called on every FlushInterval, on rotation and on
+// Close, so calling it is only needed to make records visible to readers of
+// the file sooner.
+func (l *Logger) Flush() error {
+	l.flushStaged()
+	l.flushQueue()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushCoalesced()
+	return l.flushBuffer()
+}
+
+// ensureFlushLoopRunning starts the goroutine flushing the buffer if
+// BufferSize and FlushInterval are configured. It expects l.mu to be held.
+func (l *Logger) ensureFlushLoopRunning() {
+	if l.BufferSize <= 0 || l.FlushInterval <= 0 {
+		return
+	}
+	l.startFlushOnce.Do(func() {
+		l.flushQuitCh = make(chan struct{})
+		go l.runFlush(l.flushQuitCh)
+	})
+}
+
+// runFlush flushes the buffer every FlushInterval, until quit is closed or
+// the Logger's Context ends.
+func (l *Logger) runFlush(quit chan struct{}) {
+	ticker := time.NewTicker(l.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.Lock()
+			if err := l.flushBuffer(); err != nil {
+				l.backgroundError("flush", err, "failed to flush log file")
+			}
+			l.mu.Unlock()
+		case <-quit:
+			return
+		case <-l.context().Done():
+			return
+		}
+	}
+}
+
+// stopFlushLoop signals the flush goroutine to exit.
+// It expects l.mu to be held.
+func (l *Logger) stopFlushLoop() {
+	if l.flushQuitCh != nil {
+		close(l.flushQuitCh)
+		l.flushQuitCh = nil
+	}
+}
@@ -0,0 +1,62 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBufferSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestBufferSize", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 100, BufferSize: 6, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	// Records stay in memory until the buffer fills or Flush is called. A
+	// full buffer is written in one piece.
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	existsWithContent(filename, []byte{}, t)
+	_, err = l.Write([]byte("foo!"))
+	isNil(err, t)
+	existsWithContent(filename, []byte("boo!fo"), t)
+	_, err = l.Write([]byte("bar"))
+	isNil(err, t)
+	isNil(l.Flush(), t)
+	existsWithContent(filename, []byte("boo!foo!bar"), t)
+
+	// Buffered records are written before a rotation.
+	_, err = l.Write([]byte("baz"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	existsWithContent(backupFileWithReason(dir, "size"), []byte("boo!foo!barbaz"), t)
+
+	// And on Close.
+	_, err = l.Write([]byte("qux"))
+	isNil(err, t)
+	isNil(l.Close(), t)
+	existsWithContent(filename, []byte("qux"), t)
+}
+
+func TestFlushInterval(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestFlushInterval", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 10, BufferSize: 100, FlushInterval: 10 * time.Millisecond}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	<-time.After(100 * time.Millisecond)
+	existsWithContent(filename, []byte("boo!"), t)
+}
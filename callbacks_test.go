@@ -0,0 +1,63 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOnRotate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestOnRotate", t)
+	defer os.RemoveAll(dir)
+
+	var rotated, reasons []string
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          10,
+		BackupTimeFormat: backupTimeFormat,
+		OnRotate: func(oldPath, reason string) {
+			rotated = append(rotated, oldPath)
+			reasons = append(reasons, reason)
+		},
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.RotateWithReason("manual"), t)
+
+	equals([]string{backupFileWithReason(dir, "manual")}, rotated, t)
+	equals([]string{"manual"}, reasons, t)
+	existsWithContent(rotated[0], []byte("boo!"), t)
+}
+
+func TestOnError(t *testing.T) {
+	dir := makeTempDir("TestOnError", t)
+	defer os.RemoveAll(dir)
+
+	// A backup directory that can't be listed makes cleanup fail.
+	notADir := filepath.Join(dir, "file")
+	isNil(os.WriteFile(notADir, []byte("x"), 0644), t)
+
+	var ops []string
+	var errs []error
+	l := &Logger{
+		Filename:   logFile(dir),
+		BackupDir:  notADir,
+		MaxBackups: 1,
+		OnError: func(op string, err error) {
+			ops = append(ops, op)
+			errs = append(errs, err)
+		},
+	}
+	defer l.Close()
+
+	l.millRunReported()
+	equals([]string{"cleanup"}, ops, t)
+	notNil(errs[0], t)
+	equals(int64(1), l.Stats().BackgroundErrors, t)
+}
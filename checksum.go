@@ -0,0 +1,162 @@
+package timberjack
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumSuffix marks the sidecar file holding a backup's SHA-256 digest.
+const checksumSuffix = ".sha256"
+
+// ChecksumError is returned by VerifyBackups when backups fail verification.
+type ChecksumError struct {
+	// Mismatched lists the backups whose content doesn't match their
+	// checksum sidecar.
+	Mismatched []string
+
+	// Missing lists the backups without a checksum sidecar.
+	Missing []string
+}
+
+// Error implements error.
+func (e *ChecksumError) Error() string {
+	var parts []string
+	if len(e.Mismatched) > 0 {
+		parts = append(parts, fmt.Sprintf("checksum mismatch for %s", strings.Join(e.Mismatched, ", ")))
+	}
+	if len(e.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("no checksum for %s", strings.Join(e.Missing, ", ")))
+	}
+	return "timberjack: " + strings.Join(parts, "; ")
+}
+
+// VerifyBackups checks every backup against its checksum sidecar, written
+// when Checksums is set. It returns a *ChecksumError listing the backups
+// whose content doesn't match and those without a sidecar, e.g. because
+// they were created before Checksums was set or since the last cleanup
+// pass, or another error if the backups can't be read.
+func (l *Logger) VerifyBackups() error {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	unlockNumbering := l.lockNumbering()
+	defer unlockNumbering()
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return err
+	}
+
+	var result ChecksumError
+	for _, f := range files {
+		path := filepath.Join(l.backupDir(), f.Name())
+		want, err := readChecksum(path + checksumSuffix)
+		if os.IsNotExist(err) {
+			result.Missing = append(result.Missing, f.Name())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		got, err := fileChecksum(path)
+		if err != nil {
+			return err
+		}
+		if got != want {
+			result.Mismatched = append(result.Mismatched, f.Name())
+		}
+	}
+	if len(result.Mismatched) > 0 || len(result.Missing) > 0 {
+		return &result
+	}
+	return nil
+}
+
+// updateChecksums writes the checksum sidecars of backups that don't have
+// one yet and removes those left behind by backups that are gone. It
+// expects l.millMu to be held.
+func (l *Logger) updateChecksums() {
+	unlockNumbering := l.lockNumbering()
+	defer unlockNumbering()
+
+	entries, err := l.backupEntries()
+	if err != nil {
+		return
+	}
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		path := filepath.Join(l.backupDir(), f.Name())
+		if _, err := os.Stat(path + checksumSuffix); err == nil {
+			continue
+		}
+		if err := writeChecksum(path); err != nil {
+			l.backgroundError("checksum", err, "failed to write checksum of %s", f.Name())
+		}
+	}
+	for _, e := range entries {
+		if !strings.HasSuffix(e.rel, checksumSuffix) {
+			continue
+		}
+		backup := strings.TrimSuffix(e.rel, checksumSuffix)
+		if _, err := os.Stat(filepath.Join(l.backupDir(), backup)); !os.IsNotExist(err) {
+			continue
+		}
+		if err := osRemove(filepath.Join(l.backupDir(), e.rel)); err == nil {
+			l.removeEmptyLayoutDirs(e.rel)
+		}
+	}
+}
+
+// writeChecksum writes the sidecar of the file at path in the format of
+// sha256sum, so that it can also be checked with `sha256sum -c`.
+func writeChecksum(path string) error {
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	tmp := path + checksumSuffix + tempSuffix
+	if err := os.WriteFile(tmp, []byte(sum+"  "+filepath.Base(path)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path+checksumSuffix)
+}
+
+// readChecksum returns the digest recorded in the sidecar at path.
+func readChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+// fileChecksum returns the hex-encoded SHA-256 digest of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
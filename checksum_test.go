@@ -0,0 +1,47 @@
+package timberjack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChecksums(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestChecksums", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Checksums: true, CompressAfter: 36 * time.Hour, Compress: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	newFakeTime()
+	name := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(name, []byte("boo!"), 0644), t)
+	isNil(l.millRunOnce(), t)
+
+	sum := "dba7744f3086677fef3e3f281e7a5e567315cd53d4facd66f54cad065227f38e" // sha256 of "boo!"
+	existsWithContent(name+checksumSuffix, []byte(sum+"  "+filepath.Base(name)+"\n"), t)
+	isNil(l.VerifyBackups(), t)
+
+	// Once compressed, the backup's sidecar is replaced.
+	newFakeTime()
+	isNil(l.millRunOnce(), t)
+	notExist(name+checksumSuffix, t)
+	exists(name+compressSuffix+checksumSuffix, t)
+	isNil(l.VerifyBackups(), t)
+
+	// Tampering and missing sidecars are reported.
+	isNil(os.WriteFile(name+compressSuffix, []byte("tampered"), 0644), t)
+	newFakeTime()
+	other := backupFileWithReason(dir, "time")
+	isNil(os.WriteFile(other, []byte("new"), 0644), t)
+	err := l.VerifyBackups()
+	var ce *ChecksumError
+	assert(errors.As(err, &ce), t, "expected a ChecksumError, got %v", err)
+	equals([]string{filepath.Base(name) + compressSuffix}, ce.Mismatched, t)
+	equals([]string{filepath.Base(other)}, ce.Missing, t)
+}
@@ -0,0 +1,60 @@
+package timberjack
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// writeChunks writes p, which is larger than MaxSize, across as many files
+// as needed (ChunkOversizeWrites). It expects l.mu to be held.
+func (l *Logger) writeChunks(p []byte) (n int, err error) {
+	for n < len(p) {
+		if l.file == nil {
+			if err := l.prepareWrite(0); err != nil {
+				return n, err
+			}
+		}
+		rest := p[n:]
+		size := l.chunkLen(rest, l.max()-l.size)
+		if size == 0 {
+			if l.size > 0 {
+				// No room, or no boundary in it: continue in a new file.
+				if err := l.rotate("size"); err != nil {
+					return n, fmt.Errorf("size rotation failed: %w", err)
+				}
+				continue
+			}
+			size = len(rest)
+			if int64(size) > l.max() {
+				size = int(l.max())
+			}
+		}
+		m, err := l.write(rest[:size])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// chunkLen returns the length of the next chunk of p that fits in room
+// bytes: all of p if it fits, otherwise up to the last ChunkBoundary within
+// room, or room itself without a ChunkBoundary. It returns 0 if no chunk
+// fits.
+func (l *Logger) chunkLen(p []byte, room int64) int {
+	if int64(len(p)) <= room {
+		return len(p)
+	}
+	if room <= 0 {
+		return 0
+	}
+	if l.ChunkBoundary == "" {
+		return int(room)
+	}
+	i := bytes.LastIndex(p[:room], []byte(l.ChunkBoundary))
+	if i < 0 {
+		return 0
+	}
+	return i + len(l.ChunkBoundary)
+}
@@ -0,0 +1,30 @@
+package timberjack
+
+import "time"
+
+// Clock tells a Logger the time. Setting Logger.Clock to a fake clock lets
+// tests of rotation behavior control time without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the Clock used when Logger.Clock is nil.
+type systemClock struct{}
+
+// Now returns the current time.
+func (systemClock) Now() time.Time {
+	return currentTime()
+}
+
+// clock returns the Logger's Clock, or the system clock if none is set.
+func (l *Logger) clock() Clock {
+	if l.Clock != nil {
+		return l.Clock
+	}
+	return systemClock{}
+}
+
+// now returns the current time according to the Logger's Clock.
+func (l *Logger) now() time.Time {
+	return l.clock().Now()
+}
@@ -0,0 +1,44 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// stepClock is a Clock that only moves when told to.
+type stepClock struct {
+	t time.Time
+}
+
+func (c *stepClock) Now() time.Time { return c.t }
+
+func TestClock(t *testing.T) {
+	megabyte = 1
+
+	dir := makeTempDir("TestClock", t)
+	defer os.RemoveAll(dir)
+
+	clock := &stepClock{t: time.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC)}
+	l := &Logger{
+		Filename:         logFile(dir),
+		BackupTimeFormat: backupTimeFormat,
+		MaxAge:           1,
+		Clock:            clock,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.RotateWithReason("manual"), t)
+
+	backup := filepath.Join(dir, fmt.Sprintf("foobar-%s-manual.log", clock.t.Format(backupTimeFormat)))
+	existsWithContent(backup, []byte("boo!"), t)
+
+	// Moving the clock past MaxAge expires the backup.
+	clock.t = clock.t.Add(48 * time.Hour)
+	isNil(l.millRunOnce(), t)
+	notExist(backup, t)
+}
@@ -0,0 +1,61 @@
+package timberjack
+
+import "time"
+
+// defaultCoalesceMaxBytes is used when CoalesceMaxBytes is not set.
+const defaultCoalesceMaxBytes = 64 << 10
+
+// coalesceWrite collects p with the other records arriving within
+// CoalesceWindow and writes them to the file together, in order and without
+// splitting a record between two writes.
+func (l *Logger) coalesceWrite(p []byte) (int, error) {
+	if err := l.checkWriteLen(int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return 0, ErrClosed
+	}
+
+	// Keep batches from crossing a size rotation, so that a rotation never
+	// happens in the middle of one.
+	if len(l.coalesced) > 0 && l.size+int64(len(l.coalesced)+len(p)) > l.max() {
+		l.flushCoalesced()
+	}
+	l.coalesced = append(l.coalesced, p...)
+
+	limit := l.CoalesceMaxBytes
+	if limit <= 0 {
+		limit = defaultCoalesceMaxBytes
+	}
+	if len(l.coalesced) >= limit {
+		l.flushCoalesced()
+	} else if l.coalesceTimer == nil {
+		l.coalesceTimer = time.AfterFunc(l.CoalesceWindow, func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.flushCoalesced()
+		})
+	}
+	return len(p), nil
+}
+
+// flushCoalesced writes the records collected by coalesceWrite with a single
+// write. Errors are printed to stderr, since their Write calls have already
+// returned. It expects l.mu to be held.
+func (l *Logger) flushCoalesced() {
+	if l.coalesceTimer != nil {
+		l.coalesceTimer.Stop()
+		l.coalesceTimer = nil
+	}
+	if len(l.coalesced) == 0 {
+		return
+	}
+	n, err := l.write(l.coalesced)
+	if _, err = l.writeFallback(l.coalesced, n, err); err != nil {
+		l.backgroundError("write", l.classifyError(err), "coalesced write failed")
+	}
+	l.coalesced = l.coalesced[:0]
+}
@@ -0,0 +1,70 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCoalesceWindow(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCoalesceWindow", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, CoalesceWindow: 20 * time.Millisecond}
+	defer l.Close()
+
+	for _, s := range []string{"level=info ", "msg=hello ", "user=bob\n"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+	}
+	// The records are written together once the window ends.
+	notExist(logFile(dir), t)
+	time.Sleep(100 * time.Millisecond)
+	existsWithContent(logFile(dir), []byte("level=info msg=hello user=bob\n"), t)
+}
+
+func TestCoalesceWindow_Rotation(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCoalesceWindow_Rotation", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10, CoalesceWindow: time.Hour, CoalesceMaxBytes: 8, BackupTimeFormat: backupTimeFormat}
+
+	// A full batch is written without waiting for the window.
+	for _, s := range []string{"foo\n", "bar\n"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+	}
+	existsWithContent(logFile(dir), []byte("foo\nbar\n"), t)
+
+	// A batch is not split by a rotation, and Close writes the last one.
+	for _, s := range []string{"baz\n", "qux\n"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+	}
+	isNil(l.Close(), t)
+	existsWithContent(backupFileWithReason(dir, "size"), []byte("foo\nbar\n"), t)
+	existsWithContent(logFile(dir), []byte("baz\nqux\n"), t)
+}
+
+func TestCoalesceWindow_Flush(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCoalesceWindow_Flush", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, CoalesceWindow: time.Hour}
+	defer l.Close()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	isNil(l.Flush(), t)
+	existsWithContent(logFile(dir), b, t)
+}
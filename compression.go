@@ -0,0 +1,310 @@
+package timberjack
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultCopyBufferSize is the size of the buffers backups are read with for
+// compression when CompressionBufferSize isn't set, that of io.Copy.
+const defaultCopyBufferSize = 32 << 10
+
+// errWriterClosed is returned by the built-in compressors' writers after
+// Close.
+var errWriterClosed = errors.New("timberjack: compressor closed")
+
+// Compressor compresses backups, for codecs other than the built-in ones
+// (see Logger.Compressor).
+type Compressor interface {
+	// Suffix returns the extension appended to the names of compressed
+	// backups, including the leading dot, e.g. ".br". It must not be empty.
+	Suffix() string
+
+	// Compress writes the compressed content of src to dst, finishing the
+	// compressed stream before it returns. It must not close dst.
+	Compress(dst io.Writer, src io.Reader) error
+}
+
+// builtinCodecs are the codecs selectable with CompressionCodec, in the
+// order their suffixes are recognized.
+var builtinCodecs = []struct {
+	name  string
+	codec Compressor
+}{
+	{"gzip", gzipCodec{}},
+	{"zstd", zstdCodec{}},
+	{"lz4", lz4Codec{}},
+}
+
+// gzipCodec compresses with gzip (.gz).
+type gzipCodec struct{}
+
+func (gzipCodec) Suffix() string { return compressSuffix }
+
+// Compress records the backup's name and modification time, i.e. its
+// rotation time, in the gzip header, as gzip does, so that tools like
+// `gzip -lN` can recover them.
+func (gzipCodec) Compress(dst io.Writer, src io.Reader) error {
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(dst)
+	if b, ok := src.(backupReader); ok {
+		if isLatin1(b.name) { // the header can't hold other names
+			zw.Name = b.name
+		}
+		zw.ModTime = b.modTime
+	}
+	return compressWith(zw, src)
+}
+
+// gzipWriters pools gzip writers, whose compression state is large, across
+// compressions.
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+// copyBuffers pools the buffers backups are read with for compression, by
+// size.
+var copyBuffers sync.Map // int -> *sync.Pool of *[]byte
+
+// getCopyBuffer returns a buffer of size bytes from copyBuffers.
+func getCopyBuffer(size int) *[]byte {
+	p, ok := copyBuffers.Load(size)
+	if !ok {
+		p, _ = copyBuffers.LoadOrStore(size, &sync.Pool{New: func() interface{} {
+			b := make([]byte, size)
+			return &b
+		}})
+	}
+	return p.(*sync.Pool).Get().(*[]byte)
+}
+
+// putCopyBuffer returns a buffer obtained from getCopyBuffer.
+func putCopyBuffer(size int, b *[]byte) {
+	if p, ok := copyBuffers.Load(size); ok {
+		p.(*sync.Pool).Put(b)
+	}
+}
+
+// backupReader is the reader codecs are given, describing the backup being
+// compressed.
+type backupReader struct {
+	io.Reader
+	name    string
+	modTime time.Time
+	bufSize int // size of the copy buffer; 0 is defaultCopyBufferSize
+}
+
+// WriteTo implements io.WriterTo, so that io.Copy reads the backup with a
+// pooled buffer instead of allocating one for every compression.
+func (b backupReader) WriteTo(w io.Writer) (int64, error) {
+	size := b.bufSize
+	if size <= 0 {
+		size = defaultCopyBufferSize
+	}
+	buf := getCopyBuffer(size)
+	defer putCopyBuffer(size, buf)
+	return io.CopyBuffer(w, struct{ io.Reader }{b.Reader}, *buf)
+}
+
+// bufferedCodec is a codec reading backups with buffers of bufSize bytes.
+type bufferedCodec struct {
+	Compressor
+	bufSize int
+}
+
+func (c bufferedCodec) Compress(dst io.Writer, src io.Reader) error {
+	if b, ok := src.(backupReader); ok {
+		b.bufSize = c.bufSize
+		src = b
+	}
+	return c.Compressor.Compress(dst, src)
+}
+
+// isLatin1 reports whether s only has ISO 8859-1 characters.
+func isLatin1(s string) bool {
+	for _, r := range s {
+		if r > 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+// zstdCodec compresses with Zstandard (.zst).
+type zstdCodec struct{}
+
+func (zstdCodec) Suffix() string { return ".zst" }
+
+func (zstdCodec) Compress(dst io.Writer, src io.Reader) error {
+	return compressWith(newZstdWriter(dst), src)
+}
+
+// lz4Codec compresses with LZ4 (.lz4).
+type lz4Codec struct{}
+
+func (lz4Codec) Suffix() string { return ".lz4" }
+
+func (lz4Codec) Compress(dst io.Writer, src io.Reader) error {
+	return compressWith(newLZ4Writer(dst), src)
+}
+
+// compressWith copies src to the compressing writer w and closes it.
+func compressWith(w io.WriteCloser, src io.Reader) error {
+	if _, err := io.Copy(w, src); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// ValidateCompressionCodec checks that CompressionCodec is empty or the
+// name of a supported codec, and that a custom Compressor has a suffix.
+func (l *Logger) ValidateCompressionCodec() error {
+	if l.Compressor != nil && l.Compressor.Suffix() == "" {
+		return errors.New("invalid Compressor: Suffix must not be empty")
+	}
+	if l.CompressionCodec == "" {
+		return nil
+	}
+	var names []string
+	for _, b := range builtinCodecs {
+		if b.name == l.CompressionCodec {
+			return nil
+		}
+		names = append(names, fmt.Sprintf("%q", b.name))
+	}
+	return fmt.Errorf("invalid CompressionCodec %q: expected one of %s", l.CompressionCodec, strings.Join(names, ", "))
+}
+
+// codec returns the codec new backups are compressed with: Compressor if
+// set, else the one named by CompressionCodec, followed by any encryption,
+// reading backups with CompressionBufferSize buffers. Invalid values fall
+// back to gzip.
+func (l *Logger) codec() Compressor {
+	c := l.compressor()
+	if enc := l.encrypter(); enc != nil {
+		c = encryptingCodec{c, enc}
+	}
+	if l.CompressionBufferSize > 0 {
+		c = bufferedCodec{c, l.CompressionBufferSize}
+	}
+	return c
+}
+
+// compressor returns the codec new backups are compressed with, before any
+// encryption.
+func (l *Logger) compressor() Compressor {
+	if l.Compressor != nil && l.Compressor.Suffix() != "" {
+		return l.Compressor
+	}
+	for _, b := range builtinCodecs {
+		if b.name == l.CompressionCodec {
+			return b.codec
+		}
+	}
+	return gzipCodec{}
+}
+
+// compressedSuffixes returns the suffixes of compressed backups. The
+// suffixes of all built-in codecs are recognized, so that backups compressed
+// before CompressionCodec was changed are still managed; a custom
+// Compressor's suffix comes first, so that it wins over a built-in suffix it
+// ends with. Each is also recognized followed by the suffix of an
+// encryption, for encrypted backups.
+func (l *Logger) compressedSuffixes() []string {
+	bases := make([]string, 0, len(builtinCodecs)+1)
+	if l.Compressor != nil && l.Compressor.Suffix() != "" {
+		bases = append(bases, l.Compressor.Suffix())
+	}
+	for _, b := range builtinCodecs {
+		bases = append(bases, b.codec.Suffix())
+	}
+	encrypted := l.encryptedSuffixes()
+	suffixes := make([]string, 0, len(bases)*(len(encrypted)+1))
+	for _, base := range bases {
+		for _, enc := range encrypted {
+			suffixes = append(suffixes, base+enc)
+		}
+		suffixes = append(suffixes, base)
+	}
+	return suffixes
+}
+
+// compressedSuffix returns the compression suffix name ends with, or "" if
+// it isn't compressed.
+func (l *Logger) compressedSuffix(name string) string {
+	for _, suffix := range l.compressedSuffixes() {
+		if strings.HasSuffix(name, suffix) {
+			return suffix
+		}
+	}
+	return ""
+}
+
+// compressedExists reports whether a compressed form of the uncompressed
+// backup at path exists.
+func (l *Logger) compressedExists(path string) bool {
+	for _, suffix := range l.compressedSuffixes() {
+		if _, err := os.Stat(path + suffix); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isCompressed reports whether name is a compressed backup.
+func (l *Logger) isCompressed(name string) bool {
+	return l.compressedSuffix(name) != ""
+}
+
+// trimCompressed returns name without its compression suffix.
+func (l *Logger) trimCompressed(name string) string {
+	return strings.TrimSuffix(name, l.compressedSuffix(name))
+}
+
+// compressedName returns the name of the compressed form of a backup. The
+// compressed file keeps the full backup name, including its timestamp and
+// rotation reason, and only appends the codec's suffix.
+func (l *Logger) compressedName(name string) string {
+	return name + l.codec().Suffix()
+}
+
+// CompressPending synchronously compresses every backup that isn't
+// compressed yet, with the configured codec and whether or not Compress is
+// set, e.g. before shipping logs or before a container shuts down. Backups
+// whose compressed form is already complete are left for the next cleanup
+// pass to remove. It returns the first compression failure, or the
+// Logger's context error if it ended first.
+func (l *Logger) CompressPending() error {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	unlockNumbering := l.lockNumbering()
+	files, err := l.oldLogFiles()
+	unlockNumbering()
+	if err != nil {
+		return err
+	}
+
+	var pending []logInfo
+	for _, f := range files {
+		if l.isCompressed(f.Name()) || l.adoptedCompressed(f.Name()) {
+			continue
+		}
+		if c := l.compressedForm(files, f.Name()); c != "" && l.completeCompressed(filepath.Join(l.backupDir(), c), f.Size()) {
+			continue
+		}
+		pending = append(pending, f)
+	}
+	failed, stopped := l.compressFiles(pending)
+	if stopped != nil {
+		return stopped
+	}
+	return failed
+}
@@ -0,0 +1,479 @@
+package timberjack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// upperCompressor is a stand-in for a custom codec: it "compresses" by
+// upper-casing the content.
+type upperCompressor struct{ suffix string }
+
+func (c upperCompressor) Suffix() string { return c.suffix }
+
+func (upperCompressor) Compress(dst io.Writer, src io.Reader) error {
+	b, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	_, err = dst.Write(bytes.ToUpper(b))
+	return err
+}
+
+func TestCompressor(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressor", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBackups: 2, Compress: true, CompressionCodec: "zstd", Compressor: upperCompressor{".up"}, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+	isNil(l.ValidateCompressionCodec(), t)
+
+	// A gzip backup from before the Compressor was set is still managed.
+	gz := backupFileWithReason(dir, "size") + compressSuffix
+	isNil(os.WriteFile(gz, []byte("old"), 0644), t)
+	newFakeTime()
+
+	var names []string
+	for _, content := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+		names = append(names, backupFileWithReason(dir, "size"))
+	}
+	isNil(l.millRunOnce(), t)
+
+	notExist(gz, t)
+	existsWithContent(names[0]+".up", []byte("ONE!"), t)
+	existsWithContent(names[1]+".up", []byte("TWO!"), t)
+	notExist(names[0], t)
+	notExist(names[0]+".zst", t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	assert(backups[0].Compressed, t, "expected a compressed backup")
+	equals("size", backups[0].Reason, t)
+	equals(filepath.Base(names[1])+".up", backups[0].Name, t)
+
+	l.Compressor = upperCompressor{}
+	notNil(l.ValidateCompressionCodec(), t)
+	equals(".zst", l.codec().Suffix(), t)
+}
+
+// slowCompressor is a Compressor that records how many compressions run at
+// once.
+type slowCompressor struct {
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (*slowCompressor) Suffix() string { return compressSuffix }
+
+func (c *slowCompressor) Compress(dst io.Writer, src io.Reader) error {
+	c.mu.Lock()
+	c.running++
+	if c.running > c.max {
+		c.max = c.running
+	}
+	c.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	_, err := io.Copy(dst, src)
+	return err
+}
+
+func TestCompressConcurrency(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressConcurrency", t)
+	defer os.RemoveAll(dir)
+
+	c := &slowCompressor{}
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, Compressor: c, CompressConcurrency: 2, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	var names []string
+	for i := 0; i < 5; i++ {
+		newFakeTime()
+		name := backupFileWithReason(dir, "size")
+		isNil(os.WriteFile(name, []byte("boo!"), 0644), t)
+		names = append(names, name)
+	}
+	isNil(l.millRunOnce(), t)
+
+	for _, name := range names {
+		notExist(name, t)
+		existsWithContent(name+compressSuffix, []byte("boo!"), t)
+	}
+	equals(2, c.max, t)
+	equals(int64(5), l.Stats().Compressions, t)
+}
+
+func TestCompressPending(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressPending", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	var names []string
+	for i := 0; i < 2; i++ {
+		newFakeTime()
+		name := backupFileWithReason(dir, "size")
+		isNil(os.WriteFile(name, []byte("boo!"), 0644), t)
+		names = append(names, name)
+	}
+	isNil(l.CompressPending(), t)
+
+	// Backups are compressed even though Compress isn't set.
+	for _, name := range names {
+		notExist(name, t)
+		exists(name+compressSuffix, t)
+	}
+	equals(int64(2), l.Stats().Compressions, t)
+
+	// A leftover next to its complete compressed form isn't compressed again.
+	isNil(os.WriteFile(names[0], []byte("boo!"), 0644), t)
+	isNil(l.CompressPending(), t)
+	exists(names[0], t)
+	equals(int64(2), l.Stats().Compressions, t)
+}
+
+func TestCompressAfter(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressAfter", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, CompressAfter: time.Hour, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	newFakeTime()
+	name := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(name, []byte("boo!"), 0644), t)
+
+	// Still in its grace period.
+	isNil(l.millRunOnce(), t)
+	exists(name, t)
+	notExist(name+compressSuffix, t)
+	due := fakeCurrentTime.Truncate(time.Millisecond).Add(time.Hour) // backup names have millisecond precision
+	assert(l.compressDue.Equal(due), t, "expected compression due at %v, got %v", due, l.compressDue)
+
+	fakeCurrentTime = fakeCurrentTime.Add(time.Hour)
+	isNil(l.millRunOnce(), t)
+	notExist(name, t)
+	exists(name+compressSuffix, t)
+	assert(l.compressDue.IsZero(), t, "expected no deferred compression")
+}
+
+func TestCompressAfter_Timer(t *testing.T) {
+	currentTime = time.Now
+	defer func() { currentTime = fakeTime }()
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressAfter_Timer", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, CompressAfter: 200 * time.Millisecond}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	<-time.After(100 * time.Millisecond)
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	assert(!backups[0].Compressed, t, "expected the backup to be uncompressed during the grace period")
+
+	// The mill wakes up when the grace period is over.
+	<-time.After(400 * time.Millisecond)
+	backups, err = l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	assert(backups[0].Compressed, t, "expected the backup to be compressed after the grace period")
+}
+
+func TestCompressionBytesPerSec(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressionBytesPerSec", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, CompressConcurrency: 2, CompressionBytesPerSec: 100 << 10, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	// 2 x 25 KiB at 100 KiB/s, shared by both workers: at least ~0.25s
+	// once the first read's burst is discounted.
+	var names []string
+	for i := 0; i < 2; i++ {
+		newFakeTime()
+		name := backupFileWithReason(dir, "size")
+		isNil(os.WriteFile(name, bytes.Repeat([]byte("x"), 25<<10), 0644), t)
+		names = append(names, name)
+	}
+	start := time.Now()
+	isNil(l.millRunOnce(), t)
+	elapsed := time.Since(start)
+
+	for _, name := range names {
+		notExist(name, t)
+		exists(name+compressSuffix, t)
+	}
+	assert(elapsed >= 200*time.Millisecond, t, "compression took %v, expected it to be throttled", elapsed)
+}
+
+func TestUncompressedBackups(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestUncompressedBackups", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, UncompressedBackups: 2, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		newFakeTime()
+		name := backupFileWithReason(dir, "size")
+		isNil(os.WriteFile(name, []byte("boo!"), 0644), t)
+		names = append(names, name)
+	}
+	isNil(l.millRunOnce(), t)
+
+	notExist(names[0], t)
+	exists(names[0]+compressSuffix, t)
+	for _, name := range names[1:] {
+		exists(name, t)
+		notExist(name+compressSuffix, t)
+	}
+
+	// A newer backup pushes the oldest plain one out.
+	newFakeTime()
+	isNil(os.WriteFile(backupFileWithReason(dir, "size"), []byte("boo!"), 0644), t)
+	isNil(l.millRunOnce(), t)
+	notExist(names[1], t)
+	exists(names[1]+compressSuffix, t)
+	exists(names[2], t)
+}
+
+func TestCompressMinSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressMinSize", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, CompressMinSize: 10, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	newFakeTime()
+	small := backupFileWithReason(dir, "time")
+	isNil(os.WriteFile(small, []byte("boo!"), 0644), t)
+	newFakeTime()
+	large := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(large, []byte("boo! boo! boo!"), 0644), t)
+	isNil(l.millRunOnce(), t)
+
+	exists(small, t)
+	notExist(small+compressSuffix, t)
+	notExist(large, t)
+	exists(large+compressSuffix, t)
+
+	// Streamed rotations rename small files as usual.
+	l.StreamCompress = true
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	exists(backupFileWithReason(dir, "size"), t)
+	notExist(backupFileWithReason(dir, "size")+compressSuffix, t)
+}
+
+func TestGzipHeader(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestGzipHeader", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	newFakeTime()
+	name := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(name, []byte("boo!"), 0644), t)
+	rotated := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	isNil(os.Chtimes(name, rotated, rotated), t)
+	isNil(l.millRunOnce(), t)
+
+	f, err := os.Open(name + compressSuffix)
+	isNil(err, t)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	isNil(err, t)
+	equals(filepath.Base(name), zr.Name, t)
+	assert(zr.ModTime.Equal(rotated), t, "expected ModTime %v, got %v", rotated, zr.ModTime)
+}
+
+func TestCompressSyncsDirectory(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressSyncsDirectory", t)
+	defer os.RemoveAll(dir)
+
+	var synced []string
+	syncErr := error(nil)
+	osSyncDir = func(d string) error {
+		if d != dir {
+			return syncDir(d) // another test's background work
+		}
+		synced = append(synced, d)
+		return syncErr
+	}
+	defer func() { osSyncDir = syncDir }()
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10, Compress: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	isNil(l.millRunOnce(), t)
+
+	// The rename into place is made durable before the source is removed.
+	first := backupFileWithReason(dir, "size")
+	exists(first+compressSuffix, t)
+	notExist(first, t)
+	equals([]string{dir}, synced, t)
+
+	// If it can't be, the source is kept. The backup is created by hand
+	// rather than by a rotation, whose background mill pass could fail
+	// first and leave a complete pair for this pass to clean up.
+	syncErr = errors.New("sync failed")
+	newFakeTime()
+	second := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(second, []byte("boo!"), 0644), t)
+	isNil(l.millRunOnce(), t)
+	exists(second+compressSuffix, t)
+	exists(second, t)
+}
+
+// chunkRecorder is a codec recording the largest write it is given.
+type chunkRecorder struct{ largest int }
+
+func (*chunkRecorder) Suffix() string { return ".rec" }
+
+func (c *chunkRecorder) Compress(dst io.Writer, src io.Reader) error {
+	_, err := io.Copy(writerFunc(func(p []byte) (int, error) {
+		if len(p) > c.largest {
+			c.largest = len(p)
+		}
+		return dst.Write(p)
+	}), src)
+	return err
+}
+
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestCompressionBufferSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressionBufferSize", t)
+	defer os.RemoveAll(dir)
+
+	rec := &chunkRecorder{}
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, Compressor: rec, CompressionBufferSize: 4, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	newFakeTime()
+	name := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(name, []byte("boo! boo! boo!"), 0644), t)
+	isNil(l.millRunOnce(), t)
+	existsWithContent(name+".rec", []byte("boo! boo! boo!"), t)
+	equals(4, rec.largest, t)
+
+	// Pooled gzip writers start every backup with a fresh header.
+	l.Compressor = nil
+	var names []string
+	for _, content := range []string{"one!", "two!"} {
+		newFakeTime()
+		name := backupFileWithReason(dir, "size")
+		isNil(os.WriteFile(name, []byte(content), 0644), t)
+		isNil(l.millRunOnce(), t)
+		names = append(names, name)
+	}
+	for i, content := range []string{"one!", "two!"} {
+		f, err := os.Open(names[i] + compressSuffix)
+		isNil(err, t)
+		zr, err := gzip.NewReader(f)
+		isNil(err, t)
+		got, err := io.ReadAll(zr)
+		isNil(err, t)
+		isNil(f.Close(), t)
+		equals(content, string(got), t)
+		equals(filepath.Base(names[i]), zr.Name, t)
+	}
+}
+
+func TestCompressOnClose(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressOnClose", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 100, CompressOnClose: true, BackupTimeFormat: backupTimeFormat}
+
+	// A backup left uncompressed by an earlier run is compressed too.
+	newFakeTime()
+	old := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(old, []byte("old!"), 0644), t)
+
+	newFakeTime()
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.Close(), t)
+
+	notExist(filename, t)
+	notExist(old, t)
+	exists(old+compressSuffix, t)
+	final := backupFileWithReason(dir, "close")
+	notExist(final, t)
+	f, err := os.Open(final + compressSuffix)
+	isNil(err, t)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	isNil(err, t)
+	got, err := io.ReadAll(zr)
+	isNil(err, t)
+	equals("boo!", string(got), t)
+	fileCount(dir, 2, t)
+}
@@ -0,0 +1,180 @@
+package timberjack
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClose_Semantics(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestClose_Semantics", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100}
+	_, err := l.Write([]byte("foo!"))
+	isNil(err, t)
+	isNil(l.Close(), t)
+
+	_, err = l.Write([]byte("bar!"))
+	equals(ErrClosed, err, t)
+	equals(ErrClosed, l.Rotate(), t)
+	isNil(l.Close(), t) // idempotent
+
+	existsWithContent(logFile(dir), []byte("foo!"), t)
+	fileCount(dir, 1, t)
+	equals(int64(0), l.Stats().Failures[ErrorOther], t)
+}
+
+// TestConcurrentWriteRotateClose hammers a Logger with concurrent writes and
+// rotations and closes it midway. Run it with -race. Every write that
+// succeeded must be in exactly one file, unsplit, and every other write must
+// have failed with ErrClosed.
+func TestConcurrentWriteRotateClose(t *testing.T) {
+	for _, shards := range []int{0, 4} {
+		testConcurrentWriteRotateClose(t, shards)
+	}
+}
+
+func testConcurrentWriteRotateClose(t *testing.T, shards int) {
+	// Every rotation needs a distinct backup name.
+	var clockMu sync.Mutex
+	now := fakeTime()
+	currentTime = func() time.Time {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	defer func() { currentTime = fakeTime }()
+	megabyte = 1
+
+	dir := makeTempDir("TestConcurrentWriteRotateClose", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 1000, WriteShards: shards, BackupTimeFormat: backupTimeFormat}
+
+	const writers, perWriter = 8, 200
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		written int
+	)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				_, err := l.Write([]byte("0123456789\n"))
+				if err == ErrClosed {
+					return
+				}
+				if err != nil {
+					t.Errorf("unexpected write error: %v", err)
+					return
+				}
+				mu.Lock()
+				written++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			if err := l.Rotate(); err == ErrClosed {
+				return
+			}
+		}
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mu.Lock()
+		for written < writers*perWriter/2 {
+			mu.Unlock()
+			runtime.Gosched()
+			mu.Lock()
+		}
+		mu.Unlock()
+		isNil(l.Close(), t)
+	}()
+	wg.Wait()
+	isNil(l.Close(), t)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	isNil(err, t)
+	var content []byte
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		isNil(err, t)
+		content = append(content, b...)
+	}
+	equals(written, bytes.Count(content, []byte("0123456789\n")), t)
+	equals(written*11, len(content), t)
+}
+
+// TestWriteFast checks that concurrent writes taking the shared-lock path
+// still rotate before a file would exceed MaxSize.
+func TestWriteFast(t *testing.T) {
+	var clockMu sync.Mutex
+	now := fakeTime()
+	currentTime = func() time.Time {
+		clockMu.Lock()
+		defer clockMu.Unlock()
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	defer func() { currentTime = fakeTime }()
+	megabyte = 1
+
+	dir := makeTempDir("TestWriteFast", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	// The first write opens the file; the next one takes the fast path.
+	_, err := l.Write([]byte("0123456789"))
+	isNil(err, t)
+	n, ok, err := l.writeFast([]byte("0123456789"))
+	isNil(err, t)
+	assert(ok, t, "expected the fast path to be taken")
+	equals(10, n, t)
+
+	const writers, perWriter = 8, 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWriter; i++ {
+				if _, err := l.Write([]byte("0123456789")); err != nil {
+					t.Errorf("unexpected write error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	isNil(l.Close(), t)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	isNil(err, t)
+	total := 0
+	for _, f := range files {
+		b, err := os.ReadFile(f)
+		isNil(err, t)
+		assert(len(b) <= 100, t, "%s exceeds MaxSize: %d bytes", f, len(b))
+		total += len(b)
+	}
+	equals((writers*perWriter+2)*10, total, t)
+}
@@ -0,0 +1,133 @@
+package timberjack
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"time"
+)
+
+// configPollInterval is how often WatchConfig checks the config file for
+// changes. It is a variable so tests can shorten it.
+var configPollInterval = time.Second
+
+// ErrAlreadyWatching is returned by WatchConfig when the Logger is already
+// watching a config file.
+var ErrAlreadyWatching = errors.New("timberjack: already watching a config file")
+
+// WatchConfig applies the rotation and retention settings in the file at
+// path with Reconfigure, and then checks the file every second and applies
+// it again whenever it changes, until the Logger is closed or its Context
+// ends. This lets operators change the log policy of a running service by
+// editing a file.
+//
+// The file holds the fields of Config in one of the formats of LoadConfig,
+// chosen by its extension, or JSON if the extension is unknown. Fields left
+// out of the file keep the value they had when WatchConfig was called. An
+// EventConfigChange is emitted each time the policy changes.
+//
+// WatchConfig returns an error if the file can't be read or holds an invalid
+// configuration at the start. Later problems are reported on stderr and leave
+// the current policy in place until the file is fixed.
+func (l *Logger) WatchConfig(path string) error {
+	base := l.Config()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := l.applyConfigFile(path, data, base); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return ErrClosed
+	}
+	if l.configWatchQuitCh != nil {
+		return ErrAlreadyWatching
+	}
+	l.configWatchQuitCh = make(chan struct{})
+	go l.runConfigWatch(path, data, base, l.configWatchQuitCh)
+	return nil
+}
+
+// runConfigWatch polls the config file at path, whose last applied contents
+// are applied, until quit is closed or the Logger's Context ends.
+func (l *Logger) runConfigWatch(path string, applied []byte, base Config, quit chan struct{}) {
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	var lastErr string
+	for {
+		select {
+		case <-ticker.C:
+		case <-quit:
+			return
+		case <-l.context().Done():
+			return
+		}
+
+		data, err := os.ReadFile(path)
+		if err == nil && bytes.Equal(data, applied) {
+			continue
+		}
+		if err == nil {
+			err = l.applyConfigFile(path, data, base)
+		}
+		if errors.Is(err, ErrClosed) {
+			return
+		}
+		if err != nil {
+			// Report each problem once rather than on every poll.
+			if err.Error() != lastErr {
+				l.backgroundError("config", err, "can't apply config file %s", path)
+				lastErr = err.Error()
+			}
+			continue
+		}
+		applied, lastErr = data, ""
+	}
+}
+
+// stopConfigWatch signals the config file watcher to exit.
+// It expects l.mu to be held.
+func (l *Logger) stopConfigWatch() {
+	if l.configWatchQuitCh != nil {
+		close(l.configWatchQuitCh)
+		l.configWatchQuitCh = nil
+	}
+}
+
+// applyConfigFile reconfigures the Logger with the config file contents
+// data, on top of base, and emits an EventConfigChange if that changed the
+// policy.
+func (l *Logger) applyConfigFile(path string, data []byte, base Config) error {
+	cfg, err := parseConfigFile(path, data, base)
+	if err != nil {
+		return err
+	}
+	old := l.Config()
+	if err := l.Reconfigure(cfg); err != nil {
+		return err
+	}
+	if !reflect.DeepEqual(old, l.Config()) {
+		l.emit(Event{Type: EventConfigChange, File: path})
+	}
+	return nil
+}
+
+// parseConfigFile decodes the config file contents data on top of base,
+// in the format given by the extension of path, JSON by default.
+func parseConfigFile(path string, data []byte, base Config) (Config, error) {
+	cfg := base
+	cfg.RotateAtMinutes = append([]int(nil), base.RotateAtMinutes...)
+	format := configFormat(path)
+	if format == "" {
+		format = "json"
+	}
+	if err := decodeConfig(format, data, &cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
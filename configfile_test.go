@@ -0,0 +1,86 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchConfig(t *testing.T) {
+	defer func(d time.Duration) { configPollInterval = d }(configPollInterval)
+	configPollInterval = 10 * time.Millisecond
+
+	dir := makeTempDir("TestWatchConfig", t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "log.json")
+	isNil(os.WriteFile(path, []byte(`{"maxsize": 50}`), 0644), t)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBackups: 3}
+	defer l.Close()
+	events := l.Events()
+
+	isNil(l.WatchConfig(path), t)
+	equals(Config{MaxSize: 50, MaxBackups: 3}, l.Config(), t)
+	equals(EventConfigChange, (<-events).Type, t)
+	equals(ErrAlreadyWatching, l.WatchConfig(path), t)
+
+	// Fields removed from the file go back to their original values.
+	isNil(os.WriteFile(path, []byte(`{"maxbackups": 5, "rotateAtMinutes": [0, 30]}`), 0644), t)
+	select {
+	case e := <-events:
+		equals(EventConfigChange, e.Type, t)
+		equals(path, e.File, t)
+	case <-time.After(5 * time.Second):
+		t.Fatal("config change not applied")
+	}
+	equals(Config{MaxSize: 100, MaxBackups: 5, RotateAtMinutes: []int{0, 30}}, l.Config(), t)
+
+	// An invalid file leaves the policy in place.
+	isNil(os.WriteFile(path, []byte(`{"maxbackups": -1}`), 0644), t)
+	time.Sleep(50 * time.Millisecond)
+	equals(5, l.Config().MaxBackups, t)
+}
+
+func TestWatchConfig_Invalid(t *testing.T) {
+	dir := makeTempDir("TestWatchConfig_Invalid", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100}
+	defer l.Close()
+
+	notNil(l.WatchConfig(filepath.Join(dir, "missing.json")), t)
+
+	path := filepath.Join(dir, "log.json")
+	isNil(os.WriteFile(path, []byte(`{"maxsise": 50}`), 0644), t)
+	notNil(l.WatchConfig(path), t)
+	equals(100, l.MaxSize, t)
+}
+
+func TestParseYAMLConfig(t *testing.T) {
+	data := `# log policy
+maxsize: 250
+maxbackups: "7"
+compress: true   # gzip backups
+rotationinterval: 6h
+rotateAtMinutes:
+  - 0
+  - 30
+`
+	cfg, err := parseConfigFile("log.yaml", []byte(data), Config{MaxAge: 3})
+	isNil(err, t)
+	equals(Config{MaxSize: 250, MaxAge: 3, MaxBackups: 7, Compress: true, RotationInterval: 6 * time.Hour, RotateAtMinutes: []int{0, 30}}, cfg, t)
+
+	cfg, err = parseConfigFile("log.yml", []byte("rotateatminutes: [15, 45]\n"), Config{})
+	isNil(err, t)
+	equals([]int{15, 45}, cfg.RotateAtMinutes, t)
+
+	cfg, err = parseConfigFile("log.yaml", []byte("maxsize: 1G\n"), Config{})
+	isNil(err, t)
+	equals(1024, cfg.MaxSize, t)
+
+	_, err = parseConfigFile("log.yaml", []byte("maxsise: 250\n"), Config{})
+	notNil(err, t)
+	_, err = parseConfigFile("log.yaml", []byte("compress: maybe\n"), Config{})
+	notNil(err, t)
+}
@@ -0,0 +1,28 @@
+package timberjack
+
+import (
+	"context"
+	"io"
+)
+
+// context returns the Logger's Context, or context.Background if none is set.
+func (l *Logger) context() context.Context {
+	if l.Context != nil {
+		return l.Context
+	}
+	return context.Background()
+}
+
+// contextReader is an io.Reader that fails with the context's error once the
+// context is done, so long copies can be cancelled between reads.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
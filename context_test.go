@@ -0,0 +1,83 @@
+package timberjack
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fortytw2/leaktest"
+)
+
+func TestContext_CancelStopsBackgroundWork(t *testing.T) {
+	currentTime = time.Now
+	defer func() { currentTime = fakeTime }()
+
+	dir := makeTempDir("TestContext_CancelStopsBackgroundWork", t)
+	defer os.RemoveAll(dir)
+
+	// Registered first so it runs last, after cancellation.
+	defer leaktest.CheckTimeout(t, 2*time.Second)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Logger{
+		Filename:          logFile(dir),
+		Compress:          true,
+		RotateAtMinutes:   []int{0},
+		IntegrityInterval: time.Hour,
+		Context:           ctx,
+	}
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	l.mill()
+
+	// Cancelling the context, without calling Close, stops every goroutine.
+	cancel()
+
+	// Writes keep working.
+	_, err = l.Write([]byte("still here"))
+	isNil(err, t)
+
+	l.mu.Lock()
+	isNil(l.closeFile(), t)
+	l.mu.Unlock()
+}
+
+func TestCompressLogFileContext_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "backup.log")
+	dst := src + compressSuffix
+	isNil(os.WriteFile(src, []byte("data"), 0644), t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := compressLogFileContext(ctx, gzipCodec{}, src, dst)
+	assert(errors.Is(err, context.Canceled), t, "expected context.Canceled, got %v", err)
+	exists(src, t)
+	notExist(dst, t)
+}
+
+func TestMillRunOnce_ContextCancelled(t *testing.T) {
+	currentTime = fakeTime
+
+	dir := makeTempDir("TestMillRunOnce_ContextCancelled", t)
+	defer os.RemoveAll(dir)
+
+	backup := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(backup, []byte("data"), 0644), t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+		Context:  ctx,
+	}
+
+	err := l.millRunOnce()
+	assert(errors.Is(err, context.Canceled), t, "expected context.Canceled, got %v", err)
+	exists(backup, t)
+}
@@ -0,0 +1,68 @@
+package timberjack
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-x * * * *",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	base := time.Date(2025, 5, 14, 10, 37, 12, 0, time.UTC) // a Wednesday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 5, 14, 10, 38, 0, 0, time.UTC)},
+		{"0 0 * * *", time.Date(2025, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 5, 14, 11, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 5, 14, 10, 45, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2025, 5, 14, 10, 45, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2025, 5, 14, 13, 0, 0, 0, time.UTC)},
+		{"30 6 * * 1-5", time.Date(2025, 5, 15, 6, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 5, 18, 0, 0, 0, 0, time.UTC)}, // Sunday
+		{"@weekly", time.Date(2025, 5, 18, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"@yearly", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Day-of-month and day-of-week restricted: either matches.
+		{"0 0 20 * 5", time.Date(2025, 5, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.expr, err)
+		}
+		if got := c.Next(base); !got.Equal(tt.want) {
+			t.Errorf("%q: Next(%v) = %v, want %v", tt.expr, base, got, tt.want)
+		}
+	}
+}
+
+func TestCronNext_Location(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	c, err := parseCron("0 0 * * *")
+	isNil(err, t)
+	got := c.Next(time.Date(2025, 5, 14, 23, 0, 0, 0, time.UTC).In(loc))
+	equals(time.Date(2025, 5, 16, 0, 0, 0, 0, loc).Unix(), got.Unix(), t)
+}
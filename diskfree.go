@@ -0,0 +1,100 @@
+package timberjack
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errDiskSpaceUnsupported is returned by diskSpace on platforms where free
+// disk space can't be determined.
+var errDiskSpaceUnsupported = errors.New("free disk space is not supported on this platform")
+
+// diskSpace exists so it can be mocked out by tests. It returns the bytes
+// available to unprivileged users and the total size of the filesystem
+// containing path.
+var diskSpace = statDisk
+
+// diskFreeThreshold is a parsed MinDiskFree.
+type diskFreeThreshold struct {
+	bytes   uint64
+	percent float64
+}
+
+// required returns the number of free bytes the threshold asks for on a
+// filesystem of the given total size.
+func (t diskFreeThreshold) required(total uint64) uint64 {
+	if t.percent > 0 {
+		return uint64(float64(total) * t.percent / 100)
+	}
+	return t.bytes
+}
+
+// diskFreeUnits are the size suffixes accepted by MinDiskFree.
+var diskFreeUnits = []struct {
+	suffix     string
+	multiplier uint64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// parseMinDiskFree parses a MinDiskFree value: a percentage ("10%") or a
+// number of bytes with an optional binary unit ("500MB", "2G", "1048576").
+func parseMinDiskFree(s string) (diskFreeThreshold, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	if strings.HasSuffix(v, "%") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(v, "%")), 64)
+		if err != nil || p <= 0 || p >= 100 {
+			return diskFreeThreshold{}, fmt.Errorf("invalid MinDiskFree %q: percentage must be between 0 and 100", s)
+		}
+		return diskFreeThreshold{percent: p}, nil
+	}
+	multiplier := uint64(1)
+	for _, u := range diskFreeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, multiplier = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), u.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseUint(v, 10, 64)
+	if err != nil || n == 0 {
+		return diskFreeThreshold{}, fmt.Errorf("invalid MinDiskFree %q: expected a size like 500MB or a percentage like 10%%", s)
+	}
+	return diskFreeThreshold{bytes: n * multiplier}, nil
+}
+
+// ValidateMinDiskFree checks that MinDiskFree is empty, a percentage or a
+// size.
+func (l *Logger) ValidateMinDiskFree() error {
+	if l.MinDiskFree == "" {
+		return nil
+	}
+	_, err := parseMinDiskFree(l.MinDiskFree)
+	return err
+}
+
+// diskFreeShortfall returns how many bytes must be freed in the backup
+// directory to satisfy MinDiskFree. Problems are reported on stderr once
+// and disable the check.
+func (l *Logger) diskFreeShortfall() int64 {
+	if l.MinDiskFree == "" || l.diskFreeDisabled {
+		return 0
+	}
+	threshold, err := parseMinDiskFree(l.MinDiskFree)
+	if err == nil {
+		var free, total uint64
+		if free, total, err = diskSpace(l.backupDir()); err == nil {
+			if need := threshold.required(total); need > free {
+				return int64(need - free)
+			}
+			return 0
+		}
+	}
+	fmt.Fprintf(os.Stderr, "timberjack: [%s] MinDiskFree disabled: %v\n", l.Filename, err)
+	l.diskFreeDisabled = true
+	return 0
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !darwin && !freebsd && !windows
+// +build !linux,!darwin,!freebsd,!windows
+
+package timberjack
+
+// statDisk is not supported on this platform.
+func statDisk(path string) (free, total uint64, err error) {
+	return 0, 0, errDiskSpaceUnsupported
+}
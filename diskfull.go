@@ -0,0 +1,124 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DiskFullPolicy decides what Write does when the disk is full.
+type DiskFullPolicy string
+
+const (
+	// DiskFullFail makes Write return the error. It is the default.
+	DiskFullFail DiskFullPolicy = "fail"
+
+	// DiskFullRetry makes Write retry the record with exponential backoff,
+	// a few times over about a second, before returning the error. Other
+	// writers wait meanwhile.
+	DiskFullRetry DiskFullPolicy = "retry"
+
+	// DiskFullDrop makes Write drop the record, reporting it as written.
+	// Dropped records are counted in Stats.Dropped.
+	DiskFullDrop DiskFullPolicy = "drop"
+
+	// DiskFullPrune makes Write remove the oldest backups, one at a time,
+	// retrying the record after each, until it fits or no backup is left
+	// to remove. Backups matching KeepPatterns and pinned ones are spared.
+	DiskFullPrune DiskFullPolicy = "prune"
+)
+
+// diskFullRetries and diskFullBackoff are the number of retries of
+// DiskFullRetry and the delay before the first one, which doubles with each
+// retry. They are variables so tests can shorten them.
+var (
+	diskFullRetries = 6
+	diskFullBackoff = 20 * time.Millisecond
+)
+
+// ValidateDiskFullPolicy checks that DiskFullPolicy is empty or one of the
+// DiskFullPolicy constants.
+func (l *Logger) ValidateDiskFullPolicy() error {
+	switch l.DiskFullPolicy {
+	case "", DiskFullFail, DiskFullRetry, DiskFullDrop, DiskFullPrune:
+		return nil
+	}
+	return fmt.Errorf("invalid DiskFullPolicy %q: expected %q, %q, %q or %q",
+		l.DiskFullPolicy, DiskFullFail, DiskFullRetry, DiskFullDrop, DiskFullPrune)
+}
+
+// handleDiskFull applies DiskFullPolicy to a write of p that wrote n bytes
+// and failed with err, returning the outcome of the write. Other errors are
+// returned unchanged. It expects l.mu to be held.
+func (l *Logger) handleDiskFull(p []byte, n int, err error) (int, error) {
+	if err == nil || Classify(err) != ErrorDiskFull {
+		return n, err
+	}
+	if l.OnDiskFull != nil {
+		l.OnDiskFull(err)
+	}
+
+	// retry writes the rest of p, reporting whether the disk is still full.
+	retry := func() bool {
+		m, errRetry := l.write(p[n:])
+		n += m
+		err = errRetry
+		return err != nil && Classify(err) == ErrorDiskFull
+	}
+
+	switch l.DiskFullPolicy {
+	case DiskFullDrop:
+		l.drop()
+		return len(p), nil
+	case DiskFullRetry:
+		delay := diskFullBackoff
+		for i := 0; i < diskFullRetries; i++ {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-l.context().Done():
+				timer.Stop()
+				return n, err
+			}
+			if !retry() {
+				return n, err
+			}
+			delay *= 2
+		}
+	case DiskFullPrune:
+		for l.pruneOldestBackup() {
+			if !retry() {
+				return n, err
+			}
+		}
+	}
+	return n, err
+}
+
+// pruneOldestBackup removes the oldest backup that isn't kept or pinned, for
+// DiskFullPrune. It reports whether one was removed. It expects l.mu to be
+// held.
+func (l *Logger) pruneOldestBackup() bool {
+	l.numberMu.Lock()
+	defer l.numberMu.Unlock()
+
+	files, err := l.oldLogFiles()
+	if err != nil {
+		return false
+	}
+	prunable, _ := l.splitKept(files)
+	for i := len(prunable) - 1; i >= 0; i-- {
+		name := prunable[i].Name()
+		if err := osRemove(filepath.Join(l.backupDir(), name)); err != nil {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "timberjack: [%s] disk full, removed oldest backup %s\n", l.Filename, name)
+		l.countRemovals(1)
+		l.emit(Event{Type: EventRemoval, File: filepath.Join(l.backupDir(), name), Reason: "DiskFull"})
+		l.forgetBackup(name)
+		l.removeEmptyLayoutDirs(name)
+		return true
+	}
+	return false
+}
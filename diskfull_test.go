@@ -0,0 +1,8 @@
+package timberjack
+
+import "testing"
+
+func TestValidateDiskFullPolicy(t *testing.T) {
+	l := &Logger{DiskFullPolicy: "panic"}
+	notNil(l.ValidateDiskFullPolicy(), t)
+}
@@ -0,0 +1,10 @@
+package timberjack
+
+// dropCache evicts the file name from the page cache if DropCaches is set.
+// It is best effort: pages not yet written back stay cached, and failures
+// are ignored.
+func (l *Logger) dropCache(name string) {
+	if l.DropCaches {
+		_ = dropFileCache(name)
+	}
+}
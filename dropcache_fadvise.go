@@ -0,0 +1,27 @@
+//go:build linux && (amd64 || arm64 || riscv64 || ppc64 || ppc64le)
+// +build linux
+// +build amd64 arm64 riscv64 ppc64 ppc64le
+
+package timberjack
+
+import (
+	"os"
+	"syscall"
+)
+
+// fadvDontNeed is POSIX_FADV_DONTNEED.
+const fadvDontNeed = 4
+
+// dropFileCache asks the kernel to evict the cached pages of the file name.
+var dropFileCache = func(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, _, errno := syscall.Syscall6(syscall.SYS_FADVISE64, f.Fd(), 0, 0, fadvDontNeed, 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux || !(amd64 || arm64 || riscv64 || ppc64 || ppc64le)
+// +build !linux !amd64,!arm64,!riscv64,!ppc64,!ppc64le
+
+// Dropping cached pages is only supported on 64-bit Linux; elsewhere
+// DropCaches is ignored.
+
+package timberjack
+
+// dropFileCache does nothing on this platform.
+var dropFileCache = func(_ string) error {
+	return nil
+}
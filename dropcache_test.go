@@ -0,0 +1,34 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDropCaches(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	var dropped []string
+	defer func(orig func(string) error) { dropFileCache = orig }(dropFileCache)
+	dropFileCache = func(name string) error {
+		dropped = append(dropped, name)
+		return nil
+	}
+
+	dir := makeTempDir("TestDropCaches", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, DropCaches: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	isNil(l.millRunOnce(), t)
+
+	// The backup is evicted once rotated and again once compressed.
+	backup := backupFileWithReason(dir, "size")
+	equals([]string{backup, backup + compressSuffix}, dropped, t)
+}
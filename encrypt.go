@@ -0,0 +1,286 @@
+package timberjack
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Encrypted backups are AES-256-GCM encrypted in chunks of encChunkSize
+// bytes, after a header of encMagic and a random nonce prefix. Each chunk's
+// nonce is the prefix, the chunk's index and a flag marking the last chunk,
+// so reordered, dropped or truncated chunks fail authentication.
+const (
+	encryptSuffix    = ".enc"
+	encChunkSize     = 64 << 10
+	encPrefixSize    = 7
+	encryptionKeyLen = 32
+)
+
+var encMagic = []byte("tjenc1\n\x00")
+
+// errTruncated is returned by DecryptBackup for a backup that ends before its
+// last chunk.
+var errTruncated = errors.New("timberjack: encrypted backup is truncated")
+
+// KeyProvider supplies the key backups are encrypted with, e.g. from a key
+// management service, so that keys can be rotated without reconfiguring the
+// Logger.
+type KeyProvider interface {
+	// EncryptionKey returns the 32-byte AES-256 key to encrypt the next
+	// backup with.
+	EncryptionKey() ([]byte, error)
+}
+
+// Encrypter encrypts compressed backups for an external scheme, typically
+// to public-key recipients so that the host never holds a decryption key
+// (see Logger.Encrypter). The pgp subpackage implements it for OpenPGP RSA
+// keys, readable with gpg. Its Encrypt method has the shape of
+// filippo.io/age's Encrypt and of OpenPGP libraries' Encrypt functions, so
+// they are easily adapted.
+type Encrypter interface {
+	// Suffix returns the extension appended to the names of encrypted
+	// backups after the compression suffix, including the leading dot,
+	// e.g. ".age". It must not be empty.
+	Suffix() string
+
+	// Encrypt returns a writer encrypting what is written to it into dst.
+	// Closing it must finish the encrypted stream without closing dst.
+	Encrypt(dst io.Writer) (io.WriteCloser, error)
+}
+
+// ValidateEncryption checks that, with Encrypt or an Encrypter set, Compress
+// is set too, and that a 32-byte EncryptionKey or a KeyProvider is
+// configured for Encrypt, or that the Encrypter has a suffix.
+func (l *Logger) ValidateEncryption() error {
+	if l.Encrypter != nil {
+		if !l.Compress {
+			return errors.New("invalid Encrypter: backups are only encrypted when Compress is set")
+		}
+		if l.Encrypter.Suffix() == "" {
+			return errors.New("invalid Encrypter: Suffix must not be empty")
+		}
+		return nil
+	}
+	if !l.Encrypt {
+		return nil
+	}
+	if !l.Compress {
+		return errors.New("invalid Encrypt: backups are only encrypted when Compress is set")
+	}
+	if l.KeyProvider == nil && len(l.EncryptionKey) != encryptionKeyLen {
+		return fmt.Errorf("invalid EncryptionKey: expected %d bytes, got %d", encryptionKeyLen, len(l.EncryptionKey))
+	}
+	return nil
+}
+
+// encryptionKey returns the key to encrypt a backup with.
+func (l *Logger) encryptionKey() ([]byte, error) {
+	key := l.EncryptionKey
+	if l.KeyProvider != nil {
+		var err error
+		if key, err = l.KeyProvider.EncryptionKey(); err != nil {
+			return nil, fmt.Errorf("can't get encryption key: %w", err)
+		}
+	}
+	if len(key) != encryptionKeyLen {
+		return nil, fmt.Errorf("invalid encryption key: expected %d bytes, got %d", encryptionKeyLen, len(key))
+	}
+	return key, nil
+}
+
+// encrypter returns the encryption applied to compressed backups, or nil.
+// An Encrypter takes precedence over Encrypt.
+func (l *Logger) encrypter() Encrypter {
+	if l.Encrypter != nil && l.Encrypter.Suffix() != "" {
+		return l.Encrypter
+	}
+	if l.Encrypt {
+		return aesEncrypter{l}
+	}
+	return nil
+}
+
+// encryptedSuffixes returns the suffixes encryption appends to the names of
+// compressed backups.
+func (l *Logger) encryptedSuffixes() []string {
+	if l.Encrypter != nil && l.Encrypter.Suffix() != "" && l.Encrypter.Suffix() != encryptSuffix {
+		return []string{l.Encrypter.Suffix(), encryptSuffix}
+	}
+	return []string{encryptSuffix}
+}
+
+// aesEncrypter is the AES-256-GCM encryption of Encrypt.
+type aesEncrypter struct {
+	l *Logger
+}
+
+func (aesEncrypter) Suffix() string { return encryptSuffix }
+
+func (e aesEncrypter) Encrypt(dst io.Writer) (io.WriteCloser, error) {
+	key, err := e.l.encryptionKey()
+	if err != nil {
+		return nil, err
+	}
+	return newEncryptWriter(dst, key)
+}
+
+// encryptingCodec encrypts what its codec compresses.
+type encryptingCodec struct {
+	codec Compressor
+	enc   Encrypter
+}
+
+func (c encryptingCodec) Suffix() string { return c.codec.Suffix() + c.enc.Suffix() }
+
+func (c encryptingCodec) Compress(dst io.Writer, src io.Reader) error {
+	w, err := c.enc.Encrypt(dst)
+	if err != nil {
+		return err
+	}
+	if err := c.codec.Compress(w, src); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// encryptWriter is an io.WriteCloser that encrypts what is written to it.
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	header []byte
+	buf    []byte
+	out    []byte
+	index  uint32
+}
+
+// newEncryptWriter writes the header of an encrypted backup to w and returns
+// an encryptWriter for its content.
+func newEncryptWriter(w io.Writer, key []byte) (*encryptWriter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(encMagic)+encPrefixSize)
+	copy(header, encMagic)
+	if _, err := io.ReadFull(rand.Reader, header[len(encMagic):]); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, header: header, buf: make([]byte, 0, encChunkSize)}, nil
+}
+
+// Write implements io.Writer. A full chunk is only sealed once more data
+// follows, as the last chunk is sealed differently.
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if len(e.buf) == encChunkSize {
+			if err := e.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		k := encChunkSize - len(e.buf)
+		if k > len(p) {
+			k = len(p)
+		}
+		e.buf = append(e.buf, p[:k]...)
+		p = p[k:]
+	}
+	return n, nil
+}
+
+// Close seals the last chunk. It doesn't close the underlying writer.
+func (e *encryptWriter) Close() error {
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(last bool) error {
+	e.out = e.aead.Seal(e.out[:0], chunkNonce(e.header, e.index, last), e.buf, e.header)
+	if _, err := e.w.Write(e.out); err != nil {
+		return err
+	}
+	e.index++
+	e.buf = e.buf[:0]
+	return nil
+}
+
+// DecryptBackup writes the decrypted content of the encrypted backup read
+// from src, e.g. a .gz.enc file, to dst, using the key it was encrypted
+// with. The result is the compressed backup, e.g. a gzip stream. It fails if
+// the backup was modified or truncated; dst may then have received part of
+// the content.
+func DecryptBackup(dst io.Writer, src io.Reader, key []byte) error {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(src)
+	header := make([]byte, len(encMagic)+encPrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header[:len(encMagic)], encMagic) {
+		return errors.New("timberjack: not an encrypted backup")
+	}
+
+	chunk := make([]byte, encChunkSize+aead.Overhead())
+	var plain []byte
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(r, chunk)
+		if err == io.EOF {
+			return errTruncated
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		last := err == io.ErrUnexpectedEOF
+		if !last {
+			if _, err := r.Peek(1); err == io.EOF {
+				last = true
+			}
+		}
+		plain, err = aead.Open(plain[:0], chunkNonce(header, index, last), chunk[:n], header)
+		if err != nil {
+			if _, errMore := aead.Open(nil, chunkNonce(header, index, false), chunk[:n], header); last && errMore == nil {
+				return errTruncated // a valid chunk, but not the last one
+			}
+			return fmt.Errorf("timberjack: can't decrypt backup: %w", err)
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// newAEAD returns AES-256-GCM with key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != encryptionKeyLen {
+		return nil, fmt.Errorf("invalid encryption key: expected %d bytes, got %d", encryptionKeyLen, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce returns the nonce of the chunk at index.
+func chunkNonce(header []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, header[len(encMagic):])
+	binary.BigEndian.PutUint32(nonce[encPrefixSize:], index)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
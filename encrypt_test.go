@@ -0,0 +1,166 @@
+package timberjack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{7}, encryptionKeyLen)
+
+func TestDecryptBackup(t *testing.T) {
+	for _, size := range []int{0, 1, encChunkSize, encChunkSize + 1, 3 * encChunkSize} {
+		in := bytes.Repeat([]byte("x"), size)
+		var enc bytes.Buffer
+		w, err := newEncryptWriter(&enc, testKey)
+		isNil(err, t)
+		_, err = w.Write(in)
+		isNil(err, t)
+		isNil(w.Close(), t)
+
+		var out bytes.Buffer
+		isNil(DecryptBackup(&out, bytes.NewReader(enc.Bytes()), testKey), t)
+		assert(bytes.Equal(in, out.Bytes()), t, "%d bytes: round trip mismatch", size)
+
+		wrongKey := bytes.Repeat([]byte{8}, encryptionKeyLen)
+		notNil(DecryptBackup(io.Discard, bytes.NewReader(enc.Bytes()), wrongKey), t)
+
+		tampered := append([]byte(nil), enc.Bytes()...)
+		tampered[len(tampered)-1] ^= 1
+		notNil(DecryptBackup(io.Discard, bytes.NewReader(tampered), testKey), t)
+
+		if size > encChunkSize {
+			// Drop the last chunk.
+			chunks := len(encMagic) + encPrefixSize + (size/encChunkSize)*(encChunkSize+16)
+			if size%encChunkSize == 0 {
+				chunks -= encChunkSize + 16
+			}
+			err := DecryptBackup(io.Discard, bytes.NewReader(enc.Bytes()[:chunks]), testKey)
+			assert(errors.Is(err, errTruncated), t, "%d bytes: expected truncation, got %v", size, err)
+		}
+	}
+	notNil(DecryptBackup(io.Discard, bytes.NewReader([]byte("plain text")), testKey), t)
+}
+
+type staticKey []byte
+
+func (k staticKey) EncryptionKey() ([]byte, error) { return k, nil }
+
+func TestEncrypt(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestEncrypt", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBackups: 1, Compress: true, Encrypt: true, KeyProvider: staticKey(testKey), BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+	isNil(l.ValidateEncryption(), t)
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	isNil(l.millRunOnce(), t)
+
+	name := backupFileWithReason(dir, "size")
+	notExist(name, t)
+	notExist(name+compressSuffix, t)
+	f, err := os.Open(name + compressSuffix + encryptSuffix)
+	isNil(err, t)
+	defer f.Close()
+	var gz bytes.Buffer
+	isNil(DecryptBackup(&gz, f, testKey), t)
+	zr, err := gzip.NewReader(&gz)
+	isNil(err, t)
+	got, err := io.ReadAll(zr)
+	isNil(err, t)
+	equals(string(b), string(got), t)
+
+	// Encrypted backups are managed like the others.
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	assert(backups[0].Compressed, t, "expected a compressed backup")
+	equals("size", backups[0].Reason, t)
+
+	l.KeyProvider = nil
+	notNil(l.ValidateEncryption(), t)
+	l.EncryptionKey = testKey
+	isNil(l.ValidateEncryption(), t)
+	l.Compress = false
+	notNil(l.ValidateEncryption(), t)
+}
+
+// xorEncrypter stands in for a public-key encryption such as age.
+type xorEncrypter struct{}
+
+func (xorEncrypter) Suffix() string { return ".xor" }
+
+func (xorEncrypter) Encrypt(dst io.Writer) (io.WriteCloser, error) {
+	return xorWriter{dst}, nil
+}
+
+type xorWriter struct{ w io.Writer }
+
+func (x xorWriter) Write(p []byte) (int, error) {
+	q := make([]byte, len(p))
+	for i := range p {
+		q[i] = p[i] ^ 0x5a
+	}
+	return x.w.Write(q)
+}
+
+func (xorWriter) Close() error { return nil }
+
+func TestEncrypter(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestEncrypter", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBackups: 2, Compress: true, Encrypter: xorEncrypter{}, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+	isNil(l.ValidateEncryption(), t)
+
+	// A backup encrypted with Encrypt before is still managed.
+	old := backupFileWithReason(dir, "size") + compressSuffix + encryptSuffix
+	isNil(os.WriteFile(old, []byte("old"), 0644), t)
+	newFakeTime()
+
+	var names []string
+	for _, content := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+		names = append(names, backupFileWithReason(dir, "size"))
+	}
+	isNil(l.millRunOnce(), t)
+
+	notExist(old, t)
+	data, err := os.ReadFile(names[1] + compressSuffix + ".xor")
+	isNil(err, t)
+	var gz bytes.Buffer
+	_, err = xorWriter{&gz}.Write(data)
+	isNil(err, t)
+	zr, err := gzip.NewReader(&gz)
+	isNil(err, t)
+	got, err := io.ReadAll(zr)
+	isNil(err, t)
+	equals("two!", string(got), t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	assert(backups[0].Compressed, t, "expected a compressed backup")
+	equals("size", backups[0].Reason, t)
+
+	l.Compress = false
+	notNil(l.ValidateEncryption(), t)
+}
@@ -0,0 +1,108 @@
+package timberjack
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// ErrorKind classifies the cause of a failed write or rotation, so that
+// callers can react differently to, say, a full disk and a permission
+// problem.
+type ErrorKind int
+
+const (
+	// ErrorOther is any failure not covered by a more specific kind.
+	ErrorOther ErrorKind = iota
+
+	// ErrorDiskFull means the filesystem has no space left.
+	ErrorDiskFull
+
+	// ErrorPermission means the process may not create, write or rename a
+	// file or directory.
+	ErrorPermission
+
+	// ErrorNotFound means a file or directory is missing, e.g. because the
+	// log directory was removed and couldn't be recreated.
+	ErrorNotFound
+
+	// ErrorReadOnly means the filesystem is mounted read-only.
+	ErrorReadOnly
+
+	// ErrorQuota means the user's disk quota is exhausted.
+	ErrorQuota
+)
+
+// String returns a human readable name for the error kind.
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorDiskFull:
+		return "disk-full"
+	case ErrorPermission:
+		return "permission"
+	case ErrorNotFound:
+		return "not-found"
+	case ErrorReadOnly:
+		return "read-only"
+	case ErrorQuota:
+		return "quota"
+	default:
+		return "other"
+	}
+}
+
+// Error is returned by Write and Rotate when writing or rotating fails. It
+// wraps the underlying error, whose message it keeps, and records its
+// classification. Use errors.As or Classify to inspect it.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string { return e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error { return e.Err }
+
+// Classify returns the kind of failure err represents. It recognizes errors
+// returned by a Logger as well as plain filesystem errors.
+func Classify(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if kind, ok := errnoKind(errno); ok {
+			return kind
+		}
+	}
+	switch {
+	case errors.Is(err, os.ErrPermission):
+		return ErrorPermission
+	case errors.Is(err, os.ErrNotExist):
+		return ErrorNotFound
+	}
+	return ErrorOther
+}
+
+// classifyError wraps err in an *Error and counts it in the Logger's
+// statistics. It returns nil if err is nil.
+func (l *Logger) classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Kind: Classify(err), Err: err}
+	}
+
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+	if l.stats.Failures == nil {
+		l.stats.Failures = make(map[ErrorKind]int64)
+	}
+	l.stats.Failures[e.Kind]++
+	return e
+}
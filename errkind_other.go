@@ -0,0 +1,20 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package timberjack
+
+import "syscall"
+
+// errnoKind maps the errnos that os.ErrPermission and os.ErrNotExist don't
+// cover to an ErrorKind.
+func errnoKind(errno syscall.Errno) (ErrorKind, bool) {
+	switch errno {
+	case syscall.ENOSPC:
+		return ErrorDiskFull, true
+	case syscall.EROFS:
+		return ErrorReadOnly, true
+	case syscall.EDQUOT:
+		return ErrorQuota, true
+	}
+	return ErrorOther, false
+}
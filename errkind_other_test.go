@@ -0,0 +1,16 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package timberjack
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestClassify_Errno(t *testing.T) {
+	equals(ErrorDiskFull, Classify(&os.PathError{Op: "write", Path: "x", Err: syscall.ENOSPC}), t)
+	equals(ErrorReadOnly, Classify(&os.PathError{Op: "open", Path: "x", Err: syscall.EROFS}), t)
+	equals(ErrorQuota, Classify(&os.PathError{Op: "write", Path: "x", Err: syscall.EDQUOT}), t)
+}
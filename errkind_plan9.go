@@ -0,0 +1,13 @@
+//go:build plan9
+// +build plan9
+
+package timberjack
+
+import "syscall"
+
+// errnoKind maps the errnos that os.ErrPermission and os.ErrNotExist don't
+// cover to an ErrorKind. Plan 9 reports errors as text rather than errnos,
+// so there are none to map.
+func errnoKind(errno syscall.Errno) (ErrorKind, bool) {
+	return ErrorOther, false
+}
@@ -0,0 +1,58 @@
+package timberjack
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	pathErr := func(cause error) error {
+		return fmt.Errorf("wrapped: %w", &os.PathError{Op: "write", Path: "x", Err: cause})
+	}
+	equals(ErrorPermission, Classify(pathErr(syscall.EACCES)), t)
+	equals(ErrorNotFound, Classify(pathErr(syscall.ENOENT)), t)
+	equals(ErrorNotFound, Classify(os.ErrNotExist), t)
+	equals(ErrorOther, Classify(errors.New("boom")), t)
+	equals(ErrorQuota, Classify(&Error{Kind: ErrorQuota, Err: errors.New("x")}), t)
+
+	equals("disk-full", ErrorDiskFull.String(), t)
+	equals("other", ErrorOther.String(), t)
+}
+
+func TestWrite_ClassifiesFailures(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestWrite_ClassifiesFailures", t)
+	defer os.RemoveAll(dir)
+
+	origRename := osRename
+	osRename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EACCES}
+	}
+	defer func() { osRename = origRename }()
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10}
+	defer l.Close()
+
+	_, err := l.Write([]byte("12345678"))
+	isNil(err, t)
+
+	_, err = l.Write([]byte("12345678"))
+	notNil(err, t)
+	assert(strings.Contains(err.Error(), "size rotation failed"), t, "unexpected message: %v", err)
+	var e *Error
+	assert(errors.As(err, &e), t, "expected *Error, got %T", err)
+	equals(ErrorPermission, e.Kind, t)
+	equals(ErrorPermission, Classify(err), t)
+	assert(errors.Is(err, os.ErrPermission), t, "expected the cause to be kept")
+
+	err = l.Rotate()
+	equals(ErrorPermission, Classify(err), t)
+
+	equals(map[ErrorKind]int64{ErrorPermission: 2}, l.Stats().Failures, t)
+}
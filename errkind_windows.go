@@ -0,0 +1,28 @@
+//go:build windows
+// +build windows
+
+package timberjack
+
+import "syscall"
+
+// Windows error codes without a counterpart in package syscall.
+const (
+	errorWriteProtect      = syscall.Errno(19)   // ERROR_WRITE_PROTECT
+	errorHandleDiskFull    = syscall.Errno(39)   // ERROR_HANDLE_DISK_FULL
+	errorDiskFull          = syscall.Errno(112)  // ERROR_DISK_FULL
+	errorDiskQuotaExceeded = syscall.Errno(1295) // ERROR_DISK_QUOTA_EXCEEDED
+)
+
+// errnoKind maps the errnos that os.ErrPermission and os.ErrNotExist don't
+// cover to an ErrorKind.
+func errnoKind(errno syscall.Errno) (ErrorKind, bool) {
+	switch errno {
+	case errorDiskFull, errorHandleDiskFull:
+		return ErrorDiskFull, true
+	case errorWriteProtect:
+		return ErrorReadOnly, true
+	case errorDiskQuotaExceeded:
+		return ErrorQuota, true
+	}
+	return ErrorOther, false
+}
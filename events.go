@@ -0,0 +1,117 @@
+package timberjack
+
+import "time"
+
+// eventBufferSize is the capacity of the channel returned by Events.
+const eventBufferSize = 64
+
+// EventType identifies the kind of an Event.
+type EventType int
+
+const (
+	// EventMissedRotation is emitted when the scheduled rotation goroutine
+	// wakes up after one or more RotateAtMinutes marks have already passed,
+	// e.g. after a system suspend or heavy CPU throttling.
+	EventMissedRotation EventType = iota + 1
+
+	// EventRotationTimeout is emitted when a rotation exceeds RotationTimeout.
+	// Writes continue to the old file until the rotation completes.
+	EventRotationTimeout
+
+	// EventPruneProgress is emitted after each cleanup pass that deleted
+	// backups while MaxRemovalsPerPass is set.
+	EventPruneProgress
+
+	// EventRotation is emitted when the log file has been rotated. File is
+	// the new backup and Link its place in the Logger's chain of segments.
+	EventRotation
+
+	// EventConfigChange is emitted when WatchConfig has applied a changed
+	// rotation policy. File is the config file.
+	EventConfigChange
+
+	// EventCompression is emitted when a backup has been compressed. File is
+	// the compressed backup, which is final from then on.
+	EventCompression
+
+	// EventRemoval is emitted when a backup or archive has been deleted.
+	// File is the deleted file and Reason the rule that deleted it
+	// ("MaxBackups", "MaxAge", "MaxTotalSize", "MinDiskFree", "ArchiveAfter"
+	// or "DiskFull").
+	EventRemoval
+)
+
+// String returns a human readable name for the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventMissedRotation:
+		return "missed-rotation"
+	case EventRotationTimeout:
+		return "rotation-timeout"
+	case EventPruneProgress:
+		return "prune-progress"
+	case EventRotation:
+		return "rotation"
+	case EventConfigChange:
+		return "config-change"
+	case EventCompression:
+		return "compression"
+	case EventRemoval:
+		return "removal"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes something notable that happened inside a Logger.
+type Event struct {
+	Type EventType // what happened
+	Time time.Time // when it happened
+	File string    // the file concerned, if any
+
+	// Reason is the rotation reason ("size", "time", ...) for rotation events
+	// and the retention rule for removal events.
+	Reason string
+
+	// Missed is the number of scheduled rotation marks that passed without a
+	// rotation of their own (EventMissedRotation).
+	Missed int
+
+	// Removed and Remaining report the backups deleted by a cleanup pass and
+	// those deferred to later passes (EventPruneProgress).
+	Removed   int
+	Remaining int
+
+	// Link is the rotated segment's place in the chain (EventRotation).
+	Link SegmentLink
+}
+
+// Events returns a channel on which the Logger publishes Events.
+// The channel is buffered; events are dropped rather than blocking the Logger
+// when the consumer falls behind. The channel is never closed.
+func (l *Logger) Events() <-chan Event {
+	l.eventsMu.Lock()
+	defer l.eventsMu.Unlock()
+	if l.events == nil {
+		l.events = make(chan Event, eventBufferSize)
+	}
+	return l.events
+}
+
+// emit publishes e without blocking. Events are discarded if nobody has
+// called Events yet or the channel buffer is full.
+func (l *Logger) emit(e Event) {
+	l.eventsMu.Lock()
+	ch := l.events
+	l.eventsMu.Unlock()
+	if ch == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = l.now()
+	}
+	select {
+	case ch <- e:
+	default:
+	}
+}
@@ -0,0 +1,57 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+)
+
+func TestEvents_DroppedWithoutSubscriber(t *testing.T) {
+	l := &Logger{}
+	// Must not block or panic when nobody listens.
+	l.emit(Event{Type: EventMissedRotation})
+
+	ch := l.Events()
+	equals(ch, l.Events(), t)
+	for i := 0; i < eventBufferSize+10; i++ {
+		l.emit(Event{Type: EventMissedRotation, Missed: i})
+	}
+	equals(eventBufferSize, len(ch), t)
+
+	e := <-ch
+	equals(EventMissedRotation, e.Type, t)
+	equals(0, e.Missed, t)
+	assert(!e.Time.IsZero(), t, "expected event time to be set")
+}
+
+func TestEventType_String(t *testing.T) {
+	equals("missed-rotation", EventMissedRotation.String(), t)
+	equals("removal", EventRemoval.String(), t)
+	equals("unknown", EventType(0).String(), t)
+}
+
+func TestEvents_CompressionAndRemoval(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestEvents_CompressionAndRemoval", t)
+	defer os.RemoveAll(dir)
+
+	oldest := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(oldest, []byte("old"), 0644), t)
+	newFakeTime()
+	newest := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(newest, []byte("new"), 0644), t)
+
+	l := &Logger{Filename: logFile(dir), MaxBackups: 1, Compress: true}
+	defer l.Close()
+	events := l.Events()
+
+	isNil(l.millRunOnce(), t)
+	e := <-events
+	equals(EventRemoval, e.Type, t)
+	equals(oldest, e.File, t)
+	equals("MaxBackups", e.Reason, t)
+	e = <-events
+	equals(EventCompression, e.Type, t)
+	equals(newest+compressSuffix, e.File, t)
+}
@@ -0,0 +1,50 @@
+package timberjack
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// writeFallback takes over a write of p to the log file that wrote n bytes
+// and failed with err, after DiskFullPolicy has had its go: the rest of p is
+// written to Fallback, if set, and the log file is closed so that the next
+// write opens it again, e.g. once the log volume is back. A successful write
+// to the file ends a spell on the fallback. Writes the Logger rejects, such
+// as one larger than MaxSize, fail as they would without Fallback. It
+// returns the outcome of the write. It expects l.mu to be held.
+func (l *Logger) writeFallback(p []byte, n int, err error) (int, error) {
+	if l.Fallback == nil || (err != nil && !isIOError(err)) {
+		return n, err
+	}
+	if err == nil {
+		if l.onFallback {
+			l.onFallback = false
+			fmt.Fprintf(os.Stderr, "timberjack: [%s] writing to the log file again\n", l.Filename)
+		}
+		return n, nil
+	}
+	if !l.onFallback {
+		l.onFallback = true
+		l.backgroundError("write", l.classifyError(err), "log file unavailable, writing to the fallback")
+	}
+	_ = l.closeFile()
+	m, errFallback := l.Fallback.Write(p[n:])
+	if errFallback != nil {
+		return n + m, err
+	}
+	return len(p), nil
+}
+
+// isIOError reports whether err is a failure to open, write or rotate the
+// log file, as opposed to a write rejected by the Logger's options.
+func isIOError(err error) bool {
+	if Classify(err) != ErrorOther {
+		return true
+	}
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var sysErr *os.SyscallError
+	return errors.As(err, &pathErr) || errors.As(err, &linkErr) || errors.As(err, &sysErr)
+}
@@ -0,0 +1,16 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package timberjack
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// NewSyslogFallback returns a writer sending each record to the local
+// syslog daemon with the given tag, at priority LOG_WARNING of facility
+// LOG_USER, for use as Logger.Fallback.
+func NewSyslogFallback(tag string) (io.Writer, error) {
+	return syslog.New(syslog.LOG_WARNING|syslog.LOG_USER, tag)
+}
@@ -0,0 +1,76 @@
+package timberjack
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestFallback", t)
+	defer os.RemoveAll(dir)
+
+	// The log directory can't be created while a file is in its place.
+	logDir := filepath.Join(dir, "logs")
+	isNil(os.WriteFile(logDir, []byte("x"), 0644), t)
+
+	var fallback bytes.Buffer
+	var ops []string
+	l := &Logger{
+		Filename: filepath.Join(logDir, "foobar.log"),
+		MaxSize:  100,
+		Fallback: &fallback,
+		OnError:  func(op string, err error) { ops = append(ops, op) },
+	}
+	defer l.Close()
+
+	for _, rec := range []string{"one\n", "two\n"} {
+		n, err := l.Write([]byte(rec))
+		isNil(err, t)
+		equals(len(rec), n, t)
+	}
+	equals("one\ntwo\n", fallback.String(), t)
+	equals([]string{"write"}, ops, t) // reported once, not for every record
+
+	// Once the log file can be opened again, writes go back to it.
+	isNil(os.Remove(logDir), t)
+	_, err := l.Write([]byte("three\n"))
+	isNil(err, t)
+	existsWithContent(l.Filename, []byte("three\n"), t)
+	equals("one\ntwo\n", fallback.String(), t)
+}
+
+func TestFallback_RejectedWrite(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestFallback_RejectedWrite", t)
+	defer os.RemoveAll(dir)
+
+	var fallback bytes.Buffer
+	l := &Logger{
+		Filename: logFile(dir),
+		MaxSize:  10,
+		Fallback: &fallback,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("ok\n"))
+	isNil(err, t)
+
+	// A write larger than MaxSize is an error of the caller, not an outage
+	// of the log file: it is neither taken over nor closes the file.
+	n, err := l.Write(bytes.Repeat([]byte("x"), 20))
+	notNil(err, t)
+	equals(0, n, t)
+	equals(0, fallback.Len(), t)
+
+	_, err = l.Write([]byte("still\n"))
+	isNil(err, t)
+	existsWithContent(l.Filename, []byte("ok\nstill\n"), t)
+	equals(0, fallback.Len(), t)
+}
@@ -0,0 +1,318 @@
+package timberjack
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FS returns a read-only view of the log files managed by the Logger: the
+// active file, under its base name, and every backup, under its name
+// relative to the backup directory. Backups compressed with gzip appear
+// under their uncompressed name and are decompressed as they are read, so
+// that fs.WalkDir, fs.ReadFile or http.FileServer(http.FS(l.FS())) see
+// plain log files. Backups compressed with other codecs are served as they
+// are. The view is listed anew on every Open.
+func (l *Logger) FS() fs.FS {
+	return logFS{l}
+}
+
+// logFS is the fs.FS returned by Logger.FS.
+type logFS struct {
+	l *Logger
+}
+
+// logFSEntry is a file of a logFS: its path on disk and whether it is
+// decompressed when read.
+type logFSEntry struct {
+	path    string
+	gunzip  bool
+	origLen int64 // uncompressed size, if known from the compression; else -1
+}
+
+// entries lists the files of the view by name.
+func (f logFS) entries() (map[string]logFSEntry, error) {
+	var backups []BackupInfo
+	if _, err := os.Stat(f.l.backupDir()); err == nil {
+		if backups, err = f.l.Backups(); err != nil {
+			return nil, err
+		}
+	}
+	files := make(map[string]logFSEntry, len(backups)+1)
+	active := f.l.CurrentFile()
+	if _, err := os.Stat(active); err == nil {
+		files[filepath.Base(active)] = logFSEntry{path: active, origLen: -1}
+	}
+	for _, b := range backups {
+		e := logFSEntry{path: filepath.Join(f.l.backupDir(), b.Name), origLen: -1}
+		name := filepath.ToSlash(b.Name)
+		if strings.HasSuffix(name, compressSuffix) {
+			name = strings.TrimSuffix(name, compressSuffix)
+			e.gunzip = true
+			if b.OriginalSize > 0 {
+				e.origLen = b.OriginalSize
+			}
+		}
+		// While a backup is being compressed, only its uncompressed form is
+		// known to be complete.
+		if prev, ok := files[name]; ok && !prev.gunzip {
+			continue
+		}
+		files[name] = e
+	}
+	return files, nil
+}
+
+// Open implements fs.FS.
+func (f logFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	files, err := f.entries()
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	if e, ok := files[name]; ok {
+		return openLogFSFile(name, e)
+	}
+
+	// name is a directory if any file is below it.
+	var children []fs.DirEntry
+	seen := make(map[string]bool)
+	for p, e := range files {
+		rel := p
+		if name != "." {
+			if !strings.HasPrefix(p, name+"/") {
+				continue
+			}
+			rel = p[len(name)+1:]
+		}
+		child := rel
+		if i := strings.IndexByte(rel, '/'); i >= 0 {
+			child = rel[:i]
+			if !seen[child] {
+				seen[child] = true
+				children = append(children, logFSDirInfo(child))
+			}
+			continue
+		}
+		children = append(children, logFSDirEntry{name: child, entry: e})
+	}
+	if len(children) == 0 && name != "." {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name() < children[j].Name() })
+	return &logFSDir{info: logFSDirInfo(path.Base(name)), entries: children}, nil
+}
+
+// openLogFSFile opens the file e under the name name.
+func openLogFSFile(name string, e logFSEntry) (fs.File, error) {
+	file, err := os.Open(e.path)
+	if err != nil {
+		return nil, renamePathError(err, name)
+	}
+	if !e.gunzip {
+		return file, nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, renamePathError(err, name)
+	}
+	g := &gunzipFile{file: file, name: path.Base(name), info: info, size: e.origLen}
+	if err := g.reset(); err != nil {
+		file.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return g, nil
+}
+
+// renamePathError reports err, a failure of an operation on a file of the
+// view, under the file's name in the view.
+func renamePathError(err error, name string) error {
+	if pe, ok := err.(*fs.PathError); ok {
+		return &fs.PathError{Op: pe.Op, Path: name, Err: pe.Err}
+	}
+	return &fs.PathError{Op: "open", Path: name, Err: err}
+}
+
+// gunzipFile is a gzip compressed backup read through a logFS. It supports
+// seeking, which http.FileServer needs, by decompressing again from the
+// start when seeking backwards.
+type gunzipFile struct {
+	file *os.File
+	zr   *gzip.Reader
+	name string
+	info os.FileInfo
+	size int64 // uncompressed size, or -1 until known
+	pos  int64 // offset in the uncompressed content
+}
+
+// reset rewinds the decompression to the start of the file.
+func (g *gunzipFile) reset() error {
+	if _, err := g.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var err error
+	if g.zr == nil {
+		g.zr, err = gzip.NewReader(g.file)
+	} else {
+		err = g.zr.Reset(g.file)
+	}
+	g.pos = 0
+	return err
+}
+
+func (g *gunzipFile) Read(p []byte) (int, error) {
+	n, err := g.zr.Read(p)
+	g.pos += int64(n)
+	if err == io.EOF && g.size < 0 {
+		g.size = g.pos
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker. Seeking relative to the end decompresses the
+// whole file once to learn its size, unless it is known from the
+// compression.
+func (g *gunzipFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += g.pos
+	case io.SeekEnd:
+		size, err := g.uncompressedSize()
+		if err != nil {
+			return 0, err
+		}
+		offset += size
+	}
+	if offset < 0 {
+		return 0, &fs.PathError{Op: "seek", Path: g.name, Err: fs.ErrInvalid}
+	}
+	if offset < g.pos {
+		if err := g.reset(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := io.CopyN(io.Discard, g, offset-g.pos); err != nil && err != io.EOF {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// uncompressedSize returns the size of the decompressed content, reading
+// it through if it isn't known yet.
+func (g *gunzipFile) uncompressedSize() (int64, error) {
+	if g.size >= 0 {
+		return g.size, nil
+	}
+	pos := g.pos
+	if _, err := io.Copy(io.Discard, g); err != nil {
+		return 0, err
+	}
+	g.size = g.pos
+	if err := g.reset(); err != nil {
+		return 0, err
+	}
+	if _, err := io.CopyN(io.Discard, g, pos); err != nil {
+		return 0, err
+	}
+	return g.size, nil
+}
+
+func (g *gunzipFile) Stat() (fs.FileInfo, error) {
+	size, err := g.uncompressedSize()
+	if err != nil {
+		return nil, err
+	}
+	return logFSFileInfo{FileInfo: g.info, name: g.name, size: size}, nil
+}
+
+func (g *gunzipFile) Close() error {
+	g.zr.Close()
+	return g.file.Close()
+}
+
+// logFSFileInfo describes a decompressed backup: the compressed file's
+// metadata under the uncompressed name and size.
+type logFSFileInfo struct {
+	os.FileInfo
+	name string
+	size int64
+}
+
+func (i logFSFileInfo) Name() string { return i.name }
+func (i logFSFileInfo) Size() int64  { return i.size }
+
+// logFSDirEntry is a file listed in a logFS directory. Its FileInfo is
+// only looked up when asked for, since that decompresses gzip backups
+// whose size isn't known.
+type logFSDirEntry struct {
+	name  string
+	entry logFSEntry
+}
+
+func (d logFSDirEntry) Name() string      { return d.name }
+func (d logFSDirEntry) IsDir() bool       { return false }
+func (d logFSDirEntry) Type() fs.FileMode { return 0 }
+
+func (d logFSDirEntry) Info() (fs.FileInfo, error) {
+	f, err := openLogFSFile(d.name, d.entry)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// logFSDirInfo describes a directory of a logFS, both as a FileInfo and
+// as a DirEntry.
+type logFSDirInfo string
+
+func (d logFSDirInfo) Name() string       { return string(d) }
+func (d logFSDirInfo) Size() int64        { return 0 }
+func (d logFSDirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (d logFSDirInfo) ModTime() time.Time { return time.Time{} }
+func (d logFSDirInfo) IsDir() bool        { return true }
+func (d logFSDirInfo) Sys() interface{}   { return nil }
+
+func (d logFSDirInfo) Type() fs.FileMode          { return fs.ModeDir }
+func (d logFSDirInfo) Info() (fs.FileInfo, error) { return d, nil }
+
+// logFSDir is an open directory of a logFS.
+type logFSDir struct {
+	info    logFSDirInfo
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *logFSDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *logFSDir) Close() error               { return nil }
+
+func (d *logFSDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.Name(), Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *logFSDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,101 @@
+package timberjack
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFS(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestFS", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("first\n"))
+	isNil(err, t)
+	isNil(l.RotateWithReason("manual"), t)
+	plain := filepath.Base(backupFileWithReason(dir, "manual"))
+	isNil(l.CompressPending(), t)
+	exists(backupFileWithReason(dir, "manual")+compressSuffix, t)
+
+	newFakeTime()
+	_, err = l.Write([]byte("second\n"))
+	isNil(err, t)
+	isNil(l.RotateWithReason("size"), t)
+	uncompressed := filepath.Base(backupFileWithReason(dir, "size"))
+
+	_, err = l.Write([]byte("third\n"))
+	isNil(err, t)
+
+	fsys := l.FS()
+	isNil(fstest.TestFS(fsys, "foobar.log", plain, uncompressed), t)
+
+	// The compressed backup is read decompressed, under its plain name.
+	b, err := fs.ReadFile(fsys, plain)
+	isNil(err, t)
+	equals("first\n", string(b), t)
+	b, err = fs.ReadFile(fsys, "foobar.log")
+	isNil(err, t)
+	equals("third\n", string(b), t)
+
+	f, err := fsys.Open(plain)
+	isNil(err, t)
+	defer f.Close()
+	info, err := f.Stat()
+	isNil(err, t)
+	equals(int64(len("first\n")), info.Size(), t)
+	seeker := f.(io.ReadSeeker)
+	off, err := seeker.Seek(-2, io.SeekEnd)
+	isNil(err, t)
+	equals(int64(4), off, t)
+	rest, err := io.ReadAll(seeker)
+	isNil(err, t)
+	equals("t\n", string(rest), t)
+
+	_, err = fsys.Open(plain + compressSuffix)
+	assert(errors.Is(err, fs.ErrNotExist), t, "expected fs.ErrNotExist, got %v", err)
+}
+
+func TestFS_Layout(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestFS_Layout", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		BackupTimeFormat: backupTimeFormat,
+		BackupDirLayout:  "2006/01",
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(l.RotateWithReason("manual"), t)
+	l.retiring.Wait() // the backup moves into its layout directory in the background
+
+	var files []string
+	isNil(fs.WalkDir(l.FS(), ".", func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, path)
+		}
+		return err
+	}), t)
+	equals(2, len(files), t)
+	equals("foobar.log", files[len(files)-1], t)
+	assert(strings.HasPrefix(files[0], fakeTime().UTC().Format("2006/01")+"/"), t, "unexpected backup path %q", files[0])
+}
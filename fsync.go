@@ -0,0 +1,158 @@
+package timberjack
+
+import (
+	"fmt"
+	"time"
+)
+
+// SyncPolicy decides when the active log file is fsynced to disk.
+type SyncPolicy string
+
+const (
+	// SyncNever leaves flushing to disk to the operating system. It is the
+	// default and the fastest; a machine crash may lose recent records.
+	SyncNever SyncPolicy = "never"
+
+	// SyncOnRotate fsyncs each file when it is rotated and on Close, so
+	// backups are always complete on disk.
+	SyncOnRotate SyncPolicy = "rotate"
+
+	// SyncEveryBytes fsyncs the file once SyncBytes bytes have been written
+	// since the last sync, as well as on rotation and Close.
+	SyncEveryBytes SyncPolicy = "bytes"
+
+	// SyncEveryInterval fsyncs the file every SyncInterval, as well as on
+	// rotation and Close.
+	SyncEveryInterval SyncPolicy = "interval"
+
+	// SyncEveryWrite fsyncs the file after every Write before returning, so
+	// a record reported as written is on disk. It is the slowest.
+	SyncEveryWrite SyncPolicy = "write"
+)
+
+// ValidateSyncPolicy checks that SyncPolicy is empty or one of the SyncPolicy
+// constants, and that SyncBytes or SyncInterval is set when the policy needs
+// it.
+func (l *Logger) ValidateSyncPolicy() error {
+	switch l.SyncPolicy {
+	case "", SyncNever, SyncOnRotate, SyncEveryWrite:
+		return nil
+	case SyncEveryBytes:
+		if l.SyncBytes <= 0 {
+			return fmt.Errorf("SyncPolicy %q requires SyncBytes to be greater than zero", l.SyncPolicy)
+		}
+		return nil
+	case SyncEveryInterval:
+		if l.SyncInterval <= 0 {
+			return fmt.Errorf("SyncPolicy %q requires SyncInterval to be greater than zero", l.SyncPolicy)
+		}
+		return nil
+	}
+	return fmt.Errorf("invalid SyncPolicy %q: expected %q, %q, %q, %q or %q",
+		l.SyncPolicy, SyncNever, SyncOnRotate, SyncEveryBytes, SyncEveryInterval, SyncEveryWrite)
+}
+
+// syncsOnRotate reports whether files are fsynced before they are closed.
+func (l *Logger) syncsOnRotate() bool {
+	switch l.SyncPolicy {
+	case SyncOnRotate, SyncEveryBytes, SyncEveryInterval, SyncEveryWrite:
+		return true
+	}
+	return false
+}
+
+// syncsOnWrite reports whether writes decide when to fsync, which needs
+// l.mu held exclusively.
+func (l *Logger) syncsOnWrite() bool {
+	return l.SyncPolicy == SyncEveryWrite || l.SyncPolicy == SyncEveryBytes && l.SyncBytes > 0
+}
+
+// Sync writes buffered, staged and queued records to the log file, like
+// Flush, and fsyncs it, so that everything written so far survives a
+// machine crash. It is a no-op if no file is open.
+//
+// With Write and Sync, a Logger is a zapcore.WriteSyncer itself: pass it to
+// zapcore.AddSync, which keeps this Sync, so that zap's Sync on shutdown
+// makes the log durable.
+func (l *Logger) Sync() error {
+	l.flushStaged()
+	l.flushQueue()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.flushCoalesced()
+	return l.classifyError(l.syncFile())
+}
+
+// syncFile flushes the buffer and fsyncs the active file. It expects l.mu to
+// be held.
+func (l *Logger) syncFile() error {
+	if l.file == nil {
+		return nil
+	}
+	if err := l.flushBuffer(); err != nil {
+		return err
+	}
+	l.unsynced = 0
+	return osSyncFile(l.file)
+}
+
+// syncAfterWrite fsyncs the active file after n bytes were written to it if
+// SyncPolicy calls for it. It expects l.mu to be held.
+func (l *Logger) syncAfterWrite(n int) error {
+	switch l.SyncPolicy {
+	case SyncEveryWrite:
+		return l.syncFile()
+	case SyncEveryBytes:
+		if l.SyncBytes <= 0 {
+			return nil
+		}
+		l.unsynced += int64(n)
+		if l.unsynced >= l.SyncBytes {
+			return l.syncFile()
+		}
+	}
+	return nil
+}
+
+// ensureSyncLoopRunning starts the goroutine fsyncing the file if SyncPolicy
+// is SyncEveryInterval. It expects l.mu to be held.
+func (l *Logger) ensureSyncLoopRunning() {
+	if l.SyncPolicy != SyncEveryInterval || l.SyncInterval <= 0 {
+		return
+	}
+	l.startSyncOnce.Do(func() {
+		l.syncQuitCh = make(chan struct{})
+		go l.runSync(l.syncQuitCh)
+	})
+}
+
+// runSync fsyncs the active file every SyncInterval, until quit is closed or
+// the Logger's Context ends.
+func (l *Logger) runSync(quit chan struct{}) {
+	ticker := time.NewTicker(l.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.Lock()
+			if err := l.syncFile(); err != nil {
+				l.backgroundError("sync", err, "failed to sync log file")
+			}
+			l.mu.Unlock()
+		case <-quit:
+			return
+		case <-l.context().Done():
+			return
+		}
+	}
+}
+
+// stopSyncLoop signals the sync goroutine to exit.
+// It expects l.mu to be held.
+func (l *Logger) stopSyncLoop() {
+	if l.syncQuitCh != nil {
+		close(l.syncQuitCh)
+		l.syncQuitCh = nil
+	}
+}
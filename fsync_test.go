@@ -0,0 +1,182 @@
+package timberjack
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countSyncs counts the fsyncs of log files until restore is called.
+func countSyncs() (count func() int32, restore func()) {
+	var n int32
+	orig := osSyncFile
+	osSyncFile = func(f *os.File) error {
+		atomic.AddInt32(&n, 1)
+		return orig(f)
+	}
+	return func() int32 { return atomic.LoadInt32(&n) }, func() { osSyncFile = orig }
+}
+
+func TestSyncPolicy(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	tests := []struct {
+		policy SyncPolicy
+		bytes  int64
+		writes int32 // syncs after three writes of 4 bytes
+		total  int32 // syncs after a rotation and Close
+	}{
+		{"", 0, 0, 0},
+		{SyncNever, 0, 0, 0},
+		{SyncOnRotate, 0, 0, 2},
+		{SyncEveryBytes, 6, 1, 3},
+		{SyncEveryWrite, 0, 3, 5},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			syncs, restore := countSyncs()
+			defer restore()
+
+			dir := makeTempDir("TestSyncPolicy", t)
+			defer os.RemoveAll(dir)
+
+			l := &Logger{Filename: logFile(dir), MaxSize: 100, SyncPolicy: tt.policy, SyncBytes: tt.bytes, BackupTimeFormat: backupTimeFormat}
+			isNil(l.ValidateSyncPolicy(), t)
+			for i := 0; i < 3; i++ {
+				_, err := l.Write([]byte("boo!"))
+				isNil(err, t)
+			}
+			equals(tt.writes, syncs(), t)
+
+			newFakeTime()
+			isNil(l.Rotate(), t)
+			isNil(l.Close(), t)
+			equals(tt.total, syncs(), t)
+		})
+	}
+}
+
+func TestSyncPolicy_Interval(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	syncs, restore := countSyncs()
+	defer restore()
+
+	dir := makeTempDir("TestSyncPolicy_Interval", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, SyncPolicy: SyncEveryInterval, SyncInterval: 10 * time.Millisecond}
+	defer l.Close()
+	isNil(l.ValidateSyncPolicy(), t)
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	deadline := time.Now().Add(time.Second)
+	for syncs() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert(syncs() > 0, t, "log file was not synced")
+}
+
+func TestSync(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	syncs, restore := countSyncs()
+	defer restore()
+
+	dir := makeTempDir("TestSync", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, BufferSize: 64}
+	defer l.Close()
+
+	// Without a file there is nothing to sync.
+	isNil(l.Sync(), t)
+	equals(int32(0), syncs(), t)
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	isNil(l.Sync(), t)
+	equals(int32(1), syncs(), t)
+	existsWithContent(logFile(dir), b, t)
+}
+
+// writeSyncer is zapcore.WriteSyncer, which zapcore.AddSync uses as is.
+type writeSyncer interface {
+	io.Writer
+	Sync() error
+}
+
+func TestSync_WriteSyncer(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	syncs, restore := countSyncs()
+	defer restore()
+
+	dir := makeTempDir("TestSync_WriteSyncer", t)
+	defer os.RemoveAll(dir)
+
+	var ws writeSyncer = &Logger{Filename: logFile(dir), MaxSize: 100, AsyncQueueSize: 8}
+	defer ws.(*Logger).Close()
+
+	b := []byte("boo!")
+	_, err := ws.Write(b)
+	isNil(err, t)
+	isNil(ws.Sync(), t)
+	equals(int32(1), syncs(), t)
+	existsWithContent(logFile(dir), b, t)
+}
+
+func TestValidateSyncPolicy(t *testing.T) {
+	for _, l := range []*Logger{
+		{SyncPolicy: "sometimes"},
+		{SyncPolicy: SyncEveryBytes},
+		{SyncPolicy: SyncEveryInterval},
+	} {
+		notNil(l.ValidateSyncPolicy(), t)
+	}
+}
+
+func TestDurable(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestDurable", t)
+	defer os.RemoveAll(dir)
+
+	var synced []string
+	osSyncDir = func(d string) error {
+		if d != dir {
+			return syncDir(d) // another test's background work
+		}
+		synced = append(synced, d)
+		return nil
+	}
+	defer func() { osSyncDir = syncDir }()
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Durable: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	equals([]string{dir}, synced, t)
+
+	synced = nil
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	equals([]string{dir}, synced, t)
+
+	// Without Durable, rotations don't sync the directory.
+	l.Durable = false
+	synced = nil
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	equals([]string(nil), synced, t)
+}
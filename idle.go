@@ -0,0 +1,69 @@
+package timberjack
+
+import "time"
+
+// ensureIdleLoopRunning starts the goroutine finalizing idle segments if
+// IdleFinalizeAfter is configured. It expects l.mu to be held.
+func (l *Logger) ensureIdleLoopRunning() {
+	if l.IdleFinalizeAfter <= 0 {
+		return
+	}
+	l.startIdleOnce.Do(func() {
+		l.idleQuitCh = make(chan struct{})
+		go l.runIdleFinalization(l.idleQuitCh)
+	})
+}
+
+// runIdleFinalization rotates the active file once it has gone without
+// writes for IdleFinalizeAfter, until quit is closed or the Logger's Context
+// ends.
+func (l *Logger) runIdleFinalization(quit chan struct{}) {
+	timer := time.NewTimer(l.IdleFinalizeAfter)
+	defer stopTimer(timer)
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(l.finalizeIfIdle(quit))
+		case <-quit:
+			return
+		case <-l.context().Done():
+			return
+		}
+	}
+}
+
+// finalizeIfIdle rotates the active file with reason "idle" if it has data
+// and hasn't been written to for IdleFinalizeAfter. It returns how long to
+// wait before checking again.
+func (l *Logger) finalizeIfIdle(quit chan struct{}) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-quit:
+		return l.IdleFinalizeAfter
+	default:
+	}
+	if l.isClosed() || l.file == nil || l.size == 0 {
+		return l.IdleFinalizeAfter
+	}
+	now := l.now()
+	if idle := now.Sub(l.lastWrite); idle < l.IdleFinalizeAfter {
+		return l.IdleFinalizeAfter - idle
+	}
+	if err := l.rotate("idle"); err != nil {
+		l.backgroundError("rotate", err, "idle rotation failed")
+		return l.IdleFinalizeAfter
+	}
+	l.lastRotationTime = now
+	return l.IdleFinalizeAfter
+}
+
+// stopIdleLoop signals the idle finalization goroutine to exit.
+// It expects l.mu to be held.
+func (l *Logger) stopIdleLoop() {
+	if l.idleQuitCh != nil {
+		close(l.idleQuitCh)
+		l.idleQuitCh = nil
+	}
+}
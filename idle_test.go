@@ -0,0 +1,40 @@
+package timberjack
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdleFinalizeAfter(t *testing.T) {
+	currentTime = time.Now
+	defer func() { currentTime = fakeTime }()
+	megabyte = 1
+
+	dir := makeTempDir("TestIdleFinalizeAfter", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, IdleFinalizeAfter: 50 * time.Millisecond}
+	defer l.Close()
+	events := l.Events()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	select {
+	case e := <-events:
+		equals(EventRotation, e.Type, t)
+		equals("idle", e.Reason, t)
+		assert(strings.HasSuffix(e.File, "-idle.log"), t, "unexpected backup name %s", e.File)
+		existsWithContent(e.File, b, t)
+	case <-time.After(time.Second):
+		t.Fatal("idle segment was not finalized")
+	}
+
+	// The new, empty file is not rotated again.
+	<-time.After(150 * time.Millisecond)
+	fileCount(dir, 2, t)
+	existsWithContent(logFile(dir), []byte{}, t)
+}
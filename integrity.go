@@ -0,0 +1,142 @@
+package timberjack
+
+import (
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrChecksumMismatch is returned by VerifyCheckpoint when the synced prefix
+// of a log file no longer matches the checksum recorded for it.
+var ErrChecksumMismatch = errors.New("timberjack: log file checksum mismatch")
+
+// Checkpoint records how much of the active log file was known to be durable
+// at a point in time, together with a CRC-32 (IEEE) checksum of that content.
+// Checkpoints are taken every IntegrityInterval and reset on rotation. When
+// the Logger appends to an existing file, the checksum only covers what it
+// wrote, from Start on, so that opening a large file doesn't mean reading it.
+type Checkpoint struct {
+	File   string    // path of the log file
+	Offset int64     // number of bytes fsynced
+	Start  int64     // size of the file when the Logger opened it
+	CRC32  uint32    // checksum of the bytes from Start to Offset
+	Time   time.Time // when the checkpoint was taken
+}
+
+// Checkpoint returns the most recent integrity checkpoint of the active file.
+// It is the zero Checkpoint if IntegrityInterval is not set or no checkpoint
+// has been taken yet for the current file.
+func (l *Logger) Checkpoint() Checkpoint {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.checkpoint
+}
+
+// VerifyCheckpoint checks the log file named in cp against the checkpoint,
+// as recovery tooling would after a crash. It returns the number of bytes
+// that were fsynced but are missing from the file (a torn tail), or
+// ErrChecksumMismatch if the retained content from Start on doesn't match
+// the checksum.
+func VerifyCheckpoint(cp Checkpoint) (lost int64, err error) {
+	f, err := os.Open(cp.File)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() < cp.Offset {
+		return cp.Offset - info.Size(), nil
+	}
+	if _, err := f.Seek(cp.Start, io.SeekStart); err != nil {
+		return 0, err
+	}
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, io.LimitReader(f, cp.Offset-cp.Start)); err != nil {
+		return 0, err
+	}
+	if h.Sum32() != cp.CRC32 {
+		return 0, ErrChecksumMismatch
+	}
+	return 0, nil
+}
+
+// ensureIntegrityLoopRunning starts the goroutine taking periodic checkpoints
+// if IntegrityInterval is configured. It expects l.mu to be held.
+func (l *Logger) ensureIntegrityLoopRunning() {
+	if l.IntegrityInterval <= 0 {
+		return
+	}
+	l.startIntegrityOnce.Do(func() {
+		l.integrityQuitCh = make(chan struct{})
+		go l.runIntegrity(l.integrityQuitCh)
+	})
+}
+
+// runIntegrity fsyncs the active file and takes a checkpoint every
+// IntegrityInterval until quit is closed or the Logger's Context ends.
+func (l *Logger) runIntegrity(quit chan struct{}) {
+	ticker := time.NewTicker(l.IntegrityInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.Lock()
+			if err := l.takeCheckpoint(); err != nil {
+				l.backgroundError("checkpoint", err, "integrity checkpoint failed")
+			}
+			l.mu.Unlock()
+		case <-quit:
+			return
+		case <-l.context().Done():
+			return
+		}
+	}
+}
+
+// takeCheckpoint fsyncs the active file and records a checkpoint covering
+// everything written to it so far. It expects l.mu to be held.
+func (l *Logger) takeCheckpoint() error {
+	if l.file == nil {
+		return nil
+	}
+	if err := l.flushBuffer(); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.checkpoint = Checkpoint{File: l.file.Name(), Offset: l.size, Start: l.crcStart, CRC32: l.crc, Time: l.now()}
+	return nil
+}
+
+// stopIntegrityLoop signals the checkpoint goroutine to exit.
+// It expects l.mu to be held.
+func (l *Logger) stopIntegrityLoop() {
+	if l.integrityQuitCh != nil {
+		close(l.integrityQuitCh)
+		l.integrityQuitCh = nil
+	}
+}
+
+// updateChecksum folds newly written bytes into the running checksum of the
+// active file. It expects l.mu to be held.
+func (l *Logger) updateChecksum(p []byte) {
+	if l.IntegrityInterval > 0 {
+		l.crc = crc32.Update(l.crc, crc32.IEEETable, p)
+	}
+}
+
+// resetChecksum starts a new running checksum for a newly opened file, of
+// the bytes written from offset start on: the existing content of a file
+// appended to isn't read. It expects l.mu to be held.
+func (l *Logger) resetChecksum(start int64) {
+	l.crc = 0
+	l.crcStart = start
+	l.checkpoint = Checkpoint{}
+}
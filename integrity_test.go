@@ -0,0 +1,105 @@
+package timberjack
+
+import (
+	"hash/crc32"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIntegrity_Checkpoint(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestIntegrity_Checkpoint", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	existing := []byte("existing\n")
+	isNil(os.WriteFile(filename, existing, 0644), t)
+
+	l := &Logger{
+		Filename:          filename,
+		MaxSize:           100,
+		IntegrityInterval: 10 * time.Millisecond,
+	}
+	defer l.Close()
+
+	equals(Checkpoint{}, l.Checkpoint(), t)
+
+	b := []byte("boo!\n")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	// Wait for the background task to take a checkpoint.
+	deadline := time.Now().Add(time.Second)
+	for l.Checkpoint().Offset == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The checksum covers what the Logger appended, not the existing content.
+	cp := l.Checkpoint()
+	content := append(existing, b...)
+	equals(filename, cp.File, t)
+	equals(int64(len(content)), cp.Offset, t)
+	equals(int64(len(existing)), cp.Start, t)
+	equals(crc32.ChecksumIEEE(b), cp.CRC32, t)
+
+	lost, err := VerifyCheckpoint(cp)
+	isNil(err, t)
+	equals(int64(0), lost, t)
+
+	// Simulate a torn tail.
+	isNil(os.Truncate(filename, int64(len(content)-3)), t)
+	lost, err = VerifyCheckpoint(cp)
+	isNil(err, t)
+	equals(int64(3), lost, t)
+
+	// Changes before Start aren't the Logger's to check.
+	isNil(os.WriteFile(filename, append([]byte("EXISTING\n"), b...), 0644), t)
+	lost, err = VerifyCheckpoint(cp)
+	isNil(err, t)
+	equals(int64(0), lost, t)
+
+	// Simulate corrupted content.
+	isNil(os.WriteFile(filename, []byte("garbage!!!!!!!"), 0644), t)
+	_, err = VerifyCheckpoint(cp)
+	equals(ErrChecksumMismatch, err, t)
+}
+
+func TestIntegrity_ResetOnRotate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestIntegrity_ResetOnRotate", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:          logFile(dir),
+		MaxSize:           100,
+		IntegrityInterval: time.Hour,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("before"))
+	isNil(err, t)
+	l.mu.Lock()
+	isNil(l.takeCheckpoint(), t)
+	l.mu.Unlock()
+	equals(int64(6), l.Checkpoint().Offset, t)
+
+	isNil(l.Rotate(), t)
+	equals(Checkpoint{}, l.Checkpoint(), t)
+
+	_, err = l.Write([]byte("after"))
+	isNil(err, t)
+	l.mu.Lock()
+	isNil(l.takeCheckpoint(), t)
+	l.mu.Unlock()
+	equals(crc32.ChecksumIEEE([]byte("after")), l.Checkpoint().CRC32, t)
+}
+
+func TestVerifyCheckpoint_MissingFile(t *testing.T) {
+	_, err := VerifyCheckpoint(Checkpoint{File: "/nonexistent/timberjack.log"})
+	notNil(err, t)
+}
@@ -0,0 +1,78 @@
+package timberjack
+
+import "time"
+
+// ensureIntervalLoopRunning starts the goroutine rotating the file every
+// RotationInterval if it is configured. It expects l.mu to be held.
+func (l *Logger) ensureIntervalLoopRunning() {
+	if l.RotationInterval <= 0 {
+		return
+	}
+	l.startIntervalOnce.Do(func() {
+		l.intervalQuitCh = make(chan struct{})
+		go l.runIntervalRotation(l.intervalQuitCh, l.RotationInterval)
+	})
+}
+
+// runIntervalRotation rotates the active file once interval, the
+// RotationInterval when the goroutine was started, has passed since the last
+// rotation, whether or not it is written to, until quit is closed or the
+// Logger's Context ends.
+func (l *Logger) runIntervalRotation(quit chan struct{}, interval time.Duration) {
+	timer := time.NewTimer(interval)
+	defer stopTimer(timer)
+	for {
+		select {
+		case <-timer.C:
+			timer.Reset(l.rotateIfIntervalDue(quit))
+		case <-quit:
+			return
+		case <-l.context().Done():
+			return
+		}
+	}
+}
+
+// rotateIfIntervalDue rotates the active file with reason "time" if
+// RotationInterval has passed since the last rotation. An empty file is not
+// rotated, but starts a new interval. It returns how long to wait before
+// checking again.
+func (l *Logger) rotateIfIntervalDue(quit chan struct{}) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-quit:
+		return l.RotationInterval
+	default:
+	}
+	if l.isClosed() || l.file == nil {
+		return l.RotationInterval
+	}
+	now := l.now()
+	if elapsed := now.Sub(l.lastRotationTime); elapsed < l.RotationInterval {
+		return l.RotationInterval - elapsed
+	}
+	if l.rotationPaused {
+		l.rotationMissed = true
+		return l.RotationInterval
+	}
+	l.flushCoalesced() // records of the ending interval belong in its file
+	if l.size > 0 {
+		if err := l.rotate("time"); err != nil {
+			l.backgroundError("rotate", err, "interval rotation failed")
+			return l.RotationInterval
+		}
+	}
+	l.lastRotationTime = now
+	return l.RotationInterval
+}
+
+// stopIntervalLoop signals the interval rotation goroutine to exit.
+// It expects l.mu to be held.
+func (l *Logger) stopIntervalLoop() {
+	if l.intervalQuitCh != nil {
+		close(l.intervalQuitCh)
+		l.intervalQuitCh = nil
+	}
+}
@@ -0,0 +1,41 @@
+package timberjack
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotationInterval_Timer(t *testing.T) {
+	currentTime = time.Now
+	defer func() { currentTime = fakeTime }()
+	megabyte = 1
+
+	dir := makeTempDir("TestRotationInterval_Timer", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, RotationInterval: 50 * time.Millisecond}
+	defer l.Close()
+	events := l.Events()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	// The file is rotated on time without another write.
+	select {
+	case e := <-events:
+		equals(EventRotation, e.Type, t)
+		equals("time", e.Reason, t)
+		assert(strings.HasSuffix(e.File, "-time.log"), t, "unexpected backup name %s", e.File)
+		existsWithContent(e.File, b, t)
+	case <-time.After(time.Second):
+		t.Fatal("file was not rotated by the interval timer")
+	}
+
+	// The new, empty file is not rotated.
+	<-time.After(150 * time.Millisecond)
+	fileCount(dir, 2, t)
+	existsWithContent(logFile(dir), []byte{}, t)
+}
@@ -0,0 +1,65 @@
+package timberjack
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CurrentFile returns the path of the file the Logger writes to. Backups
+// are renamed away from it, so it stays the same across rotations.
+func (l *Logger) CurrentFile() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.file != nil {
+		return l.file.Name()
+	}
+	return l.filename()
+}
+
+// CurrentSize returns the number of bytes in the current file, as tracked by
+// the Logger, including records still held by BufferSize. It is 0 until the
+// file is opened by the first write.
+func (l *Logger) CurrentSize() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return atomic.LoadInt64(&l.size)
+}
+
+// NextScheduledRotation returns when the next time-based rotation is due
+// under RotationInterval, RotateAtMinutes, RotateAtTimes, RotationSchedule
+// and RotationPeriod, whichever comes first, or the zero time if none of
+// them is configured. Before the first write, RotationInterval counts from
+// now. Size-based rotations can't be predicted and aren't included.
+func (l *Logger) NextScheduledRotation() time.Time {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	now := l.now().In(l.location())
+	var next time.Time
+	consider := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	if l.RotationInterval > 0 {
+		last := l.lastRotationTime
+		if last.IsZero() {
+			last = now
+		}
+		consider(last.Add(l.RotationInterval).In(l.location()))
+	}
+	minutes := l.processedRotateAtMinutes
+	if minutes == nil {
+		minutes = validRotateAtMinutes(l.RotateAtMinutes)
+	}
+	if mark, ok := nextMinuteMark(minutes, now); ok {
+		consider(mark)
+	}
+	schedules := l.schedules
+	if schedules == nil && l.ValidateRotationSchedule() == nil && l.ValidateRotateAtTimes() == nil && l.ValidateRotationPeriod() == nil {
+		schedules = l.buildSchedules() // not built until the first write; valid, so it warns of nothing
+	}
+	consider(nextRotation(schedules, now))
+	return next
+}
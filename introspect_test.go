@@ -0,0 +1,56 @@
+package timberjack
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCurrentFileAndSize(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	l := &Logger{Filename: filename, MaxSize: 10}
+	defer l.Close()
+
+	equals(filename, l.CurrentFile(), t)
+	equals(int64(0), l.CurrentSize(), t)
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	equals(filename, l.CurrentFile(), t)
+	equals(int64(4), l.CurrentSize(), t)
+
+	isNil(l.Rotate(), t)
+	equals(filename, l.CurrentFile(), t)
+	equals(int64(0), l.CurrentSize(), t)
+}
+
+func TestNextScheduledRotation(t *testing.T) {
+	now := time.Date(2025, 5, 14, 10, 20, 0, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = fakeTime }()
+
+	dir := t.TempDir()
+	l := &Logger{Filename: filepath.Join(dir, "app.log"), MaxSize: Unlimited}
+	defer l.Close()
+	isZero := l.NextScheduledRotation().IsZero()
+	assert(isZero, t, "expected no scheduled rotation")
+
+	l.RotationInterval = 2 * time.Hour
+	equals(now.Add(2*time.Hour), l.NextScheduledRotation(), t)
+
+	// The earliest of all schedules wins.
+	l.RotateAtMinutes = []int{0, 30}
+	equals(time.Date(2025, 5, 14, 10, 30, 0, 0, time.UTC), l.NextScheduledRotation(), t)
+	l.RotateAtTimes = []string{"10:25"}
+	equals(time.Date(2025, 5, 14, 10, 25, 0, 0, time.UTC), l.NextScheduledRotation(), t)
+
+	// Once the file is open, the interval counts from the last rotation.
+	l2 := &Logger{Filename: filepath.Join(dir, "interval.log"), MaxSize: Unlimited, RotationInterval: 2 * time.Hour}
+	defer l2.Close()
+	_, err := l2.Write([]byte("boo!"))
+	isNil(err, t)
+	start := now
+	now = now.Add(time.Hour)
+	equals(start.Add(2*time.Hour), l2.NextScheduledRotation(), t)
+}
@@ -0,0 +1,98 @@
+package timberjack
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets is the number of buckets in a LatencyHistogram. Bucket i
+// counts durations below 2^i microseconds; the last one counts everything
+// slower.
+const latencyBuckets = 32
+
+// LatencyHistogram is a histogram of Write durations with exponentially
+// growing buckets.
+type LatencyHistogram struct {
+	// Count is the number of recorded writes.
+	Count int64
+
+	// Buckets[i] is the number of writes that took less than
+	// LatencyBucketBound(i) but not less than LatencyBucketBound(i-1).
+	Buckets [latencyBuckets]int64
+}
+
+// LatencyBucketBound returns the exclusive upper bound of bucket i of a
+// LatencyHistogram. The last bucket has no upper bound; its nominal bound is
+// returned.
+func LatencyBucketBound(i int) time.Duration {
+	return time.Microsecond << uint(i)
+}
+
+// Percentile returns an upper bound for the latency below which the fraction
+// p (between 0 and 1) of writes fall, at the resolution of the buckets. It
+// returns 0 if no writes have been recorded.
+func (h LatencyHistogram) Percentile(p float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	rank := int64(p*float64(h.Count) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for i, n := range h.Buckets {
+		seen += n
+		if seen >= rank {
+			return LatencyBucketBound(i)
+		}
+	}
+	return LatencyBucketBound(latencyBuckets - 1)
+}
+
+// P50 returns the median write latency. See Percentile.
+func (h LatencyHistogram) P50() time.Duration { return h.Percentile(0.50) }
+
+// P95 returns the 95th percentile write latency. See Percentile.
+func (h LatencyHistogram) P95() time.Duration { return h.Percentile(0.95) }
+
+// P99 returns the 99th percentile write latency. See Percentile.
+func (h LatencyHistogram) P99() time.Duration { return h.Percentile(0.99) }
+
+// latencyRecorder collects Write latencies without locking. It is allocated
+// separately so its counters are 64-bit aligned for atomic access.
+type latencyRecorder struct {
+	buckets [latencyBuckets]int64
+}
+
+// writeLatency returns the Logger's latency recorder, creating it on first
+// use.
+func (l *Logger) writeLatency() *latencyRecorder {
+	l.latencyOnce.Do(func() {
+		l.latency = &latencyRecorder{}
+	})
+	return l.latency
+}
+
+// record counts a write that started at start.
+func (r *latencyRecorder) record(start time.Time) {
+	atomic.AddInt64(&r.buckets[latencyBucket(time.Since(start))], 1)
+}
+
+// latencyBucket returns the index of the bucket d falls into.
+func latencyBucket(d time.Duration) int {
+	i := 0
+	for i < latencyBuckets-1 && d >= LatencyBucketBound(i) {
+		i++
+	}
+	return i
+}
+
+// snapshot returns the recorded latencies as a LatencyHistogram.
+func (r *latencyRecorder) snapshot() LatencyHistogram {
+	var h LatencyHistogram
+	for i := range r.buckets {
+		h.Buckets[i] = atomic.LoadInt64(&r.buckets[i])
+		h.Count += h.Buckets[i]
+	}
+	return h
+}
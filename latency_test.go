@@ -0,0 +1,51 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram_Percentile(t *testing.T) {
+	var h LatencyHistogram
+	equals(time.Duration(0), h.P50(), t)
+
+	// 90 fast writes, 9 slower ones and one outlier.
+	h.Buckets[latencyBucket(500*time.Nanosecond)] = 90
+	h.Buckets[latencyBucket(100*time.Microsecond)] = 9
+	h.Buckets[latencyBucket(time.Second)] = 1
+	h.Count = 100
+
+	equals(time.Microsecond, h.P50(), t)
+	equals(128*time.Microsecond, h.P95(), t)
+	equals(128*time.Microsecond, h.P99(), t)
+	equals(LatencyBucketBound(20), h.Percentile(1), t)
+}
+
+func TestLatencyBucket(t *testing.T) {
+	equals(0, latencyBucket(0), t)
+	equals(0, latencyBucket(999*time.Nanosecond), t)
+	equals(1, latencyBucket(time.Microsecond), t)
+	equals(10, latencyBucket(1023*time.Microsecond), t)
+	equals(latencyBuckets-1, latencyBucket(24*time.Hour), t)
+}
+
+func TestStats_WriteLatency(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestStats_WriteLatency", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100}
+	defer l.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err := l.Write([]byte("boo!"))
+		isNil(err, t)
+	}
+
+	h := l.Stats().WriteLatency
+	equals(int64(10), h.Count, t)
+	assert(h.P50() > 0 && h.P50() <= h.P99(), t, "unexpected percentiles %v %v", h.P50(), h.P99())
+}
@@ -0,0 +1,128 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// layoutFileInfo is a backup found in a BackupDirLayout subdirectory. Its
+// Name is the path relative to the backup directory, so that the mill can
+// address it like any other backup.
+type layoutFileInfo struct {
+	os.FileInfo
+	name string
+}
+
+// Name implements os.FileInfo.
+func (fi layoutFileInfo) Name() string { return fi.name }
+
+// ValidateBackupDirLayout checks that BackupDirLayout produces a relative
+// path that stays within the backup directory.
+func (l *Logger) ValidateBackupDirLayout() error {
+	if l.BackupDirLayout == "" {
+		return nil
+	}
+	sample := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(l.BackupDirLayout)
+	if filepath.IsAbs(sample) || strings.HasPrefix(sample, "/") {
+		return fmt.Errorf("invalid BackupDirLayout %q: must be a relative path", l.BackupDirLayout)
+	}
+	for _, elem := range strings.Split(filepath.ToSlash(sample), "/") {
+		if elem == "" || elem == "." || elem == ".." {
+			return fmt.Errorf("invalid BackupDirLayout %q: empty, . or .. path element", l.BackupDirLayout)
+		}
+	}
+	return nil
+}
+
+// backupLayout returns the BackupDirLayout in effect. An invalid layout is
+// reported on stderr once and ignored, as is the layout for NumberedBackups.
+func (l *Logger) backupLayout() string {
+	if l.BackupDirLayout == "" || l.NumberedBackups {
+		return ""
+	}
+	l.layoutOnce.Do(func() {
+		if err := l.ValidateBackupDirLayout(); err != nil {
+			fmt.Fprintf(os.Stderr, "timberjack: [%s] %v, backups stay in the backup directory\n", l.Filename, err)
+			l.layoutInvalid = true
+		}
+	})
+	if l.layoutInvalid {
+		return ""
+	}
+	return l.BackupDirLayout
+}
+
+// archiveDir returns the directory a backup rotated now is stored in.
+func (l *Logger) archiveDir() string {
+	layout := l.backupLayout()
+	if layout == "" {
+		return l.backupDir()
+	}
+	loc := time.UTC
+	if l.LocalTime {
+		loc = time.Local
+	}
+	return filepath.Join(l.backupDir(), filepath.FromSlash(l.now().In(loc).Format(layout)))
+}
+
+// layoutEntry is a file in the backup directory or one of its layout
+// subdirectories.
+type layoutEntry struct {
+	dir  string // directory containing the file
+	rel  string // path relative to the backup directory
+	info os.FileInfo
+}
+
+// backupEntries lists the files in the backup directory and, if
+// BackupDirLayout is set, in the subdirectories at the layout's depth.
+func (l *Logger) backupEntries() ([]layoutEntry, error) {
+	root := l.backupDir()
+	depth := 0
+	if layout := l.backupLayout(); layout != "" {
+		depth = strings.Count(filepath.ToSlash(layout), "/") + 1
+	}
+
+	var files []layoutEntry
+	var walk func(dir, rel string, level int) error
+	walk = func(dir, rel string, level int) error {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				if level < depth {
+					// Unreadable subdirectories are skipped.
+					_ = walk(filepath.Join(dir, e.Name()), filepath.Join(rel, e.Name()), level+1)
+				}
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			if rel != "" {
+				info = layoutFileInfo{info, filepath.Join(rel, e.Name())}
+			}
+			files = append(files, layoutEntry{dir: dir, rel: filepath.Join(rel, e.Name()), info: info})
+		}
+		return nil
+	}
+	if err := walk(root, "", 0); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// removeEmptyLayoutDirs removes the layout subdirectories containing the
+// removed backup rel that have become empty.
+func (l *Logger) removeEmptyLayoutDirs(rel string) {
+	for dir := filepath.Dir(rel); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if err := os.Remove(filepath.Join(l.backupDir(), dir)); err != nil {
+			return // not empty, or already gone
+		}
+	}
+}
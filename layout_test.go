@@ -0,0 +1,67 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupDirLayout(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestBackupDirLayout", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:        logFile(dir),
+		MaxSize:         10,
+		MaxBackups:      1,
+		Compress:        true,
+		BackupDirLayout: "2006/01/02",
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("foo!"))
+	isNil(err, t)
+	first := filepath.Join(dir, fakeTime().UTC().Format("2006/01/02"))
+	isNil(l.Rotate(), t)
+	<-time.After(300 * time.Millisecond)
+	fileCount(first, 1, t)
+
+	// The next day's backup goes into a new directory; retention removes
+	// the old backup along with its now empty directories.
+	newFakeTime()
+	_, err = l.Write([]byte("bar!"))
+	isNil(err, t)
+	second := filepath.Join(dir, fakeTime().UTC().Format("2006/01/02"))
+	isNil(l.Rotate(), t)
+	<-time.After(300 * time.Millisecond)
+
+	fileCount(second, 1, t)
+	notExist(first, t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	equals(filepath.FromSlash(fakeTime().UTC().Format("2006/01/02")), filepath.Dir(backups[0].Name), t)
+	equals("size", backups[0].Reason, t)
+	assert(backups[0].Compressed, t, "expected %s to be compressed", backups[0].Name)
+}
+
+func TestValidateBackupDirLayout(t *testing.T) {
+	for layout, valid := range map[string]bool{
+		"":             true,
+		"2006/01/02":   true,
+		"2006-01":      true,
+		"/2006/01":     false,
+		"../2006":      false,
+		"2006//01":     false,
+		"2006/./01/02": false,
+	} {
+		l := &Logger{BackupDirLayout: layout}
+		err := l.ValidateBackupDirLayout()
+		equals(valid, err == nil, t)
+	}
+}
@@ -0,0 +1,70 @@
+package timberjack
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// SegmentLink ties a backup to the chain of segments rotated by one Logger,
+// so that downstream systems (e.g. uploaders) can verify that they have seen
+// every segment and detect gaps.
+type SegmentLink struct {
+	// StreamID identifies the Logger instance that rotated the segment. It
+	// is the same for every segment of one Logger and differs between
+	// Loggers and process restarts.
+	StreamID string
+
+	// Sequence numbers the segments of a stream, starting at 1.
+	Sequence int64
+
+	// Predecessor is the base name of the previous backup of the stream, as
+	// it was named when rotated (i.e. before compression). It is empty for
+	// the first segment.
+	Predecessor string
+}
+
+// StreamID returns the identifier of this Logger's chain of segments. See
+// SegmentLink.
+func (l *Logger) StreamID() string {
+	l.streamOnce.Do(func() {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			// Fall back to something unique enough for one host.
+			l.streamID = fmt.Sprintf("%016x", time.Now().UnixNano())
+			return
+		}
+		l.streamID = hex.EncodeToString(b[:])
+	})
+	return l.streamID
+}
+
+// linkSegment records the chain data of a freshly rotated backup and
+// announces the rotation. The data is keyed by the backup's uncompressed
+// name, even if it was compressed while rotated, so that forgetBackup drops
+// it once the backup is removed. It expects l.mu to be held.
+func (l *Logger) linkSegment(backup, reason string) {
+	name := l.trimCompressed(filepath.Base(backup))
+	l.segmentSeq++
+	link := SegmentLink{
+		StreamID:    l.StreamID(),
+		Sequence:    l.segmentSeq,
+		Predecessor: l.lastBackup,
+	}
+	l.lastBackup = name
+
+	l.statsMu.Lock()
+	if l.links == nil {
+		l.links = make(map[string]SegmentLink)
+	}
+	l.links[name] = link
+	l.countRotation(reason)
+	l.statsMu.Unlock()
+
+	l.emit(Event{Type: EventRotation, File: backup, Reason: reason, Link: link})
+	if l.OnRotate != nil {
+		l.OnRotate(backup, reason)
+	}
+}
@@ -0,0 +1,85 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSegmentLinks(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestSegmentLinks", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10}
+	defer l.Close()
+	events := l.Events()
+
+	var names []string
+	for i := 0; i < 3; i++ {
+		_, err := l.Write([]byte("foo!"))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+
+		e := <-events
+		equals(EventRotation, e.Type, t)
+		equals(int64(i+1), e.Link.Sequence, t)
+		equals(l.StreamID(), e.Link.StreamID, t)
+		if i == 0 {
+			equals("", e.Link.Predecessor, t)
+		} else {
+			equals(names[i-1], e.Link.Predecessor, t)
+		}
+		names = append(names, filepath.Base(e.File))
+	}
+	assert(len(l.StreamID()) == 16, t, "unexpected stream ID %q", l.StreamID())
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(3, len(backups), t)
+	for _, b := range backups {
+		assert(b.Link.Sequence > 0, t, "backup %s has no link", b.Name)
+		equals(l.StreamID(), b.Link.StreamID, t)
+	}
+
+	other := &Logger{}
+	assert(other.StreamID() != l.StreamID(), t, "expected distinct stream IDs")
+}
+
+func TestSegmentLinks_Forgotten(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestSegmentLinks_Forgotten", t)
+	defer os.RemoveAll(dir)
+
+	// Streamed rotations name the backup after its compressed form.
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          10,
+		MaxBackups:       1,
+		Compress:         true,
+		StreamCompress:   true,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := l.Write([]byte("foo!"))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+	}
+	isNil(l.millRunOnce(), t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	equals(int64(3), backups[0].Link.Sequence, t)
+	l.statsMu.Lock()
+	equals(1, len(l.links), t)
+	l.statsMu.Unlock()
+}
@@ -221,3 +221,111 @@ func TestChownInvalidSys(t *testing.T) {
 		t.Fatalf("expected chown to fail on invalid Sys(), got: %v", err)
 	}
 }
+
+func TestPreallocateKeepsSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestPreallocateKeepsSize", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 1000, Preallocate: true}
+	defer l.Close()
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+
+	// The reserved space doesn't show up in the file's size or content.
+	existsWithContent(filename, b, t)
+}
+
+// diskFullLogger returns a Logger whose log file in dir is a symlink to
+// /dev/full, so that every write fails with ENOSPC.
+func diskFullLogger(dir string, t testing.TB) *Logger {
+	isNil(os.Symlink("/dev/full", logFile(dir)), t)
+	return &Logger{Filename: logFile(dir), MaxSize: 100, BackupTimeFormat: backupTimeFormat}
+}
+
+func TestDiskFullPolicy_Drop(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestDiskFullPolicy_Drop", t)
+	defer os.RemoveAll(dir)
+
+	l := diskFullLogger(dir, t)
+	l.DiskFullPolicy = DiskFullDrop
+	var full []error
+	l.OnDiskFull = func(err error) { full = append(full, err) }
+	defer l.Close()
+	isNil(l.ValidateDiskFullPolicy(), t)
+
+	b := []byte("boo!")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+	equals(1, len(full), t)
+	equals(ErrorDiskFull, Classify(full[0]), t)
+	equals(int64(1), l.Stats().Dropped, t)
+}
+
+func TestDiskFullPolicy_Retry(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestDiskFullPolicy_Retry", t)
+	defer os.RemoveAll(dir)
+
+	defer func(retries int, backoff time.Duration) {
+		diskFullRetries, diskFullBackoff = retries, backoff
+	}(diskFullRetries, diskFullBackoff)
+	diskFullRetries, diskFullBackoff = 2, time.Millisecond
+
+	l := diskFullLogger(dir, t)
+	l.DiskFullPolicy = DiskFullRetry
+	defer l.Close()
+
+	// The disk stays full, so the error is returned after the retries.
+	start := time.Now()
+	_, err := l.Write([]byte("boo!"))
+	notNil(err, t)
+	equals(ErrorDiskFull, Classify(err), t)
+	assert(time.Since(start) >= 3*time.Millisecond, t, "write was not retried with backoff")
+}
+
+func TestDiskFullPolicy_Prune(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+	dir := makeTempDir("TestDiskFullPolicy_Prune", t)
+	defer os.RemoveAll(dir)
+
+	l := diskFullLogger(dir, t)
+	l.DiskFullPolicy = DiskFullPrune
+	l.KeepPatterns = []string{"*-keep.log"}
+	defer l.Close()
+
+	oldest := backupFileWithReason(dir, "size")
+	newFakeTime()
+	newest := backupFileWithReason(dir, "size")
+	newFakeTime()
+	keep := backupFileWithReason(dir, "keep")
+	for _, name := range []string{oldest, newest, keep} {
+		isNil(os.WriteFile(name, []byte("old"), 0644), t)
+	}
+
+	// Backups are removed oldest first while the disk stays full; kept
+	// ones are spared.
+	_, err := l.Write([]byte("boo!"))
+	notNil(err, t)
+	notExist(oldest, t)
+	notExist(newest, t)
+	exists(keep, t)
+}
+
+func TestDropFileCache(t *testing.T) {
+	dir := makeTempDir("TestDropFileCache", t)
+	defer os.RemoveAll(dir)
+
+	name := logFile(dir)
+	isNil(os.WriteFile(name, []byte("boo!"), 0644), t)
+	isNil(dropFileCache(name), t)
+	existsWithContent(name, []byte("boo!"), t)
+}
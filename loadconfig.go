@@ -0,0 +1,330 @@
+package timberjack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// LoadConfig reads a Logger's configuration from the file at path, in the
+// format given by its extension: JSON (.json), YAML (.yaml, .yml) or TOML
+// (.toml). Keys are the fields' json, yaml and toml tags, which are the
+// same, and durations and sizes may be written as in UnmarshalJSON.
+//
+// YAML and TOML files must be flat: a "key: value" or "key = value" line per
+// field, with lists written as [a, b] (or as "- " items in YAML). Strings
+// may be quoted. Double-quoted strings decode the escapes \\, \", \b, \f, \n,
+// \r, \t, \uXXXX and \UXXXXXXXX, and in YAML also \/, \0, \a, \v, \e and
+// \xXX; other escapes are errors. Single-quoted strings are taken as they
+// are, except that YAML reads a doubled single quote as one. Unknown keys
+// are errors in every format, so that typos don't go unnoticed. Call
+// Validate on the result to check the configuration itself.
+func LoadConfig(path string) (*Logger, error) {
+	format := configFormat(path)
+	if format == "" {
+		return nil, fmt.Errorf("timberjack: %s: unknown config file format %q", path, filepath.Ext(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	l := &Logger{}
+	if err := decodeConfig(format, data, l); err != nil {
+		return nil, fmt.Errorf("timberjack: %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// configFormat returns the config file format of path by its extension, or
+// "" if it isn't known.
+func configFormat(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	default:
+		return ""
+	}
+}
+
+// decodeConfig decodes data in format into v, a *Logger or *Config.
+func decodeConfig(format string, data []byte, v interface{}) error {
+	if format != "json" {
+		return decodeFlatConfig(format, data, v)
+	}
+	if err := checkConfigKeys(data, reflect.TypeOf(v).Elem()); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// checkConfigKeys reports keys of the JSON object data that aren't fields of
+// the struct type t, which encoding/json would silently ignore.
+func checkConfigKeys(data []byte, t reflect.Type) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	for key := range m {
+		if _, ok := configTagFields(t, "json")[strings.ToLower(key)]; !ok {
+			return fmt.Errorf("unknown field %q", key)
+		}
+	}
+	return nil
+}
+
+// configTagFields returns the indexes of the fields of the struct type t by
+// the lowercased value of their tag.
+func configTagFields(t reflect.Type, tag string) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" {
+			fields[strings.ToLower(name)] = i
+		}
+	}
+	return fields
+}
+
+// decodeFlatConfig decodes a flat YAML mapping or TOML table in data into
+// v, a pointer to a struct.
+func decodeFlatConfig(format string, data []byte, v interface{}) error {
+	sv := reflect.ValueOf(v).Elem()
+	fields := configTagFields(sv.Type(), format)
+	sep, want := ":", "key: value"
+	if format == "toml" {
+		sep, want = "=", "key = value"
+	}
+
+	var list reflect.Value // the list field YAML block items are added to
+	var listKey string
+	for n, line := range strings.Split(string(data), "\n") {
+		line = stripConfigComment(line)
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || (format == "yaml" && trimmed == "---") {
+			continue
+		}
+		if format == "yaml" && (strings.HasPrefix(trimmed, "- ") || trimmed == "-") {
+			if !list.IsValid() {
+				return fmt.Errorf("line %d: list item outside a list", n+1)
+			}
+			if err := appendConfigItem(format, list, strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))); err != nil {
+				return fmt.Errorf("line %d: %s: %v", n+1, listKey, err)
+			}
+			continue
+		}
+		list = reflect.Value{}
+
+		i := strings.Index(trimmed, sep)
+		if i < 0 || line[0] == ' ' || line[0] == '\t' {
+			return fmt.Errorf("line %d: expected %q", n+1, want)
+		}
+		key, err := unquoteConfig(format, strings.TrimSpace(trimmed[:i]))
+		if err != nil {
+			return fmt.Errorf("line %d: %v", n+1, err)
+		}
+		value := strings.TrimSpace(trimmed[i+1:])
+		index, ok := fields[strings.ToLower(key)]
+		if !ok {
+			return fmt.Errorf("line %d: unknown field %q", n+1, key)
+		}
+		field := sv.Field(index)
+		if err := setConfigValue(format, strings.ToLower(key), field, value); err != nil {
+			return fmt.Errorf("line %d: %s: %v", n+1, key, err)
+		}
+		if format == "yaml" && field.Kind() == reflect.Slice && value == "" {
+			list, listKey = field, key
+		}
+	}
+	return nil
+}
+
+// stripConfigComment removes a # comment from line, unless the # is quoted
+// or part of a word.
+func stripConfigComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++ // an escaped character
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// setConfigValue sets the field called name from the scalar or flow list
+// value, written in format.
+func setConfigValue(format, name string, field reflect.Value, value string) error {
+	if field.Kind() == reflect.Slice {
+		field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+		if value == "" || value == "[]" {
+			return nil
+		}
+		if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+			return fmt.Errorf("expected a list, got %q", value)
+		}
+		for _, item := range splitConfigList(value[1 : len(value)-1]) {
+			if err := appendConfigItem(format, field, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, err := unquoteConfig(format, value)
+	if err != nil {
+		return err
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int64:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			field.SetInt(n)
+			return nil
+		}
+		n, ok, err := humanizedValue(name, field.Type(), value)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// splitConfigList splits the items of a flow list at commas outside quotes.
+func splitConfigList(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++ // an escaped character
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ',':
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		items = append(items, last) // a trailing comma is allowed
+	}
+	return items
+}
+
+// appendConfigItem appends item, written in format, to the list field, a
+// []int or []string.
+func appendConfigItem(format string, field reflect.Value, item string) error {
+	item, err := unquoteConfig(format, item)
+	if err != nil {
+		return err
+	}
+	if field.Type().Elem().Kind() == reflect.String {
+		field.Set(reflect.Append(field, reflect.ValueOf(item)))
+		return nil
+	}
+	n, err := strconv.Atoi(item)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", item)
+	}
+	field.Set(reflect.Append(field, reflect.ValueOf(n)))
+	return nil
+}
+
+// unquoteConfig returns the string s, written in format, without its
+// quotes and with its escapes decoded, or s itself if it isn't quoted.
+func unquoteConfig(format, s string) (string, error) {
+	if len(s) < 2 || (s[0] != '"' && s[0] != '\'') || s[len(s)-1] != s[0] {
+		return s, nil
+	}
+	body := s[1 : len(s)-1]
+	if s[0] == '\'' {
+		if format == "yaml" {
+			return strings.Replace(body, "''", "'", -1), nil
+		}
+		return body, nil // a TOML literal string
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i++; i == len(body) {
+			return "", fmt.Errorf("invalid escape at the end of %s", s)
+		}
+		if r, ok := configEscapes[body[i]]; ok {
+			b.WriteByte(r)
+			continue
+		}
+		if r, ok := yamlConfigEscapes[body[i]]; ok && format == "yaml" {
+			b.WriteByte(r)
+			continue
+		}
+		var digits int
+		switch {
+		case body[i] == 'u':
+			digits = 4
+		case body[i] == 'U':
+			digits = 8
+		case body[i] == 'x' && format == "yaml":
+			digits = 2
+		}
+		if digits == 0 || i+digits >= len(body) {
+			return "", fmt.Errorf("invalid escape \\%c in %s", body[i], s)
+		}
+		code, err := strconv.ParseUint(body[i+1:i+1+digits], 16, 32)
+		if err != nil || (digits > 2 && !utf8.ValidRune(rune(code))) {
+			return "", fmt.Errorf("invalid escape \\%s in %s", body[i:i+1+digits], s)
+		}
+		b.WriteRune(rune(code))
+		i += digits
+	}
+	return b.String(), nil
+}
+
+// configEscapes are the single-character escapes of TOML basic strings and
+// YAML double-quoted strings.
+var configEscapes = map[byte]byte{
+	'\\': '\\', '"': '"', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t',
+}
+
+// yamlConfigEscapes are the single-character escapes only YAML knows.
+var yamlConfigEscapes = map[byte]byte{
+	'/': '/', '0': 0, 'a': '\a', 'v': '\v', 'e': 0x1b,
+}
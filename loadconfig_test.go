@@ -0,0 +1,104 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := makeTempDir("TestLoadConfig", t)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"log.json": `{"filename": "/var/log/app.log", "maxsize": "250MB", "compress": true,
+			"rotationinterval": "6h", "rotationperiod": "daily", "keeppatterns": ["*-manual*", "a,b"]}`,
+		"log.yaml": `# application log
+filename: /var/log/app.log
+maxsize: 250MB
+compress: true
+rotationinterval: 6h
+rotationperiod: "daily"
+keeppatterns:
+  - "*-manual*"
+  - a,b
+`,
+		"log.toml": `filename = "/var/log/app.log" # the active file
+maxsize = "250MB"
+compress = true
+rotationinterval = "6h"
+rotationperiod = "daily"
+keeppatterns = ["*-manual*", "a,b"]
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		isNil(os.WriteFile(path, []byte(content), 0644), t)
+		l, err := LoadConfig(path)
+		isNil(err, t)
+		equals("/var/log/app.log", l.Filename, t)
+		equals(250, l.MaxSize, t)
+		equals(true, l.Compress, t)
+		equals(6*time.Hour, l.RotationInterval, t)
+		equals(RotationDaily, l.RotationPeriod, t)
+		equals([]string{"*-manual*", "a,b"}, l.KeepPatterns, t)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := makeTempDir("TestLoadConfig_Errors", t)
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"unknown.json": `{"maxsise": 10}`,
+		"unknown.yaml": "maxsise: 10\n",
+		"unknown.toml": "maxsise = 10\n",
+		"nested.toml":  "[logger]\nmaxsize = 10\n",
+		"format.ini":   "maxsize = 10\n",
+	} {
+		path := filepath.Join(dir, name)
+		isNil(os.WriteFile(path, []byte(content), 0644), t)
+		_, err := LoadConfig(path)
+		notNil(err, t)
+	}
+
+	_, err := LoadConfig(filepath.Join(dir, "missing.json"))
+	assert(os.IsNotExist(err), t, "expected a not-exist error, got %v", err)
+}
+
+func TestLoadConfig_Escapes(t *testing.T) {
+	dir := makeTempDir("TestLoadConfig_Escapes", t)
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"basic.toml":   `filename = "C:\\logs\\app\t\"x\" \u00e9.log" # comment` + "\n" + `keeppatterns = ["a\"#,b", 'c\d']` + "\n",
+		"literal.toml": `filename = 'C:\logs\app` + "\t" + `"x" é.log'` + "\n" + `keeppatterns = ["a\"#,b", 'c\d']` + "\n",
+		"double.yaml":  `filename: "C:\\logs\\app\t\"x\" \xe9.log"` + "\n" + "keeppatterns:\n  - \"a\\\"#,b\"\n  - 'c\\d'\n",
+	} {
+		path := filepath.Join(dir, name)
+		isNil(os.WriteFile(path, []byte(content), 0644), t)
+		l, err := LoadConfig(path)
+		isNil(err, t)
+		equals("C:\\logs\\app\t\"x\" é.log", l.Filename, t)
+		equals([]string{`a"#,b`, `c\d`}, l.KeepPatterns, t)
+	}
+
+	path := filepath.Join(dir, "quote.yaml")
+	isNil(os.WriteFile(path, []byte("filename: 'it''s.log'\n"), 0644), t)
+	l, err := LoadConfig(path)
+	isNil(err, t)
+	equals("it's.log", l.Filename, t)
+
+	for name, content := range map[string]string{
+		"unknown.toml":   `filename = "a\qb"` + "\n",
+		"yamlonly.toml":  `filename = "a\x41"` + "\n",
+		"truncated.yaml": `filename: "a\u12"` + "\n",
+		"trailing.yaml":  `filename: "a\"` + "\n",
+	} {
+		path := filepath.Join(dir, name)
+		isNil(os.WriteFile(path, []byte(content), 0644), t)
+		_, err := LoadConfig(path)
+		notNil(err, t)
+	}
+}
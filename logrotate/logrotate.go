@@ -0,0 +1,355 @@
+// Package logrotate converts between timberjack Logger configurations and
+// logrotate(8) configuration files.
+//
+// It helps teams migrate between external and in-process rotation without the
+// retention policy drifting: Generate renders the logrotate equivalent of a
+// Logger, and Parse builds Loggers from an existing logrotate configuration.
+//
+// Only the subset of settings that both systems understand is converted:
+// rotation frequency, size threshold, number of backups, maximum age,
+// compression and the backup directory. Settings that have no equivalent are reported as errors by
+// Generate and ignored by Parse.
+//
+// The number of backups maps between MaxBackups and the rotate directive,
+// whose defaults differ: a MaxBackups of 0 keeps every backup and is
+// written as "rotate -1". logrotate's "rotate 0", also its default when no
+// rotate directive applies, keeps no backups, which a Logger can't express,
+// so Parse rejects it.
+package logrotate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DeRuina/timberjack"
+)
+
+// keepNone is the MaxBackups Parse uses for "rotate 0" until the stanza is
+// complete, when a Logger still set to it is an error.
+const keepNone = -1
+
+// intervals maps logrotate frequency directives to rotation intervals.
+var intervals = []struct {
+	directive string
+	interval  time.Duration
+}{
+	{"hourly", time.Hour},
+	{"daily", 24 * time.Hour},
+	{"weekly", 7 * 24 * time.Hour},
+}
+
+// cronFrequencies maps RotationSchedule expressions to logrotate frequencies.
+var cronFrequencies = map[string]string{
+	"@hourly":   "hourly",
+	"0 * * * *": "hourly",
+	"@daily":    "daily",
+	"@midnight": "daily",
+	"0 0 * * *": "daily",
+	"@weekly":   "weekly",
+	"0 0 * * 0": "weekly",
+	"@monthly":  "monthly",
+	"0 0 1 * *": "monthly",
+	"@yearly":   "yearly",
+	"0 0 1 1 *": "yearly",
+}
+
+// Generate returns a logrotate configuration stanza equivalent to l's
+// rotation and retention policy. Because the application keeps the log file
+// open, the stanza uses copytruncate.
+//
+// It returns an error if l uses settings logrotate can't express, such as a
+// RotationInterval other than one hour, day or week.
+func Generate(l *timberjack.Logger) (string, error) {
+	if l.Filename == "" {
+		return "", errors.New("logrotate: Filename is required")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s {\n", l.Filename)
+
+	frequency, err := frequencyOf(l)
+	if err != nil {
+		return "", err
+	}
+	if frequency != "" {
+		fmt.Fprintf(&b, "    %s\n", frequency)
+	}
+	if l.MaxSize > 0 {
+		directive := "size"
+		if frequency != "" {
+			// maxsize rotates on size even before the frequency period is up,
+			// just like timberjack combining MaxSize and RotationInterval.
+			directive = "maxsize"
+		}
+		fmt.Fprintf(&b, "    %s %dM\n", directive, l.MaxSize)
+	}
+	if l.MaxBackups < 0 {
+		return "", fmt.Errorf("logrotate: invalid MaxBackups %d", l.MaxBackups)
+	}
+	fmt.Fprintf(&b, "    rotate %d\n", rotateCount(l.MaxBackups))
+	if l.MaxAge > 0 {
+		fmt.Fprintf(&b, "    maxage %d\n", l.MaxAge)
+	}
+	if l.Compress {
+		b.WriteString("    compress\n")
+	}
+	if l.BackupDir != "" {
+		fmt.Fprintf(&b, "    olddir %s\n", l.BackupDir)
+	}
+	b.WriteString("    missingok\n")
+	b.WriteString("    copytruncate\n")
+	b.WriteString("}\n")
+	return b.String(), nil
+}
+
+// rotateCount returns the logrotate rotate count equivalent to maxBackups.
+func rotateCount(maxBackups int) int {
+	if maxBackups == 0 {
+		return -1 // keep all
+	}
+	return maxBackups
+}
+
+// frequencyOf returns the logrotate frequency directive for l, if any.
+func frequencyOf(l *timberjack.Logger) (string, error) {
+	if l.RotationPeriod != "" {
+		if l.RotationSchedule != "" || l.RotationInterval != 0 || len(l.RotateAtMinutes) > 0 || len(l.RotateAtTimes) > 0 {
+			return "", errors.New("logrotate: RotationPeriod can't be combined with other time-based rotation")
+		}
+		if err := l.ValidateRotationPeriod(); err != nil {
+			return "", fmt.Errorf("logrotate: %w", err)
+		}
+		return string(l.RotationPeriod), nil
+	}
+	if len(l.RotateAtTimes) > 0 {
+		if l.RotationSchedule != "" || l.RotationInterval != 0 || len(l.RotateAtMinutes) > 0 {
+			return "", errors.New("logrotate: RotateAtTimes can't be combined with other time-based rotation")
+		}
+		if len(l.RotateAtTimes) == 1 && l.RotateAtTimes[0] == "00:00" {
+			return "daily", nil
+		}
+		return "", fmt.Errorf("logrotate: RotateAtTimes %v has no logrotate equivalent", l.RotateAtTimes)
+	}
+	if l.RotationSchedule != "" {
+		if l.RotationInterval != 0 || len(l.RotateAtMinutes) > 0 {
+			return "", errors.New("logrotate: RotationSchedule can't be combined with other time-based rotation")
+		}
+		if directive, ok := cronFrequencies[l.RotationSchedule]; ok {
+			return directive, nil
+		}
+		return "", fmt.Errorf("logrotate: RotationSchedule %q has no logrotate equivalent", l.RotationSchedule)
+	}
+	if len(l.RotateAtMinutes) > 0 {
+		if len(l.RotateAtMinutes) == 1 && l.RotateAtMinutes[0] == 0 && l.RotationInterval == 0 {
+			return "hourly", nil
+		}
+		return "", fmt.Errorf("logrotate: RotateAtMinutes %v has no logrotate equivalent", l.RotateAtMinutes)
+	}
+	if l.RotationInterval == 0 {
+		return "", nil
+	}
+	for _, iv := range intervals {
+		if iv.interval == l.RotationInterval {
+			return iv.directive, nil
+		}
+	}
+	return "", fmt.Errorf("logrotate: RotationInterval %v has no logrotate equivalent", l.RotationInterval)
+}
+
+// Parse reads a logrotate configuration and returns one Logger per log file
+// path, configured with the equivalent timberjack policy. Directives that
+// appear outside of a stanza act as defaults for all following stanzas.
+// Unknown directives and include statements are ignored. Glob paths are
+// returned verbatim in Filename. A stanza keeping no backups ("rotate 0",
+// or no rotate directive at all) is an error.
+func Parse(r io.Reader) ([]*timberjack.Logger, error) {
+	var (
+		loggers  []*timberjack.Logger
+		defaults timberjack.Logger
+		current  []*timberjack.Logger // loggers of the stanza being parsed
+		paths    []string             // paths seen before the opening brace
+	)
+	defaults.MaxBackups = keepNone // logrotate's default is "rotate 0"
+
+	open := func(lineNo int) error {
+		if len(paths) == 0 {
+			return fmt.Errorf("logrotate: line %d: stanza without a path", lineNo)
+		}
+		for _, p := range paths {
+			l := &timberjack.Logger{}
+			copyPolicy(l, &defaults)
+			l.Filename = strings.Trim(p, `"'`)
+			current = append(current, l)
+		}
+		paths = nil
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if current == nil {
+			switch {
+			case strings.HasSuffix(line, "{"):
+				paths = append(paths, strings.Fields(strings.TrimSuffix(line, "{"))...)
+				if err := open(lineNo); err != nil {
+					return nil, err
+				}
+			case isPath(line):
+				paths = append(paths, strings.Fields(line)...)
+			default:
+				// Global directive: a default for all following stanzas.
+				if err := apply(&defaults, strings.Fields(line)); err != nil {
+					return nil, fmt.Errorf("logrotate: line %d: %v", lineNo, err)
+				}
+			}
+			continue
+		}
+
+		if line == "}" {
+			for _, l := range current {
+				if l.MaxBackups == keepNone {
+					return nil, fmt.Errorf("logrotate: line %d: %s keeps no backups (rotate 0, the default), which timberjack can't express; set rotate", lineNo, l.Filename)
+				}
+			}
+			loggers = append(loggers, current...)
+			current = nil
+			continue
+		}
+		for _, l := range current {
+			if err := apply(l, strings.Fields(line)); err != nil {
+				return nil, fmt.Errorf("logrotate: line %d: %v", lineNo, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if current != nil {
+		return nil, errors.New("logrotate: unterminated stanza")
+	}
+	return loggers, nil
+}
+
+// isPath reports whether line lists log file paths rather than a directive.
+func isPath(line string) bool {
+	word := strings.Trim(strings.Fields(line)[0], `"'`)
+	return strings.HasPrefix(word, "/") || strings.HasPrefix(word, "~") || strings.ContainsAny(word, "*?")
+}
+
+// copyPolicy copies the policy fields handled by Parse from src to dst.
+func copyPolicy(dst, src *timberjack.Logger) {
+	dst.MaxSize = src.MaxSize
+	dst.MaxBackups = src.MaxBackups
+	dst.MaxAge = src.MaxAge
+	dst.Compress = src.Compress
+	dst.RotationInterval = src.RotationInterval
+	dst.RotationPeriod = src.RotationPeriod
+	dst.RotationSchedule = src.RotationSchedule
+	dst.BackupDir = src.BackupDir
+}
+
+// apply updates l according to a single logrotate directive.
+func apply(l *timberjack.Logger, fields []string) error {
+	switch fields[0] {
+	case "hourly", "daily", "weekly":
+		setFrequency(l)
+		for _, iv := range intervals {
+			if iv.directive == fields[0] {
+				l.RotationInterval = iv.interval
+			}
+		}
+	case "monthly":
+		setFrequency(l)
+		l.RotationPeriod = timberjack.RotationMonthly
+	case "yearly":
+		setFrequency(l)
+		l.RotationSchedule = "@yearly"
+	case "size", "maxsize":
+		if len(fields) < 2 {
+			return fmt.Errorf("%s requires an argument", fields[0])
+		}
+		mb, err := parseSize(fields[1])
+		if err != nil {
+			return err
+		}
+		l.MaxSize = mb
+	case "rotate", "maxage":
+		if len(fields) < 2 {
+			return fmt.Errorf("%s requires an argument", fields[0])
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid %s count %q", fields[0], fields[1])
+		}
+		switch {
+		case fields[0] == "maxage":
+			l.MaxAge = n
+		case n == 0:
+			l.MaxBackups = keepNone
+		case n == -1:
+			l.MaxBackups = 0 // keep all
+		case n > 0:
+			l.MaxBackups = n
+		default:
+			return fmt.Errorf("invalid rotate count %q", fields[1])
+		}
+	case "olddir":
+		if len(fields) < 2 {
+			return fmt.Errorf("%s requires an argument", fields[0])
+		}
+		l.BackupDir = fields[1]
+	case "compress":
+		l.Compress = true
+	case "nocompress":
+		l.Compress = false
+	}
+	return nil
+}
+
+// setFrequency clears the time-based rotation of l before a frequency
+// directive sets it.
+func setFrequency(l *timberjack.Logger) {
+	l.RotationInterval = 0
+	l.RotationPeriod = ""
+	l.RotationSchedule = ""
+}
+
+// parseSize converts a logrotate size ("100", "100k", "10M", "1G") to
+// megabytes, rounding up so small sizes still rotate.
+func parseSize(s string) (int, error) {
+	multiplier := int64(1)
+	num := s
+	switch suffix := strings.ToLower(s[len(s)-1:]); suffix {
+	case "k":
+		multiplier = 1 << 10
+		num = s[:len(s)-1]
+	case "m":
+		multiplier = 1 << 20
+		num = s[:len(s)-1]
+	case "g":
+		multiplier = 1 << 30
+		num = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	bytes := n * multiplier
+	return int((bytes + (1<<20 - 1)) >> 20), nil
+}
@@ -0,0 +1,274 @@
+package logrotate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DeRuina/timberjack"
+)
+
+func TestGenerate(t *testing.T) {
+	l := &timberjack.Logger{
+		Filename:         "/var/log/app/app.log",
+		MaxSize:          100,
+		MaxBackups:       7,
+		MaxAge:           28,
+		Compress:         true,
+		RotationInterval: 24 * time.Hour,
+	}
+	got, err := Generate(l)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := `/var/log/app/app.log {
+    daily
+    maxsize 100M
+    rotate 7
+    maxage 28
+    compress
+    missingok
+    copytruncate
+}
+`
+	if got != want {
+		t.Fatalf("Generate mismatch\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGenerate_Unrepresentable(t *testing.T) {
+	for _, l := range []*timberjack.Logger{
+		{},
+		{Filename: "/var/log/a.log", RotationInterval: 90 * time.Minute},
+		{Filename: "/var/log/a.log", RotateAtMinutes: []int{0, 30}},
+		{Filename: "/var/log/a.log", RotationSchedule: "*/5 * * * *"},
+		{Filename: "/var/log/a.log", RotateAtTimes: []string{"06:30"}},
+		{Filename: "/var/log/a.log", RotationPeriod: "fortnightly"},
+		{Filename: "/var/log/a.log", RotationPeriod: timberjack.RotationDaily, RotationInterval: time.Hour},
+		{Filename: "/var/log/a.log", RotationSchedule: "@daily", RotationInterval: time.Hour},
+	} {
+		if _, err := Generate(l); err == nil {
+			t.Errorf("expected error for %+v", l)
+		}
+	}
+
+	got, err := Generate(&timberjack.Logger{Filename: "/var/log/a.log", MaxSize: 5, RotateAtMinutes: []int{0}})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.Contains(got, "hourly") || !strings.Contains(got, "maxsize 5M") {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+
+	got, err = Generate(&timberjack.Logger{Filename: "/var/log/a.log", RotationPeriod: timberjack.RotationWeekly})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.Contains(got, "    weekly\n") {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+
+	got, err = Generate(&timberjack.Logger{Filename: "/var/log/a.log", RotationSchedule: "@monthly"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.Contains(got, "    monthly\n") {
+		t.Fatalf("unexpected output:\n%s", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	conf := `
+# global defaults
+weekly
+rotate 4
+compress
+
+/var/log/app/app.log /var/log/app/err.log {
+    daily
+    size 512k   # rounded up to 1M
+    maxage 14
+    postrotate
+        /usr/bin/true
+    endscript
+}
+
+"/var/log/other.log"
+{
+    nocompress
+    size 2G
+}
+`
+	loggers, err := Parse(strings.NewReader(conf))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(loggers) != 3 {
+		t.Fatalf("expected 3 loggers, got %d", len(loggers))
+	}
+
+	app := loggers[0]
+	if app.Filename != "/var/log/app/app.log" || loggers[1].Filename != "/var/log/app/err.log" {
+		t.Fatalf("unexpected filenames %q, %q", app.Filename, loggers[1].Filename)
+	}
+	if app.RotationInterval != 24*time.Hour || app.MaxSize != 1 || app.MaxAge != 14 ||
+		app.MaxBackups != 4 || !app.Compress {
+		t.Fatalf("unexpected policy %+v", app)
+	}
+
+	other := loggers[2]
+	if other.Filename != "/var/log/other.log" || other.RotationInterval != 7*24*time.Hour ||
+		other.MaxSize != 2048 || other.Compress {
+		t.Fatalf("unexpected policy %+v", other)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, conf := range []string{
+		"/var/log/a.log {\n rotate many\n}\n",
+		"/var/log/a.log {\n size\n}\n",
+		"/var/log/a.log {\n size 10X\n}\n",
+		"/var/log/a.log {\n daily\n",
+		"{\n daily\n}\n",
+	} {
+		if _, err := Parse(strings.NewReader(conf)); err == nil {
+			t.Errorf("expected error for %q", conf)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	in := &timberjack.Logger{
+		Filename:         "/var/log/app.log",
+		MaxSize:          50,
+		MaxBackups:       3,
+		MaxAge:           7,
+		Compress:         true,
+		RotationInterval: time.Hour,
+		BackupDir:        "/var/log/archive",
+	}
+	conf, err := Generate(in)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	loggers, err := Parse(strings.NewReader(conf))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	out := loggers[0]
+	if out.Filename != in.Filename || out.MaxSize != in.MaxSize || out.MaxBackups != in.MaxBackups ||
+		out.MaxAge != in.MaxAge || out.Compress != in.Compress || out.RotationInterval != in.RotationInterval ||
+		out.BackupDir != in.BackupDir {
+		t.Fatalf("round trip mismatch: %+v", out)
+	}
+}
+
+func TestRoundTrip_Policies(t *testing.T) {
+	for _, in := range []*timberjack.Logger{
+		{Filename: "/var/log/a.log", MaxSize: 10},                                  // keeps all backups
+		{Filename: "/var/log/a.log", MaxAge: 30},                                   // keeps backups by age only
+		{Filename: "/var/log/a.log", RotationPeriod: timberjack.RotationMonthly},   // monthly
+		{Filename: "/var/log/a.log", RotationSchedule: "@yearly", MaxBackups: 2},   // yearly
+		{Filename: "/var/log/a.log", RotationInterval: 7 * 24 * time.Hour},         // weekly
+		{Filename: "/var/log/a.log", RotationSchedule: "@monthly", Compress: true}, // read back as RotationPeriod
+	} {
+		conf, err := Generate(in)
+		if err != nil {
+			t.Fatalf("Generate(%+v): %v", in, err)
+		}
+		loggers, err := Parse(strings.NewReader(conf))
+		if err != nil {
+			t.Fatalf("Parse:\n%s\n%v", conf, err)
+		}
+		again, err := Generate(loggers[0])
+		if err != nil {
+			t.Fatalf("Generate of parsed %+v: %v", loggers[0], err)
+		}
+		if again != conf {
+			t.Errorf("round trip mismatch\nfirst:\n%s\nsecond:\n%s", conf, again)
+		}
+		if out := loggers[0]; out.MaxBackups != in.MaxBackups || out.MaxAge != in.MaxAge {
+			t.Errorf("retention changed: in MaxBackups=%d MaxAge=%d, out MaxBackups=%d MaxAge=%d",
+				in.MaxBackups, in.MaxAge, out.MaxBackups, out.MaxAge)
+		}
+	}
+}
+
+func TestGenerate_Retention(t *testing.T) {
+	for _, tc := range []struct {
+		maxBackups int
+		want       string
+	}{
+		{0, "    rotate -1\n"},
+		{5, "    rotate 5\n"},
+	} {
+		got, err := Generate(&timberjack.Logger{Filename: "/var/log/a.log", MaxBackups: tc.maxBackups})
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		if !strings.Contains(got, tc.want) {
+			t.Errorf("MaxBackups %d: expected %q in:\n%s", tc.maxBackups, tc.want, got)
+		}
+	}
+	if _, err := Generate(&timberjack.Logger{Filename: "/var/log/a.log", MaxBackups: -1}); err == nil {
+		t.Error("expected error for a negative MaxBackups")
+	}
+}
+
+func TestParse_Retention(t *testing.T) {
+	conf := `
+monthly
+rotate 3
+size 10M
+/var/log/default.log {
+}
+/var/log/all.log {
+    rotate -1
+}
+/var/log/yearly.log {
+    yearly
+}
+`
+	loggers, err := Parse(strings.NewReader(conf))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(loggers) != 3 {
+		t.Fatalf("expected 3 loggers, got %d", len(loggers))
+	}
+	for i, want := range []int{3, 0, 3} {
+		if got := loggers[i].MaxBackups; got != want {
+			t.Errorf("%s: expected MaxBackups %d, got %d", loggers[i].Filename, want, got)
+		}
+	}
+	for _, l := range loggers[:2] {
+		if l.RotationPeriod != timberjack.RotationMonthly {
+			t.Errorf("%s: expected the global monthly, got %+v", l.Filename, l)
+		}
+	}
+	for _, l := range loggers {
+		if err := l.Validate(); err != nil {
+			t.Errorf("%s: %v", l.Filename, err)
+		}
+	}
+	if y := loggers[2]; y.RotationSchedule != "@yearly" || y.RotationPeriod != "" || y.RotationInterval != 0 {
+		t.Errorf("unexpected yearly policy %+v", y)
+	}
+
+	// Keeping no backups can't be expressed.
+	for _, conf := range []string{
+		"/var/log/a.log {\n rotate 0\n}\n",
+		"/var/log/a.log {\n daily\n}\n",
+		"rotate 0\n/var/log/a.log {\n}\n",
+		"/var/log/a.log {\n rotate -2\n}\n",
+	} {
+		if _, err := Parse(strings.NewReader(conf)); err == nil {
+			t.Errorf("expected error for %q", conf)
+		}
+	}
+	// A stanza's rotate overrides a global rotate 0.
+	loggers, err = Parse(strings.NewReader("rotate 0\n/var/log/a.log {\n rotate 2\n}\n"))
+	if err != nil || loggers[0].MaxBackups != 2 {
+		t.Errorf("expected MaxBackups 2, got %v, %v", loggers, err)
+	}
+}
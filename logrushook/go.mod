@@ -0,0 +1,10 @@
+module github.com/DeRuina/timberjack/logrushook
+
+go 1.16
+
+require (
+	github.com/DeRuina/timberjack v0.0.0-00010101000000-000000000000
+	github.com/sirupsen/logrus v1.9.3
+)
+
+replace github.com/DeRuina/timberjack => ../
@@ -0,0 +1,63 @@
+// Package logrushook routes logrus entries to a timberjack Logger. It's a
+// module of its own so that timberjack itself doesn't depend on logrus:
+//
+//	logrus.SetOutput(io.Discard)
+//	logrus.AddHook(logrushook.New(logger))
+//
+// Each entry is written in one piece, so rotation never splits one. Entries
+// at logrus.ErrorLevel are flushed to the file right away when the Logger
+// buffers writes, and Fatal and Panic entries are also fsynced, since
+// logrus exits or panics right after firing the hooks and deferred calls
+// such as the Logger's Close may never run.
+package logrushook
+
+import (
+	"github.com/DeRuina/timberjack"
+	"github.com/sirupsen/logrus"
+)
+
+// Hook is a logrus.Hook writing entries to a Logger.
+type Hook struct {
+	// Formatter formats the entries. If nil, the formatter of the entry's
+	// logrus Logger is used.
+	Formatter logrus.Formatter
+
+	l      *timberjack.Logger
+	levels []logrus.Level
+}
+
+// New returns a Hook writing entries at the given levels, all of them if
+// none are given, to l.
+func New(l *timberjack.Logger, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+	return &Hook{l: l, levels: levels}
+}
+
+// Levels implements logrus.Hook.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire implements logrus.Hook.
+func (h *Hook) Fire(e *logrus.Entry) error {
+	f := h.Formatter
+	if f == nil {
+		f = e.Logger.Formatter
+	}
+	b, err := f.Format(e)
+	if err != nil {
+		return err
+	}
+	if _, err := h.l.Write(b); err != nil {
+		return err
+	}
+	switch {
+	case e.Level <= logrus.FatalLevel: // Panic or Fatal
+		return h.l.Sync()
+	case e.Level == logrus.ErrorLevel:
+		return h.l.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package logrushook
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DeRuina/timberjack"
+	"github.com/sirupsen/logrus"
+)
+
+// newLogger returns a logrus Logger writing through a Hook to a buffering
+// Logger, and the name of its file.
+func newLogger(t *testing.T, levels ...logrus.Level) (*logrus.Logger, *timberjack.Logger, string) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "app.log")
+	l := &timberjack.Logger{Filename: filename, BufferSize: 1 << 16}
+	t.Cleanup(func() { l.Close() })
+
+	log := logrus.New()
+	log.Out = io.Discard
+	log.Formatter = &logrus.TextFormatter{DisableTimestamp: true}
+	log.AddHook(New(l, levels...))
+	return log, l, filename
+}
+
+func content(t *testing.T, filename string) string {
+	t.Helper()
+	b, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestHook(t *testing.T) {
+	log, l, filename := newLogger(t)
+
+	log.WithField("user", "ann").Info("logged in")
+	if got := content(t, filename); got != "" {
+		t.Fatalf("expected the Info entry to stay buffered, got %q", got)
+	}
+
+	log.Error("failed")
+	want := "level=info msg=\"logged in\" user=ann\nlevel=error msg=failed\n"
+	if got := content(t, filename); got != want {
+		t.Fatalf("expected the Error entry to flush the buffer, got %q, want %q", got, want)
+	}
+
+	isNil(l.Close(), t)
+}
+
+func TestHook_Fatal(t *testing.T) {
+	log, _, filename := newLogger(t)
+	exited := -1
+	log.ExitFunc = func(code int) { exited = code }
+
+	log.Info("starting")
+	log.Fatal("no config")
+	if exited != 1 {
+		t.Fatalf("expected exit code 1, got %d", exited)
+	}
+	if got := content(t, filename); !strings.Contains(got, "msg=starting") || !strings.Contains(got, `msg="no config"`) {
+		t.Fatalf("expected both entries on disk before exiting, got %q", got)
+	}
+}
+
+func TestHook_Panic(t *testing.T) {
+	log, _, filename := newLogger(t)
+
+	func() {
+		defer func() { recover() }()
+		log.Panic("corrupt state")
+	}()
+	if got := content(t, filename); !strings.Contains(got, `msg="corrupt state"`) {
+		t.Fatalf("expected the Panic entry on disk, got %q", got)
+	}
+}
+
+func TestHook_Levels(t *testing.T) {
+	log, l, filename := newLogger(t, logrus.WarnLevel, logrus.ErrorLevel)
+	h := New(l, logrus.WarnLevel)
+	if got := h.Levels(); len(got) != 1 || got[0] != logrus.WarnLevel {
+		t.Fatalf("unexpected levels %v", got)
+	}
+	if got := len(New(l).Levels()); got != len(logrus.AllLevels) {
+		t.Fatalf("expected all levels by default, got %d", got)
+	}
+
+	log.Info("skipped")
+	log.Warn("kept")
+	isNil(l.Close(), t)
+	if got := content(t, filename); got != "level=warning msg=kept\n" {
+		t.Fatalf("unexpected content %q", got)
+	}
+}
+
+func TestHook_Formatter(t *testing.T) {
+	log, l, filename := newLogger(t)
+	h := New(l)
+	h.Formatter = &logrus.JSONFormatter{DisableTimestamp: true}
+	log.ReplaceHooks(logrus.LevelHooks{})
+	log.AddHook(h)
+
+	log.Warn("as json")
+	isNil(l.Close(), t)
+	if got := content(t, filename); got != `{"level":"warning","msg":"as json"}`+"\n" {
+		t.Fatalf("unexpected content %q", got)
+	}
+}
+
+func isNil(err error, t *testing.T) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
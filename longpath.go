@@ -0,0 +1,24 @@
+package timberjack
+
+import "strings"
+
+// maxShortPath is the length from which Windows paths need the extended-length
+// prefix. MAX_PATH is 260, but directories are limited to 248 characters
+// (MAX_PATH minus room for an 8.3 file name), so the prefix is applied early.
+const maxShortPath = 248
+
+// extendedLengthPath returns the Windows extended-length (`\\?\`) form of the
+// absolute, cleaned path abs if it is too long for the classic Win32 APIs.
+// UNC paths (`\\server\share\...`) become `\\?\UNC\server\share\...`.
+// Because the prefix disables path normalization, forward slashes are
+// converted to backslashes.
+func extendedLengthPath(abs string) string {
+	if len(abs) < maxShortPath || strings.HasPrefix(abs, `\\?\`) {
+		return abs
+	}
+	abs = strings.ReplaceAll(abs, "/", `\`)
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+// +build !windows
+
+// Path normalization is only needed on Windows; elsewhere paths are used as-is.
+
+package timberjack
+
+// normalizePath returns path unchanged.
+func normalizePath(path string) string {
+	return path
+}
@@ -0,0 +1,20 @@
+package timberjack
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtendedLengthPath(t *testing.T) {
+	long := `C:\Users\someone\AppData\Local\` + strings.Repeat(`nested\`, 40) + "app.log"
+
+	equals(`C:\logs\app.log`, extendedLengthPath(`C:\logs\app.log`), t)
+	equals(`\\?\`+long, extendedLengthPath(long), t)
+	// Already prefixed paths are left alone.
+	equals(`\\?\`+long, extendedLengthPath(`\\?\`+long), t)
+	// Forward slashes are not normalized once the prefix is added.
+	equals(`\\?\`+long, extendedLengthPath(strings.ReplaceAll(long, `\`, "/")), t)
+
+	unc := `\\server\share\` + strings.Repeat(`nested\`, 40) + "app.log"
+	equals(`\\?\UNC\`+unc[2:], extendedLengthPath(unc), t)
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+// Extended-length path support for Windows, so log files nested deeper than
+// MAX_PATH can be opened, renamed and listed.
+
+package timberjack
+
+import "path/filepath"
+
+// normalizePath returns the extended-length form of path when it exceeds the
+// classic MAX_PATH limit.
+func normalizePath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return extendedLengthPath(abs)
+}
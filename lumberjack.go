@@ -0,0 +1,106 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// lumberjackTimeFormat is the timestamp layout of lumberjack's backup names.
+const lumberjackTimeFormat = "2006-01-02T15-04-05.000"
+
+// lumberjackName returns the lumberjack-style backup name for the log file
+// name rotated at t: <prefix>-<timestamp><ext>.
+func (l *Logger) lumberjackName(name string, t time.Time) string {
+	dir := filepath.Dir(name)
+	filename := filepath.Base(name)
+	ext := filepath.Ext(filename)
+	prefix := filename[:len(filename)-len(ext)]
+
+	loc := time.UTC
+	if l.LocalTime {
+		loc = time.Local
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s%s", prefix, t.In(loc).Format(lumberjackTimeFormat), ext))
+}
+
+// parseLumberjackName returns the rotation time encoded in a lumberjack-style
+// backup name, which lacks timberjack's rotation reason. Like timberjack's
+// own names, it may carry a sequence number after a name collision.
+func (l *Logger) parseLumberjackName(filename, prefix, ext string) (time.Time, error) {
+	if !strings.HasPrefix(filename, prefix) || !strings.HasSuffix(filename, ext) || len(filename) < len(prefix)+len(ext) {
+		return time.Time{}, fmt.Errorf("%s is not a lumberjack backup", filename)
+	}
+	loc := time.UTC
+	if l.LocalTime {
+		loc = time.Local
+	}
+	stamp, seq := splitSequence(filename[len(prefix) : len(filename)-len(ext)])
+	if len(stamp) != len(lumberjackTimeFormat) {
+		// The timestamp's milliseconds look like a sequence number.
+		stamp, seq = filename[len(prefix):len(filename)-len(ext)], 0
+	}
+	t, err := time.ParseInLocation(lumberjackTimeFormat, stamp, loc)
+	return t.Add(time.Duration(seq)), err
+}
+
+// lumberjackBackupTime returns the rotation time of name if LumberjackCompat
+// is set and name is a lumberjack-style backup, compressed or not.
+func (l *Logger) lumberjackBackupTime(name string) (time.Time, bool) {
+	if !l.LumberjackCompat {
+		return time.Time{}, false
+	}
+	prefix, ext := l.prefixAndExt()
+	t, err := l.parseLumberjackName(l.trimCompressed(name), prefix, ext)
+	return t, err == nil
+}
+
+// MigrateBackups renames backups written by lumberjack (or by a Logger with
+// LumberjackCompat set) into timberjack's naming scheme, so that retention
+// and the rotation reason work for them after switching libraries. As
+// lumberjack only rotates on size, migrated backups get the reason "size".
+// Backups whose new name is already taken are left alone. It returns the
+// number of backups renamed.
+func (l *Logger) MigrateBackups() (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	dir := l.backupDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("can't read log file directory: %w", err)
+	}
+
+	layout := l.BackupTimeFormat
+	if layout == "" || l.ValidateBackupTimeFormat() != nil {
+		layout = backupTimeFormat
+	}
+	prefix, ext := l.prefixAndExt()
+
+	migrated := 0
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			continue
+		}
+		uncompressed := l.trimCompressed(name)
+		t, err := l.parseLumberjackName(uncompressed, prefix, ext)
+		if err != nil {
+			continue
+		}
+
+		// Reattach the log file's name, so backupName sees prefix and ext.
+		newName := backupName(filepath.Join(dir, filepath.Base(l.filename())), l.LocalTime, "size", t, layout)
+		newName += l.compressedSuffix(name)
+		if _, err := osStat(newName); err == nil {
+			continue
+		}
+		if err := osRename(filepath.Join(dir, name), newName); err != nil {
+			return migrated, fmt.Errorf("can't migrate backup %s: %w", name, err)
+		}
+		migrated++
+	}
+	return migrated, nil
+}
@@ -0,0 +1,90 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// lumberjackBackup returns the lumberjack-style backup name at fakeTime.
+func lumberjackBackup(dir string) string {
+	return filepath.Join(dir, "foobar-"+fakeTime().UTC().Format(lumberjackTimeFormat)+".log")
+}
+
+func TestLumberjackCompat(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestLumberjackCompat", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          10,
+		MaxBackups:       1,
+		LumberjackCompat: true,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("first"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	first := lumberjackBackup(dir)
+	existsWithContent(first, []byte("first"), t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	equals(fakeTime().UTC().Truncate(time.Millisecond), backups[0].Timestamp.UTC(), t)
+	equals("", backups[0].Reason, t)
+
+	// Lumberjack-style backups are subject to retention.
+	newFakeTime()
+	_, err = l.Write([]byte("second"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	isNil(l.millRunOnce(), t)
+	notExist(first, t)
+	existsWithContent(lumberjackBackup(dir), []byte("second"), t)
+}
+
+func TestMigrateBackups(t *testing.T) {
+	currentTime = fakeTime
+
+	dir := makeTempDir("TestMigrateBackups", t)
+	defer os.RemoveAll(dir)
+
+	plain := lumberjackBackup(dir)
+	isNil(os.WriteFile(plain, []byte("plain"), 0644), t)
+	newFakeTime()
+	compressed := lumberjackBackup(dir) + compressSuffix
+	isNil(os.WriteFile(compressed, []byte("compressed"), 0644), t)
+	unrelated := filepath.Join(dir, "foobar-notatime.log")
+	isNil(os.WriteFile(unrelated, []byte("other"), 0644), t)
+
+	l := &Logger{Filename: logFile(dir)}
+	defer l.Close()
+
+	files, err := l.oldLogFiles()
+	isNil(err, t)
+	equals(0, len(files), t)
+
+	n, err := l.MigrateBackups()
+	isNil(err, t)
+	equals(2, n, t)
+
+	notExist(plain, t)
+	notExist(compressed, t)
+	existsWithContent(backupFileWithReason(dir, "size")+compressSuffix, []byte("compressed"), t)
+	exists(unrelated, t)
+
+	files, err = l.oldLogFiles()
+	isNil(err, t)
+	equals(2, len(files), t)
+
+	// Running it again is a no-op.
+	n, err = l.MigrateBackups()
+	isNil(err, t)
+	equals(0, n, t)
+}
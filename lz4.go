@@ -0,0 +1,255 @@
+package timberjack
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+)
+
+// This file implements an LZ4 frame encoder for the "lz4" CompressionCodec.
+// Blocks are compressed independently with a single hash probe per
+// position, which keeps compression cheap on CPU; the frame carries a
+// content checksum, so any LZ4 decoder can verify it.
+
+const (
+	lz4Magic     = 0x184D2204
+	lz4BlockSize = 256 << 10
+	lz4HashLog   = 14
+	lz4MinMatch  = 4
+	lz4MaxOffset = 65535
+
+	lz4LastLiterals = 5  // the last bytes of a block are always literals
+	lz4MatchLimit   = 12 // no match starts within this many bytes of the end
+)
+
+// lz4Writer is an io.WriteCloser that compresses what is written to it
+// into a single LZ4 frame.
+type lz4Writer struct {
+	w       io.Writer
+	buf     []byte
+	out     []byte
+	table   []int32
+	sum     xxh32
+	started bool
+	err     error
+}
+
+// newLZ4Writer returns an lz4Writer writing to w.
+func newLZ4Writer(w io.Writer) *lz4Writer {
+	return &lz4Writer{w: w, buf: make([]byte, 0, lz4BlockSize), table: make([]int32, 1<<lz4HashLog)}
+}
+
+// Write implements io.Writer.
+func (z *lz4Writer) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	n := len(p)
+	z.sum.write(p)
+	for len(p) > 0 {
+		room := lz4BlockSize - len(z.buf)
+		if room > len(p) {
+			room = len(p)
+		}
+		z.buf = append(z.buf, p[:room]...)
+		p = p[room:]
+		if len(z.buf) == lz4BlockSize {
+			if z.err = z.writeBlock(); z.err != nil {
+				return 0, z.err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the last block, the end mark and the content checksum. It
+// doesn't close the underlying writer.
+func (z *lz4Writer) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	if len(z.buf) > 0 || !z.started {
+		if z.err = z.writeBlock(); z.err != nil {
+			return z.err
+		}
+	}
+	var end [8]byte
+	binary.LittleEndian.PutUint32(end[4:], z.sum.sum())
+	if _, z.err = z.w.Write(end[:]); z.err != nil {
+		return z.err
+	}
+	z.err = errWriterClosed
+	return nil
+}
+
+// writeBlock writes the buffered input as one block, preceded by the frame
+// header if this is the first.
+func (z *lz4Writer) writeBlock() error {
+	if !z.started {
+		// FLG: version 01, independent blocks, content checksum.
+		// BD: 256 KB maximum block size.
+		hdr := []byte{0, 0, 0, 0, 0x64, 0x50, 0}
+		binary.LittleEndian.PutUint32(hdr, lz4Magic)
+		var h xxh32
+		h.write(hdr[4:6])
+		hdr[6] = byte(h.sum() >> 8)
+		if _, err := z.w.Write(hdr); err != nil {
+			return err
+		}
+		z.started = true
+	}
+	if len(z.buf) == 0 {
+		return nil
+	}
+
+	z.out = lz4CompressBlock(z.out[:0], z.buf, z.table)
+	size := uint32(len(z.out))
+	block := z.out
+	if len(z.out) >= len(z.buf) {
+		size, block = uint32(len(z.buf))|1<<31, z.buf // stored uncompressed
+	}
+	var sizeField [4]byte
+	binary.LittleEndian.PutUint32(sizeField[:], size)
+	if _, err := z.w.Write(sizeField[:]); err != nil {
+		return err
+	}
+	if _, err := z.w.Write(block); err != nil {
+		return err
+	}
+	z.buf = z.buf[:0]
+	return nil
+}
+
+// lz4CompressBlock appends the LZ4 block compression of src to dst. table
+// is scratch space for the hash table.
+func lz4CompressBlock(dst, src []byte, table []int32) []byte {
+	for i := range table {
+		table[i] = 0
+	}
+	anchor := 0
+	if len(src) > lz4MatchLimit {
+		for i := 0; i < len(src)-lz4MatchLimit; {
+			h := binary.LittleEndian.Uint32(src[i:]) * 2654435761 >> (32 - lz4HashLog)
+			cand := int(table[h]) - 1 // 0 means empty
+			table[h] = int32(i + 1)
+			if cand < 0 || i-cand > lz4MaxOffset ||
+				binary.LittleEndian.Uint32(src[cand:]) != binary.LittleEndian.Uint32(src[i:]) {
+				i += 1 + (i-anchor)>>6 // skip faster through incompressible data
+				continue
+			}
+			n := lz4MinMatch + matchLen(src[cand+lz4MinMatch:], src[i+lz4MinMatch:len(src)-lz4LastLiterals])
+			for i > anchor && cand > 0 && src[cand-1] == src[i-1] {
+				i, cand, n = i-1, cand-1, n+1
+			}
+			dst = lz4AppendSequence(dst, src[anchor:i], i-cand, n)
+			i += n
+			anchor = i
+		}
+	}
+	return lz4AppendSequence(dst, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence appends a sequence of literals followed by a match of
+// length matchLen at offset, or just the literals if matchLen is 0.
+func lz4AppendSequence(dst, literals []byte, offset, matchLen int) []byte {
+	token := len(dst)
+	dst = append(dst, 0)
+	if n := len(literals); n >= 15 {
+		dst[token] = 15 << 4
+		dst = lz4AppendLength(dst, n-15)
+	} else {
+		dst[token] = byte(n << 4)
+	}
+	dst = append(dst, literals...)
+	if matchLen == 0 {
+		return dst
+	}
+	dst = append(dst, byte(offset), byte(offset>>8))
+	if n := matchLen - lz4MinMatch; n >= 15 {
+		dst[token] |= 15
+		dst = lz4AppendLength(dst, n-15)
+	} else {
+		dst[token] |= byte(n)
+	}
+	return dst
+}
+
+// lz4AppendLength appends the extension bytes of a length.
+func lz4AppendLength(dst []byte, n int) []byte {
+	for ; n >= 255; n -= 255 {
+		dst = append(dst, 255)
+	}
+	return append(dst, byte(n))
+}
+
+const (
+	xxh32Prime1 = 2654435761
+	xxh32Prime2 = 2246822519
+	xxh32Prime3 = 3266489917
+	xxh32Prime4 = 668265263
+	xxh32Prime5 = 374761393
+)
+
+// xxh32 computes the 32-bit xxHash, with seed 0, of the data written to it.
+type xxh32 struct {
+	v     [4]uint32
+	buf   [16]byte
+	nbuf  int
+	total uint64
+}
+
+func xxh32Round(v, lane uint32) uint32 {
+	return bits.RotateLeft32(v+lane*xxh32Prime2, 13) * xxh32Prime1
+}
+
+func (h *xxh32) write(p []byte) {
+	if h.total == 0 {
+		// Prime1+Prime2, Prime2, 0 and -Prime1, modulo 2^32.
+		h.v = [4]uint32{606290984, xxh32Prime2, 0, 1640531535}
+	}
+	h.total += uint64(len(p))
+	if h.nbuf > 0 {
+		n := copy(h.buf[h.nbuf:], p)
+		h.nbuf += n
+		p = p[n:]
+		if h.nbuf < 16 {
+			return
+		}
+		h.stripe(h.buf[:])
+		h.nbuf = 0
+	}
+	for ; len(p) >= 16; p = p[16:] {
+		h.stripe(p)
+	}
+	h.nbuf = copy(h.buf[:], p)
+}
+
+func (h *xxh32) stripe(p []byte) {
+	for i := range h.v {
+		h.v[i] = xxh32Round(h.v[i], binary.LittleEndian.Uint32(p[4*i:]))
+	}
+}
+
+func (h *xxh32) sum() uint32 {
+	var acc uint32
+	if h.total >= 16 {
+		acc = bits.RotateLeft32(h.v[0], 1) + bits.RotateLeft32(h.v[1], 7) +
+			bits.RotateLeft32(h.v[2], 12) + bits.RotateLeft32(h.v[3], 18)
+	} else {
+		acc = xxh32Prime5
+	}
+	acc += uint32(h.total)
+	p := h.buf[:h.nbuf]
+	for ; len(p) >= 4; p = p[4:] {
+		acc = bits.RotateLeft32(acc+binary.LittleEndian.Uint32(p)*xxh32Prime3, 17) * xxh32Prime4
+	}
+	for _, b := range p {
+		acc = bits.RotateLeft32(acc+uint32(b)*xxh32Prime5, 11) * xxh32Prime1
+	}
+	acc ^= acc >> 15
+	acc *= xxh32Prime2
+	acc ^= acc >> 13
+	acc *= xxh32Prime3
+	acc ^= acc >> 16
+	return acc
+}
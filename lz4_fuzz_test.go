@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package timberjack
+
+import (
+	"bytes"
+	"math/rand"
+	"os/exec"
+	"testing"
+)
+
+// FuzzLZ4RoundTrip compresses data, repeated repeat+1 times so that inputs
+// span several blocks, and checks that both the decoder of the tests and,
+// if installed, the reference lz4 tool restore it.
+func FuzzLZ4RoundTrip(f *testing.F) {
+	random := make([]byte, lz4BlockSize+1000)
+	rand.New(rand.NewSource(1)).Read(random)
+	f.Add([]byte{}, uint8(0))
+	f.Add([]byte("hello hello hello hello world\n"), uint8(3))
+	f.Add(interopSample(), uint8(1))
+	f.Add(random, uint8(0))          // incompressible, more than a block
+	f.Add(random[:1999], uint8(200)) // periodic, spanning two blocks
+	f.Add([]byte{0}, uint8(255))
+
+	_, errTool := exec.LookPath("lz4")
+	f.Fuzz(func(t *testing.T, data []byte, repeat uint8) {
+		in := bytes.Repeat(data, int(repeat)+1)
+		var out bytes.Buffer
+		isNil(lz4Codec{}.Compress(&out, bytes.NewReader(in)), t)
+		got, err := decodeLZ4(out.Bytes())
+		isNil(err, t)
+		assert(bytes.Equal(in, got), t, "round trip mismatch of %d bytes", len(in))
+		if errTool == nil {
+			checkDecompressedByTool(t, "lz4", out.Bytes(), in)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package timberjack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLZ4RoundTrip(t *testing.T) {
+	var logs bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&logs, "2025-05-%02d 12:%02d:%02d INFO request id=%d path=/api/v1/items/%d status=200\n", i%28+1, i%60, i%60, i*7, i%1000)
+	}
+	random := make([]byte, 300<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for name, in := range map[string][]byte{
+		"empty":  {},
+		"short":  []byte("hello hello hello hello world\n"),
+		"logs":   logs.Bytes(),
+		"random": random,
+		"zeros":  make([]byte, 3*lz4BlockSize),
+	} {
+		var out bytes.Buffer
+		isNil(lz4Codec{}.Compress(&out, bytes.NewReader(in)), t)
+		got, err := decodeLZ4(out.Bytes())
+		isNil(err, t)
+		assert(bytes.Equal(in, got), t, "%s: round trip mismatch", name)
+		if name == "logs" || name == "zeros" {
+			assert(out.Len() < len(in)/4, t, "%s: compressed %d bytes to %d", name, len(in), out.Len())
+		}
+	}
+}
+
+func TestXXH32(t *testing.T) {
+	for in, want := range map[string]uint32{
+		"":    0x02CC5D05,
+		"abc": 0x32D153FF,
+		"Nobody inspects the spammish repetition": 0xE2293B2F,
+	} {
+		// Write in pieces to exercise the stripe buffering.
+		var h xxh32
+		for i := 0; i < len(in); i += 5 {
+			end := i + 5
+			if end > len(in) {
+				end = len(in)
+			}
+			h.write([]byte(in[i:end]))
+		}
+		equals(want, h.sum(), t)
+	}
+}
+
+func TestCompressionCodec_LZ4(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressionCodec_LZ4", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Compress: true, CompressionCodec: "lz4", BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+	isNil(l.ValidateCompressionCodec(), t)
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	name := backupFileWithReason(dir, "size")
+
+	isNil(l.millRunOnce(), t)
+	notExist(name, t)
+	exists(name+".lz4", t)
+
+	// The uncompressed form left by a crash after the .lz4 was written is
+	// removed, as with gzip.
+	isNil(os.WriteFile(name, b, 0644), t)
+	isNil(l.millRunOnce(), t)
+
+	notExist(name, t)
+	data, err := os.ReadFile(name + ".lz4")
+	isNil(err, t)
+	got, err := decodeLZ4(data)
+	isNil(err, t)
+	equals(string(b), string(got), t)
+	fileCount(dir, 2, t)
+}
+
+// decodeLZ4 decodes an LZ4 frame as written by lz4Writer, checking the
+// header and content checksums.
+func decodeLZ4(b []byte) ([]byte, error) {
+	if len(b) < 7 || binary.LittleEndian.Uint32(b) != lz4Magic {
+		return nil, errors.New("bad magic")
+	}
+	var h xxh32
+	h.write(b[4:6])
+	if b[6] != byte(h.sum()>>8) {
+		return nil, errors.New("bad header checksum")
+	}
+	b = b[7:]
+	var out []byte
+	for {
+		if len(b) < 4 {
+			return nil, errors.New("truncated block size")
+		}
+		size := binary.LittleEndian.Uint32(b)
+		b = b[4:]
+		if size == 0 {
+			break
+		}
+		n := int(size &^ (1 << 31))
+		if n > len(b) || n > lz4BlockSize {
+			return nil, errors.New("bad block size")
+		}
+		if size&(1<<31) != 0 {
+			out = append(out, b[:n]...)
+		} else {
+			var err error
+			if out, err = decodeLZ4Block(out, b[:n]); err != nil {
+				return nil, err
+			}
+		}
+		b = b[n:]
+	}
+	if len(b) != 4 {
+		return nil, errors.New("missing content checksum")
+	}
+	h = xxh32{}
+	h.write(out)
+	if binary.LittleEndian.Uint32(b) != h.sum() {
+		return nil, errors.New("bad content checksum")
+	}
+	return out, nil
+}
+
+// decodeLZ4Block appends the decoding of an LZ4 block to out.
+func decodeLZ4Block(out, b []byte) ([]byte, error) {
+	start := len(out)
+	length := func(n int) (int, error) {
+		if n < 15 {
+			return n, nil
+		}
+		for {
+			if len(b) == 0 {
+				return 0, errors.New("truncated length")
+			}
+			c := b[0]
+			b = b[1:]
+			n += int(c)
+			if c != 255 {
+				return n, nil
+			}
+		}
+	}
+	for len(b) > 0 {
+		token := b[0]
+		b = b[1:]
+		lit, err := length(int(token >> 4))
+		if err != nil {
+			return nil, err
+		}
+		if lit > len(b) {
+			return nil, errors.New("truncated literals")
+		}
+		out = append(out, b[:lit]...)
+		b = b[lit:]
+		if len(b) == 0 {
+			break // the last sequence has no match
+		}
+		if len(b) < 2 {
+			return nil, errors.New("truncated offset")
+		}
+		offset := int(binary.LittleEndian.Uint16(b))
+		b = b[2:]
+		n, err := length(int(token & 15))
+		if err != nil {
+			return nil, err
+		}
+		if offset == 0 || offset > len(out)-start {
+			return nil, errors.New("bad offset")
+		}
+		for i := 0; i < n+lz4MinMatch; i++ {
+			out = append(out, out[len(out)-offset])
+		}
+	}
+	return out, nil
+}
+
+// TestLZ4Golden checks that the encoder still produces the frame in
+// testdata, which the reference lz4 (v1.9.4) decompresses to
+// interopSample, so that backups stay readable without this package.
+func TestLZ4Golden(t *testing.T) {
+	golden, err := os.ReadFile(filepath.Join("testdata", "interop.log.lz4"))
+	isNil(err, t)
+	var out bytes.Buffer
+	isNil(lz4Codec{}.Compress(&out, bytes.NewReader(interopSample())), t)
+	assert(bytes.Equal(golden, out.Bytes()), t, "the encoder's output changed; check it with lz4 -d and update the golden frame")
+}
+
+// TestLZ4Interop decompresses frames with the lz4 tool, if installed.
+func TestLZ4Interop(t *testing.T) {
+	random := make([]byte, 300<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	for name, in := range map[string][]byte{
+		"empty":  {},
+		"short":  []byte("hello hello hello hello world\n"),
+		"sample": interopSample(),
+		"random": random,
+		"zeros":  make([]byte, 3*lz4BlockSize),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			isNil(lz4Codec{}.Compress(&out, bytes.NewReader(in)), t)
+			checkDecompressedByTool(t, "lz4", out.Bytes(), in)
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package timberjack
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// managerNamePlaceholder is replaced by a Logger's name in Manager.Filename.
+const managerNamePlaceholder = "{name}"
+
+// Manager owns a set of Loggers keyed by name, e.g. one file per tenant or
+// subsystem, all configured alike. Instead of a goroutine per Logger, their
+// backups are compressed and cleaned up by a shared pool of Workers, which
+// also keeps the backups of all the Loggers together within MaxTotalSize.
+//
+// A Manager must not be copied after first use.
+type Manager struct {
+	// Filename is the template of the Loggers' file names: "{name}" is
+	// replaced by each Logger's name, e.g. "/var/log/app/{name}.log".
+	Filename string `json:"filename" yaml:"filename" toml:"filename"`
+
+	// Config is the rotation and retention policy of every Logger.
+	// Config.MaxTotalSize applies to each Logger on its own.
+	Config Config `json:"config" yaml:"config" toml:"config"`
+
+	// MaxTotalSize is the maximum size in megabytes of the backups of all
+	// the Loggers together. Once it is exceeded, the oldest backups are
+	// removed first, whichever Logger they belong to; pinned backups are
+	// spared. 0 means no shared limit.
+	MaxTotalSize int `json:"maxtotalsize" yaml:"maxtotalsize" toml:"maxtotalsize"`
+
+	// Workers is the number of goroutines compressing and cleaning up
+	// backups for all the Loggers. The default is 1.
+	Workers int `json:"workers" yaml:"workers" toml:"workers"`
+
+	// Configure, if set, is called with every new Logger, after Filename
+	// and Config are applied and before it is first used, to set other
+	// options.
+	Configure func(name string, l *Logger) `json:"-" yaml:"-" toml:"-"`
+
+	mu      sync.Mutex
+	loggers map[string]*Logger
+	queue   []*Logger        // Loggers waiting for a cleanup pass
+	queued  map[*Logger]bool // Loggers in queue
+	wake    chan struct{}    // signals the workers that queue isn't empty
+	quit    chan struct{}    // closed by Close to stop the workers
+	wg      sync.WaitGroup   // running workers
+	start   sync.Once        // starts the workers
+	closed  bool
+}
+
+// ErrManagerClosed is returned by Manager.Logger after Close.
+var ErrManagerClosed = errors.New("timberjack: manager closed")
+
+// Logger returns the Logger called name, creating it on first use. Names
+// must be non-empty and must not contain path separators or "..".
+func (m *Manager) Logger(name string) (*Logger, error) {
+	if name == "" || name == ".." || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid logger name %q", name)
+	}
+	if !strings.Contains(m.Filename, managerNamePlaceholder) {
+		return nil, fmt.Errorf("invalid Filename %q: must contain %s", m.Filename, managerNamePlaceholder)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrManagerClosed
+	}
+	if l, ok := m.loggers[name]; ok {
+		return l, nil
+	}
+	l := &Logger{
+		Filename:         strings.Replace(m.Filename, managerNamePlaceholder, name, -1),
+		MaxSize:          m.Config.MaxSize,
+		MaxAge:           m.Config.MaxAge,
+		MaxBackups:       m.Config.MaxBackups,
+		MaxTotalSize:     m.Config.MaxTotalSize,
+		Compress:         m.Config.Compress,
+		RotationInterval: m.Config.RotationInterval,
+		RotateAtMinutes:  append([]int(nil), m.Config.RotateAtMinutes...),
+	}
+	if m.Configure != nil {
+		m.Configure(name, l)
+	}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	l.manager = m
+	m.start.Do(m.startWorkers)
+	if m.loggers == nil {
+		m.loggers = make(map[string]*Logger)
+	}
+	m.loggers[name] = l
+	return l, nil
+}
+
+// Names returns the names of the Manager's Loggers, sorted.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.loggers))
+	for name := range m.loggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close closes every Logger and then stops the workers, once the cleanup
+// passes in progress are done. It returns the first error from closing a
+// Logger. Logger returns ErrManagerClosed afterwards.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	loggers := make([]*Logger, 0, len(m.loggers))
+	for _, l := range m.loggers {
+		loggers = append(loggers, l)
+	}
+	m.mu.Unlock()
+
+	var firstErr error
+	for _, l := range loggers {
+		if err := l.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	m.start.Do(func() {}) // no workers to stop if none were started
+	if m.quit != nil {
+		close(m.quit)
+		m.wg.Wait()
+	}
+	return firstErr
+}
+
+// startWorkers starts the goroutines running cleanup passes. It expects
+// m.mu to be held.
+func (m *Manager) startWorkers() {
+	workers := m.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	m.queued = make(map[*Logger]bool)
+	m.wake = make(chan struct{}, 1)
+	m.quit = make(chan struct{})
+	m.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer m.wg.Done()
+			m.work()
+		}()
+	}
+}
+
+// schedule queues a cleanup pass for l, unless one is queued already.
+func (m *Manager) schedule(l *Logger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed || m.queued[l] {
+		return
+	}
+	m.queued[l] = true
+	m.queue = append(m.queue, l)
+	select {
+	case m.wake <- struct{}{}:
+	default: // a worker is being woken up already
+	}
+}
+
+// work runs queued cleanup passes until the Manager is closed.
+func (m *Manager) work() {
+	for {
+		m.mu.Lock()
+		var l *Logger
+		if len(m.queue) > 0 {
+			l = m.queue[0]
+			m.queue = m.queue[1:]
+			delete(m.queued, l)
+		}
+		m.mu.Unlock()
+
+		if l == nil {
+			select {
+			case <-m.wake:
+				continue
+			case <-m.quit:
+				return
+			}
+		}
+		l.millRunReported()
+		m.enforceTotalSize()
+	}
+}
+
+// enforceTotalSize removes the oldest backups of all the Loggers until
+// together they fit in MaxTotalSize.
+func (m *Manager) enforceTotalSize() {
+	if m.MaxTotalSize <= 0 {
+		return
+	}
+	m.mu.Lock()
+	loggers := make([]*Logger, 0, len(m.loggers))
+	for _, l := range m.loggers {
+		loggers = append(loggers, l)
+	}
+	m.mu.Unlock()
+
+	type backup struct {
+		l *Logger
+		BackupInfo
+	}
+	var backups []backup
+	var total int64
+	for _, l := range loggers {
+		infos, err := l.Backups()
+		if err != nil {
+			continue // nothing rotated yet, or reported by the Logger's own pass
+		}
+		for _, b := range infos {
+			total += b.Size
+			if !b.Pinned {
+				backups = append(backups, backup{l, b})
+			}
+		}
+	}
+	limit := int64(m.MaxTotalSize) * int64(megabyte)
+	sort.SliceStable(backups, func(i, j int) bool { return backups[i].Timestamp.Before(backups[j].Timestamp) })
+	for _, b := range backups {
+		if total <= limit {
+			return
+		}
+		if err := b.l.removeBackup(b.Name, "MaxTotalSize"); err != nil {
+			b.l.backgroundError("remove", err, "failed to remove old log file %s", b.Name)
+			continue
+		}
+		total -= b.Size
+	}
+}
+
+// removeBackup removes the backup name, as listed by Backups, for the
+// retention rule reason.
+func (l *Logger) removeBackup(name, reason string) error {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	unlockNumbering := l.lockNumbering()
+	defer unlockNumbering()
+
+	path := filepath.Join(l.backupDir(), name)
+	if err := osRemove(path); err != nil {
+		return err
+	}
+	l.forgetBackup(name)
+	l.emit(Event{Type: EventRemoval, File: path, Reason: reason})
+	l.countRemovals(1)
+	l.removeEmptyLayoutDirs(name)
+	return nil
+}
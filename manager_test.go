@@ -0,0 +1,73 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManager(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestManager", t)
+	defer os.RemoveAll(dir)
+
+	m := &Manager{
+		Filename:     filepath.Join(dir, "{name}.log"),
+		Config:       Config{MaxSize: 10},
+		MaxTotalSize: 12, // room for three 4-byte backups
+		Configure: func(name string, l *Logger) {
+			l.BackupTimeFormat = backupTimeFormat
+		},
+	}
+	defer m.Close()
+
+	a, err := m.Logger("a")
+	isNil(err, t)
+	again, err := m.Logger("a")
+	isNil(err, t)
+	assert(a == again, t, "expected the same Logger for the same name")
+	b, err := m.Logger("b")
+	isNil(err, t)
+	equals([]string{"a", "b"}, m.Names(), t)
+	equals(filepath.Join(dir, "b.log"), b.Filename, t)
+	equals(10, b.MaxSize, t)
+
+	_, err = m.Logger("../escape")
+	notNil(err, t)
+
+	// Four backups, alternating between the Loggers, one more than fits.
+	var backups []string
+	for i, l := range []*Logger{a, b, a, b} {
+		_, err := l.Write([]byte("boo!"))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+		name := "a"
+		if i%2 == 1 {
+			name = "b"
+		}
+		backups = append(backups, filepath.Join(dir, name+"-"+fakeTime().UTC().Format(backupTimeFormat)+"-size.log"))
+		newFakeTime()
+	}
+
+	// The oldest backup overall goes, whichever Logger it belongs to.
+	deadline := time.Now().Add(5 * time.Second)
+	for fileExists(backups[0]) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	notExist(backups[0], t)
+	for _, name := range backups[1:] {
+		exists(name, t)
+	}
+
+	isNil(m.Close(), t)
+	_, err = m.Logger("c")
+	equals(ErrManagerClosed, err, t)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,108 @@
+package timberjack
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// moveFile renames src to dst. If they are on different filesystems, it falls
+// back to copying src to dst, fsyncing the copy and removing src.
+func moveFile(src, dst string) error {
+	err := osRename(src, dst)
+	if err == nil || !isCrossDevice(err) {
+		return err
+	}
+	return copyAndRemove(src, dst)
+}
+
+// copyAndRemove copies src to dst, preserving its mode and ownership, and then
+// removes src. The copy is written under a temporary name and renamed to dst
+// once durable, so src is never deleted without a complete copy.
+func copyAndRemove(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	info, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + tempSuffix
+	dstFile, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dstFile, srcFile); err == nil {
+		err = dstFile.Sync()
+	}
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, dst)
+	}
+	if err == nil {
+		err = osSyncDir(filepath.Dir(dst))
+	}
+	if err != nil {
+		_ = osRemove(tmp)
+		return fmt.Errorf("failed to copy %s to %s: %w", src, dst, err)
+	}
+
+	if errChown := chown(dst, info); errChown != nil {
+		fmt.Fprintf(os.Stderr, "timberjack: failed to chown %s: %v\n", dst, errChown)
+	}
+	return osRemove(src)
+}
+
+// syncDir fsyncs the directory dir, making the renames into it durable. It
+// does nothing on Windows, where directories can't be opened for syncing.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// backupDir returns the directory rotated backups are stored in.
+func (l *Logger) backupDir() string {
+	if l.BackupDir == "" {
+		return l.dir()
+	}
+	if filepath.IsAbs(l.BackupDir) {
+		return normalizePath(l.BackupDir)
+	}
+	return normalizePath(filepath.Join(l.dir(), l.BackupDir))
+}
+
+// archiveBackup moves a freshly rotated backup from the log directory into
+// BackupDir (and its BackupDirLayout subdirectory), if one is configured. The backup's file must be closed, so that
+// a cross-device copy captures all of its content.
+func (l *Logger) archiveBackup(backup string) {
+	dir := l.archiveDir()
+	if backup == "" || filepath.Dir(backup) == dir {
+		return
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		l.backgroundError("move", err, "failed to create backup directory %s", dir)
+		return
+	}
+	dst := filepath.Join(dir, filepath.Base(backup))
+	if err := moveFile(backup, dst); err != nil {
+		l.backgroundError("move", err, "failed to move backup %s to %s", backup, dst)
+	}
+}
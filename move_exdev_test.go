@@ -0,0 +1,85 @@
+//go:build !plan9
+// +build !plan9
+
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestMoveFile_CrossDevice(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	dst := filepath.Join(dir, "dst.log")
+	isNil(os.WriteFile(src, []byte("payload"), 0640), t)
+
+	origRename := osRename
+	osRename = func(oldpath, newpath string) error {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+	}
+	defer func() { osRename = origRename }()
+
+	isNil(moveFile(src, dst), t)
+	notExist(src, t)
+	existsWithContent(dst, []byte("payload"), t)
+	info, err := os.Stat(dst)
+	isNil(err, t)
+	equals(os.FileMode(0640), info.Mode().Perm(), t)
+}
+
+func TestIsCrossDevice(t *testing.T) {
+	equals(true, isCrossDevice(&os.LinkError{Err: syscall.EXDEV}), t)
+	equals(false, isCrossDevice(&os.LinkError{Err: syscall.ENOENT}), t)
+	equals(false, isCrossDevice(nil), t)
+}
+
+func TestBackupDir_CrossDeviceOffLock(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestBackupDir_CrossDeviceOffLock", t)
+	defer os.RemoveAll(dir)
+	backupDir := filepath.Join(dir, "old")
+
+	origRename := osRename
+	osRename = func(oldpath, newpath string) error {
+		if filepath.Dir(newpath) == backupDir {
+			return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: syscall.EXDEV}
+		}
+		return origRename(oldpath, newpath)
+	}
+	defer func() { osRename = origRename }()
+	release := make(chan struct{})
+	osSyncDir = func(d string) error {
+		if d == backupDir {
+			<-release
+		}
+		return syncDir(d)
+	}
+	defer func() { osSyncDir = syncDir }()
+
+	// Streamed rotations close the file first and so go through openNew.
+	l := &Logger{
+		Filename:         logFile(dir),
+		BackupDir:        "old",
+		MaxSize:          100,
+		Compress:         true,
+		StreamCompress:   true,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	_, err := l.Write([]byte("a"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+
+	// The copy into BackupDir is stuck, yet writes go on.
+	_, err = l.Write([]byte("b"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("b"), t)
+
+	close(release)
+	isNil(l.Close(), t)
+	fileCount(backupDir, 1, t)
+}
@@ -0,0 +1,18 @@
+//go:build plan9
+// +build plan9
+
+package timberjack
+
+import (
+	"errors"
+	"os"
+)
+
+// isCrossDevice reports whether err is a rename failure caused by the source
+// and destination being in places a rename can't move a file between. Plan 9
+// can't rename a file into another directory at all, which os.Rename reports
+// as os.ErrInvalid, so moves into BackupDir are always copies there.
+func isCrossDevice(err error) bool {
+	var linkErr *os.LinkError
+	return errors.As(err, &linkErr) && errors.Is(err, os.ErrInvalid)
+}
@@ -0,0 +1,75 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupDir(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestBackupDir", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	archive := filepath.Join(dir, "archive")
+	l := &Logger{
+		Filename:   filename,
+		MaxSize:    10,
+		MaxBackups: 1,
+		BackupDir:  "archive",
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("first"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	_, err = l.Write([]byte("second"))
+	isNil(err, t)
+
+	// The backup is moved into BackupDir in the background.
+	l.retiring.Wait()
+
+	existsWithContent(filename, []byte("second"), t)
+	existsWithContent(backupFileWithReason(archive, "size"), []byte("first"), t)
+	fileCount(dir, 2, t) // the active file and the archive directory
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+
+	// Retention is applied in BackupDir.
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	l.retiring.Wait()
+	isNil(l.millRunOnce(), t)
+	fileCount(archive, 1, t)
+	existsWithContent(backupFileWithReason(archive, "size"), []byte("second"), t)
+}
+
+func TestMoveFile_OtherRenameError(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	isNil(os.WriteFile(src, []byte("payload"), 0644), t)
+
+	// Errors other than EXDEV are returned as-is, without copying.
+	err := moveFile(src, filepath.Join(dir, "missing", "dst.log"))
+	notNil(err, t)
+	exists(src, t)
+}
+
+func TestCopyAndRemove_KeepsSourceOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	isNil(os.WriteFile(src, []byte("payload"), 0644), t)
+
+	err := copyAndRemove(src, filepath.Join(dir, "missing", "dst.log"))
+	notNil(err, t)
+	existsWithContent(src, []byte("payload"), t)
+
+	err = copyAndRemove(filepath.Join(dir, "nope.log"), filepath.Join(dir, "dst.log"))
+	notNil(err, t)
+	notExist(filepath.Join(dir, "dst.log"), t)
+}
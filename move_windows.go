@@ -0,0 +1,18 @@
+//go:build windows
+// +build windows
+
+package timberjack
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errNotSameDevice is Windows' ERROR_NOT_SAME_DEVICE, its equivalent of EXDEV.
+const errNotSameDevice = syscall.Errno(17)
+
+// isCrossDevice reports whether err is a rename failure caused by the source
+// and destination being on different filesystems.
+func isCrossDevice(err error) bool {
+	return errors.Is(err, syscall.EXDEV) || errors.Is(err, errNotSameDevice)
+}
@@ -0,0 +1,194 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// backupNumber returns the sequence number of name if NumberedBackups is set
+// and name is a numbered backup (<filename>.<n>, optionally compressed).
+func (l *Logger) backupNumber(name string) (int, bool) {
+	if !l.NumberedBackups {
+		return 0, false
+	}
+	base := filepath.Base(l.filename()) + "."
+	name = l.trimCompressed(name)
+	if !strings.HasPrefix(name, base) {
+		return 0, false
+	}
+	digits := name[len(base):]
+	if digits == "" || digits[0] == '0' || strings.Trim(digits, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(digits)
+	return n, err == nil
+}
+
+// numberedName returns the name of backup number n of the log file name.
+func numberedName(name string, n int) string {
+	return name + "." + strconv.Itoa(n)
+}
+
+// renumber returns the path of the numbered backup path once it has been
+// shifted by shift rotations.
+func (l *Logger) renumber(path string, shift int64) string {
+	if shift == 0 {
+		return path
+	}
+	name := filepath.Base(path)
+	n, ok := l.backupNumber(name)
+	if !ok {
+		return path
+	}
+	suffix := l.compressedSuffix(name)
+	return filepath.Join(filepath.Dir(path), numberedName(filepath.Base(l.filename()), n+int(shift))+suffix)
+}
+
+// shiftNumberedBackups makes room for a new backup number 1 by renaming each
+// numbered backup n in the backup directory to n+1, highest first. If a
+// rename fails, the remaining backups are left in place, so no backup is
+// ever overwritten.
+func (l *Logger) shiftNumberedBackups() error {
+	l.numberMu.Lock()
+	defer l.numberMu.Unlock()
+
+	dir := l.backupDir()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("can't read log file directory: %w", err)
+	}
+	type numbered struct {
+		name string
+		n    int
+	}
+	var backups []numbered
+	for _, e := range entries {
+		if n, ok := l.backupNumber(e.Name()); ok && !e.IsDir() {
+			backups = append(backups, numbered{e.Name(), n})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].n > backups[j].n })
+
+	renamed := make(map[string]string, len(backups))
+	for _, b := range backups {
+		newName := filepath.Base(l.renumber(b.name, 1))
+		if err := osRename(filepath.Join(dir, b.name), filepath.Join(dir, newName)); err != nil {
+			return fmt.Errorf("can't renumber backup %s: %w", b.name, err)
+		}
+		if l.pinned(filepath.Join(dir, b.name)) { // the pin follows its backup
+			pin := l.trimCompressed(b.name) + pinSuffix
+			_ = osRename(filepath.Join(dir, pin), filepath.Join(dir, l.trimCompressed(newName)+pinSuffix))
+		}
+		// So does its checksum.
+		_ = osRename(filepath.Join(dir, b.name+checksumSuffix), filepath.Join(dir, newName+checksumSuffix))
+		renamed[b.name] = newName
+	}
+	l.numberGen++
+
+	// Per-backup records follow their files.
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+	for _, b := range backups {
+		if rec, ok := l.compressions[b.name]; ok {
+			delete(l.compressions, b.name)
+			l.compressions[renamed[b.name]] = rec
+		}
+		if link, ok := l.links[b.name]; ok {
+			delete(l.links, b.name)
+			l.links[renamed[b.name]] = link
+		}
+	}
+	return nil
+}
+
+// numberedLogFiles orders numbered backups for retention. Their age is the
+// modification time, but as numbers are authoritative for the order, the
+// timestamps are adjusted to strictly decrease with increasing numbers.
+// A compressed and an uncompressed file with the same number share one
+// timestamp, like the two forms of any other backup.
+func numberedLogFiles(files []logInfo, numbers []int) []logInfo {
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return numbers[order[i]] < numbers[order[j]] })
+
+	newest := make(map[int]time.Time)
+	for i, f := range files {
+		if t := f.ModTime(); t.After(newest[numbers[i]]) {
+			newest[numbers[i]] = t
+		}
+	}
+	var prev time.Time
+	prevNumber := 0
+	stamps := make(map[int]time.Time)
+	for _, i := range order {
+		n := numbers[i]
+		if n == prevNumber {
+			continue
+		}
+		t := newest[n]
+		if !prev.IsZero() && !t.Before(prev) {
+			t = prev.Add(-time.Nanosecond)
+		}
+		stamps[n] = t
+		prev, prevNumber = t, n
+	}
+	for i := range files {
+		files[i].timestamp = stamps[numbers[i]]
+	}
+	return files
+}
+
+// lockNumbering keeps numbered backups from being shifted until the returned
+// function is called. It does nothing unless NumberedBackups is set. While
+// it is held, the mill notes the numbering generation its file names refer
+// to.
+func (l *Logger) lockNumbering() (unlock func()) {
+	if !l.NumberedBackups {
+		return func() {}
+	}
+	l.numberMu.Lock()
+	l.millGen = l.numberGen
+	return l.numberMu.Unlock
+}
+
+// compressNumbered compresses the numbered backup src, listed by the mill,
+// into dst and records the compression in the Logger's statistics. Rotations
+// may shift the backup to a higher number at any time; the compressed file is
+// put in place under the backup's number at the time compression finished.
+func (l *Logger) compressNumbered(src, dst string) error {
+	l.numberMu.Lock()
+	shift := l.numberGen - l.millGen
+	src, dst = l.renumber(src, shift), l.renumber(dst, shift)
+	gen := l.numberGen
+	l.numberMu.Unlock()
+
+	start := time.Now()
+	tmp, info, err := compressToTemp(l.context(), l.backgroundCodec(), src, dst)
+	if err != nil {
+		return err
+	}
+
+	l.numberMu.Lock()
+	defer l.numberMu.Unlock()
+	shift = l.numberGen - gen
+	src, dst = l.renumber(src, shift), l.renumber(dst, shift)
+	if cur, err := os.Stat(src); err != nil || !os.SameFile(cur, info) {
+		_ = osRemove(tmp)
+		return fmt.Errorf("backup %s was renumbered during compression", src)
+	}
+	if err := finishCompression(src, dst, tmp, info); err != nil {
+		return err
+	}
+	// Keep the backup's age for MaxAge.
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	l.recordCompression(dst, info, time.Since(start))
+	l.dropCache(dst)
+	return nil
+}
@@ -0,0 +1,96 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNumberedBackups(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestNumberedBackups", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 10, MaxBackups: 2, NumberedBackups: true}
+	defer l.Close()
+
+	for _, content := range []string{"one!", "two!", "three!", "four!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+		<-time.After(10 * time.Millisecond)
+	}
+
+	// The newest backup is always .1; MaxBackups removed the oldest.
+	existsWithContent(filename+".1", []byte("four!"), t)
+	existsWithContent(filename+".2", []byte("three!"), t)
+	notExist(filename+".3", t)
+	fileCount(dir, 3, t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals(filepath.Base(filename)+".1", backups[0].Name, t)
+	equals(filepath.Base(filename)+".2", backups[1].Name, t)
+}
+
+func TestNumberedBackups_Compress(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestNumberedBackups_Compress", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 10, Compress: true, NumberedBackups: true}
+	defer l.Close()
+
+	_, err := l.Write([]byte("one!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	<-time.After(300 * time.Millisecond)
+	exists(filename+".1.gz", t)
+
+	_, err = l.Write([]byte("two!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	<-time.After(300 * time.Millisecond)
+
+	exists(filename+".1.gz", t)
+	exists(filename+".2.gz", t)
+	notExist(filename+".1", t)
+	fileCount(dir, 3, t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals(int64(4), backups[0].OriginalSize, t)
+	assert(backups[0].Timestamp.After(backups[1].Timestamp), t, "expected .1 to be newer than .2")
+}
+
+func TestBackupNumber(t *testing.T) {
+	l := &Logger{Filename: "/var/log/app.log", NumberedBackups: true}
+	for name, want := range map[string]int{
+		"app.log.1":     1,
+		"app.log.12.gz": 12,
+		"app.log.0":     0,
+		"app.log.01":    0,
+		"app.log.":      0,
+		"app.log.1x":    0,
+		"app.log":       0,
+		"other.log.1":   0,
+	} {
+		n, ok := l.backupNumber(name)
+		equals(want, n, t)
+		equals(want != 0, ok, t)
+	}
+
+	l.NumberedBackups = false
+	_, ok := l.backupNumber("app.log.1")
+	assert(!ok, t, "numbered backups recognized without NumberedBackups")
+}
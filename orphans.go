@@ -0,0 +1,97 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// repairOrphans deals with temporary files left behind by a compression or a
+// cross-device copy that was interrupted, e.g. by a crash. If the file the
+// temporary was made from still exists, the temporary is removed and the
+// work is redone: the next compression pass picks the backup up again and a
+// backup stranded next to the log file is archived again. Otherwise the
+// temporary is the last remaining copy and is renamed into place.
+//
+// Only temporaries named after one of the Logger's backups are touched, and
+// that of an interrupted StreamCompress rotation, which is always removed.
+func (l *Logger) repairOrphans() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Temporaries are made next to their final file: in the backup
+	// directory (or a BackupDirLayout subdirectory) and, for copies not yet
+	// archived, in the log directory.
+	entries, _ := l.backupEntries()
+	if l.dir() != l.backupDir() {
+		if dirEntries, err := os.ReadDir(l.dir()); err == nil {
+			for _, e := range dirEntries {
+				if info, err := e.Info(); err == nil && !e.IsDir() {
+					entries = append(entries, layoutEntry{dir: l.dir(), rel: e.Name(), info: info})
+				}
+			}
+		}
+	}
+	prefix, ext := l.prefixAndExt()
+	l.removeStreamTemp()
+
+	for _, e := range entries {
+		name := filepath.Base(e.rel)
+		if !strings.HasSuffix(name, tempSuffix) {
+			continue
+		}
+		final := strings.TrimSuffix(name, tempSuffix)
+		backup := l.trimCompressed(final)
+		if !l.ownBackup(backup, prefix, ext) {
+			continue
+		}
+
+		// A compression works within one directory; a copy moves a
+		// backup from the log directory into BackupDir.
+		compressing := backup != final
+		source := filepath.Join(e.dir, backup)
+		if !compressing {
+			source = filepath.Join(l.dir(), backup)
+		}
+		l.repairOrphan(filepath.Join(e.dir, name), filepath.Join(e.dir, final), source, compressing)
+	}
+}
+
+// repairOrphan removes or recovers the temporary tmp of the file final that
+// was being made from source.
+func (l *Logger) repairOrphan(tmp, final, source string, compressing bool) {
+	if _, err := osStat(source); err == nil {
+		if err := osRemove(tmp); err != nil {
+			l.backgroundError("remove", err, "failed to remove orphaned temporary file %s", tmp)
+			return
+		}
+		l.statsMu.Lock()
+		l.stats.OrphansRemoved++
+		l.statsMu.Unlock()
+		if !compressing {
+			l.archiveBackup(source)
+		}
+		return
+	}
+
+	if err := os.Rename(tmp, final); err != nil {
+		l.backgroundError("recover", err, "failed to recover orphaned temporary file %s", tmp)
+		return
+	}
+	l.statsMu.Lock()
+	l.stats.OrphansRecovered++
+	l.statsMu.Unlock()
+}
+
+// ownBackup reports whether the uncompressed name backup is named like one of
+// the Logger's backups.
+func (l *Logger) ownBackup(backup, prefix, ext string) bool {
+	if _, _, err := l.parseBackupName(backup, prefix, ext); err == nil || l.customBackup(backup) {
+		return true
+	}
+	if _, ok := l.lumberjackBackupTime(backup); ok {
+		return true
+	}
+	_, ok := l.backupNumber(backup)
+	return ok
+}
@@ -0,0 +1,75 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRepairOrphans_Compression(t *testing.T) {
+	currentTime = fakeTime
+	dir := t.TempDir()
+	l := &Logger{Filename: logFile(dir), Compress: true}
+	defer l.Close()
+
+	// An interrupted compression whose source is still there is redone.
+	backup := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(backup, []byte("data"), 0644), t)
+	isNil(os.WriteFile(backup+compressSuffix+tempSuffix, []byte("partial"), 0644), t)
+
+	// A temporary without its source is the only copy left.
+	newFakeTime()
+	stranded := backupFileWithReason(dir, "time")
+	isNil(os.WriteFile(stranded+compressSuffix+tempSuffix, []byte("complete"), 0644), t)
+
+	// Temporaries that aren't ours are left alone.
+	foreign := filepath.Join(dir, "other.log.tmp")
+	isNil(os.WriteFile(foreign, []byte("foreign"), 0644), t)
+
+	isNil(l.millRunOnce(), t)
+
+	notExist(backup, t)
+	notExist(backup+compressSuffix+tempSuffix, t)
+	exists(backup+compressSuffix, t)
+	notExist(stranded+compressSuffix+tempSuffix, t)
+	existsWithContent(stranded+compressSuffix, []byte("complete"), t)
+	existsWithContent(foreign, []byte("foreign"), t)
+
+	stats := l.Stats()
+	equals(int64(1), stats.OrphansRemoved, t)
+	equals(int64(1), stats.OrphansRecovered, t)
+}
+
+func TestRepairOrphans_Copy(t *testing.T) {
+	currentTime = fakeTime
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "archive")
+	isNil(os.Mkdir(archive, 0755), t)
+	l := &Logger{Filename: logFile(dir), BackupDir: "archive"}
+	defer l.Close()
+
+	// A backup stranded next to the log by an interrupted copy is archived
+	// again.
+	backup := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(backup, []byte("data"), 0644), t)
+	partial := filepath.Join(archive, filepath.Base(backup)) + tempSuffix
+	isNil(os.WriteFile(partial, []byte("da"), 0644), t)
+
+	l.repairOrphans()
+
+	notExist(backup, t)
+	notExist(partial, t)
+	existsWithContent(filepath.Join(archive, filepath.Base(backup)), []byte("data"), t)
+	equals(int64(1), l.Stats().OrphansRemoved, t)
+}
+
+func TestCompressLogFile_NoTemporaryLeft(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.log")
+	isNil(os.WriteFile(src, []byte("payload"), 0644), t)
+
+	isNil(compressLogFile(src, src+compressSuffix), t)
+	exists(src+compressSuffix, t)
+	notExist(src+compressSuffix+tempSuffix, t)
+	fileCount(dir, 1, t)
+}
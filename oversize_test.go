@@ -0,0 +1,111 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAllowOversizeWrites(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestAllowOversizeWrites", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 5, AllowOversizeWrites: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	// Into an empty file, an oversize write goes without rotating.
+	big := []byte("booooooooooooooo!")
+	n, err := l.Write(big)
+	isNil(err, t)
+	equals(len(big), n, t)
+	existsWithContent(logFile(dir), big, t)
+	fileCount(dir, 1, t)
+
+	// The next write rotates it into a backup of its own.
+	newFakeTime()
+	b := []byte("foo\n")
+	_, err = l.Write(b)
+	isNil(err, t)
+	existsWithContent(backupFileWithReason(dir, "size"), big, t)
+	existsWithContent(logFile(dir), b, t)
+
+	// A file with data is rotated before an oversize write.
+	newFakeTime()
+	_, err = l.Write(big)
+	isNil(err, t)
+	existsWithContent(backupFileWithReason(dir, "size"), b, t)
+	existsWithContent(logFile(dir), big, t)
+	fileCount(dir, 3, t)
+}
+
+func TestAllowOversizeWrites_Async(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestAllowOversizeWrites_Async", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 5, AllowOversizeWrites: true, AsyncQueueSize: 4}
+
+	big := []byte("booooooooooooooo!")
+	_, err := l.Write(big)
+	isNil(err, t)
+	isNil(l.Close(), t)
+	existsWithContent(logFile(dir), big, t)
+}
+
+func TestChunkOversizeWrites(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestChunkOversizeWrites", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10, ChunkOversizeWrites: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	_, err := l.Write([]byte("foo\n"))
+	isNil(err, t)
+
+	// The write fills the file up to MaxSize and continues in new ones.
+	b := []byte("abcdefghijklmnopqrstuvwxyz")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+	existsWithContent(logFile(dir), []byte("qrstuvwxyz"), t)
+	fileCount(dir, 3, t)
+
+	// Rotations within the same instant are numbered.
+	backup := backupFileWithReason(dir, "size")
+	existsWithContent(backup, []byte("foo\nabcdef"), t)
+	existsWithContent(l.withSequence(backup, 1), []byte("ghijklmnop"), t)
+}
+
+func TestChunkBoundary(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestChunkBoundary", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10, ChunkOversizeWrites: true, ChunkBoundary: "\n", BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	_, err := l.Write([]byte("foo\n"))
+	isNil(err, t)
+
+	// Chunks end at line boundaries; a line longer than MaxSize is cut.
+	b := []byte("ab\ncdefgh\nijklmnopqrstu\nvw\n")
+	n, err := l.Write(b)
+	isNil(err, t)
+	equals(len(b), n, t)
+
+	backup := backupFileWithReason(dir, "size")
+	existsWithContent(backup, []byte("foo\nab\n"), t)
+	existsWithContent(l.withSequence(backup, 1), []byte("cdefgh\n"), t)
+	existsWithContent(l.withSequence(backup, 2), []byte("ijklmnopqr"), t)
+	existsWithContent(logFile(dir), []byte("stu\nvw\n"), t)
+	fileCount(dir, 4, t)
+}
@@ -0,0 +1,108 @@
+package timberjack
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// PairPolicy controls how retention treats a backup that exists both
+// uncompressed and compressed, which happens while it is being compressed
+// or when a compression was interrupted after the compressed file was
+// moved into place.
+type PairPolicy string
+
+const (
+	// PairCountOnce counts the two files of a pair as one backup towards
+	// MaxBackups. It is the default.
+	PairCountOnce PairPolicy = "once"
+
+	// PairCountEach counts every file towards MaxBackups on its own, so a
+	// pair takes up two places. The uncompressed file is the first to go.
+	PairCountEach PairPolicy = "each"
+)
+
+// ValidatePairPolicy checks that PairPolicy is empty, PairCountOnce or
+// PairCountEach.
+func (l *Logger) ValidatePairPolicy() error {
+	switch l.PairPolicy {
+	case "", PairCountOnce, PairCountEach:
+		return nil
+	}
+	return fmt.Errorf("invalid PairPolicy %q: expected %q or %q", l.PairPolicy, PairCountOnce, PairCountEach)
+}
+
+// pairPolicy returns the effective PairPolicy; invalid values count pairs
+// once.
+func (l *Logger) pairPolicy() PairPolicy {
+	if l.PairPolicy == PairCountEach {
+		return PairCountEach
+	}
+	return PairCountOnce
+}
+
+// sortPairs sorts files newest first and, within a pair, puts the compressed
+// file first, so that pruning from the old end drops the uncompressed one.
+func (l *Logger) sortPairs(files []logInfo) {
+	sort.SliceStable(files, func(i, j int) bool {
+		if !files[i].timestamp.Equal(files[j].timestamp) {
+			return files[i].timestamp.After(files[j].timestamp)
+		}
+		return l.isCompressed(files[i].Name()) && !l.isCompressed(files[j].Name())
+	})
+}
+
+// compressedForm returns the name of the compressed form of the uncompressed
+// backup name in files, or "" if there is none.
+func (l *Logger) compressedForm(files []logInfo, name string) string {
+	for _, f := range files {
+		if l.trimCompressed(f.Name()) == name && f.Name() != name {
+			return f.Name()
+		}
+	}
+	return ""
+}
+
+// completeCompressed reports whether the compressed backup at path is
+// complete, given the size of its uncompressed form. Only gzip files need
+// checking: other codecs were added after compressed files started to be
+// moved into place only once complete.
+func (l *Logger) completeCompressed(path string, size int64) bool {
+	if strings.HasSuffix(path, compressSuffix) {
+		return completeGzip(path, size)
+	}
+	return true
+}
+
+// completeGzip reports whether path holds a complete gzip stream of size
+// bytes. Older versions compressed straight into the final file, so a
+// compressed file next to its uncompressed form may have been cut short by
+// a crash. Rather than decompressing the whole file on every cleanup pass,
+// it checks the header and that the trailer records the uncompressed size,
+// which the last bytes of a cut-short stream don't.
+func completeGzip(path string, size int64) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if _, err := gzip.NewReader(f); err != nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil || info.Size() < gzipOverhead {
+		return false
+	}
+	var trailer [8]byte // CRC-32 and the uncompressed size modulo 2^32
+	if _, err := f.ReadAt(trailer[:], info.Size()-int64(len(trailer))); err != nil {
+		return false
+	}
+	return binary.LittleEndian.Uint32(trailer[4:]) == uint32(size)
+}
+
+// gzipOverhead is the size of the shortest gzip stream: a 10-byte header, an
+// empty deflate block and the 8-byte trailer.
+const gzipOverhead = 20
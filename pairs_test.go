@@ -0,0 +1,83 @@
+package timberjack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPairPolicy(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestPairPolicy", t)
+	defer os.RemoveAll(dir)
+
+	older := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(older, []byte("older!"), 0644), t)
+	newFakeTime()
+	newer := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(newer, []byte("newer!"), 0644), t)
+	compressed := new(bytes.Buffer)
+	zw := gzip.NewWriter(compressed)
+	_, err := zw.Write([]byte("newer!"))
+	isNil(err, t)
+	isNil(zw.Close(), t)
+	isNil(os.WriteFile(newer+compressSuffix, compressed.Bytes(), 0644), t)
+
+	// Counted once, the pair leaves room for the older backup.
+	l := &Logger{Filename: logFile(dir), MaxBackups: 2, BackupTimeFormat: backupTimeFormat}
+	plan, err := l.RetentionPlan()
+	isNil(err, t)
+	equals(0, len(plan), t)
+
+	// Counted individually, it doesn't; the uncompressed file goes before
+	// the compressed one.
+	l.PairPolicy = PairCountEach
+	isNil(l.ValidatePairPolicy(), t)
+	plan, err = l.RetentionPlan()
+	isNil(err, t)
+	equals([]PlannedAction{{Action: ActionRemove, Name: filepath.Base(older), Size: 6, Rule: "MaxBackups"}}, plan, t)
+	l.MaxBackups = 1
+	plan, err = l.RetentionPlan()
+	isNil(err, t)
+	equals(2, len(plan), t)
+	equals(filepath.Base(newer), plan[1].Name, t)
+
+	// With compression, the leftover uncompressed file is dropped rather
+	// than compressed again, as its compressed form is complete.
+	l = &Logger{Filename: logFile(dir), Compress: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+	isNil(l.millRunOnce(), t)
+	notExist(newer, t)
+	existsWithContent(newer+compressSuffix, compressed.Bytes(), t)
+	notExist(older, t)
+	exists(older+compressSuffix, t)
+
+	l.PairPolicy = "twice"
+	notNil(l.ValidatePairPolicy(), t)
+}
+
+func TestCompleteGzip(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("log line\n"), 1000)
+	compressed := new(bytes.Buffer)
+	zw := gzip.NewWriter(compressed)
+	_, err := zw.Write(data)
+	isNil(err, t)
+	isNil(zw.Close(), t)
+
+	path := filepath.Join(dir, "backup.log.gz")
+	isNil(os.WriteFile(path, compressed.Bytes(), 0644), t)
+	equals(true, completeGzip(path, int64(len(data))), t)
+	equals(false, completeGzip(path, int64(len(data))-1), t)
+
+	// Cut short by a crash, the stream has no trailer.
+	isNil(os.WriteFile(path, compressed.Bytes()[:compressed.Len()/2], 0644), t)
+	equals(false, completeGzip(path, int64(len(data))), t)
+	isNil(os.WriteFile(path, compressed.Bytes()[:5], 0644), t)
+	equals(false, completeGzip(path, int64(len(data))), t)
+	equals(false, completeGzip(filepath.Join(dir, "missing.gz"), 0), t)
+}
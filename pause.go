@@ -0,0 +1,50 @@
+package timberjack
+
+// PauseRotation suspends time-based rotation (RotationInterval,
+// RotateAtMinutes, RotationSchedule, RotateAtTimes and RotationPeriod), e.g.
+// during a maintenance window in which new files would confuse log
+// collectors. Size-based and explicit rotations still happen. Rotations that
+// come due while paused are held back until ResumeRotation.
+func (l *Logger) PauseRotation() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rotationPaused = true
+}
+
+// ResumeRotation ends a pause begun by PauseRotation. If a time-based
+// rotation came due meanwhile, the log file is rotated right away, once, with
+// reason "time"; the schedule then carries on as usual. It returns the error
+// of that rotation, if any.
+func (l *Logger) ResumeRotation() error {
+	l.flushStaged()
+	l.flushQueue()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rotationPaused = false
+	missed := l.rotationMissed
+	l.rotationMissed = false
+	if !missed || l.isClosed() || l.file == nil {
+		return nil
+	}
+	l.flushCoalesced()
+	if l.size == 0 {
+		return nil
+	}
+	if err := l.rotate("time"); err != nil {
+		return l.classifyError(err)
+	}
+	l.lastRotationTime = l.now()
+	return nil
+}
+
+// rotateOnSchedule rotates the log file with reason "time" for a rotation
+// that came due, unless PauseRotation holds it back. It reports whether the
+// file was rotated. It expects l.mu to be held.
+func (l *Logger) rotateOnSchedule() (bool, error) {
+	if l.rotationPaused {
+		l.rotationMissed = true
+		return false, nil
+	}
+	return true, l.rotate("time")
+}
@@ -0,0 +1,42 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPauseRotation(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestPauseRotation", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		BackupTimeFormat: backupTimeFormat,
+		RotationInterval: time.Hour,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("before\n"))
+	isNil(err, t)
+
+	l.PauseRotation()
+	newFakeTime()
+	_, err = l.Write([]byte("during\n"))
+	isNil(err, t)
+	fileCount(dir, 1, t)
+	existsWithContent(logFile(dir), []byte("before\nduring\n"), t)
+
+	// The rotation held back by the pause happens on resume.
+	isNil(l.ResumeRotation(), t)
+	fileCount(dir, 2, t)
+	existsWithContent(backupFileWithReason(dir, "time"), []byte("before\nduring\n"), t)
+
+	// Without a rotation due, resuming does nothing.
+	l.PauseRotation()
+	isNil(l.ResumeRotation(), t)
+	fileCount(dir, 2, t)
+}
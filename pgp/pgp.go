@@ -0,0 +1,617 @@
+// Package pgp encrypts timberjack backups to an OpenPGP public key, so that
+// backups can be shipped off-host and read with gpg without the host holding
+// a decryption key:
+//
+//	key, err := os.ReadFile("backups.asc") // gpg --armor --export ops@example.com
+//	if err != nil { ... }
+//	recipient, err := pgp.ParseRecipient(key)
+//	if err != nil { ... }
+//	logger.Encrypter = recipient // backups become foo-...-size.log.gz.gpg
+//
+// Backups are written as RFC 4880 messages: a random AES-256 session key is
+// encrypted to the recipient's RSA key, and the backup with the session key
+// in an integrity protected (MDC) packet, which gpg --decrypt reads. Like
+// timberjack itself, the package only uses the standard library, so only RSA
+// keys are supported. Expired and revoked keys are refused, going by the
+// key's self-signatures, which aren't cryptographically verified: like any
+// key handed to gpg --import, the key must come from a trusted source.
+package pgp
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/DeRuina/timberjack"
+)
+
+// Packet tags, algorithms and flags of RFC 4880 used by the package.
+const (
+	tagPKESK         = 1  // public-key encrypted session key
+	tagSignature     = 2  // signature
+	tagPublicKey     = 6  // public key
+	tagLiteral       = 11 // literal data
+	tagUserID        = 13 // user ID
+	tagSubkey        = 14 // public subkey
+	tagUserAttribute = 17 // user attribute
+	tagSEIPD         = 18 // symmetrically encrypted integrity protected data
+	tagMDC           = 19 // modification detection code
+
+	algoRSA        = 1 // RSA, encrypt or sign
+	algoRSAEncrypt = 2 // RSA, encrypt only
+	algoAES256     = 9
+
+	sigCertGeneric      = 0x10 // user ID certifications, from generic
+	sigCertPositive     = 0x13 // to positive
+	sigSubkeyBinding    = 0x18
+	sigDirectKey        = 0x1f
+	sigKeyRevocation    = 0x20
+	sigSubkeyRevocation = 0x28
+
+	subpacketCreated           = 2
+	subpacketKeyExpiry         = 9
+	subpacketIssuer            = 16
+	subpacketKeyFlags          = 27
+	subpacketIssuerFingerprint = 33
+	keyFlagsEncrypt            = 0x04 | 0x08 // encrypt communications, encrypt storage
+
+	// partialChunk is the size of the chunks of the packets written with
+	// partial body lengths. It must be a power of two of at least 512.
+	partialChunk = 1 << 16
+)
+
+// Recipient is an OpenPGP RSA public key backups are encrypted to. It
+// implements timberjack.Encrypter.
+type Recipient struct {
+	keyID [8]byte
+	key   *rsa.PublicKey
+}
+
+var _ timberjack.Encrypter = (*Recipient)(nil)
+
+// currentTime exists so it can be mocked out by tests.
+var currentTime = time.Now
+
+// ParseRecipient reads the OpenPGP public key key, ASCII armored or binary,
+// as exported by gpg --export. Backups are encrypted to its first RSA subkey
+// usable for encryption, or to its primary key if it has none. Subkeys that
+// have expired, were revoked or aren't flagged for encryption are skipped,
+// and a primary key that has expired or was revoked is an error.
+func ParseRecipient(key []byte) (*Recipient, error) {
+	data, err := dearmor(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var primary *publicKey
+	var subkeys []*publicKey
+	var last *publicKey // the key the next signatures are about
+packets:
+	for len(data) > 0 {
+		tag, body, rest, err := readPacket(data)
+		if err != nil {
+			return nil, err
+		}
+		data = rest
+		switch tag {
+		case tagPublicKey:
+			if primary != nil {
+				break packets // the next key of a keyring
+			}
+			if primary, err = parsePublicKey(body); err != nil {
+				return nil, err
+			}
+			last = primary
+		case tagSubkey:
+			if last, err = parsePublicKey(body); err != nil {
+				return nil, err
+			}
+			subkeys = append(subkeys, last)
+		case tagUserID, tagUserAttribute:
+			last = primary // self-certifications are about the primary key
+		case tagSignature:
+			if sig, ok := parseSignature(body); ok && last != nil && primary != nil {
+				last.addSignature(sig, primary.id, last == primary)
+			}
+		}
+	}
+	if primary == nil {
+		return nil, errors.New("pgp: no public key found")
+	}
+	now := currentTime()
+	if primary.revoked {
+		return nil, fmt.Errorf("pgp: key %X is revoked", primary.id)
+	}
+	if primary.expired(now) {
+		return nil, fmt.Errorf("pgp: key %X expired on %s", primary.id, primary.expires().Format("2006-01-02"))
+	}
+
+	var skipped error // why the first RSA key was skipped
+	for _, k := range append(subkeys, primary) {
+		if k.rsa == nil {
+			continue
+		}
+		err := k.usable(now, k == primary)
+		if err == nil {
+			return &Recipient{keyID: k.id, key: k.rsa}, nil
+		}
+		if skipped == nil {
+			skipped = err
+		}
+	}
+	if skipped != nil {
+		return nil, fmt.Errorf("pgp: no RSA key usable for encryption: %w", skipped)
+	}
+	return nil, errors.New("pgp: no RSA public key found")
+}
+
+// KeyID returns the ID of the key backups are encrypted to, as gpg prints
+// it, e.g. "3A4F81CA2D2C5B25".
+func (r *Recipient) KeyID() string {
+	return fmt.Sprintf("%X", r.keyID[:])
+}
+
+// Suffix implements timberjack.Encrypter.
+func (r *Recipient) Suffix() string {
+	return ".gpg"
+}
+
+// Encrypt implements timberjack.Encrypter: it writes the encrypted session
+// key to dst and returns a writer encrypting a backup after it.
+func (r *Recipient) Encrypt(dst io.Writer) (io.WriteCloser, error) {
+	sessionKey := make([]byte, 32)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, err
+	}
+	var sum uint16
+	for _, b := range sessionKey {
+		sum += uint16(b)
+	}
+	m := append([]byte{algoAES256}, sessionKey...)
+	m = append(m, byte(sum>>8), byte(sum))
+	c, err := rsa.EncryptPKCS1v15(rand.Reader, r.key, m)
+	if err != nil {
+		return nil, err
+	}
+	pkesk := append([]byte{3}, r.keyID[:]...)
+	pkesk = append(pkesk, algoRSA)
+	pkesk = appendMPI(pkesk, c)
+	if err := writePacket(dst, tagPKESK, pkesk); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	w := &messageWriter{seipd: &partialWriter{w: dst, tag: tagSEIPD}, mdc: sha1.New()}
+	w.seipd.buf = append(w.seipd.buf, 1) // version
+	w.enc = cipher.StreamWriter{S: cipher.NewCFBEncrypter(block, make([]byte, block.BlockSize())), W: w.seipd}
+
+	// The random prefix, whose last two bytes are repeated, lets a reader
+	// tell a wrong session key.
+	prefix := make([]byte, block.BlockSize()+2)
+	if _, err := rand.Read(prefix[:block.BlockSize()]); err != nil {
+		return nil, err
+	}
+	copy(prefix[block.BlockSize():], prefix[block.BlockSize()-2:block.BlockSize()])
+	w.literal = &partialWriter{w: io.MultiWriter(w.mdc, w.enc), tag: tagLiteral}
+	if _, err := w.literal.w.Write(prefix); err != nil {
+		return nil, err
+	}
+	// Binary data, without a file name or date.
+	w.literal.buf = append(w.literal.buf, 'b', 0, 0, 0, 0, 0)
+	return w, nil
+}
+
+// messageWriter encrypts a backup as a literal data packet inside an
+// integrity protected packet.
+type messageWriter struct {
+	literal *partialWriter      // the literal data packet, written to mdc and enc
+	mdc     hash                // SHA-1 of the plaintext, for the MDC packet
+	enc     cipher.StreamWriter // encrypts to seipd
+	seipd   *partialWriter      // the integrity protected packet, written to dst
+}
+
+// hash is the part of hash.Hash messageWriter uses.
+type hash interface {
+	io.Writer
+	Sum(b []byte) []byte
+}
+
+func (w *messageWriter) Write(p []byte) (int, error) {
+	return w.literal.Write(p)
+}
+
+// Close finishes the message without closing the destination.
+func (w *messageWriter) Close() error {
+	if err := w.literal.Close(); err != nil {
+		return err
+	}
+	header := []byte{0xc0 | tagMDC, sha1.Size}
+	w.mdc.Write(header)
+	if _, err := w.enc.Write(w.mdc.Sum(header)); err != nil {
+		return err
+	}
+	return w.seipd.Close()
+}
+
+// partialWriter writes a packet of unknown length, in chunks with partial
+// body lengths. A packet shorter than a chunk is written with its length.
+type partialWriter struct {
+	w       io.Writer
+	tag     byte
+	buf     []byte
+	started bool // the packet's tag has been written
+}
+
+func (p *partialWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+	for len(p.buf) > partialChunk {
+		if err := p.writeHeader(); err != nil {
+			return 0, err
+		}
+		if _, err := p.w.Write([]byte{224 + 16}); err != nil { // 1<<16
+			return 0, err
+		}
+		if _, err := p.w.Write(p.buf[:partialChunk]); err != nil {
+			return 0, err
+		}
+		p.buf = append(p.buf[:0], p.buf[partialChunk:]...)
+	}
+	return len(b), nil
+}
+
+// Close writes the last chunk of the packet.
+func (p *partialWriter) Close() error {
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+	if _, err := p.w.Write(appendLength(nil, len(p.buf))); err != nil {
+		return err
+	}
+	_, err := p.w.Write(p.buf)
+	p.buf = nil
+	return err
+}
+
+// writeHeader writes the packet's tag, once.
+func (p *partialWriter) writeHeader() error {
+	if p.started {
+		return nil
+	}
+	p.started = true
+	_, err := p.w.Write([]byte{0xc0 | p.tag})
+	return err
+}
+
+// writePacket writes a packet with the given tag and body.
+func writePacket(w io.Writer, tag byte, body []byte) error {
+	packet := appendLength([]byte{0xc0 | tag}, len(body))
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+// appendLength appends the new format length of a packet body of n bytes.
+func appendLength(b []byte, n int) []byte {
+	switch {
+	case n < 192:
+		return append(b, byte(n))
+	case n < 8384:
+		n -= 192
+		return append(b, byte(n>>8)+192, byte(n))
+	}
+	return append(b, 255, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+// appendMPI appends the multiprecision integer with the big-endian bytes n.
+func appendMPI(b, n []byte) []byte {
+	n = bytes.TrimLeft(n, "\x00")
+	bits := new(big.Int).SetBytes(n).BitLen()
+	b = append(b, byte(bits>>8), byte(bits))
+	return append(b, n...)
+}
+
+// readMPI reads a multiprecision integer from the start of data and returns
+// its bytes and the rest of data.
+func readMPI(data []byte) (n, rest []byte, err error) {
+	if len(data) < 2 {
+		return nil, nil, errors.New("pgp: truncated key")
+	}
+	size := (int(binary.BigEndian.Uint16(data)) + 7) / 8
+	if len(data) < 2+size {
+		return nil, nil, errors.New("pgp: truncated key")
+	}
+	return data[2 : 2+size], data[2+size:], nil
+}
+
+// readPacket reads the packet at the start of data and returns its tag, its
+// body and the rest of data.
+func readPacket(data []byte) (tag byte, body, rest []byte, err error) {
+	if len(data) < 2 || data[0]&0x80 == 0 {
+		return 0, nil, nil, errors.New("pgp: invalid packet")
+	}
+	var n, header int
+	if data[0]&0x40 != 0 { // new format
+		tag = data[0] & 0x3f
+		switch o := int(data[1]); {
+		case o < 192:
+			n, header = o, 2
+		case o < 224:
+			if len(data) < 3 {
+				return 0, nil, nil, errors.New("pgp: truncated packet")
+			}
+			n, header = (o-192)<<8+int(data[2])+192, 3
+		case o == 255:
+			if len(data) < 6 {
+				return 0, nil, nil, errors.New("pgp: truncated packet")
+			}
+			n, header = int(binary.BigEndian.Uint32(data[2:])), 6
+		default:
+			return 0, nil, nil, errors.New("pgp: unexpected partial length in a key")
+		}
+	} else {
+		tag = data[0] >> 2 & 0x0f
+		switch data[0] & 3 {
+		case 0:
+			n, header = int(data[1]), 2
+		case 1:
+			if len(data) < 3 {
+				return 0, nil, nil, errors.New("pgp: truncated packet")
+			}
+			n, header = int(binary.BigEndian.Uint16(data[1:])), 3
+		case 2:
+			if len(data) < 5 {
+				return 0, nil, nil, errors.New("pgp: truncated packet")
+			}
+			n, header = int(binary.BigEndian.Uint32(data[1:])), 5
+		default:
+			n, header = len(data)-1, 1
+		}
+	}
+	if n < 0 || len(data) < header+n {
+		return 0, nil, nil, errors.New("pgp: truncated packet")
+	}
+	return tag, data[header : header+n], data[header+n:], nil
+}
+
+// publicKey is a public key or subkey, with what its self-signatures say
+// about it.
+type publicKey struct {
+	id      [8]byte
+	rsa     *rsa.PublicKey // nil if it isn't an RSA encryption key
+	created time.Time
+	binding *signature // the latest self-signature, giving flags and expiry
+	revoked bool
+}
+
+// addSignature applies the signature sig, following the key k, whose
+// primary key has the ID primaryID. Certifications by other keys are
+// ignored.
+func (k *publicKey) addSignature(sig *signature, primaryID [8]byte, isPrimary bool) {
+	switch {
+	case sig.hasIssuer && sig.issuer != primaryID:
+		return
+	case sig.sigType == sigKeyRevocation && isPrimary, sig.sigType == sigSubkeyRevocation && !isPrimary:
+		k.revoked = true
+	case sig.sigType == sigSubkeyBinding && !isPrimary,
+		(sig.sigType == sigDirectKey || sig.sigType >= sigCertGeneric && sig.sigType <= sigCertPositive) && isPrimary:
+		if k.binding == nil || !sig.created.Before(k.binding.created) {
+			k.binding = sig
+		}
+	}
+}
+
+// expires returns the time the key expires, or the zero time if it doesn't.
+func (k *publicKey) expires() time.Time {
+	if k.binding == nil || k.binding.keyExpiry == 0 {
+		return time.Time{}
+	}
+	return k.created.Add(k.binding.keyExpiry)
+}
+
+// expired reports whether the key has expired at now.
+func (k *publicKey) expired(now time.Time) bool {
+	expires := k.expires()
+	return !expires.IsZero() && !now.Before(expires)
+}
+
+// usable reports why the key can't be encrypted to at now, if it can't.
+// Subkeys need a binding signature; keys without key flags may be used for
+// whatever their algorithm allows.
+func (k *publicKey) usable(now time.Time, isPrimary bool) error {
+	switch {
+	case !isPrimary && k.binding == nil:
+		return fmt.Errorf("subkey %X isn't bound to the key", k.id)
+	case k.revoked:
+		return fmt.Errorf("subkey %X is revoked", k.id)
+	case k.expired(now):
+		return fmt.Errorf("key %X expired on %s", k.id, k.expires().Format("2006-01-02"))
+	case k.binding != nil && k.binding.hasFlags && k.binding.flags&keyFlagsEncrypt == 0:
+		return fmt.Errorf("key %X isn't flagged for encryption", k.id)
+	}
+	return nil
+}
+
+// parsePublicKey reads the body of a version 4 public key or subkey packet.
+// Its rsa field is nil if the key isn't an RSA encryption key.
+func parsePublicKey(body []byte) (*publicKey, error) {
+	if len(body) < 6 {
+		return nil, errors.New("pgp: truncated key")
+	}
+	if body[0] != 4 {
+		return nil, fmt.Errorf("pgp: unsupported key version %d", body[0])
+	}
+	h := sha1.New()
+	h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	h.Write(body)
+	k := &publicKey{created: time.Unix(int64(binary.BigEndian.Uint32(body[1:])), 0)}
+	copy(k.id[:], h.Sum(nil)[12:])
+	if body[5] != algoRSA && body[5] != algoRSAEncrypt {
+		return k, nil
+	}
+
+	n, rest, err := readMPI(body[6:])
+	if err != nil {
+		return nil, err
+	}
+	e, _, err := readMPI(rest)
+	if err != nil {
+		return nil, err
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() > 1<<31-1 || exp.Int64() < 3 {
+		return nil, errors.New("pgp: unsupported RSA exponent")
+	}
+	k.rsa = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}
+	return k, nil
+}
+
+// signature is what the package reads of a version 4 signature. The
+// signature itself isn't verified.
+type signature struct {
+	sigType   byte
+	created   time.Time
+	keyExpiry time.Duration // after the key's creation; 0 if it doesn't expire
+	flags     byte
+	hasFlags  bool
+	issuer    [8]byte
+	hasIssuer bool
+}
+
+// parseSignature reads the body of a version 4 signature packet. Creation
+// time, key expiry and key flags are only taken from the hashed subpackets;
+// the issuer may also be in the unhashed ones.
+func parseSignature(body []byte) (*signature, bool) {
+	if len(body) < 6 || body[0] != 4 {
+		return nil, false
+	}
+	sig := &signature{sigType: body[1]}
+	hashed := int(binary.BigEndian.Uint16(body[4:]))
+	if len(body) < 6+hashed+2 {
+		return nil, false
+	}
+	unhashed := int(binary.BigEndian.Uint16(body[6+hashed:]))
+	if len(body) < 8+hashed+unhashed {
+		return nil, false
+	}
+	ok := readSubpackets(body[6:6+hashed], func(typ byte, data []byte) {
+		switch {
+		case typ == subpacketCreated && len(data) == 4:
+			sig.created = time.Unix(int64(binary.BigEndian.Uint32(data)), 0)
+		case typ == subpacketKeyExpiry && len(data) == 4:
+			sig.keyExpiry = time.Duration(binary.BigEndian.Uint32(data)) * time.Second
+		case typ == subpacketKeyFlags && len(data) > 0:
+			sig.flags, sig.hasFlags = data[0], true
+		default:
+			sig.readIssuer(typ, data)
+		}
+	})
+	ok = ok && readSubpackets(body[8+hashed:8+hashed+unhashed], sig.readIssuer)
+	return sig, ok
+}
+
+// readIssuer records the issuer subpacket typ, if it is one.
+func (sig *signature) readIssuer(typ byte, data []byte) {
+	switch {
+	case typ == subpacketIssuer && len(data) == 8:
+		copy(sig.issuer[:], data)
+		sig.hasIssuer = true
+	case typ == subpacketIssuerFingerprint && len(data) == 21 && data[0] == 4:
+		copy(sig.issuer[:], data[13:])
+		sig.hasIssuer = true
+	}
+}
+
+// readSubpackets calls fn with the type, without its critical bit, and the
+// data of every signature subpacket in sub. It reports whether sub was well
+// formed.
+func readSubpackets(sub []byte, fn func(typ byte, data []byte)) bool {
+	for len(sub) > 0 {
+		var n, header int
+		switch o := int(sub[0]); {
+		case o < 192:
+			n, header = o, 1
+		case o < 255:
+			if len(sub) < 2 {
+				return false
+			}
+			n, header = (o-192)<<8+int(sub[1])+192, 2
+		default:
+			if len(sub) < 5 {
+				return false
+			}
+			n, header = int(binary.BigEndian.Uint32(sub[1:])), 5
+		}
+		if n < 1 || len(sub) < header+n {
+			return false
+		}
+		fn(sub[header]&0x7f, sub[header+1:header+n])
+		sub = sub[header+n:]
+	}
+	return true
+}
+
+// dearmor returns the binary content of an ASCII armored key, or data itself
+// if it isn't armored.
+func dearmor(data []byte) ([]byte, error) {
+	const begin = "-----BEGIN PGP PUBLIC KEY BLOCK-----"
+	s := string(data)
+	i := strings.Index(s, begin)
+	if i < 0 {
+		return data, nil
+	}
+	lines := strings.Split(s[i+len(begin):], "\n")[1:]
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) != "" { // armor headers
+		lines = lines[1:]
+	}
+	var b64, checksum string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "-----END"):
+			key, err := base64.StdEncoding.DecodeString(b64)
+			if err != nil {
+				return nil, fmt.Errorf("pgp: invalid armor: %v", err)
+			}
+			if checksum != "" {
+				want, err := base64.StdEncoding.DecodeString(checksum)
+				if err != nil || len(want) != 3 || crc24(key) != uint32(want[0])<<16|uint32(want[1])<<8|uint32(want[2]) {
+					return nil, errors.New("pgp: armor checksum mismatch")
+				}
+			}
+			return key, nil
+		case strings.HasPrefix(line, "="):
+			checksum = line[1:]
+		default:
+			b64 += line
+		}
+	}
+	return nil, errors.New("pgp: armor without end line")
+}
+
+// crc24 returns the CRC-24 checksum of ASCII armor.
+func crc24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
@@ -0,0 +1,438 @@
+package pgp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DeRuina/timberjack"
+)
+
+func TestParseRecipient(t *testing.T) {
+	// Exported with gpg --armor --export; its RSA subkey is for encryption.
+	armored, err := os.ReadFile(filepath.Join("testdata", "recipient.asc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := ParseRecipient(armored)
+	if err != nil {
+		t.Fatalf("ParseRecipient: %v", err)
+	}
+	if got := r.KeyID(); got != "3A4F81CA2D2C5B25" {
+		t.Fatalf("expected the encryption subkey, got key ID %s", got)
+	}
+
+	binary, err := dearmor(armored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := ParseRecipient(binary); err != nil || r.KeyID() != "3A4F81CA2D2C5B25" {
+		t.Fatalf("binary key: %v, %v", r, err)
+	}
+
+	corrupt := bytes.Replace(armored, []byte("mQENBG"), []byte("mQENBH"), 1)
+	for _, key := range [][]byte{
+		nil,
+		[]byte("not a key"),
+		corrupt,
+		armored[:len(armored)/2],
+		binary[:len(binary)/2],
+	} {
+		if _, err := ParseRecipient(key); err == nil {
+			t.Errorf("expected error for %.40q", key)
+		}
+	}
+}
+
+func TestEncrypt(t *testing.T) {
+	priv, r := newRecipient(t)
+	for _, n := range []int{0, 100, partialChunk - 20, 3*partialChunk + 1} {
+		plain := make([]byte, n)
+		rand.Read(plain)
+
+		var msg bytes.Buffer
+		w, err := r.Encrypt(&msg)
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+		// Odd write sizes exercise the chunking.
+		for p := plain; len(p) > 0; {
+			k := 7777
+			if k > len(p) {
+				k = len(p)
+			}
+			if _, err := w.Write(p[:k]); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			p = p[k:]
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+
+		got, err := decrypt(priv, msg.Bytes())
+		if err != nil {
+			t.Fatalf("%d bytes: decrypt: %v", n, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("%d bytes: decrypted content differs", n)
+		}
+
+		// A flipped bit fails the integrity check.
+		tampered := append([]byte(nil), msg.Bytes()...)
+		tampered[len(tampered)-5] ^= 1
+		if _, err := decrypt(priv, tampered); err == nil {
+			t.Fatalf("%d bytes: expected tampering to be detected", n)
+		}
+	}
+}
+
+func TestLogger(t *testing.T) {
+	priv, r := newRecipient(t)
+	dir := t.TempDir()
+	l := &timberjack.Logger{
+		Filename:         filepath.Join(dir, "app.log"),
+		BackupTimeFormat: "2006-01-02T15-04-05.000",
+		Compress:         true,
+		Encrypter:        r,
+	}
+	content := []byte(strings.Repeat("encrypted to a public key\n", 1000))
+	if _, err := l.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	// Shutdown waits for the backup to be compressed and encrypted.
+	if err := l.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log.gz.gpg"))
+	if len(backups) != 1 {
+		files, _ := os.ReadDir(dir)
+		t.Fatalf("expected one .log.gz.gpg backup, got %v", files)
+	}
+	msg, err := os.ReadFile(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := decrypt(priv, msg)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatal("backup content differs")
+	}
+}
+
+// TestGPG checks that gpg decrypts the messages, if it's installed.
+func TestGPG(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg not installed")
+	}
+	home, err := os.MkdirTemp("", "pgp-gnupg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Not t.TempDir: gpg-agent's socket path is limited in length.
+	defer os.RemoveAll(home)
+	gpg := func(stdin []byte, args ...string) []byte {
+		t.Helper()
+		cmd := exec.Command("gpg", append([]string{"--homedir", home, "--batch", "--quiet"}, args...)...)
+		cmd.Stdin = bytes.NewReader(stdin)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("gpg %s: %v\n%s", strings.Join(args, " "), err, stderr.String())
+		}
+		return out
+	}
+	defer exec.Command("gpgconf", "--homedir", home, "--kill", "gpg-agent").Run()
+
+	gpg(nil, "--passphrase", "", "--quick-gen-key", "Backups <backups@example.com>", "rsa2048", "cert", "never")
+	fpr := ""
+	for _, line := range strings.Split(string(gpg(nil, "--with-colons", "--list-keys")), "\n") {
+		if strings.HasPrefix(line, "fpr:") {
+			fpr = strings.Split(line, ":")[9]
+			break
+		}
+	}
+	gpg(nil, "--passphrase", "", "--quick-add-key", fpr, "rsa2048", "encr", "never")
+	r, err := ParseRecipient(gpg(nil, "--armor", "--export", fpr))
+	if err != nil {
+		t.Fatalf("ParseRecipient: %v", err)
+	}
+
+	// A key whose encryption subkey expired is refused.
+	faked := []string{"--faked-system-time", "20200101T000000", "--passphrase", ""}
+	gpg(nil, append(faked, "--quick-gen-key", "Old <old@example.com>", "rsa2048", "cert", "never")...)
+	old := ""
+	for _, line := range strings.Split(string(gpg(nil, "--with-colons", "--list-keys", "old@example.com")), "\n") {
+		if strings.HasPrefix(line, "fpr:") {
+			old = strings.Split(line, ":")[9]
+			break
+		}
+	}
+	gpg(nil, append(faked, "--quick-add-key", old, "rsa2048", "encr", "1d")...)
+	if _, err := ParseRecipient(gpg(nil, "--armor", "--export", old)); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Fatalf("expected the expired subkey to be refused, got %v", err)
+	}
+
+	for _, n := range []int{0, 1000, 3*partialChunk + 1} {
+		plain := make([]byte, n)
+		rand.Read(plain)
+		var msg bytes.Buffer
+		w, err := r.Encrypt(&msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(plain)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if got := gpg(msg.Bytes(), "--decrypt"); !bytes.Equal(got, plain) {
+			t.Fatalf("%d bytes: gpg decrypted %d different bytes", n, len(got))
+		}
+	}
+}
+
+func TestParseRecipient_Validity(t *testing.T) {
+	created := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	now := created.AddDate(0, 0, 100)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = time.Now }()
+
+	primary := keyPacket(tagPublicKey, created, 1)
+	primaryID := keyID(t, primary)
+	sub1 := keyPacket(tagSubkey, created, 2)
+	sub2 := keyPacket(tagSubkey, created, 3)
+	uid := append([]byte{0xc0 | tagUserID, 4}, "test"...)
+	selfSig := sigPacket(0x13, created, primaryID, subpacket(subpacketKeyFlags, 0x01))
+	binding := func(sigCreated time.Time, flags byte, expiry time.Duration) []byte {
+		hashed := subpacket(subpacketKeyFlags, flags)
+		if expiry > 0 {
+			hashed = append(hashed, subpacket(subpacketKeyExpiry, uint32Bytes(uint32(expiry/time.Second))...)...)
+		}
+		return sigPacket(sigSubkeyBinding, sigCreated, primaryID, hashed)
+	}
+	valid := binding(created, keyFlagsEncrypt, 0)
+	key := func(packets ...[]byte) []byte {
+		return bytes.Join(append([][]byte{primary, uid, selfSig}, packets...), nil)
+	}
+	day := 24 * time.Hour
+	var otherID [8]byte
+
+	for _, tt := range []struct {
+		name string
+		key  []byte
+		want []byte // the key packet of the recipient, or nil for an error
+	}{
+		{"encryption subkey", key(sub1, valid), sub1},
+		{"expired subkey", key(sub1, binding(created, keyFlagsEncrypt, 30*day)), nil},
+		{"renewed subkey", key(sub1, binding(created, keyFlagsEncrypt, 30*day), binding(created.Add(day), keyFlagsEncrypt, 365*day)), sub1},
+		{"revoked subkey", key(sub1, valid, sigPacket(sigSubkeyRevocation, created.Add(day), primaryID, nil)), nil},
+		{"signing subkey", key(sub1, binding(created, 0x02, 0)), nil},
+		{"unbound subkey", key(sub1), nil},
+		{"skipped subkey", key(sub1, binding(created, keyFlagsEncrypt, 30*day), sub2, valid), sub2},
+		{"revoked key", bytes.Join([][]byte{primary, sigPacket(sigKeyRevocation, created.Add(day), primaryID, nil), uid, selfSig, sub1, valid}, nil), nil},
+		{"expired key", bytes.Join([][]byte{primary, uid, sigPacket(0x13, created, primaryID, subpacket(subpacketKeyExpiry, uint32Bytes(uint32(30*day/time.Second))...)), sub1, valid}, nil), nil},
+		{"third-party certification", key(sigPacket(0x10, created.Add(day), otherID, subpacket(subpacketKeyExpiry, uint32Bytes(1)...)), sub1, valid), sub1},
+		{"primary key without flags", primary, primary},
+		{"certification-only primary key", key(), nil},
+	} {
+		r, err := ParseRecipient(tt.key)
+		switch {
+		case tt.want == nil && err == nil:
+			t.Errorf("%s: expected an error, got key %s", tt.name, r.KeyID())
+		case tt.want != nil && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != nil && r.keyID != keyID(t, tt.want):
+			t.Errorf("%s: unexpected key %s", tt.name, r.KeyID())
+		}
+	}
+}
+
+// keyPacket returns a public key or subkey packet created at the given
+// time, with a made-up RSA modulus filled with b.
+func keyPacket(tag byte, created time.Time, b byte) []byte {
+	body := append([]byte{4}, uint32Bytes(uint32(created.Unix()))...)
+	body = append(body, algoRSA)
+	body = appendMPI(body, bytes.Repeat([]byte{b}, 256))
+	body = appendMPI(body, big.NewInt(65537).Bytes())
+	var packet bytes.Buffer
+	writePacket(&packet, tag, body)
+	return packet.Bytes()
+}
+
+// keyID returns the key ID of the key packet.
+func keyID(t *testing.T, packet []byte) [8]byte {
+	t.Helper()
+	_, body, _, err := readPacket(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := parsePublicKey(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k.id
+}
+
+// sigPacket returns a signature packet of the given type made at created by
+// issuer, with the hashed subpackets hashed. Its signature is made up.
+func sigPacket(sigType byte, created time.Time, issuer [8]byte, hashed []byte) []byte {
+	hashed = append(subpacket(subpacketCreated, uint32Bytes(uint32(created.Unix()))...), hashed...)
+	unhashed := subpacket(subpacketIssuer, issuer[:]...)
+	body := []byte{4, sigType, algoRSA, 8, byte(len(hashed) >> 8), byte(len(hashed))}
+	body = append(body, hashed...)
+	body = append(body, byte(len(unhashed)>>8), byte(len(unhashed)))
+	body = append(body, unhashed...)
+	body = append(body, 0, 0)
+	body = appendMPI(body, []byte{0xff})
+	var packet bytes.Buffer
+	writePacket(&packet, tagSignature, body)
+	return packet.Bytes()
+}
+
+// subpacket returns a signature subpacket.
+func subpacket(typ byte, data ...byte) []byte {
+	return append([]byte{byte(len(data) + 1), typ}, data...)
+}
+
+func uint32Bytes(n uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, n)
+	return b
+}
+
+// newRecipient returns a new RSA key and a Recipient parsed from its
+// public key packet.
+func newRecipient(t *testing.T) (*rsa.PrivateKey, *Recipient) {
+	t.Helper()
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte{4, 0, 0, 0, 0, algoRSA}
+	body = appendMPI(body, priv.N.Bytes())
+	body = appendMPI(body, big.NewInt(int64(priv.E)).Bytes())
+	var key bytes.Buffer
+	if err := writePacket(&key, tagPublicKey, body); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ParseRecipient(key.Bytes())
+	if err != nil {
+		t.Fatalf("ParseRecipient: %v", err)
+	}
+	return priv, r
+}
+
+// decrypt reads a message written by Encrypt with the private key priv and
+// returns the content of its literal data packet.
+func decrypt(priv *rsa.PrivateKey, msg []byte) ([]byte, error) {
+	tag, pkesk, rest, err := readPacket(msg)
+	if err != nil || tag != tagPKESK || len(pkesk) < 10 || pkesk[9] != algoRSA {
+		return nil, fmt.Errorf("bad PKESK packet: %v", err)
+	}
+	c, _, err := readMPI(pkesk[10:])
+	if err != nil {
+		return nil, err
+	}
+	m, err := rsa.DecryptPKCS1v15(nil, priv, c)
+	if err != nil {
+		return nil, err
+	}
+	if len(m) != 35 || m[0] != algoAES256 {
+		return nil, fmt.Errorf("bad session key")
+	}
+
+	tag, seipd, err := readPartial(rest)
+	if err != nil || tag != tagSEIPD || len(seipd) < 1 || seipd[0] != 1 {
+		return nil, fmt.Errorf("bad SEIPD packet: %v", err)
+	}
+	block, err := aes.NewCipher(m[1:33])
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(seipd)-1)
+	cipher.NewCFBDecrypter(block, make([]byte, 16)).XORKeyStream(plain, seipd[1:])
+	if len(plain) < 18+22 || !bytes.Equal(plain[14:16], plain[16:18]) {
+		return nil, fmt.Errorf("bad prefix")
+	}
+	mdc := plain[len(plain)-20:]
+	h := sha1.Sum(plain[:len(plain)-20])
+	if !bytes.Equal(h[:], mdc) || !bytes.Equal(plain[len(plain)-22:len(plain)-20], []byte{0xd3, 0x14}) {
+		return nil, fmt.Errorf("MDC mismatch")
+	}
+
+	tag, literal, err := readPartial(plain[18 : len(plain)-22])
+	if err != nil || tag != tagLiteral || len(literal) < 6 || literal[0] != 'b' {
+		return nil, fmt.Errorf("bad literal packet: %v", err)
+	}
+	return literal[2+int(literal[1])+4:], nil
+}
+
+// readPartial reads a new format packet filling data, with partial body
+// lengths or not, and returns its tag and body.
+func readPartial(data []byte) (byte, []byte, error) {
+	if len(data) < 2 || data[0]&0xc0 != 0xc0 {
+		return 0, nil, fmt.Errorf("bad packet header")
+	}
+	tag := data[0] & 0x3f
+	var body []byte
+	data = data[1:]
+	for {
+		if len(data) == 0 {
+			return 0, nil, fmt.Errorf("truncated packet")
+		}
+		var n, header int
+		partial := false
+		switch o := int(data[0]); {
+		case o < 192:
+			n, header = o, 1
+		case o < 224:
+			n, header = (o-192)<<8+int(data[1])+192, 2
+		case o == 255:
+			n, header = int(binary.BigEndian.Uint32(data[1:])), 5
+		default:
+			n, header, partial = 1<<(o&0x1f), 1, true
+		}
+		if len(data) < header+n {
+			return 0, nil, fmt.Errorf("truncated packet")
+		}
+		body = append(body, data[header:header+n]...)
+		data = data[header+n:]
+		if !partial {
+			break
+		}
+	}
+	if len(data) != 0 {
+		return 0, nil, fmt.Errorf("trailing data")
+	}
+	return tag, body, nil
+}
@@ -0,0 +1,55 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// pinSuffix marks the sidecar file that pins a backup.
+const pinSuffix = ".keep"
+
+// Pin protects a backup from MaxBackups, MaxAge, MaxTotalSize and
+// MinDiskFree, e.g. while an operator investigates an incident. name is the
+// backup's name as reported by Backups, or its absolute path.
+//
+// A pin is recorded as an empty sidecar file named after the uncompressed
+// backup with ".keep" appended (e.g. foo-2025-01-02T15-04-05.000-size.log.keep),
+// so it survives restarts, covers the compressed form of the backup too and
+// can be created by hand. Like backups matching KeepPatterns, pinned backups
+// don't count towards MaxBackups.
+func (l *Logger) Pin(name string) error {
+	backup := l.pinTarget(name)
+	if _, err := os.Stat(backup); err != nil && !l.compressedExists(backup) {
+		return fmt.Errorf("can't pin backup: %w", err)
+	}
+	f, err := os.OpenFile(backup+pinSuffix, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("can't pin backup: %w", err)
+	}
+	return f.Close()
+}
+
+// Unpin removes the pin of a backup, leaving it to retention again. It is not
+// an error to unpin a backup that isn't pinned.
+func (l *Logger) Unpin(name string) error {
+	if err := os.Remove(l.pinTarget(name) + pinSuffix); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't unpin backup: %w", err)
+	}
+	return nil
+}
+
+// pinTarget returns the path of the uncompressed form of the backup name,
+// resolving names relative to the backup directory.
+func (l *Logger) pinTarget(name string) string {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(l.backupDir(), name)
+	}
+	return l.trimCompressed(name)
+}
+
+// pinned reports whether the backup at path has a pin sidecar.
+func (l *Logger) pinned(path string) bool {
+	_, err := os.Stat(l.trimCompressed(path) + pinSuffix)
+	return err == nil
+}
@@ -0,0 +1,84 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPin(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestPin", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBackups: 1, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	_, err := l.Write([]byte("incident!"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	incident := backupFileWithReason(dir, "size")
+	isNil(l.Pin(filepath.Base(incident)), t)
+	exists(incident+pinSuffix, t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	assert(backups[0].Pinned, t, "expected the backup to be pinned")
+
+	// The pinned backup doesn't count towards MaxBackups and survives.
+	var names []string
+	for _, content := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+		names = append(names, backupFileWithReason(dir, "size"))
+	}
+	<-time.After(10 * time.Millisecond)
+	existsWithContent(incident, []byte("incident!"), t)
+	notExist(names[0], t)
+	existsWithContent(names[1], []byte("two!"), t)
+
+	// Once unpinned, it's pruned like any other backup.
+	isNil(l.Unpin(incident), t)
+	notExist(incident+pinSuffix, t)
+	isNil(l.Unpin(incident), t)
+	isNil(l.millRunOnce(), t)
+	notExist(incident, t)
+
+	notNil(l.Pin("no-such-backup.log"), t)
+}
+
+func TestPin_Numbered(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestPin_Numbered", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBackups: 1, NumberedBackups: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	_, err := l.Write([]byte("incident!"))
+	isNil(err, t)
+	isNil(l.Rotate(), t)
+	isNil(l.Pin("foobar.log.1"), t)
+
+	// The pin moves along as the backup is renumbered.
+	for _, content := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+	}
+	<-time.After(10 * time.Millisecond)
+	existsWithContent(filepath.Join(dir, "foobar.log.3"), []byte("incident!"), t)
+	exists(filepath.Join(dir, "foobar.log.3"+pinSuffix), t)
+	notExist(filepath.Join(dir, "foobar.log.1"+pinSuffix), t)
+	notExist(filepath.Join(dir, "foobar.log.2"), t)
+	existsWithContent(filepath.Join(dir, "foobar.log.1"), []byte("two!"), t)
+}
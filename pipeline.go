@@ -0,0 +1,217 @@
+package timberjack
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Pipeline is an io.WriteCloser that runs each record through a chain of
+// common stages before it reaches a Logger:
+//
+//	Interceptors -> buffer (BufferSize) -> Tee / GzipTee -> Logger
+//
+// It replaces wrapping a Logger by hand, where Close and Flush have to be
+// threaded through every layer in the right order. Like Logger, a Pipeline
+// is configured through its fields and must not be copied or reconfigured
+// after the first Write. It is safe for concurrent use.
+type Pipeline struct {
+	// Logger is the rotating file the pipeline ends in. It is required.
+	Logger *Logger
+
+	// Interceptors are applied to each record in order. An interceptor
+	// returns the record to pass on, which may be p itself, a modified copy
+	// (e.g. with secrets redacted) or nil to drop the record.
+	Interceptors []func(p []byte) []byte
+
+	// BufferSize, if greater than zero, collects records in memory and
+	// passes them on in batches of up to BufferSize bytes, trading latency
+	// for fewer writes. Records are never split between batches, so a
+	// rotation never cuts one in half. Use Flush to pass buffered records
+	// on, e.g. periodically or before a crash-prone operation.
+	BufferSize int
+
+	// Tee lists additional writers, e.g. os.Stderr or a network connection,
+	// that receive every record the Logger receives. Writers that have a
+	// Flush() error method are flushed by Flush. Tee writers are not closed
+	// by Close; their owner remains responsible for them.
+	Tee []io.Writer
+
+	// GzipTee is like Tee, but every writer receives one gzip stream of the
+	// records, compressed on the fly, e.g. for shipping logs over the
+	// network. Flush flushes the streams, Close finishes them.
+	GzipTee []io.Writer
+
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	buf     []byte // buffered records (BufferSize)
+	bounds  []int  // end offsets of the records in buf
+	gzips   []*gzip.Writer
+}
+
+// ErrPipelineClosed is returned by a Pipeline's Write and Flush after Close.
+var ErrPipelineClosed = errors.New("timberjack: pipeline closed")
+
+// errNoLogger is returned by a Pipeline without a Logger.
+var errNoLogger = errors.New("timberjack: Pipeline.Logger is required")
+
+// Write implements io.Writer. It reports len(p) as written if the record
+// was accepted, even when an interceptor changed or dropped it. Errors from
+// the Logger and tee writers are returned, but a failing tee writer doesn't
+// keep the record from the other destinations.
+func (p *Pipeline) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.start(); err != nil {
+		return 0, err
+	}
+
+	record := b
+	for _, intercept := range p.Interceptors {
+		if record = intercept(record); len(record) == 0 {
+			return len(b), nil
+		}
+	}
+
+	if p.BufferSize <= 0 {
+		return len(b), p.emit(record)
+	}
+	if len(p.buf)+len(record) > p.BufferSize {
+		if err := p.flushBuffer(); err != nil {
+			return 0, err
+		}
+	}
+	if len(record) >= p.BufferSize {
+		return len(b), p.emit(record)
+	}
+	p.buf = append(p.buf, record...)
+	p.bounds = append(p.bounds, len(p.buf))
+	return len(b), nil
+}
+
+// Flush passes buffered records on and flushes the gzip streams, the Tee
+// writers that support it and the Logger.
+func (p *Pipeline) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.start(); err != nil {
+		return err
+	}
+	return p.flush()
+}
+
+// Close flushes the pipeline, finishes the gzip streams and closes the
+// Logger. Tee writers are left open.
+func (p *Pipeline) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	if p.Logger == nil {
+		return errNoLogger
+	}
+	p.closed = true
+	if !p.started {
+		return p.Logger.Close()
+	}
+
+	err := p.flushBuffer()
+	for _, zw := range p.gzips {
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := p.Logger.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// start checks the configuration and sets up the gzip streams on first use.
+// It expects p.mu to be held.
+func (p *Pipeline) start() error {
+	if p.closed {
+		return ErrPipelineClosed
+	}
+	if p.Logger == nil {
+		return errNoLogger
+	}
+	if !p.started {
+		for _, w := range p.GzipTee {
+			p.gzips = append(p.gzips, gzip.NewWriter(w))
+		}
+		p.started = true
+	}
+	return nil
+}
+
+// flush passes buffered records on and flushes the downstream writers.
+// It expects p.mu to be held.
+func (p *Pipeline) flush() error {
+	err := p.flushBuffer()
+	for _, zw := range p.gzips {
+		if flushErr := zw.Flush(); err == nil {
+			err = flushErr
+		}
+	}
+	for _, w := range p.Tee {
+		if f, ok := w.(interface{ Flush() error }); ok {
+			if flushErr := f.Flush(); err == nil {
+				err = flushErr
+			}
+		}
+	}
+	if flushErr := p.Logger.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// flushBuffer passes buffered records on. The batch is written in one piece
+// unless it is too large for the Logger, in which case it is written record
+// by record. It expects p.mu to be held.
+func (p *Pipeline) flushBuffer() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+	defer func() {
+		p.buf = p.buf[:0]
+		p.bounds = p.bounds[:0]
+	}()
+
+	if int64(len(p.buf)) <= p.Logger.max() {
+		return p.emit(p.buf)
+	}
+	start := 0
+	var err error
+	for _, end := range p.bounds {
+		if emitErr := p.emit(p.buf[start:end]); err == nil {
+			err = emitErr
+		}
+		start = end
+	}
+	return err
+}
+
+// emit writes b to the Logger and the tee writers and returns the first
+// error. It expects p.mu to be held.
+func (p *Pipeline) emit(b []byte) error {
+	_, err := p.Logger.Write(b)
+	for _, w := range p.Tee {
+		if _, teeErr := w.Write(b); err == nil {
+			err = teeErr
+		}
+	}
+	for _, zw := range p.gzips {
+		if _, teeErr := zw.Write(b); err == nil {
+			err = teeErr
+		}
+	}
+	return err
+}
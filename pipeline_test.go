@@ -0,0 +1,88 @@
+package timberjack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestPipeline", t)
+	defer os.RemoveAll(dir)
+
+	var tee, zipped bytes.Buffer
+	p := &Pipeline{
+		Logger: &Logger{Filename: logFile(dir), MaxSize: 100},
+		Interceptors: []func([]byte) []byte{
+			func(b []byte) []byte { return bytes.ReplaceAll(b, []byte("secret"), []byte("******")) },
+			func(b []byte) []byte {
+				if bytes.HasPrefix(b, []byte("debug")) {
+					return nil
+				}
+				return b
+			},
+		},
+		BufferSize: 32,
+		Tee:        []io.Writer{&tee},
+		GzipTee:    []io.Writer{&zipped},
+	}
+
+	for _, record := range []string{"foo secret\n", "debug noise\n", "bar\n"} {
+		n, err := p.Write([]byte(record))
+		isNil(err, t)
+		equals(len(record), n, t)
+	}
+	// Still buffered.
+	equals(0, tee.Len(), t)
+	notExist(logFile(dir), t)
+
+	isNil(p.Flush(), t)
+	want := []byte("foo ******\nbar\n")
+	existsWithContent(logFile(dir), want, t)
+	equals(string(want), tee.String(), t)
+
+	isNil(p.Close(), t)
+	zr, err := gzip.NewReader(&zipped)
+	isNil(err, t)
+	unzipped, err := ioutil.ReadAll(zr)
+	isNil(err, t)
+	equals(string(want), string(unzipped), t)
+
+	_, err = p.Write([]byte("late\n"))
+	equals(ErrPipelineClosed, err, t)
+	isNil(p.Close(), t)
+}
+
+func TestPipeline_BufferKeepsRecordsWhole(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestPipeline_BufferKeepsRecordsWhole", t)
+	defer os.RemoveAll(dir)
+
+	// The batch is larger than MaxSize, so it is written record by record
+	// and the rotation falls between records.
+	l := &Logger{Filename: logFile(dir), MaxSize: 10}
+	p := &Pipeline{Logger: l, BufferSize: 64}
+	_, err := p.Write([]byte("aaaaaa\n"))
+	isNil(err, t)
+	_, err = p.Write([]byte("bbbbbb\n"))
+	isNil(err, t)
+	isNil(p.Close(), t)
+
+	existsWithContent(logFile(dir), []byte("bbbbbb\n"), t)
+	fileCount(dir, 2, t)
+}
+
+func TestPipeline_NoLogger(t *testing.T) {
+	p := &Pipeline{}
+	_, err := p.Write([]byte("foo"))
+	notNil(err, t)
+	notNil(p.Close(), t)
+}
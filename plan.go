@@ -0,0 +1,90 @@
+package timberjack
+
+import "path/filepath"
+
+// ActionType identifies what a PlannedAction would do to a backup.
+type ActionType int
+
+const (
+	// ActionRemove deletes a backup.
+	ActionRemove ActionType = iota + 1
+
+	// ActionCompress compresses a backup.
+	ActionCompress
+
+	// ActionArchive bundles a backup into an archive (ArchiveAfter).
+	ActionArchive
+)
+
+// String returns a human readable name for the action type.
+func (t ActionType) String() string {
+	switch t {
+	case ActionRemove:
+		return "remove"
+	case ActionCompress:
+		return "compress"
+	case ActionArchive:
+		return "archive"
+	default:
+		return "unknown"
+	}
+}
+
+// PlannedAction describes something the next cleanup pass would do to a
+// backup.
+type PlannedAction struct {
+	Action ActionType
+
+	// Name is the name of the backup relative to the backup directory, as in
+	// BackupInfo.
+	Name string
+
+	// Size is the current size of the backup in bytes.
+	Size int64
+
+	// Rule is the option responsible for a removal: "MaxBackups", "MaxAge",
+	// "MaxTotalSize" or "MinDiskFree", or "Compress" for an uncompressed file
+	// whose compressed form already exists. It is empty for compressions.
+	Rule string
+
+	// Archive is the name of the archive an ActionArchive adds the backup to.
+	Archive string
+}
+
+// RetentionPlan reports which backups a cleanup pass would remove and which
+// it would compress under the current configuration, without touching them,
+// so that a retention policy can be audited before it destroys data.
+// Removals are listed oldest first, followed by expired archives,
+// archiving and compressions. A pass
+// limited by MaxRemovalsPerPass spreads the removals over several passes.
+func (l *Logger) RetentionPlan() ([]PlannedAction, error) {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	if !l.cleanupEnabled() {
+		return nil, nil
+	}
+
+	unlockNumbering := l.lockNumbering()
+	files, err := l.oldLogFiles()
+	unlockNumbering()
+	if err != nil {
+		return nil, err
+	}
+
+	mp := l.planMill(files)
+	var plan []PlannedAction
+	for _, f := range mp.remove {
+		plan = append(plan, PlannedAction{Action: ActionRemove, Name: f.Name(), Size: f.Size(), Rule: mp.rules[f.Name()]})
+	}
+	for _, info := range l.expiredArchives() {
+		plan = append(plan, PlannedAction{Action: ActionRemove, Name: info.Name(), Size: info.Size(), Rule: "MaxAge"})
+	}
+	for _, f := range mp.archive {
+		plan = append(plan, PlannedAction{Action: ActionArchive, Name: f.Name(), Size: f.Size(), Archive: filepath.Base(l.archivePath(f.timestamp))})
+	}
+	for _, f := range mp.compress {
+		plan = append(plan, PlannedAction{Action: ActionCompress, Name: f.Name(), Size: f.Size()})
+	}
+	return plan, nil
+}
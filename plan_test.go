@@ -0,0 +1,42 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRetentionPlan(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestRetentionPlan", t)
+	defer os.RemoveAll(dir)
+
+	var names []string
+	for _, content := range []string{"one!", "two!", "six!"} {
+		name := backupFileWithReason(dir, "size")
+		isNil(os.WriteFile(name, []byte(content), 0644), t)
+		names = append(names, filepath.Base(name))
+		newFakeTime()
+	}
+
+	l := &Logger{Filename: logFile(dir), MaxBackups: 1, Compress: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	plan, err := l.RetentionPlan()
+	isNil(err, t)
+	equals([]PlannedAction{
+		{Action: ActionRemove, Name: names[0], Size: 4, Rule: "MaxBackups"},
+		{Action: ActionRemove, Name: names[1], Size: 4, Rule: "MaxBackups"},
+		{Action: ActionCompress, Name: names[2], Size: 4},
+	}, plan, t)
+	equals("remove", plan[0].Action.String(), t)
+
+	// Nothing was touched.
+	fileCount(dir, 3, t)
+
+	plan, err = (&Logger{Filename: logFile(dir)}).RetentionPlan()
+	isNil(err, t)
+	equals(0, len(plan), t)
+}
@@ -0,0 +1,21 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+)
+
+// preallocate reserves MaxSize bytes for the new log file f if Preallocate is
+// set. Running out of space is returned, so that it fails the rotation rather
+// than a later write; other failures, e.g. from filesystems that don't
+// support preallocation, are ignored.
+func (l *Logger) preallocate(f *os.File) error {
+	if !l.Preallocate || l.MaxSize == Unlimited {
+		return nil
+	}
+	err := fallocate(f, l.max())
+	if err == nil || Classify(err) != ErrorDiskFull {
+		return nil
+	}
+	return fmt.Errorf("can't preallocate %d bytes for %s: %w", l.max(), f.Name(), err)
+}
@@ -0,0 +1,15 @@
+package timberjack
+
+import (
+	"os"
+	"syscall"
+)
+
+// fallocKeepSize is FALLOC_FL_KEEP_SIZE: the space is reserved without
+// changing the file's size.
+const fallocKeepSize = 0x1
+
+// fallocate reserves size bytes of disk space for f.
+var fallocate = func(f *os.File, size int64) error {
+	return syscall.Fallocate(int(f.Fd()), fallocKeepSize, 0, size)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+// Preallocation is only supported on Linux; elsewhere Preallocate is ignored.
+
+package timberjack
+
+import "os"
+
+// fallocate does nothing on this platform.
+var fallocate = func(_ *os.File, _ int64) error {
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package timberjack
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestPreallocate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	var sizes []int64
+	defer func(orig func(*os.File, int64) error) { fallocate = orig }(fallocate)
+	fallocate = func(_ *os.File, size int64) error {
+		sizes = append(sizes, size)
+		return nil
+	}
+
+	dir := makeTempDir("TestPreallocate", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, Preallocate: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	equals([]int64{100, 100}, sizes, t)
+
+	// Unsupported filesystems are ignored.
+	fallocate = func(_ *os.File, _ int64) error {
+		return os.NewSyscallError("fallocate", syscall.EOPNOTSUPP)
+	}
+	newFakeTime()
+	isNil(l.Rotate(), t)
+
+	// A full disk fails the rotation.
+	fallocate = func(_ *os.File, _ int64) error {
+		return os.NewSyscallError("fallocate", syscall.ENOSPC)
+	}
+	newFakeTime()
+	err = l.Rotate()
+	notNil(err, t)
+	assert(errors.Is(err, syscall.ENOSPC), t, "unexpected error %v", err)
+}
+
+func TestPreallocate_Unlimited(t *testing.T) {
+	currentTime = fakeTime
+
+	called := false
+	defer func(orig func(*os.File, int64) error) { fallocate = orig }(fallocate)
+	fallocate = func(_ *os.File, _ int64) error {
+		called = true
+		return nil
+	}
+
+	dir := makeTempDir("TestPreallocate_Unlimited", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: Unlimited, Preallocate: true}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	assert(!called, t, "preallocated a file of unlimited size")
+}
@@ -0,0 +1,37 @@
+package timberjack
+
+import "fmt"
+
+// Prune synchronously removes the backups that MaxBackups, MaxAge,
+// MaxTotalSize, MinDiskFree and KeepPatterns no longer retain, and expired
+// archives, without waiting for the next rotation, e.g. from an admin
+// endpoint or before taking a backup of the log volume. Unlike the cleanup
+// pass after a rotation, it neither compresses nor archives backups and
+// ignores MaxRemovalsPerPass. It returns the first removal that failed,
+// after attempting the others.
+func (l *Logger) Prune() error {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	if !l.cleanupEnabled() {
+		return nil
+	}
+	unlockNumbering := l.lockNumbering()
+	files, err := l.oldLogFiles()
+	if err != nil {
+		unlockNumbering()
+		return err
+	}
+	plan := l.planMill(files)
+	var firstErr error
+	l.removeBackups(plan, plan.remove, func(name string, err error) {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("failed to remove old log file %s: %w", name, err)
+		}
+	})
+	l.removalsPending = 0
+	unlockNumbering()
+
+	l.expireArchives()
+	return firstErr
+}
@@ -0,0 +1,42 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+)
+
+func TestPrune(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestPrune", t)
+	defer os.RemoveAll(dir)
+
+	// Backups left by an earlier run; nothing is rotated, so only Prune
+	// enforces MaxBackups.
+	var backups []string
+	for i := 0; i < 3; i++ {
+		name := backupFileWithReason(dir, "size")
+		isNil(os.WriteFile(name, []byte("old"), 0644), t)
+		backups = append(backups, name)
+		newFakeTime()
+	}
+
+	l := &Logger{
+		Filename:           logFile(dir),
+		BackupTimeFormat:   backupTimeFormat,
+		MaxBackups:         1,
+		MaxRemovalsPerPass: 1,
+	}
+	defer l.Close()
+
+	isNil(l.Prune(), t)
+	notExist(backups[0], t)
+	notExist(backups[1], t)
+	exists(backups[2], t)
+	equals(int64(2), l.Stats().Removals, t)
+
+	// Nothing left to do.
+	isNil(l.Prune(), t)
+	exists(backups[2], t)
+}
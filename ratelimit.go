@@ -0,0 +1,112 @@
+package timberjack
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy decides what Write does with records over the
+// MaxBytesPerSecond budget.
+type RateLimitPolicy string
+
+const (
+	// RateLimitBlock makes Write wait until the budget allows the record.
+	// It is the default.
+	RateLimitBlock RateLimitPolicy = "block"
+
+	// RateLimitDrop makes Write drop records over budget, reporting them as
+	// written.
+	RateLimitDrop RateLimitPolicy = "drop"
+
+	// RateLimitSample is like RateLimitDrop, but still writes every
+	// RateLimitSampleEvery-th record over budget, so a flood stays visible
+	// in the log.
+	RateLimitSample RateLimitPolicy = "sample"
+)
+
+// defaultRateLimitSampleEvery is used when RateLimitSampleEvery is not set.
+const defaultRateLimitSampleEvery = 100
+
+// ValidateRateLimitPolicy checks that RateLimitPolicy is empty,
+// RateLimitBlock, RateLimitDrop or RateLimitSample.
+func (l *Logger) ValidateRateLimitPolicy() error {
+	switch l.RateLimitPolicy {
+	case "", RateLimitBlock, RateLimitDrop, RateLimitSample:
+		return nil
+	}
+	return fmt.Errorf("invalid RateLimitPolicy %q: expected %q, %q or %q", l.RateLimitPolicy, RateLimitBlock, RateLimitDrop, RateLimitSample)
+}
+
+// tokenBucket holds the write budget of MaxBytesPerSecond: it fills at that
+// rate up to one second's worth of bytes, so short bursts pass unhindered.
+type tokenBucket struct {
+	mu      sync.Mutex
+	tokens  float64   // bytes that may be written now; negative while blocked writers are owed
+	last    time.Time // when tokens was last refilled
+	skipped int       // records over budget since the last sampled one (RateLimitSample)
+}
+
+// refill adds the tokens accrued since the last refill. It expects b.mu to be
+// held.
+func (b *tokenBucket) refill(now time.Time, rate int) {
+	if b.last.IsZero() {
+		b.tokens = float64(rate)
+	} else {
+		b.tokens += now.Sub(b.last).Seconds() * float64(rate)
+	}
+	if b.tokens > float64(rate) {
+		b.tokens = float64(rate)
+	}
+	b.last = now
+}
+
+// admitWrite applies MaxBytesPerSecond to a record of n bytes. It reports
+// whether the record is to be written, waiting for the budget to allow it
+// with RateLimitBlock.
+func (l *Logger) admitWrite(n int) (bool, error) {
+	rate := l.MaxBytesPerSecond
+	b := &l.writeBucket
+	b.mu.Lock()
+	b.refill(time.Now(), rate)
+
+	switch l.RateLimitPolicy {
+	case RateLimitDrop, RateLimitSample:
+		// A record larger than the bucket passes once the bucket is full.
+		if b.tokens >= float64(n) || b.tokens >= float64(rate) {
+			b.tokens -= float64(n)
+			b.mu.Unlock()
+			return true, nil
+		}
+		if l.RateLimitPolicy == RateLimitSample {
+			every := l.RateLimitSampleEvery
+			if every <= 0 {
+				every = defaultRateLimitSampleEvery
+			}
+			if b.skipped++; b.skipped >= every {
+				b.skipped = 0
+				b.mu.Unlock()
+				return true, nil
+			}
+		}
+		b.mu.Unlock()
+		l.drop()
+		return false, nil
+	}
+
+	// Reserve the tokens, going into debt, and wait until it is paid off.
+	b.tokens -= float64(n)
+	delay := time.Duration(-b.tokens / float64(rate) * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return true, nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-l.context().Done():
+		return false, l.context().Err()
+	}
+}
@@ -0,0 +1,74 @@
+package timberjack
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMaxBytesPerSecond_Block(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMaxBytesPerSecond_Block", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10000, MaxBytesPerSecond: 1000}
+	defer l.Close()
+	isNil(l.ValidateRateLimitPolicy(), t)
+
+	// A burst of one second's worth passes at once; more has to wait.
+	start := time.Now()
+	_, err := l.Write(bytes.Repeat([]byte("a"), 1000))
+	isNil(err, t)
+	assert(time.Since(start) < 100*time.Millisecond, t, "burst was delayed")
+	_, err = l.Write(bytes.Repeat([]byte("b"), 200))
+	isNil(err, t)
+	assert(time.Since(start) >= 150*time.Millisecond, t, "write over budget was not delayed")
+	equals(int64(0), l.Stats().Dropped, t)
+}
+
+func TestMaxBytesPerSecond_Drop(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMaxBytesPerSecond_Drop", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBytesPerSecond: 10, RateLimitPolicy: RateLimitDrop}
+	defer l.Close()
+
+	b := []byte("boo!")
+	for i := 0; i < 3; i++ {
+		n, err := l.Write(b)
+		isNil(err, t)
+		equals(len(b), n, t)
+	}
+	existsWithContent(logFile(dir), []byte("boo!boo!"), t)
+	equals(int64(1), l.Stats().Dropped, t)
+}
+
+func TestMaxBytesPerSecond_Sample(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMaxBytesPerSecond_Sample", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBytesPerSecond: 4, RateLimitPolicy: RateLimitSample, RateLimitSampleEvery: 3}
+	defer l.Close()
+
+	for _, s := range []string{"a\n", "b\n", "c\n", "d\n", "e\n", "f\n", "g\n", "h\n"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+	}
+	// Two fit the budget, then every third record over it is written.
+	existsWithContent(logFile(dir), []byte("a\nb\ne\nh\n"), t)
+	equals(int64(4), l.Stats().Dropped, t)
+}
+
+func TestValidateRateLimitPolicy(t *testing.T) {
+	l := &Logger{RateLimitPolicy: "sometimes"}
+	notNil(l.ValidateRateLimitPolicy(), t)
+}
@@ -0,0 +1,171 @@
+package timberjack
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tailChunkSize is how much of a file Tail reads at a time, backwards from
+// its end.
+const tailChunkSize = 32 << 10
+
+// OpenSegment opens the managed log file name for reading: the active file,
+// by its base name, or a backup, by its name as reported by Backups. Gzip
+// backups are decompressed as they are read, and may also be named without
+// their compression suffix, as in FS.
+func (l *Logger) OpenSegment(name string) (io.ReadCloser, error) {
+	name = filepath.ToSlash(name)
+	fsys := logFS{l}
+	f, err := fsys.Open(name)
+	if err != nil && strings.HasSuffix(name, compressSuffix) {
+		f, err = fsys.Open(strings.TrimSuffix(name, compressSuffix))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return f, nil
+}
+
+// Tail returns the last n lines written to the log, oldest first. Lines are
+// taken from the active file and, if it holds fewer than n, from the newest
+// backups, decompressing them as needed. Records still held in memory by
+// BufferSize, CoalesceWindow or AsyncQueueSize are only included once
+// flushed. It returns nil if n <= 0 or nothing has been written.
+func (l *Logger) Tail(n int) ([]byte, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	files, err := logFS{l}.entries()
+	if err != nil {
+		return nil, err
+	}
+	var sources []logFSEntry
+	if active, ok := files[filepath.Base(l.CurrentFile())]; ok {
+		sources = append(sources, active)
+	}
+	if _, err := os.Stat(l.backupDir()); err == nil {
+		backups, err := l.Backups() // newest first
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range backups {
+			e, ok := files[l.trimCompressed(filepath.ToSlash(b.Name))]
+			if !ok || e.path != filepath.Join(l.backupDir(), b.Name) {
+				continue // the other file of a pair
+			}
+			sources = append(sources, e)
+		}
+	}
+
+	var lines [][]byte // newest first
+	for _, src := range sources {
+		got, err := tailFile(src, n-len(lines))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue // removed meanwhile
+			}
+			return nil, err
+		}
+		for i := len(got) - 1; i >= 0; i-- {
+			lines = append(lines, got[i])
+		}
+		if len(lines) == n {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	var out []byte
+	for i := len(lines) - 1; i >= 0; i-- {
+		out = append(out, lines[i]...)
+	}
+	return out, nil
+}
+
+// tailFile returns the last n lines of the file e, oldest first. Each line
+// keeps its newline; the file's last line may lack one.
+func tailFile(e logFSEntry, n int) ([][]byte, error) {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if e.gunzip {
+		g := &gunzipFile{file: f, name: filepath.Base(e.path), size: -1}
+		if err := g.reset(); err != nil {
+			return nil, err
+		}
+		defer g.zr.Close()
+		return tailStream(g, n)
+	}
+	return tailBackwards(f, n)
+}
+
+// tailStream returns the last n lines read from r, oldest first, for
+// content that can only be read from the start.
+func tailStream(r io.Reader, n int) ([][]byte, error) {
+	ring := newRecordRing(n)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			ring.add(line)
+		}
+		if err == io.EOF {
+			return ring.last(n), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tailBackwards returns the last n lines of f, oldest first, reading it
+// backwards from its end so that only the tail is read.
+func tailBackwards(f *os.File, n int) ([][]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	end := info.Size()
+	var buf []byte
+	for off := end; off > 0; {
+		size := int64(tailChunkSize)
+		if size > off {
+			size = off
+		}
+		off -= size
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, off); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+		// n lines are complete once n newlines precede the last line's end.
+		if bytes.Count(bytes.TrimSuffix(buf, []byte("\n")), []byte("\n")) >= n {
+			break
+		}
+	}
+	var lines [][]byte
+	for len(buf) > 0 {
+		i := bytes.LastIndexByte(bytes.TrimSuffix(buf, []byte("\n")), '\n')
+		lines = append(lines, buf[i+1:])
+		buf = buf[:i+1]
+		if len(lines) == n {
+			break
+		}
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return lines, nil
+}
@@ -0,0 +1,110 @@
+package timberjack
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenSegment(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestOpenSegment", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("old\n"))
+	isNil(err, t)
+	isNil(l.RotateWithReason("manual"), t)
+	isNil(l.CompressPending(), t)
+	_, err = l.Write([]byte("new\n"))
+	isNil(err, t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	assert(backups[0].Compressed, t, "expected a compressed backup")
+
+	for name, want := range map[string]string{
+		"foobar.log":    "new\n",
+		backups[0].Name: "old\n",
+		strings.TrimSuffix(backups[0].Name, compressSuffix): "old\n",
+	} {
+		r, err := l.OpenSegment(name)
+		isNil(err, t)
+		b, err := io.ReadAll(r)
+		isNil(err, t)
+		isNil(r.Close(), t)
+		equals(want, string(b), t)
+	}
+
+	_, err = l.OpenSegment("nope.log")
+	assert(os.IsNotExist(err), t, "expected a not-exist error, got %v", err)
+}
+
+func TestTail(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestTail", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+
+	b, err := l.Tail(3)
+	isNil(err, t)
+	equals(0, len(b), t)
+
+	_, err = l.Write([]byte("one\ntwo\n"))
+	isNil(err, t)
+	isNil(l.RotateWithReason("manual"), t)
+	isNil(l.CompressPending(), t)
+	newFakeTime()
+	_, err = l.Write([]byte("three\nfour\n"))
+	isNil(err, t)
+	isNil(l.RotateWithReason("manual"), t)
+	_, err = l.Write([]byte("five\nsix"))
+	isNil(err, t)
+
+	for n, want := range map[int]string{
+		1:  "six",
+		2:  "five\nsix",
+		3:  "four\nfive\nsix",
+		5:  "two\nthree\nfour\nfive\nsix",
+		10: "one\ntwo\nthree\nfour\nfive\nsix",
+	} {
+		b, err := l.Tail(n)
+		isNil(err, t)
+		equals(want, string(b), t)
+	}
+}
+
+func TestTailBackwards_LongFile(t *testing.T) {
+	dir := makeTempDir("TestTailBackwards_LongFile", t)
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "long.log")
+	line := strings.Repeat("x", 1000) + "\n"
+	isNil(os.WriteFile(name, []byte(strings.Repeat(line, 100)+"last\n"), 0644), t)
+	f, err := os.Open(name)
+	isNil(err, t)
+	defer f.Close()
+
+	lines, err := tailBackwards(f, 40)
+	isNil(err, t)
+	equals(40, len(lines), t)
+	equals(line, string(lines[0]), t)
+	equals("last\n", string(lines[39]), t)
+}
@@ -0,0 +1,51 @@
+package timberjack
+
+import (
+	"bytes"
+	"io"
+)
+
+// readFromBufferSize is the most ReadFrom passes to Write at once.
+const readFromBufferSize = 256 << 10
+
+// ReadFrom implements io.ReaderFrom, so that io.Copy from a socket or pipe
+// streams into the log in large chunks instead of many small writes. Each
+// chunk goes through Write, ending after the last newline read so far where
+// possible, so rotations don't split lines; a partial line is held back
+// until its newline or EOF arrives, unless it fills the buffer.
+func (l *Logger) ReadFrom(r io.Reader) (n int64, err error) {
+	size := int64(readFromBufferSize)
+	if max := l.max(); max < size {
+		size = max
+	}
+	buf := make([]byte, size)
+	pending := 0
+	for {
+		m, errRead := r.Read(buf[pending:])
+		pending += m
+
+		end := pending
+		if errRead == nil {
+			if i := bytes.LastIndexByte(buf[:pending], '\n'); i >= 0 {
+				end = i + 1
+			} else if pending < len(buf) {
+				end = 0
+			}
+		}
+		if end > 0 {
+			written, errWrite := l.Write(buf[:end])
+			n += int64(written)
+			if errWrite != nil {
+				return n, errWrite
+			}
+			pending = copy(buf, buf[end:pending])
+		}
+
+		if errRead == io.EOF {
+			return n, nil
+		}
+		if errRead != nil {
+			return n, errRead
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package timberjack
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadFrom(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestReadFrom", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	// Hide strings.Reader's WriteTo, which io.Copy would prefer.
+	src := struct{ io.Reader }{strings.NewReader("foo\nbar\nbaz\npartial")}
+	n, err := io.Copy(l, src)
+	isNil(err, t)
+	equals(int64(19), n, t)
+
+	// Rotations happened between lines, and the tail was written at EOF.
+	backup := backupFileWithReason(dir, "size")
+	existsWithContent(backup, []byte("foo\nbar\n"), t)
+	existsWithContent(l.withSequence(backup, 1), []byte("baz\n"), t)
+	existsWithContent(logFile(dir), []byte("partial"), t)
+}
+
+func TestReadFrom_LongLine(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestReadFrom_LongLine", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 5, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	// A line longer than MaxSize is written in pieces.
+	n, err := l.ReadFrom(strings.NewReader("abcdefgh"))
+	isNil(err, t)
+	equals(int64(8), n, t)
+	existsWithContent(backupFileWithReason(dir, "size"), []byte("abcde"), t)
+	existsWithContent(logFile(dir), []byte("fgh"), t)
+}
@@ -0,0 +1,105 @@
+package timberjack
+
+import (
+	"sync"
+	"time"
+)
+
+// Config holds the rotation and retention settings that can be changed on a
+// live Logger with Reconfigure. The fields have the same meaning as the
+// Logger fields of the same name.
+type Config struct {
+	MaxSize          int           `json:"maxsize" yaml:"maxsize" toml:"maxsize"`
+	MaxAge           int           `json:"maxage" yaml:"maxage" toml:"maxage"`
+	MaxBackups       int           `json:"maxbackups" yaml:"maxbackups" toml:"maxbackups"`
+	MaxTotalSize     int           `json:"maxtotalsize" yaml:"maxtotalsize" toml:"maxtotalsize"`
+	Compress         bool          `json:"compress" yaml:"compress" toml:"compress"`
+	RotationInterval time.Duration `json:"rotationinterval" yaml:"rotationinterval" toml:"rotationinterval"`
+	RotateAtMinutes  []int         `json:"rotateAtMinutes" yaml:"rotateAtMinutes" toml:"rotateAtMinutes"`
+}
+
+// Config returns the Logger's current rotation and retention settings,
+// for use as the starting point of a Reconfigure.
+func (l *Logger) Config() Config {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return Config{
+		MaxSize:          l.MaxSize,
+		MaxAge:           l.MaxAge,
+		MaxBackups:       l.MaxBackups,
+		MaxTotalSize:     l.MaxTotalSize,
+		Compress:         l.Compress,
+		RotationInterval: l.RotationInterval,
+		RotateAtMinutes:  append([]int(nil), l.RotateAtMinutes...),
+	}
+}
+
+// Reconfigure applies cfg to a live Logger, so that long-running services
+// can change their log policy without a restart. The new settings take
+// effect together: no write or rotation sees some of them but not others.
+// The RotateAtMinutes and RotationInterval timers are rescheduled, and a
+// cleanup pass enforces the new retention limits on existing backups.
+//
+// cfg is checked as by Validate first; if it is invalid, the Logger is left
+// unchanged and the *ValidationError is returned. Reconfigure returns
+// ErrClosed once Close has begun.
+func (l *Logger) Reconfigure(cfg Config) error {
+	probe := &Logger{
+		Filename:         l.Filename,
+		BackupDir:        l.BackupDir,
+		MaxSize:          cfg.MaxSize,
+		MaxAge:           cfg.MaxAge,
+		MaxBackups:       cfg.MaxBackups,
+		MaxTotalSize:     cfg.MaxTotalSize,
+		Compress:         cfg.Compress,
+		RotationInterval: cfg.RotationInterval,
+		RotateAtMinutes:  cfg.RotateAtMinutes,
+	}
+	if err := probe.Validate(); err != nil {
+		return err
+	}
+
+	l.reconfigureMu.Lock()
+	defer l.reconfigureMu.Unlock()
+
+	// Cleanup passes read the retention settings under millMu.
+	l.millMu.Lock()
+	l.mu.Lock()
+	if l.isClosed() {
+		l.mu.Unlock()
+		l.millMu.Unlock()
+		return ErrClosed
+	}
+	l.MaxSize = cfg.MaxSize
+	l.MaxAge = cfg.MaxAge
+	l.MaxBackups = cfg.MaxBackups
+	l.MaxTotalSize = cfg.MaxTotalSize
+	l.Compress = cfg.Compress
+	l.RotationInterval = cfg.RotationInterval
+	l.RotateAtMinutes = append([]int(nil), cfg.RotateAtMinutes...)
+
+	l.stopIntervalLoop()
+	l.startIntervalOnce = sync.Once{}
+	if l.scheduledRotationQuitCh != nil {
+		close(l.scheduledRotationQuitCh)
+	}
+	l.mu.Unlock()
+	l.millMu.Unlock()
+
+	// The scheduled rotation goroutine may be waiting for l.mu, and reads
+	// processedRotateAtMinutes without it.
+	l.scheduledRotationWg.Wait()
+
+	l.mu.Lock()
+	l.scheduledRotationQuitCh = nil
+	l.startScheduledRotationOnce = sync.Once{}
+	l.processedRotateAtMinutes = nil
+	if !l.isClosed() && l.file != nil {
+		l.ensureScheduledRotationLoopRunning()
+		l.ensureIntervalLoopRunning()
+	}
+	l.mu.Unlock()
+
+	l.mill()
+	return nil
+}
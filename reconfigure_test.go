@@ -0,0 +1,85 @@
+package timberjack
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestReconfigure(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestReconfigure", t)
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 3; i++ {
+		isNil(os.WriteFile(backupFileWithReason(dir, "size"), []byte("old"), 0644), t)
+		newFakeTime()
+	}
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	isNil(l.Reconfigure(Config{MaxSize: 5, MaxBackups: 1}), t)
+	equals(Config{MaxSize: 5, MaxBackups: 1}, l.Config(), t)
+
+	// The cleanup pass removes the backups beyond the new MaxBackups.
+	deadline := time.Now().Add(time.Second)
+	for countFiles(dir) > 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	fileCount(dir, 2, t)
+
+	// The new MaxSize applies to the next write.
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+}
+
+func countFiles(dir string) int {
+	files, _ := os.ReadDir(dir)
+	return len(files)
+}
+
+func TestReconfigure_Invalid(t *testing.T) {
+	dir := makeTempDir("TestReconfigure_Invalid", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100}
+	defer l.Close()
+
+	err := l.Reconfigure(Config{MaxSize: 100, RotateAtMinutes: []int{61}})
+	var verr *ValidationError
+	assert(errors.As(err, &verr), t, "expected a *ValidationError, got %v", err)
+	equals(0, len(l.RotateAtMinutes), t)
+}
+
+func TestReconfigure_Reschedules(t *testing.T) {
+	dir := makeTempDir("TestReconfigure_Reschedules", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, RotateAtMinutes: []int{0, 30}}
+	defer l.Close()
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	equals([]int{0, 30}, l.processedRotateAtMinutes, t)
+
+	isNil(l.Reconfigure(Config{MaxSize: 100, RotateAtMinutes: []int{45, 15}}), t)
+	l.mu.RLock()
+	minutes := l.processedRotateAtMinutes
+	l.mu.RUnlock()
+	equals([]int{15, 45}, minutes, t)
+}
+
+func TestReconfigure_Closed(t *testing.T) {
+	dir := makeTempDir("TestReconfigure_Closed", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100}
+	isNil(l.Close(), t)
+	equals(ErrClosed, l.Reconfigure(Config{MaxSize: 10}), t)
+}
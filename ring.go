@@ -0,0 +1,70 @@
+package timberjack
+
+// recordRing is a fixed-capacity ring of the most recently written records.
+// It is not safe for concurrent use; the Logger guards it with l.mu.
+type recordRing struct {
+	records [][]byte
+	next    int  // index the next record is stored at
+	full    bool // whether the ring has wrapped at least once
+}
+
+// newRecordRing returns a ring holding up to size records.
+func newRecordRing(size int) *recordRing {
+	return &recordRing{records: make([][]byte, size)}
+}
+
+// add stores a copy of p, evicting the oldest record when the ring is full.
+// The evicted record's memory is reused, so that once the ring is warm,
+// records no larger than earlier ones are stored without allocating.
+func (r *recordRing) add(p []byte) {
+	r.records[r.next] = append(r.records[r.next][:0], p...)
+	r.next++
+	if r.next == len(r.records) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// last returns copies of the n most recent records, oldest first.
+func (r *recordRing) last(n int) [][]byte {
+	count := r.next
+	if r.full {
+		count = len(r.records)
+	}
+	if n > count {
+		n = count
+	}
+	out := make([][]byte, 0, n)
+	for i := n; i > 0; i-- {
+		idx := (r.next - i + len(r.records)) % len(r.records)
+		out = append(out, append([]byte(nil), r.records[idx]...))
+	}
+	return out
+}
+
+// LastN returns the n most recently written records, oldest first, from the
+// in-memory buffer enabled by TailBufferSize. Each record is the payload of a
+// single Write call. It returns nil if the buffer is disabled or n <= 0.
+//
+// LastN is intended for integration tests and liveness checks that want to
+// assert on recent log output without opening and seeking log files.
+func (l *Logger) LastN(n int) [][]byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ring == nil || n <= 0 {
+		return nil
+	}
+	return l.ring.last(n)
+}
+
+// recordTail stores a written record in the tail buffer, if enabled.
+// It expects l.mu to be held.
+func (l *Logger) recordTail(p []byte) {
+	if l.TailBufferSize <= 0 || len(p) == 0 {
+		return
+	}
+	if l.ring == nil {
+		l.ring = newRecordRing(l.TailBufferSize)
+	}
+	l.ring.add(p)
+}
@@ -0,0 +1,52 @@
+package timberjack
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLastN(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestLastN", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:       logFile(dir),
+		MaxSize:        100,
+		TailBufferSize: 3,
+	}
+	defer l.Close()
+
+	equals(0, len(l.LastN(5)), t)
+
+	for _, s := range []string{"one\n", "two\n", "three\n", "four\n"} {
+		_, err := l.Write([]byte(s))
+		isNil(err, t)
+	}
+
+	equals([][]byte{[]byte("three\n"), []byte("four\n")}, l.LastN(2), t)
+	// Asking for more than is buffered returns everything retained.
+	equals([][]byte{[]byte("two\n"), []byte("three\n"), []byte("four\n")}, l.LastN(10), t)
+	equals(0, len(l.LastN(0)), t)
+
+	// Returned records are copies.
+	got := l.LastN(1)
+	got[0][0] = 'X'
+	equals([][]byte{[]byte("four\n")}, l.LastN(1), t)
+}
+
+func TestLastN_Disabled(t *testing.T) {
+	currentTime = fakeTime
+
+	dir := makeTempDir("TestLastN_Disabled", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir)}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	equals(0, len(l.LastN(1)), t)
+}
@@ -0,0 +1,260 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// schedule produces the calendar times at which a rotation is due.
+type schedule interface {
+	// Next returns the first rotation time strictly after t, or the zero
+	// time if there is none.
+	Next(t time.Time) time.Time
+}
+
+// startOfHour returns the start of the wall-clock hour containing t. Unlike
+// time.Date, it is unambiguous in the hour repeated when daylight saving time
+// ends.
+func startOfHour(t time.Time) time.Time {
+	return t.Add(-(time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second +
+		time.Duration(t.Nanosecond())))
+}
+
+// minuteSchedule is due at the given minutes (sorted, 0-59) of every hour.
+type minuteSchedule []int
+
+// Next implements schedule.
+func (m minuteSchedule) Next(t time.Time) time.Time {
+	if len(m) == 0 {
+		return time.Time{}
+	}
+	hour := startOfHour(t)
+	for {
+		for _, minute := range m {
+			if candidate := hour.Add(time.Duration(minute) * time.Minute); candidate.After(t) {
+				return candidate
+			}
+		}
+		hour = hour.Add(time.Hour)
+	}
+}
+
+// timesOfDay is due at the given minutes after midnight (sorted) every day.
+type timesOfDay []int
+
+// parseTimesOfDay parses "HH:MM" values into a timesOfDay schedule.
+func parseTimesOfDay(values []string) (timesOfDay, error) {
+	var times timesOfDay
+	seen := make(map[int]bool)
+	for _, v := range values {
+		t, err := time.Parse("15:04", v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time of day %q: expected HH:MM", v)
+		}
+		m := t.Hour()*60 + t.Minute()
+		if !seen[m] {
+			times = append(times, m)
+			seen[m] = true
+		}
+	}
+	sort.Ints(times)
+	return times, nil
+}
+
+// Next implements schedule. Times that don't exist on a given day because of
+// a daylight saving transition are normalized by time.Date.
+func (d timesOfDay) Next(t time.Time) time.Time {
+	if len(d) == 0 {
+		return time.Time{}
+	}
+	for day := 0; day <= 2; day++ {
+		for _, m := range d {
+			candidate := time.Date(t.Year(), t.Month(), t.Day()+day, m/60, m%60, 0, 0, t.Location())
+			if candidate.After(t) {
+				return candidate
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// RotationPeriod is a calendar period for RotationPeriod rotation.
+type RotationPeriod string
+
+const (
+	// RotationDaily rotates at midnight.
+	RotationDaily RotationPeriod = "daily"
+
+	// RotationWeekly rotates at midnight between Sunday and Monday.
+	RotationWeekly RotationPeriod = "weekly"
+
+	// RotationMonthly rotates at midnight on the first of the month.
+	RotationMonthly RotationPeriod = "monthly"
+)
+
+// Next implements schedule.
+func (p RotationPeriod) Next(t time.Time) time.Time {
+	var next time.Time
+	switch p {
+	case RotationDaily:
+		next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+	case RotationWeekly:
+		days := (int(time.Monday) - int(t.Weekday()) + 7) % 7
+		next = time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, t.Location())
+		if !next.After(t) {
+			next = time.Date(t.Year(), t.Month(), t.Day()+days+7, 0, 0, 0, 0, t.Location())
+		}
+	case RotationMonthly:
+		next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+	}
+	return next
+}
+
+// ValidateRotationPeriod checks that RotationPeriod is empty or one of
+// RotationDaily, RotationWeekly and RotationMonthly.
+func (l *Logger) ValidateRotationPeriod() error {
+	switch l.RotationPeriod {
+	case "", RotationDaily, RotationWeekly, RotationMonthly:
+		return nil
+	}
+	return fmt.Errorf("invalid RotationPeriod %q: expected %q, %q or %q", l.RotationPeriod, RotationDaily, RotationWeekly, RotationMonthly)
+}
+
+// ValidateRotateAtTimes checks that every RotateAtTimes entry is a valid
+// "HH:MM" time of day.
+func (l *Logger) ValidateRotateAtTimes() error {
+	_, err := parseTimesOfDay(l.RotateAtTimes)
+	return err
+}
+
+// ValidateRotationSchedule checks that RotationSchedule is a valid cron
+// expression. An empty RotationSchedule is valid and disables cron rotation.
+func (l *Logger) ValidateRotationSchedule() error {
+	if l.RotationSchedule == "" {
+		return nil
+	}
+	_, err := parseCron(l.RotationSchedule)
+	return err
+}
+
+// buildSchedules returns the calendar schedules configured on the Logger.
+// Invalid configurations are reported on stderr and ignored.
+func (l *Logger) buildSchedules() []schedule {
+	var schedules []schedule
+	if l.RotationSchedule != "" {
+		c, err := parseCron(l.RotationSchedule)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "timberjack: [%s] invalid RotationSchedule, cron rotation disabled: %v\n", l.Filename, err)
+		} else {
+			schedules = append(schedules, c)
+		}
+	}
+	if len(l.RotateAtTimes) > 0 {
+		times, err := parseTimesOfDay(l.RotateAtTimes)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "timberjack: [%s] invalid RotateAtTimes, time-of-day rotation disabled: %v\n", l.Filename, err)
+		} else {
+			schedules = append(schedules, times)
+		}
+	}
+	if l.RotationPeriod != "" {
+		if err := l.ValidateRotationPeriod(); err != nil {
+			fmt.Fprintf(os.Stderr, "timberjack: [%s] %v, period rotation disabled\n", l.Filename, err)
+		} else {
+			schedules = append(schedules, l.RotationPeriod)
+		}
+	}
+	return schedules
+}
+
+// nextCalendarRotation returns the earliest time strictly after t at which
+// one of the calendar schedules is due, or the zero time if none is.
+func (l *Logger) nextCalendarRotation(t time.Time) time.Time {
+	return nextRotation(l.schedules, t.In(l.location()))
+}
+
+// nextRotation returns the earliest time strictly after t at which one of
+// schedules is due, or the zero time if none is.
+func nextRotation(schedules []schedule, t time.Time) time.Time {
+	var next time.Time
+	for _, s := range schedules {
+		if n := s.Next(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// ensureCalendarLoopRunning builds the calendar schedules and starts the
+// goroutine rotating on them, once. It expects l.mu to be held.
+func (l *Logger) ensureCalendarLoopRunning() {
+	l.startCalendarOnce.Do(func() {
+		l.schedules = l.buildSchedules()
+		if len(l.schedules) == 0 {
+			return
+		}
+		l.calendarQuitCh = make(chan struct{})
+		go l.runCalendarRotations(l.calendarQuitCh)
+	})
+}
+
+// runCalendarRotations sleeps until the next calendar rotation is due and
+// performs it, until quit is closed or the Logger's Context ends.
+func (l *Logger) runCalendarRotations(quit chan struct{}) {
+	for {
+		now := l.now()
+		next := l.nextCalendarRotation(now)
+		if next.IsZero() {
+			return
+		}
+		timer := time.NewTimer(next.Sub(now))
+		select {
+		case <-timer.C:
+			l.handleCalendarMark(next, quit)
+		case <-quit:
+			stopTimer(timer)
+			return
+		case <-l.context().Done():
+			stopTimer(timer)
+			return
+		}
+	}
+}
+
+// handleCalendarMark rotates for a calendar mark that has been reached,
+// unless another rotation already happened since then or the Logger has been
+// closed in the meantime.
+func (l *Logger) handleCalendarMark(mark time.Time, quit chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-quit:
+		return
+	default:
+	}
+	if l.isClosed() || !l.lastRotationTime.Before(mark) {
+		return
+	}
+	if l.rotationPaused {
+		l.rotationMissed = true
+		return
+	}
+	if err := l.rotate("time"); err != nil {
+		l.backgroundError("rotate", err, "scheduled rotation failed")
+		return
+	}
+	l.lastRotationTime = l.now()
+}
+
+// stopCalendarLoop signals the calendar rotation goroutine to exit.
+// It expects l.mu to be held.
+func (l *Logger) stopCalendarLoop() {
+	if l.calendarQuitCh != nil {
+		close(l.calendarQuitCh)
+		l.calendarQuitCh = nil
+	}
+}
@@ -0,0 +1,220 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	_ "time/tzdata" // America/New_York for the DST tests
+)
+
+func TestRotationSchedule_RotatesOnWrite(t *testing.T) {
+	now := time.Date(2025, 5, 14, 23, 59, 0, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = fakeTime }()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "cron.log")
+	l := &Logger{
+		Filename:         filename,
+		RotationSchedule: "0 0 * * *",
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("before midnight\n"))
+	isNil(err, t)
+	fileCount(dir, 1, t)
+
+	now = now.Add(2 * time.Minute)
+	_, err = l.Write([]byte("after midnight\n"))
+	isNil(err, t)
+	fileCount(dir, 2, t)
+	existsWithContent(filename, []byte("after midnight\n"), t)
+
+	// No further rotation until the next midnight.
+	now = now.Add(time.Hour)
+	_, err = l.Write([]byte("later\n"))
+	isNil(err, t)
+	fileCount(dir, 2, t)
+}
+
+func TestRotationSchedule_Invalid(t *testing.T) {
+	l := &Logger{RotationSchedule: "not a cron"}
+	notNil(l.ValidateRotationSchedule(), t)
+	equals(0, len(l.buildSchedules()), t)
+
+	l = &Logger{}
+	isNil(l.ValidateRotationSchedule(), t)
+	equals(true, l.nextCalendarRotation(time.Now()).IsZero(), t)
+}
+
+func TestHandleCalendarMark(t *testing.T) {
+	mark := time.Date(2025, 5, 15, 0, 0, 0, 0, time.UTC)
+	currentTime = func() time.Time { return mark }
+	defer func() { currentTime = fakeTime }()
+
+	dir := t.TempDir()
+	l := &Logger{
+		Filename:         filepath.Join(dir, "mark.log"),
+		lastRotationTime: mark.Add(-time.Hour),
+	}
+	defer l.Close()
+	isNil(os.WriteFile(l.Filename, []byte("old"), 0644), t)
+
+	quit := make(chan struct{})
+	l.handleCalendarMark(mark, quit)
+	fileCount(dir, 2, t)
+	equals(mark, l.lastRotationTime, t)
+
+	// Already rotated for this mark.
+	l.handleCalendarMark(mark, quit)
+	fileCount(dir, 2, t)
+
+	// Closed loggers don't rotate.
+	close(quit)
+	l.handleCalendarMark(mark.Add(time.Hour), quit)
+	fileCount(dir, 2, t)
+}
+
+func TestRunCalendarRotations_Quit(t *testing.T) {
+	currentTime = time.Now
+	defer func() { currentTime = fakeTime }()
+
+	l := &Logger{
+		Filename:         filepath.Join(t.TempDir(), "quit.log"),
+		RotationSchedule: "@yearly",
+	}
+	l.mu.Lock()
+	l.ensureCalendarLoopRunning()
+	notNil(l.calendarQuitCh, t)
+	l.mu.Unlock()
+	isNil(l.Close(), t)
+	isNil(l.calendarQuitCh, t)
+}
+
+func TestRotateAtTimes_Next(t *testing.T) {
+	times, err := parseTimesOfDay([]string{"12:00", "06:30", "00:00", "06:30"})
+	isNil(err, t)
+	equals(timesOfDay{0, 390, 720}, times, t)
+
+	at := func(day, hour, min int) time.Time { return time.Date(2025, 3, day, hour, min, 0, 0, time.UTC) }
+	equals(at(10, 6, 30), times.Next(at(10, 0, 0)), t)
+	equals(at(10, 12, 0), times.Next(at(10, 6, 30)), t)
+	equals(at(11, 0, 0), times.Next(at(10, 12, 0)), t)
+	// Month rollover.
+	equals(time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), times.Next(at(31, 23, 59)), t)
+
+	_, err = parseTimesOfDay([]string{"25:00"})
+	notNil(err, t)
+	notNil((&Logger{RotateAtTimes: []string{"6.30"}}).ValidateRotateAtTimes(), t)
+	isNil((&Logger{RotateAtTimes: []string{"23:59"}}).ValidateRotateAtTimes(), t)
+}
+
+func TestRotateAtTimes_RotatesOnWrite(t *testing.T) {
+	now := time.Date(2025, 5, 14, 6, 0, 0, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = fakeTime }()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "times.log")
+	l := &Logger{
+		Filename:      filename,
+		RotateAtTimes: []string{"06:30", "18:00"},
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("early\n"))
+	isNil(err, t)
+
+	now = now.Add(20 * time.Minute)
+	_, err = l.Write([]byte("still early\n"))
+	isNil(err, t)
+	fileCount(dir, 1, t)
+
+	now = now.Add(20 * time.Minute)
+	_, err = l.Write([]byte("after 06:30\n"))
+	isNil(err, t)
+	fileCount(dir, 2, t)
+	existsWithContent(filename, []byte("after 06:30\n"), t)
+}
+
+func TestRotationPeriod_Next(t *testing.T) {
+	// Wednesday 2025-01-15 10:30.
+	now := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	equals(time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC), RotationDaily.Next(now), t)
+	equals(time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), RotationWeekly.Next(now), t)
+	equals(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), RotationMonthly.Next(now), t)
+
+	// Boundaries themselves roll over to the next period.
+	monday := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	equals(time.Date(2025, 1, 27, 0, 0, 0, 0, time.UTC), RotationWeekly.Next(monday), t)
+	equals(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), RotationMonthly.Next(time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)), t)
+
+	equals(true, RotationPeriod("").Next(now).IsZero(), t)
+}
+
+func TestRotationPeriod_Validate(t *testing.T) {
+	isNil((&Logger{}).ValidateRotationPeriod(), t)
+	isNil((&Logger{RotationPeriod: RotationMonthly}).ValidateRotationPeriod(), t)
+
+	l := &Logger{RotationPeriod: "fortnightly"}
+	notNil(l.ValidateRotationPeriod(), t)
+	equals(0, len(l.buildSchedules()), t)
+}
+
+func TestRotationPeriod_RotatesOnWrite(t *testing.T) {
+	now := time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = fakeTime }()
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "monthly.log")
+	l := &Logger{Filename: filename, RotationPeriod: RotationMonthly}
+	defer l.Close()
+
+	_, err := l.Write([]byte("january\n"))
+	isNil(err, t)
+
+	now = now.Add(2 * time.Hour)
+	_, err = l.Write([]byte("february\n"))
+	isNil(err, t)
+	fileCount(dir, 2, t)
+	existsWithContent(filename, []byte("february\n"), t)
+
+	now = now.Add(27 * 24 * time.Hour) // still February
+	_, err = l.Write([]byte("more\n"))
+	isNil(err, t)
+	fileCount(dir, 2, t)
+}
+
+func TestLocation_DST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	isNil(err, t)
+	l := &Logger{Location: ny, RotateAtMinutes: []int{30}}
+	l.processedRotateAtMinutes = validRotateAtMinutes(l.RotateAtMinutes)
+	equals(ny, l.location(), t)
+
+	// Clocks fall back from 02:00 EDT to 01:00 EST: 01:30 happens twice.
+	first := time.Date(2025, 11, 2, 5, 30, 0, 0, time.UTC) // 01:30 EDT
+	next, ok := l.nextScheduledMark(first.In(ny))
+	equals(true, ok, t)
+	equals(first.Add(time.Hour), next.UTC(), t) // 01:30 EST
+	equals(next.UTC(), minuteSchedule{30}.Next(first.In(ny)).UTC(), t)
+
+	// Clocks spring forward from 02:00 EST to 03:00 EDT: 02:30 doesn't exist.
+	before := time.Date(2025, 3, 9, 6, 30, 0, 0, time.UTC) // 01:30 EST
+	next, ok = l.nextScheduledMark(before.In(ny))
+	equals(true, ok, t)
+	equals(before.Add(time.Hour), next.UTC(), t) // 03:30 EDT
+	equals(3, next.Hour(), t)
+
+	// Wall-clock times and periods are computed in the chosen zone.
+	noon := time.Date(2025, 6, 1, 15, 0, 0, 0, time.UTC) // 11:00 EDT
+	equals(time.Date(2025, 6, 1, 16, 0, 0, 0, time.UTC), timesOfDay{12 * 60}.Next(noon.In(ny)).UTC(), t)
+	equals(time.Date(2025, 6, 2, 4, 0, 0, 0, time.UTC), RotationDaily.Next(noon.In(ny)).UTC(), t)
+}
+
+func TestLocation_Default(t *testing.T) {
+	equals(time.UTC, (&Logger{}).location(), t)
+	equals(time.Local, (&Logger{LocalTime: true}).location(), t)
+}
@@ -0,0 +1,282 @@
+package timberjack
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configDescriptions documents the configuration fields by their JSON name,
+// for JSONSchema and RegisterFlags.
+var configDescriptions = map[string]string{
+	"filename":               "File to write logs to. Defaults to <processname>-timberjack.log in the temp directory.",
+	"maxsize":                "Maximum size in megabytes before the file is rotated. 0 means 100, -1 means unlimited.",
+	"maxage":                 "Maximum number of days to retain backups. 0 keeps them regardless of age.",
+	"maxbackups":             "Maximum number of backups to retain. 0 keeps all of them.",
+	"maxtotalsize":           "Maximum combined size of all backups in megabytes. 0 is unlimited.",
+	"mindiskfree":            "Free space to preserve on the backup filesystem, e.g. 500MB or 10%.",
+	"localtime":              "Use local time instead of UTC in backup names.",
+	"compress":               "Compress backups with compressioncodec.",
+	"compressioncodec":       "Compression of backups: gzip (default), zstd or lz4.",
+	"rotationinterval":       "Maximum duration between rotations. 0 disables interval rotation.",
+	"backuptimeformat":       "Go time layout of the timestamp in backup names.",
+	"lumberjackcompat":       "Name backups like lumberjack (<name>-<timestamp>.log).",
+	"numberedbackups":        "Name backups <filename>.1, <filename>.2, ... like logrotate.",
+	"rotateAtMinutes":        "Minutes (0-59) of every hour at which to rotate.",
+	"rotationschedule":       "Cron expression of the times at which to rotate.",
+	"rotateAtTimes":          "Times of day (HH:MM) at which to rotate.",
+	"rotationperiod":         "Calendar period each file covers: daily, weekly or monthly.",
+	"missedtickpolicy":       "What to do about missed RotateAtMinutes marks: 0 rotates once, 1 skips them.",
+	"rotationtimeout":        "Maximum time a rotation may block writes. 0 disables the budget.",
+	"backupdir":              "Directory for backups, absolute or relative to the log directory.",
+	"backupdirlayout":        "Go time layout of backup subdirectories, e.g. 2006/01/02.",
+	"adoptexisting":          "Manage foreign backups matching adoptpatterns.",
+	"adoptpatterns":          "Glob patterns of foreign backups to adopt.",
+	"adoptcompressed":        "Suffixes of adopted backups already compressed in a foreign format, never compressed again.",
+	"keeppatterns":           "Glob patterns of backups never deleted by retention.",
+	"archiveafter":           "Bundle backups older than this many days into one tar.gz archive per archiveperiod. 0 disables it.",
+	"archiveperiod":          "Period covered by each archive: daily, weekly or monthly (default).",
+	"compressconcurrency":    "Maximum number of backups compressed in parallel. 0 compresses one at a time.",
+	"streamcompress":         "Compress the log file straight into its backup on rotation, never writing it uncompressed.",
+	"compressafter":          "Grace period before rotated backups are compressed.",
+	"checksums":              "Write a SHA-256 sidecar file next to every backup.",
+	"encrypt":                "Encrypt compressed backups with AES-256-GCM; the key is set in code.",
+	"compressionbytespersec": "Maximum rate at which backups are read for compression, in bytes per second. 0 is unlimited.",
+	"compressionbuffersize":  "Size in bytes of the buffers backups are read with for compression. 0 is 32 KiB.",
+	"uncompressedbackups":    "Number of the newest backups kept uncompressed.",
+	"compressminsize":        "Size in bytes below which backups are left uncompressed.",
+	"pairpolicy":             "How a backup present both compressed and uncompressed counts towards maxbackups: once or each.",
+	"idlefinalizeafter":      "Rotate a file that has received no writes for this long. 0 disables it.",
+	"buffersize":             "Size in bytes of the in-memory write buffer. 0 writes directly.",
+	"flushinterval":          "Interval at which buffered writes are flushed to the file. 0 flushes when the buffer fills.",
+	"preallocate":            "Reserve maxsize bytes of disk space for every new log file (Linux).",
+	"syncpolicy":             "When the log file is fsynced: never, rotate, bytes (every syncbytes), interval (every syncinterval) or write.",
+	"syncbytes":              "Bytes written between fsyncs with syncpolicy bytes.",
+	"syncinterval":           "Time between fsyncs with syncpolicy interval.",
+	"durable":                "Fsync the log directory after every rotation so it survives a power loss.",
+	"allowoversizewrites":    "Write records larger than maxsize into a file of their own instead of failing them.",
+	"chunkoversizewrites":    "Split records larger than maxsize across as many files as needed instead of failing them.",
+	"chunkboundary":          "Where chunkoversizewrites prefers to split records, e.g. a newline.",
+	"maxbytespersecond":      "Maximum rate at which records are written, in bytes per second. 0 is unlimited.",
+	"ratelimitpolicy":        "What Write does with records over maxbytespersecond: block, drop or sample.",
+	"ratelimitsampleevery":   "Records over budget the sample policy drops for each one it writes. 0 means 100.",
+	"coalescewindow":         "Window within which small writes are merged into a single write. 0 disables coalescing.",
+	"coalescemaxbytes":       "Size at which a coalesced batch is written before its window ends. 0 means 64 KiB.",
+	"diskfullpolicy":         "What Write does when the disk is full: fail, retry, drop or prune.",
+	"dropcaches":             "Evict backups from the page cache once rotated and compressed (64-bit Linux).",
+	"compressonclose":        "On close, rotate the log file and compress every backup left uncompressed.",
+	"integrityinterval":      "Interval of fsync integrity checkpoints. 0 disables them.",
+	"maxremovalsperpass":     "Maximum backups deleted per cleanup pass. 0 is unlimited.",
+	"removalpassinterval":    "Delay between cleanup passes while deletions are pending.",
+	"cleanupinterval":        "Interval of background retention and compression. 0 cleans up only after rotations.",
+	"enforceonopen":          "Run retention and pending compressions before the first write.",
+	"tailbuffersize":         "Number of recent records kept in memory for LastN.",
+	"writeshards":            "Number of staging buffers for concurrent writes. 0 writes directly.",
+	"asyncqueuesize":         "Number of records queued for a background writer. 0 writes synchronously.",
+	"queuefullpolicy":        "What Write does when the queue is full: block, error or drop.",
+}
+
+// configMinimums are the lowest valid values of numeric configuration fields.
+var configMinimums = map[string]int{
+	"maxsize":                Unlimited,
+	"maxage":                 0,
+	"maxbackups":             0,
+	"maxtotalsize":           0,
+	"archiveafter":           0,
+	"compressconcurrency":    0,
+	"uncompressedbackups":    0,
+	"compressminsize":        0,
+	"syncbytes":              0,
+	"maxbytespersecond":      0,
+	"ratelimitsampleevery":   0,
+	"coalescemaxbytes":       0,
+	"buffersize":             0,
+	"compressionbytespersec": 0,
+	"compressionbuffersize":  0,
+	"maxremovalsperpass":     0,
+	"tailbuffersize":         0,
+	"writeshards":            0,
+	"asyncqueuesize":         0,
+}
+
+// configField is a configuration field of Logger with its JSON name.
+type configField struct {
+	name  string
+	field reflect.StructField
+}
+
+// configFields returns the Logger fields that are part of its JSON
+// configuration, in declaration order.
+func configFields() []configField {
+	t := reflect.TypeOf(Logger{})
+	var fields []configField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, configField{name, f})
+	}
+	return fields
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// JSONSchema returns a JSON Schema (draft 2020-12) of the JSON form of a
+// Logger's configuration, as read by encoding/json, so that platforms
+// embedding timberjack can validate user-supplied configurations or build
+// forms for them. It is generated from the Logger type, so it always matches
+// the fields of the running version. Durations are integer nanoseconds and
+// sizes plain numbers, the canonical forms; Logger.UnmarshalJSON also
+// accepts strings such as "6h" and "250MB".
+func JSONSchema() []byte {
+	properties := make(map[string]interface{})
+	for _, cf := range configFields() {
+		prop := map[string]interface{}{}
+		switch ft := cf.field.Type; {
+		case ft == durationType:
+			prop["type"] = "integer"
+			prop["minimum"] = 0
+			prop["description"] = configDescriptions[cf.name] + " In nanoseconds."
+		case ft == reflect.TypeOf(RotationPeriod("")):
+			prop["type"] = "string"
+			prop["enum"] = []string{"", string(RotationDaily), string(RotationWeekly), string(RotationMonthly)}
+		case ft == reflect.TypeOf(PairPolicy("")):
+			prop["type"] = "string"
+			prop["enum"] = []string{"", string(PairCountOnce), string(PairCountEach)}
+		case ft == reflect.TypeOf(QueueFullPolicy("")):
+			prop["type"] = "string"
+			prop["enum"] = []string{"", string(QueueBlock), string(QueueError), string(QueueDrop)}
+		case ft == reflect.TypeOf(SyncPolicy("")):
+			prop["type"] = "string"
+			prop["enum"] = []string{"", string(SyncNever), string(SyncOnRotate), string(SyncEveryBytes), string(SyncEveryInterval), string(SyncEveryWrite)}
+		case ft == reflect.TypeOf(RateLimitPolicy("")):
+			prop["type"] = "string"
+			prop["enum"] = []string{"", string(RateLimitBlock), string(RateLimitDrop), string(RateLimitSample)}
+		case ft == reflect.TypeOf(DiskFullPolicy("")):
+			prop["type"] = "string"
+			prop["enum"] = []string{"", string(DiskFullFail), string(DiskFullRetry), string(DiskFullDrop), string(DiskFullPrune)}
+		case ft == reflect.TypeOf(MissedTickPolicy(0)):
+			prop["type"] = "integer"
+			prop["enum"] = []int{int(MissedTickRotateOnce), int(MissedTickSkip)}
+		case ft.Kind() == reflect.Slice:
+			prop["type"] = "array"
+			prop["items"] = map[string]string{"type": schemaType(ft.Elem().Kind())}
+		default:
+			prop["type"] = schemaType(ft.Kind())
+		}
+		if _, ok := prop["description"]; !ok {
+			prop["description"] = configDescriptions[cf.name]
+		}
+		if min, ok := configMinimums[cf.name]; ok {
+			prop["minimum"] = min
+		}
+		properties[cf.name] = prop
+	}
+
+	schema := map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "timberjack.Logger",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	b, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		panic(err) // the schema is built from plain maps and can't fail to encode
+	}
+	return b
+}
+
+// schemaType returns the JSON Schema type of a Go kind.
+func schemaType(k reflect.Kind) string {
+	switch k {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	default:
+		return "string"
+	}
+}
+
+// RegisterFlags defines a command-line flag for every configuration field
+// of l on fs, named prefix followed by the field's JSON name (e.g. with
+// prefix "log.", -log.maxsize=50). The flags set l's fields directly and
+// default to their current values, so set any defaults before calling it.
+// Durations use time.ParseDuration syntax and lists are comma-separated.
+//
+// For github.com/spf13/pflag, register the flags on a flag.FlagSet and add
+// it with pflag.FlagSet.AddGoFlagSet.
+func (l *Logger) RegisterFlags(fs *flag.FlagSet, prefix string) {
+	v := reflect.ValueOf(l).Elem()
+	for _, cf := range configFields() {
+		name := prefix + cf.name
+		usage := configDescriptions[cf.name]
+		fv := v.FieldByIndex(cf.field.Index)
+		switch {
+		case cf.field.Type == durationType:
+			p := fv.Addr().Interface().(*time.Duration)
+			fs.DurationVar(p, name, *p, usage)
+		case cf.field.Type.Kind() == reflect.Slice:
+			fs.Var(listFlag{fv}, name, usage)
+		case cf.field.Type.Kind() == reflect.Bool:
+			p := fv.Addr().Interface().(*bool)
+			fs.BoolVar(p, name, *p, usage)
+		case cf.field.Type.Kind() == reflect.Int:
+			p := fv.Addr().Convert(reflect.TypeOf((*int)(nil))).Interface().(*int)
+			fs.IntVar(p, name, *p, usage)
+		case cf.field.Type.Kind() == reflect.Int64:
+			p := fv.Addr().Convert(reflect.TypeOf((*int64)(nil))).Interface().(*int64)
+			fs.Int64Var(p, name, *p, usage)
+		case cf.field.Type.Kind() == reflect.String:
+			p := fv.Addr().Convert(reflect.TypeOf((*string)(nil))).Interface().(*string)
+			fs.StringVar(p, name, *p, usage)
+		}
+	}
+}
+
+// listFlag is a flag.Value for a []int or []string field, set from a
+// comma-separated list.
+type listFlag struct {
+	v reflect.Value
+}
+
+// String implements flag.Value.
+func (f listFlag) String() string {
+	if !f.v.IsValid() {
+		return ""
+	}
+	parts := make([]string, f.v.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(f.v.Index(i).Interface())
+	}
+	return strings.Join(parts, ",")
+}
+
+// Set implements flag.Value.
+func (f listFlag) Set(s string) error {
+	list := reflect.MakeSlice(f.v.Type(), 0, 0)
+	if s != "" {
+		for _, item := range strings.Split(s, ",") {
+			item = strings.TrimSpace(item)
+			switch f.v.Type().Elem().Kind() {
+			case reflect.Int:
+				n, err := strconv.Atoi(item)
+				if err != nil {
+					return fmt.Errorf("invalid list item %q: %v", item, err)
+				}
+				list = reflect.Append(list, reflect.ValueOf(n))
+			default:
+				list = reflect.Append(list, reflect.ValueOf(item))
+			}
+		}
+	}
+	f.v.Set(list)
+	return nil
+}
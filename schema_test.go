@@ -0,0 +1,73 @@
+package timberjack
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestJSONSchema(t *testing.T) {
+	var schema struct {
+		Type       string `json:"type"`
+		Properties map[string]struct {
+			Type        string        `json:"type"`
+			Description string        `json:"description"`
+			Enum        []interface{} `json:"enum"`
+			Items       struct {
+				Type string `json:"type"`
+			} `json:"items"`
+		} `json:"properties"`
+	}
+	isNil(json.Unmarshal(JSONSchema(), &schema), t)
+	equals("object", schema.Type, t)
+
+	// Every configuration field is described, so the map can't fall behind
+	// the Logger type.
+	for _, cf := range configFields() {
+		prop, ok := schema.Properties[cf.name]
+		assert(ok, t, "field %s missing from schema", cf.name)
+		assert(prop.Description != "", t, "field %s has no description", cf.name)
+	}
+	_, ok := schema.Properties["context"]
+	assert(!ok, t, "non-JSON field in schema")
+
+	equals("integer", schema.Properties["maxsize"].Type, t)
+	equals("boolean", schema.Properties["compress"].Type, t)
+	equals("integer", schema.Properties["rotationinterval"].Type, t)
+	equals("array", schema.Properties["rotateAtTimes"].Type, t)
+	equals("string", schema.Properties["rotateAtTimes"].Items.Type, t)
+	equals(4, len(schema.Properties["rotationperiod"].Enum), t)
+}
+
+func TestRegisterFlags(t *testing.T) {
+	l := &Logger{MaxSize: 50}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	l.RegisterFlags(fs, "log.")
+
+	equals("50", fs.Lookup("log.maxsize").DefValue, t)
+	isNil(fs.Parse([]string{
+		"-log.filename=/var/log/app.log",
+		"-log.maxsize=10",
+		"-log.compress",
+		"-log.rotationinterval=1h",
+		"-log.rotationperiod=daily",
+		"-log.rotateAtMinutes=0,30",
+		"-log.keeppatterns=*-deploy-*",
+		"-log.missedtickpolicy=1",
+	}), t)
+
+	equals("/var/log/app.log", l.Filename, t)
+	equals(10, l.MaxSize, t)
+	equals(true, l.Compress, t)
+	equals(time.Hour, l.RotationInterval, t)
+	equals(RotationDaily, l.RotationPeriod, t)
+	equals([]int{0, 30}, l.RotateAtMinutes, t)
+	equals([]string{"*-deploy-*"}, l.KeepPatterns, t)
+	equals(MissedTickSkip, l.MissedTickPolicy, t)
+
+	notNil(fs.Parse([]string{"-log.rotateAtMinutes=x"}), t)
+	assert(fs.Lookup("log.context") == nil, t, "non-JSON field registered as flag")
+}
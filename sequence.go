@@ -0,0 +1,61 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// uniqueBackupPath returns path, or, if a backup of that name already exists
+// (e.g. after two rotations within the same millisecond), path with the
+// lowest free sequence number inserted before the extension:
+// foo-<timestamp>-size.1.log, foo-<timestamp>-size.2.log, ...
+func (l *Logger) uniqueBackupPath(path string) string {
+	if l.NumberedBackups {
+		return path // shifted out of the way instead
+	}
+	candidate := path
+	for seq := 1; l.backupExists(candidate); seq++ {
+		candidate = l.withSequence(path, seq)
+	}
+	return candidate
+}
+
+// backupExists reports whether a file named path exists, next to the log
+// file or where the backup would be archived.
+func (l *Logger) backupExists(path string) bool {
+	for _, p := range []string{path, filepath.Join(l.archiveDir(), filepath.Base(path))} {
+		if _, err := os.Stat(p); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// withSequence inserts ".seq" before the log file's extension in path.
+func (l *Logger) withSequence(path string, seq int) string {
+	ext := filepath.Ext(l.filename())
+	if ext == "" || !strings.HasSuffix(path, ext) {
+		return path + "." + strconv.Itoa(seq)
+	}
+	return path[:len(path)-len(ext)] + "." + strconv.Itoa(seq) + ext
+}
+
+// splitSequence splits the sequence number added by uniqueBackupPath off the
+// end of s ("size.2" -> "size", 2). It returns s and 0 if there is none.
+func splitSequence(s string) (string, int) {
+	i := strings.LastIndex(s, ".")
+	if i < 0 {
+		return s, 0
+	}
+	digits := s[i+1:]
+	if digits == "" || digits[0] == '0' || strings.Trim(digits, "0123456789") != "" {
+		return s, 0
+	}
+	seq, err := strconv.Atoi(digits)
+	if err != nil {
+		return s, 0
+	}
+	return s[:i], seq
+}
@@ -0,0 +1,56 @@
+package timberjack
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupNameCollision(t *testing.T) {
+	currentTime = fakeTime // frozen: every rotation gets the same timestamp
+	megabyte = 1
+
+	dir := makeTempDir("TestBackupNameCollision", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBackups: 2, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	for _, content := range []string{"one!", "two!", "three!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		isNil(l.Rotate(), t)
+	}
+	<-time.After(10 * time.Millisecond)
+
+	base := backupFileWithReason(dir, "size")
+	ext := filepath.Ext(base)
+	notExist(base, t) // the oldest, removed by MaxBackups
+	existsWithContent(base[:len(base)-len(ext)]+".1"+ext, []byte("two!"), t)
+	existsWithContent(base[:len(base)-len(ext)]+".2"+ext, []byte("three!"), t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	equals("size", backups[0].Reason, t)
+	assert(backups[0].Timestamp.After(backups[1].Timestamp), t, "expected .2 to sort before .1")
+}
+
+func TestSplitSequence(t *testing.T) {
+	for in, want := range map[string]struct {
+		rest string
+		seq  int
+	}{
+		"size":    {"size", 0},
+		"size.1":  {"size", 1},
+		"time.12": {"time", 12},
+		"size.01": {"size.01", 0},
+		"size.+1": {"size.+1", 0},
+		"size.":   {"size.", 0},
+	} {
+		rest, seq := splitSequence(in)
+		equals(want.rest, rest, t)
+		equals(want.seq, seq, t)
+	}
+}
@@ -0,0 +1,53 @@
+package timberjack
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestShutdown", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          100,
+		Compress:         true,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	isNil(l.Shutdown(ctx), t)
+
+	// The backup is compressed by the time Shutdown returns.
+	fileCount(dir, 2, t)
+	compressed, err := filepath.Glob(filepath.Join(dir, "*"+compressSuffix))
+	isNil(err, t)
+	equals(1, len(compressed), t)
+
+	equals(ErrClosed, l.Rotate(), t)
+	isNil(l.Shutdown(ctx), t)
+}
+
+func TestShutdown_ContextDone(t *testing.T) {
+	dir := makeTempDir("TestShutdown_ContextDone", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir)}
+	defer l.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := l.Shutdown(ctx)
+	assert(err == nil || err == context.Canceled, t, "unexpected error %v", err)
+}
@@ -0,0 +1,95 @@
+package timberjack
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// HandleSignals rotates or reopens the log file whenever the process
+// receives one of sigs, SIGHUP if none are given, as daemons conventionally
+// do (there is no SIGHUP on js, where it does nothing without sigs). If the log file has been moved away, e.g. by an external logrotate
+// that sends the signal afterwards, the Logger reopens Filename, creating a
+// new file, instead of writing on to the moved one. Otherwise it rotates the
+// file with reason "signal". Failures are reported like those of other
+// background work, with op "rotate".
+//
+// Handling stops when stop is called, the Logger is closed or its Context
+// ends; the signals are then no longer caught, unless by other handlers.
+func (l *Logger) HandleSignals(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = defaultSignals
+	}
+	l.mu.Lock()
+	if l.isClosed() || len(sigs) == 0 {
+		l.mu.Unlock()
+		return func() {}
+	}
+	if l.signalQuitCh == nil {
+		l.signalQuitCh = make(chan struct{})
+	}
+	closed := l.signalQuitCh
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+	l.signalWg.Add(1)
+	l.mu.Unlock()
+
+	quit := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer l.signalWg.Done()
+		defer close(done)
+		defer signal.Stop(c)
+		for {
+			select {
+			case <-c:
+				if err := l.rotateOrReopen(); err != nil && err != ErrClosed {
+					l.backgroundError("rotate", err, "rotation on signal failed")
+				}
+			case <-quit:
+				return
+			case <-closed:
+				return
+			case <-l.context().Done():
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(quit)
+			<-done
+		})
+	}
+}
+
+// stopSignalHandlers signals the HandleSignals goroutines to exit.
+// It expects l.mu to be held.
+func (l *Logger) stopSignalHandlers() {
+	if l.signalQuitCh != nil {
+		close(l.signalQuitCh)
+		l.signalQuitCh = nil
+	}
+}
+
+// rotateOrReopen reopens the log file if it is no longer at Filename, and
+// rotates it otherwise.
+func (l *Logger) rotateOrReopen() error {
+	l.flushStaged()
+	l.flushQueue()
+
+	l.mu.Lock()
+	if l.file != nil {
+		info, errStat := osStat(l.filename())
+		open, errOpen := l.file.Stat()
+		if errStat != nil || errOpen != nil || !os.SameFile(info, open) {
+			l.flushCoalesced()
+			err := l.closeFile() // the next write opens Filename
+			l.mu.Unlock()
+			return l.classifyError(err)
+		}
+	}
+	l.mu.Unlock()
+	return l.RotateWithReason("signal")
+}
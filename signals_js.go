@@ -0,0 +1,10 @@
+//go:build js
+// +build js
+
+package timberjack
+
+import "os"
+
+// defaultSignals are the signals HandleSignals handles if given none: js
+// has no SIGHUP.
+var defaultSignals []os.Signal
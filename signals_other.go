@@ -0,0 +1,12 @@
+//go:build !js
+// +build !js
+
+package timberjack
+
+import (
+	"os"
+	"syscall"
+)
+
+// defaultSignals are the signals HandleSignals handles if given none.
+var defaultSignals = []os.Signal{syscall.SIGHUP}
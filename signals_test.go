@@ -0,0 +1,135 @@
+//go:build !windows && !plan9 && !js
+// +build !windows,!plan9,!js
+
+package timberjack
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond for up to five seconds.
+func waitFor(cond func() bool) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+func TestHandleSignals_Rotate(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestHandleSignals_Rotate", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          100,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+	stop := l.HandleSignals(syscall.SIGUSR1)
+	defer stop()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	isNil(syscall.Kill(os.Getpid(), syscall.SIGUSR1), t)
+
+	backup := backupFileWithReason(dir, "signal")
+	assert(waitFor(func() bool { _, err := os.Stat(backup); return err == nil }), t, "expected a rotation on the signal")
+	existsWithContent(backup, []byte("boo!"), t)
+}
+
+func TestHandleSignals_Reopen(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestHandleSignals_Reopen", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          100,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+	stop := l.HandleSignals(syscall.SIGUSR1)
+	defer stop()
+
+	_, err := l.Write([]byte("old\n"))
+	isNil(err, t)
+
+	// An external logrotate moves the file away, then signals.
+	moved := logFile(dir) + ".1"
+	isNil(os.Rename(logFile(dir), moved), t)
+	isNil(syscall.Kill(os.Getpid(), syscall.SIGUSR1), t)
+	assert(waitFor(func() bool {
+		l.mu.RLock()
+		defer l.mu.RUnlock()
+		return l.file == nil
+	}), t, "expected the log file to be closed on the signal")
+
+	_, err = l.Write([]byte("new\n"))
+	isNil(err, t)
+	existsWithContent(moved, []byte("old\n"), t)
+	existsWithContent(logFile(dir), []byte("new\n"), t)
+	fileCount(dir, 2, t)
+}
+
+func TestHandleSignals_StopsOnClose(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestHandleSignals_StopsOnClose", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          100,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	stop := l.HandleSignals(syscall.SIGUSR1)
+	defer stop()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	// Close waits for the handler to exit, which unregisters it.
+	closed := make(chan struct{})
+	go func() {
+		l.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close didn't stop the signal handler")
+	}
+	l.mu.RLock()
+	equals(true, l.signalQuitCh == nil, t)
+	l.mu.RUnlock()
+
+	// The signal now reaches other handlers only.
+	probe := make(chan os.Signal, 1)
+	signal.Notify(probe, syscall.SIGUSR1)
+	defer signal.Stop(probe)
+	isNil(syscall.Kill(os.Getpid(), syscall.SIGUSR1), t)
+	select {
+	case <-probe:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the signal to reach the probe")
+	}
+	fileCount(dir, 1, t)
+	existsWithContent(logFile(dir), []byte("boo!"), t)
+
+	// A handler can't be started on a closed Logger.
+	l.HandleSignals(syscall.SIGUSR1)()
+}
@@ -0,0 +1,228 @@
+package timberjack
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// defaultSimulationWriteSize is the size of a single write in a WriteProfile
+// that doesn't specify one.
+const defaultSimulationWriteSize = 1024
+
+// WriteProfile describes a synthetic write load for Simulate.
+type WriteProfile struct {
+	// BytesPerSecond is the steady rate at which log data is written.
+	BytesPerSecond int64
+
+	// BurstBytes is written on top of the steady rate every BurstInterval,
+	// e.g. to model a batch job that dumps its output once an hour.
+	BurstBytes    int64
+	BurstInterval time.Duration
+
+	// WriteSize is the size of each individual Write call. It defaults to
+	// 1 KiB and must not exceed MaxSize.
+	WriteSize int64
+
+	// Duration is the span of time to simulate.
+	Duration time.Duration
+
+	// Start is the simulated start time. It defaults to the current time.
+	Start time.Time
+
+	// CompressionRatio is the expected size of a compressed backup relative
+	// to the original, e.g. 0.1 for typical text logs. It is only used when
+	// Compress is set; zero means backups are assumed not to shrink.
+	CompressionRatio float64
+}
+
+// SimulationResult reports the outcome of Simulate.
+type SimulationResult struct {
+	// BytesWritten is the total amount of log data written.
+	BytesWritten int64
+
+	// Rotations is the number of rotations, broken down by reason.
+	Rotations     int
+	SizeRotations int
+	TimeRotations int
+
+	// Removed is the number of backups deleted by MaxBackups, MaxAge and
+	// MaxTotalSize.
+	Removed int
+
+	// Backups is the number of backups left at the end of the simulation.
+	Backups int
+
+	// DiskUsage is the size of the log file and its backups at the end of the
+	// simulation, and PeakDiskUsage the largest size seen along the way.
+	DiskUsage     int64
+	PeakDiskUsage int64
+}
+
+// simulatedBackup is a backup file that exists only in a simulation.
+type simulatedBackup struct {
+	time time.Time
+	size int64
+}
+
+// simulation holds the state of a running Simulate call.
+type simulation struct {
+	l         *Logger
+	profile   WriteProfile
+	schedules []schedule
+	size      int64
+	backups   []simulatedBackup // oldest first
+	backupSum int64             // total size of backups
+	result    SimulationResult
+}
+
+// Simulate replays the write load described by p against the Logger's
+// rotation and retention settings and reports how many rotations and
+// backups would result and how much disk space they would use. It doesn't
+// touch the disk or the Logger's state, so it can be used to tune a
+// configuration before rolling it out.
+//
+// Time is simulated in one-second steps, and writes and scheduled rotations
+// are assumed to happen at the start of each step.
+func (l *Logger) Simulate(p WriteProfile) (SimulationResult, error) {
+	if p.Duration <= 0 {
+		return SimulationResult{}, errors.New("simulation duration must be positive")
+	}
+	if p.BytesPerSecond < 0 || p.BurstBytes < 0 {
+		return SimulationResult{}, errors.New("simulated write volume must not be negative")
+	}
+	if p.BurstBytes > 0 && p.BurstInterval <= 0 {
+		return SimulationResult{}, errors.New("BurstInterval must be positive when BurstBytes is set")
+	}
+	if p.WriteSize == 0 {
+		p.WriteSize = defaultSimulationWriteSize
+	}
+	if p.WriteSize < 0 || p.WriteSize > l.max() {
+		return SimulationResult{}, fmt.Errorf("write size %d must be between 1 and the maximum file size %d", p.WriteSize, l.max())
+	}
+	if p.Start.IsZero() {
+		p.Start = l.now()
+	}
+	if err := l.ValidateRotationSchedule(); err != nil {
+		return SimulationResult{}, err
+	}
+	if err := l.ValidateRotateAtTimes(); err != nil {
+		return SimulationResult{}, err
+	}
+	if err := l.ValidateRotationPeriod(); err != nil {
+		return SimulationResult{}, err
+	}
+
+	s := &simulation{l: l, profile: p, schedules: l.buildSchedules()}
+	if minutes := validRotateAtMinutes(l.RotateAtMinutes); len(minutes) > 0 {
+		s.schedules = append(s.schedules, minuteSchedule(minutes))
+	}
+	s.run()
+	return s.result, nil
+}
+
+// run steps through the simulated time span.
+func (s *simulation) run() {
+	p := s.profile
+	start := p.Start.In(s.l.location())
+	lastRotation := start
+	nextMark := nextRotation(s.schedules, lastRotation)
+	nextBurst := start
+	for elapsed := time.Duration(0); elapsed < p.Duration; elapsed += time.Second {
+		now := start.Add(elapsed)
+
+		bytes := p.BytesPerSecond
+		if p.BurstBytes > 0 && !now.Before(nextBurst) {
+			bytes += p.BurstBytes
+			nextBurst = nextBurst.Add(p.BurstInterval)
+		}
+
+		// Interval rotation is checked by Write, scheduled rotations run in
+		// the background whether or not anything is written.
+		if (bytes > 0 && s.l.RotationInterval > 0 && now.Sub(lastRotation) >= s.l.RotationInterval) ||
+			(!nextMark.IsZero() && !nextMark.After(now)) {
+			s.rotate(now, "time")
+			lastRotation = now
+			nextMark = nextRotation(s.schedules, lastRotation)
+		}
+
+		s.write(now, bytes)
+	}
+}
+
+// write appends n bytes in WriteSize chunks, rotating whenever the next
+// chunk wouldn't fit into the current file.
+func (s *simulation) write(now time.Time, n int64) {
+	max := s.l.max()
+	chunk := s.profile.WriteSize
+	for n > 0 {
+		if chunk > n {
+			chunk = n
+		}
+		if s.size+chunk > max {
+			s.rotate(now, "size")
+		}
+		fit := (max - s.size) / chunk * chunk
+		if fit > n {
+			fit = n
+		}
+		s.size += fit
+		n -= fit
+		s.result.BytesWritten += fit
+		s.updateDiskUsage()
+	}
+}
+
+// rotate turns the current file into a backup and applies retention.
+func (s *simulation) rotate(now time.Time, reason string) {
+	s.result.Rotations++
+	if reason == "size" {
+		s.result.SizeRotations++
+	} else {
+		s.result.TimeRotations++
+	}
+
+	size := s.size
+	if s.l.Compress && s.profile.CompressionRatio > 0 {
+		size = int64(float64(size) * s.profile.CompressionRatio)
+	}
+	s.backups = append(s.backups, simulatedBackup{time: now, size: size})
+	s.backupSum += size
+	s.size = 0
+
+	if s.l.MaxAge > 0 {
+		cutoff := now.Add(-time.Duration(int64(24*time.Hour) * int64(s.l.MaxAge)))
+		for len(s.backups) > 0 && s.backups[0].time.Before(cutoff) {
+			s.remove()
+		}
+	}
+	if s.l.MaxBackups > 0 {
+		for len(s.backups) > s.l.MaxBackups {
+			s.remove()
+		}
+	}
+	if s.l.MaxTotalSize > 0 {
+		limit := int64(s.l.MaxTotalSize) * int64(megabyte)
+		for len(s.backups) > 0 && s.backupSum > limit {
+			s.remove()
+		}
+	}
+	s.result.Backups = len(s.backups)
+	s.updateDiskUsage()
+}
+
+// remove deletes the oldest simulated backup.
+func (s *simulation) remove() {
+	s.backupSum -= s.backups[0].size
+	s.backups = s.backups[1:]
+	s.result.Removed++
+}
+
+// updateDiskUsage recomputes the current and peak disk usage.
+func (s *simulation) updateDiskUsage() {
+	usage := s.size + s.backupSum
+	s.result.DiskUsage = usage
+	if usage > s.result.PeakDiskUsage {
+		s.result.PeakDiskUsage = usage
+	}
+}
@@ -0,0 +1,84 @@
+package timberjack
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSimulate_SizeRotation(t *testing.T) {
+	megabyte = 1
+	l := &Logger{MaxSize: 1000, MaxBackups: 3}
+	res, err := l.Simulate(WriteProfile{
+		BytesPerSecond: 100,
+		WriteSize:      10,
+		Duration:       95 * time.Second,
+	})
+	isNil(err, t)
+
+	equals(int64(9500), res.BytesWritten, t)
+	equals(9, res.SizeRotations, t)
+	equals(0, res.TimeRotations, t)
+	equals(9, res.Rotations, t)
+	equals(3, res.Backups, t)
+	equals(6, res.Removed, t)
+	equals(int64(3500), res.DiskUsage, t)
+	// Three full backups and a full log file.
+	equals(int64(4000), res.PeakDiskUsage, t)
+}
+
+func TestSimulate_TimeRotationAndRetention(t *testing.T) {
+	megabyte = 1
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := &Logger{MaxSize: 1000000, RotationSchedule: "@hourly", MaxAge: 1, Compress: true}
+	res, err := l.Simulate(WriteProfile{
+		BytesPerSecond:   10,
+		Duration:         72 * time.Hour,
+		Start:            start,
+		CompressionRatio: 0.5,
+	})
+	isNil(err, t)
+
+	equals(71, res.TimeRotations, t)
+	equals(0, res.SizeRotations, t)
+	// Backups older than a day are removed, one per rotation.
+	equals(25, res.Backups, t)
+	equals(46, res.Removed, t)
+	equals(int64(25*3600*10/2+3600*10), res.DiskUsage, t)
+}
+
+func TestSimulate_Bursts(t *testing.T) {
+	megabyte = 1
+	l := &Logger{MaxSize: 1024, RotateAtMinutes: []int{0}}
+	res, err := l.Simulate(WriteProfile{
+		BurstBytes:    512,
+		BurstInterval: 10 * time.Minute,
+		WriteSize:     64,
+		Duration:      time.Hour,
+		Start:         time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC),
+	})
+	isNil(err, t)
+
+	equals(int64(6*512), res.BytesWritten, t)
+	// Two bursts fill a file; the 01:00 mark rotates once more.
+	equals(2, res.SizeRotations, t)
+	equals(1, res.TimeRotations, t)
+	equals(res.BytesWritten, res.DiskUsage, t)
+}
+
+func TestSimulate_Invalid(t *testing.T) {
+	megabyte = 1
+	l := &Logger{MaxSize: 1000}
+
+	_, err := l.Simulate(WriteProfile{BytesPerSecond: 1})
+	notNil(err, t)
+
+	_, err = l.Simulate(WriteProfile{BurstBytes: 1, Duration: time.Second})
+	notNil(err, t)
+
+	_, err = l.Simulate(WriteProfile{WriteSize: 2000, Duration: time.Second})
+	notNil(err, t)
+
+	l.RotationSchedule = "bogus"
+	_, err = l.Simulate(WriteProfile{WriteSize: 10, Duration: time.Second})
+	notNil(err, t)
+}
@@ -0,0 +1,54 @@
+//go:build go1.21
+// +build go1.21
+
+package timberjack
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHandler is a log/slog Handler writing JSON records to a Logger, one
+// Write per record, so that rotation never splits a record between files.
+// Records at slog.LevelError and above are flushed to the file right away
+// when the Logger buffers writes (BufferSize, AsyncQueueSize, ...), so that
+// errors are on disk before a crash that may follow them.
+type SlogHandler struct {
+	slog.Handler
+	l *Logger
+}
+
+// NewSlogHandler returns a SlogHandler writing to l with opts, which may be
+// nil, as slog.NewJSONHandler does:
+//
+//	slog.SetDefault(slog.New(timberjack.NewSlogHandler(logger, nil)))
+func NewSlogHandler(l *Logger, opts *slog.HandlerOptions) *SlogHandler {
+	return &SlogHandler{Handler: slog.NewJSONHandler(l, opts), l: l}
+}
+
+// Handle implements slog.Handler.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	if err := h.Handler.Handle(ctx, r); err != nil {
+		return err
+	}
+	if r.Level >= slog.LevelError {
+		return h.l.Flush()
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SlogHandler{Handler: h.Handler.WithAttrs(attrs), l: h.l}
+}
+
+// WithGroup implements slog.Handler.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	return &SlogHandler{Handler: h.Handler.WithGroup(name), l: h.l}
+}
+
+// Sync writes every record handled so far to the log file and fsyncs it,
+// e.g. before the program exits.
+func (h *SlogHandler) Sync() error {
+	return h.l.Sync()
+}
@@ -0,0 +1,56 @@
+//go:build go1.21
+// +build go1.21
+
+package timberjack
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSlogHandler(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestSlogHandler", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          1 << 10,
+		BackupTimeFormat: backupTimeFormat,
+		BufferSize:       1 << 10,
+	}
+	defer l.Close()
+
+	h := NewSlogHandler(l, &slog.HandlerOptions{Level: slog.LevelInfo})
+	logger := slog.New(h).With("svc", "api")
+	logger.Debug("hidden")
+	logger.Info("started", "port", 8080)
+
+	// Buffered until an error comes along.
+	b, err := os.ReadFile(logFile(dir))
+	if err == nil {
+		equals(0, len(b), t)
+	}
+	logger.WithGroup("req").Error("failed", "id", 7)
+
+	b, err = os.ReadFile(logFile(dir))
+	isNil(err, t)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	equals(2, len(lines), t)
+
+	var rec map[string]interface{}
+	isNil(json.Unmarshal([]byte(lines[0]), &rec), t)
+	equals("started", rec["msg"], t)
+	equals("api", rec["svc"], t)
+	equals(float64(8080), rec["port"], t)
+	isNil(json.Unmarshal([]byte(lines[1]), &rec), t)
+	equals("ERROR", rec["level"], t)
+	equals(map[string]interface{}{"id": float64(7)}, rec["req"], t)
+
+	isNil(h.Sync(), t)
+}
@@ -0,0 +1,178 @@
+package timberjack
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maxStagedBatch is the most staged data written to the file in one call.
+const maxStagedBatch = 256 * 1024
+
+// stagedRecord is a record waiting in a staging shard. seq is its position
+// in the order Write was called.
+type stagedRecord struct {
+	seq uint64
+	p   []byte
+}
+
+// stagingShard is one staging buffer. The padding keeps shards on separate
+// cache lines so writers on different shards don't slow each other down.
+type stagingShard struct {
+	mu      sync.Mutex
+	records []stagedRecord
+	_       [40]byte
+}
+
+// stager holds the staging buffers used when WriteShards is set.
+type stager struct {
+	seq       uint64 // next sequence number to hand out (atomic; first for alignment)
+	appending int32  // 1 while a writer is draining the shards (atomic)
+
+	shards []stagingShard
+
+	flushMu sync.Mutex        // serializes draining
+	next    uint64            // next sequence number to write (flushMu)
+	pending map[uint64][]byte // records drained ahead of a gap (flushMu)
+}
+
+// stagingBuffers returns the Logger's staging buffers, creating them on
+// first use.
+func (l *Logger) stagingBuffers() *stager {
+	l.stagingOnce.Do(func() {
+		l.staging = &stager{
+			shards:  make([]stagingShard, l.WriteShards),
+			pending: make(map[uint64][]byte),
+		}
+	})
+	return l.staging
+}
+
+// stageWrite copies p into a staging shard and, unless another writer is
+// already doing so, drains the shards to the file.
+func (l *Logger) stageWrite(p []byte) (int, error) {
+	if err := l.checkWriteLen(int64(len(p))); err != nil {
+		return 0, err
+	}
+	st := l.stagingBuffers()
+
+	// Close can't begin while a record is being staged, so every record
+	// staged before it is flushed by it.
+	l.intake.RLock()
+	if l.isClosed() {
+		l.intake.RUnlock()
+		return 0, ErrClosed
+	}
+	seq := atomic.AddUint64(&st.seq, 1) - 1
+	shard := &st.shards[seq%uint64(len(st.shards))]
+	shard.mu.Lock()
+	shard.records = append(shard.records, stagedRecord{seq: seq, p: append([]byte(nil), p...)})
+	shard.mu.Unlock()
+	l.intake.RUnlock()
+
+	// A writer that loses the race leaves its record to the current
+	// appender, which checks the shards again after stepping down.
+	for atomic.CompareAndSwapInt32(&st.appending, 0, 1) {
+		l.drainStaged(st)
+		atomic.StoreInt32(&st.appending, 0)
+		if !st.hasStaged() {
+			break
+		}
+	}
+	return len(p), nil
+}
+
+// flushStaged writes out all staged records. It must not be called with
+// l.mu held.
+func (l *Logger) flushStaged() {
+	if l.WriteShards <= 0 {
+		return
+	}
+	l.drainStaged(l.stagingBuffers())
+}
+
+// drainStaged empties the shards and writes every record whose predecessors
+// have all been written. Records that arrived ahead of a record still being
+// staged are kept until it shows up.
+func (l *Logger) drainStaged(st *stager) {
+	st.flushMu.Lock()
+	defer st.flushMu.Unlock()
+
+	for i := range st.shards {
+		shard := &st.shards[i]
+		shard.mu.Lock()
+		records := shard.records
+		shard.records = nil
+		shard.mu.Unlock()
+		for _, r := range records {
+			st.pending[r.seq] = r.p
+		}
+	}
+
+	if _, ok := st.pending[st.next]; !ok {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Coalesce consecutive records into as few file writes as possible,
+	// without letting a batch cross a size rotation.
+	var batch []byte
+	var records [][]byte
+	for {
+		p, ok := st.pending[st.next]
+		if len(records) > 0 && (!ok || len(batch)+len(p) > maxStagedBatch || l.size+int64(len(batch)+len(p)) > l.max()) {
+			l.writeStaged(batch, records)
+			batch, records = batch[:0], records[:0]
+		}
+		if !ok {
+			return
+		}
+		delete(st.pending, st.next)
+		st.next++
+		batch = append(batch, p...)
+		records = append(records, p)
+	}
+}
+
+// writeStaged writes a batch of staged records with a single call to the
+// file. It expects l.mu to be held.
+func (l *Logger) writeStaged(batch []byte, records [][]byte) {
+	if l.ChunkOversizeWrites && int64(len(batch)) > l.max() {
+		if _, err := l.write(batch); err != nil {
+			l.backgroundError("write", l.classifyError(err), "staged write failed")
+		}
+		return
+	}
+	if err := l.prepareWrite(int64(len(batch))); err != nil {
+		l.backgroundError("write", l.classifyError(err), "staged write failed")
+		return
+	}
+	n, err := l.writeFile(batch)
+	l.size += int64(n)
+	l.updateChecksum(batch[:n])
+	for _, r := range records {
+		if n < len(r) {
+			break
+		}
+		l.recordTail(r)
+		n -= len(r)
+	}
+	if err != nil {
+		l.backgroundError("write", l.classifyError(err), "staged write failed")
+	}
+}
+
+// hasStaged reports whether any shard holds records.
+func (st *stager) hasStaged() bool {
+	for i := range st.shards {
+		shard := &st.shards[i]
+		shard.mu.Lock()
+		n := len(shard.records)
+		shard.mu.Unlock()
+		if n > 0 {
+			return true
+		}
+	}
+	return false
+}
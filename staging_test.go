@@ -0,0 +1,103 @@
+package timberjack
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteShards_PreservesPerGoroutineOrder(t *testing.T) {
+	megabyte = 1024 * 1024
+	defer func() { megabyte = 1 }()
+
+	dir := makeTempDir("TestWriteShards_PreservesPerGoroutineOrder", t)
+	defer os.RemoveAll(dir)
+
+	const goroutines, records = 16, 500
+	l := &Logger{Filename: logFile(dir), WriteShards: 4}
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < records; i++ {
+				n, err := fmt.Fprintf(l, "%d %d\n", g, i)
+				isNil(err, t)
+				assert(n > 0, t, "short write")
+			}
+		}(g)
+	}
+	wg.Wait()
+	isNil(l.Close(), t)
+
+	f, err := os.Open(logFile(dir))
+	isNil(err, t)
+	defer f.Close()
+
+	next := make([]int, goroutines)
+	total := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var g, i int
+		_, err := fmt.Sscanf(scanner.Text(), "%d %d", &g, &i)
+		isNil(err, t)
+		equals(next[g], i, t)
+		next[g]++
+		total++
+	}
+	isNil(scanner.Err(), t)
+	equals(goroutines*records, total, t)
+}
+
+func TestWriteShards_RotatesAndTooLong(t *testing.T) {
+	megabyte = 1
+	dir := makeTempDir("TestWriteShards_RotatesAndTooLong", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10, WriteShards: 2}
+
+	_, err := l.Write([]byte(strings.Repeat("x", 11)))
+	notNil(err, t)
+
+	for i := 0; i < 2; i++ {
+		n, err := l.Write([]byte("12345678\n"))
+		isNil(err, t)
+		equals(9, n, t)
+	}
+	isNil(l.Close(), t)
+
+	existsWithContent(logFile(dir), []byte("12345678\n"), t)
+	fileCount(dir, 2, t)
+}
+
+func BenchmarkWriteParallel(b *testing.B) {
+	megabyte = 1024 * 1024
+	defer func() { megabyte = 1 }()
+
+	record := []byte(strings.Repeat("x", 127) + "\n")
+	for _, shards := range []int{0, 8, 64} {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			dir := makeTempDir("BenchmarkWriteParallel", b)
+			defer os.RemoveAll(dir)
+
+			l := &Logger{Filename: logFile(dir), MaxSize: 1024, WriteShards: shards}
+			defer l.Close()
+
+			b.SetBytes(int64(len(record)))
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := l.Write(record); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
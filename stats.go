@@ -0,0 +1,211 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// Stats holds counters describing the work a Logger has done since it was
+// created. Use Logger.Stats to obtain a snapshot.
+type Stats struct {
+	// Compressions is the number of backups compressed.
+	Compressions int64
+
+	// CompressionBytesIn is the total size of backups before compression.
+	CompressionBytesIn int64
+
+	// CompressionBytesOut is the total size of backups after compression.
+	CompressionBytesOut int64
+
+	// CompressionTime is the total time spent compressing backups.
+	CompressionTime time.Duration
+
+	// OrphansRemoved is the number of temporary files left by an interrupted
+	// compression or copy that were deleted because the work could be redone.
+	OrphansRemoved int64
+
+	// OrphansRecovered is the number of such temporary files that were the
+	// last remaining copy of a backup and were moved into place instead.
+	OrphansRecovered int64
+
+	// Failures counts failed writes and rotations by kind.
+	Failures map[ErrorKind]int64
+
+	// Dropped is the number of records dropped by QueueDrop and by
+	// RateLimitDrop or RateLimitSample.
+	Dropped int64
+
+	// WriteLatency is a histogram of how long Write calls took, including
+	// any rotation and file creation they triggered.
+	WriteLatency LatencyHistogram
+
+	// Writes is the number of Write calls that succeeded, and BytesWritten
+	// the number of bytes accepted by all Write calls.
+	Writes       int64
+	BytesWritten int64
+
+	// Rotations counts rotations by reason ("size", "time", "manual", ...).
+	Rotations map[string]int64
+
+	// Removals is the number of backups and archives deleted by retention
+	// (MaxBackups, MaxAge, MaxTotalSize, MinDiskFree, ArchiveAfter) and by
+	// DiskFullPrune.
+	Removals int64
+
+	// BackgroundErrors is the number of failures of background work, such
+	// as cleanup, compression, flushing and timer-driven rotations. Each is
+	// also reported on stderr.
+	BackgroundErrors int64
+}
+
+// CompressionRatio returns the ratio of compressed to uncompressed bytes over
+// all compressions (e.g. 0.1 means backups shrank to a tenth of their size).
+// It returns 0 if nothing has been compressed yet.
+func (s Stats) CompressionRatio() float64 {
+	if s.CompressionBytesIn == 0 {
+		return 0
+	}
+	return float64(s.CompressionBytesOut) / float64(s.CompressionBytesIn)
+}
+
+// compressionRecord describes the compression of a single backup.
+type compressionRecord struct {
+	originalSize int64
+	duration     time.Duration
+}
+
+// Stats returns a snapshot of the Logger's counters.
+func (l *Logger) Stats() Stats {
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+	s := l.stats
+	s.Dropped = atomic.LoadInt64(&l.drops().total)
+	if l.stats.Failures != nil {
+		s.Failures = make(map[ErrorKind]int64, len(l.stats.Failures))
+		for kind, n := range l.stats.Failures {
+			s.Failures[kind] = n
+		}
+	}
+	if l.stats.Rotations != nil {
+		s.Rotations = make(map[string]int64, len(l.stats.Rotations))
+		for reason, n := range l.stats.Rotations {
+			s.Rotations[reason] = n
+		}
+	}
+	s.WriteLatency = l.writeLatency().snapshot()
+	counts := l.writeCounts()
+	s.Writes = atomic.LoadInt64(&counts.writes)
+	s.BytesWritten = atomic.LoadInt64(&counts.bytes)
+	return s
+}
+
+// writeCounter counts Write calls and bytes.
+type writeCounter struct {
+	writes int64
+	bytes  int64
+}
+
+// writeCounts returns the Logger's write counter, creating it on first use.
+func (l *Logger) writeCounts() *writeCounter {
+	l.writeCountsOnce.Do(func() {
+		l.writeCounter = &writeCounter{}
+	})
+	return l.writeCounter
+}
+
+// countWrite records a Write call that accepted n bytes and returned err.
+func (l *Logger) countWrite(n int, err error) {
+	counts := l.writeCounts()
+	if err == nil {
+		atomic.AddInt64(&counts.writes, 1)
+	}
+	atomic.AddInt64(&counts.bytes, int64(n))
+}
+
+// countRotation records a rotation for reason. It expects l.statsMu to be
+// held.
+func (l *Logger) countRotation(reason string) {
+	if l.stats.Rotations == nil {
+		l.stats.Rotations = make(map[string]int64)
+	}
+	l.stats.Rotations[reason]++
+}
+
+// countRemovals records n backups or archives deleted by retention.
+func (l *Logger) countRemovals(n int) {
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+	l.stats.Removals += int64(n)
+}
+
+// backgroundError reports err, a failure of the background work op, on
+// stderr after the message given by format and args, counts it in
+// Stats.BackgroundErrors, keeps it for Err and passes it to OnError.
+func (l *Logger) backgroundError(op string, err error, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "timberjack: [%s] %s: %v\n", l.Filename, fmt.Sprintf(format, args...), err)
+	l.statsMu.Lock()
+	l.stats.BackgroundErrors++
+	if len(l.pendingErrors) == maxPendingErrors {
+		l.pendingErrors = append(l.pendingErrors[:0], l.pendingErrors[1:]...)
+	}
+	l.pendingErrors = append(l.pendingErrors, &BackgroundError{Op: op, Err: err, Time: time.Now()})
+	l.statsMu.Unlock()
+	if l.OnError != nil {
+		l.OnError(op, err)
+	}
+}
+
+// compressBackup compresses src into dst and records the compression in the
+// Logger's statistics.
+func (l *Logger) compressBackup(src, dst string) error {
+	if _, numbered := l.backupNumber(filepath.Base(src)); numbered {
+		return l.compressNumbered(src, dst)
+	}
+
+	info, err := osStat(src)
+	if err != nil {
+		return compressLogFileContext(l.context(), l.backgroundCodec(), src, dst) // let it report the problem
+	}
+
+	start := time.Now()
+	if err := compressLogFileContext(l.context(), l.backgroundCodec(), src, dst); err != nil {
+		return err
+	}
+	l.recordCompression(dst, info, time.Since(start))
+	l.dropCache(dst)
+	return nil
+}
+
+// recordCompression adds the compression of the file described by info
+// into dst to the Logger's statistics and announces it.
+func (l *Logger) recordCompression(dst string, info os.FileInfo, elapsed time.Duration) {
+	var compressedSize int64
+	if dstInfo, err := os.Stat(dst); err == nil {
+		compressedSize = dstInfo.Size()
+	}
+
+	l.statsMu.Lock()
+	l.stats.Compressions++
+	l.stats.CompressionBytesIn += info.Size()
+	l.stats.CompressionBytesOut += compressedSize
+	l.stats.CompressionTime += elapsed
+	if l.compressions == nil {
+		l.compressions = make(map[string]compressionRecord)
+	}
+	l.compressions[filepath.Base(dst)] = compressionRecord{originalSize: info.Size(), duration: elapsed}
+	l.statsMu.Unlock()
+
+	l.emit(Event{Type: EventCompression, File: dst})
+}
+
+// forgetBackup drops per-backup data kept for a file that was removed.
+func (l *Logger) forgetBackup(name string) {
+	name = filepath.Base(name)
+	l.statsMu.Lock()
+	defer l.statsMu.Unlock()
+	delete(l.compressions, name)
+	delete(l.links, l.trimCompressed(name))
+}
@@ -0,0 +1,85 @@
+package timberjack
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStats_Compression(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestStats_Compression", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename: logFile(dir),
+		Compress: true,
+	}
+	defer l.Close()
+
+	equals(Stats{}, l.Stats(), t)
+	equals(0.0, l.Stats().CompressionRatio(), t)
+
+	data := bytes.Repeat([]byte("compressible log line\n"), 100)
+	backup := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(backup, data, 0644), t)
+
+	isNil(l.millRunOnce(), t)
+
+	s := l.Stats()
+	equals(int64(1), s.Compressions, t)
+	equals(int64(len(data)), s.CompressionBytesIn, t)
+	info, err := os.Stat(backup + compressSuffix)
+	isNil(err, t)
+	equals(info.Size(), s.CompressionBytesOut, t)
+	assert(s.CompressionRatio() > 0 && s.CompressionRatio() < 1, t, "unexpected ratio %v", s.CompressionRatio())
+	assert(s.CompressionTime > 0, t, "expected compression time to be recorded")
+}
+
+func TestStats_CompressionFailureNotCounted(t *testing.T) {
+	dir := makeTempDir("TestStats_CompressionFailureNotCounted", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir)}
+	err := l.compressBackup(filepath.Join(dir, "missing.log"), filepath.Join(dir, "missing.log.gz"))
+	notNil(err, t)
+	equals(Stats{}, l.Stats(), t)
+}
+
+func TestStats_Counters(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestStats_Counters", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          10,
+		MaxBackups:       1,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err := l.Write([]byte("boo!"))
+		isNil(err, t)
+	}
+	_, err := l.Write([]byte("this is too long"))
+	notNil(err, t)
+	newFakeTime()
+	isNil(l.RotateWithReason("manual"), t)
+	isNil(l.millRunOnce(), t)
+
+	s := l.Stats()
+	equals(int64(3), s.Writes, t)
+	equals(int64(12), s.BytesWritten, t)
+	equals(map[string]int64{"size": 1, "manual": 1}, s.Rotations, t)
+	equals(int64(1), s.Removals, t)
+
+	l.backgroundError("remove", os.ErrPermission, "failed to do something")
+	equals(int64(1), l.Stats().BackgroundErrors, t)
+}
@@ -0,0 +1,64 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// streamSuffix marks the temporary a streamed rotation compresses the log
+// file into. It is named after the log file rather than the backup, so that
+// repairOrphans never mistakes an interrupted one for a complete backup.
+const streamSuffix = ".stream"
+
+// streamCompress reports whether rotations compress the log file straight
+// into its backup.
+func (l *Logger) streamCompress() bool {
+	return l.StreamCompress && l.Compress && l.RotationTimeout <= 0 && l.CompressAfter <= 0 && l.UncompressedBackups <= 0
+}
+
+// streamTemp returns the path of the temporary of a streamed rotation.
+func (l *Logger) streamTemp() string {
+	return l.filename() + streamSuffix + tempSuffix
+}
+
+// streamBackup compresses the closed log file name into the compressed
+// backup dst, leaving name in place to be truncated, and records the
+// compression in the Logger's statistics. Until dst is renamed into place,
+// a crash leaves all data in name.
+func (l *Logger) streamBackup(name, dst string) error {
+	start := time.Now()
+	tmp, info, err := compressToFile(l.context(), l.codec(), name, dst, l.streamTemp())
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = osRemove(tmp)
+		return fmt.Errorf("failed to rename compressed file to %s: %w", dst, err)
+	}
+	if err := osSyncDir(filepath.Dir(dst)); err != nil {
+		return fmt.Errorf("failed to sync directory of compressed file %s: %w", dst, err)
+	}
+	if errChown := chown(dst, info); errChown != nil {
+		fmt.Fprintf(os.Stderr, "timberjack: [%s] failed to chown compressed log file %s: %v (source %s)\n",
+			filepath.Base(name), dst, errChown, name)
+	}
+	l.recordCompression(dst, info, time.Since(start))
+	l.dropCache(dst)
+	return nil
+}
+
+// removeStreamTemp removes the temporary of a streamed rotation that was
+// interrupted. The log file it was made from still holds its data.
+func (l *Logger) removeStreamTemp() {
+	if err := osRemove(l.streamTemp()); err != nil {
+		if !os.IsNotExist(err) {
+			l.backgroundError("remove", err, "failed to remove orphaned temporary file %s", l.streamTemp())
+		}
+		return
+	}
+	l.statsMu.Lock()
+	l.stats.OrphansRemoved++
+	l.statsMu.Unlock()
+}
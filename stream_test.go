@@ -0,0 +1,83 @@
+package timberjack
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStreamCompress(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestStreamCompress", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 10, Compress: true, StreamCompress: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	// An interrupted streamed rotation is discarded; the log file still holds
+	// its data.
+	isNil(os.WriteFile(l.streamTemp(), []byte("partial"), 0644), t)
+	isNil(l.millRunOnce(), t)
+	notExist(l.streamTemp(), t)
+	equals(int64(1), l.Stats().OrphansRemoved, t)
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	info, err := os.Stat(filename)
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+
+	// The compressed backup exists as soon as Rotate returns, and the
+	// uncompressed one never did.
+	existsWithContent(filename, []byte{}, t)
+	notExist(backupFileWithReason(dir, "size"), t)
+	bc := new(bytes.Buffer)
+	gz := gzip.NewWriter(bc)
+	gz.Name = filepath.Base(backupFileWithReason(dir, "size"))
+	gz.ModTime = info.ModTime()
+	_, err = gz.Write(b)
+	isNil(err, t)
+	isNil(gz.Close(), t)
+	existsWithContent(backupFileWithReason(dir, "size")+compressSuffix, bc.Bytes(), t)
+	notExist(l.streamTemp(), t)
+	fileCount(dir, 2, t)
+	equals(int64(1), l.Stats().Compressions, t)
+
+	// Writes continue in the truncated file.
+	_, err = l.Write(b)
+	isNil(err, t)
+	existsWithContent(filename, b, t)
+}
+
+func TestStreamCompress_Fails(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestStreamCompress_Fails", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+	l := &Logger{Filename: filename, MaxSize: 10, Compress: true, StreamCompress: true, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	// Make the stream's temporary impossible to create, or to remove.
+	isNil(os.Mkdir(l.streamTemp(), 0755), t)
+	isNil(os.WriteFile(filepath.Join(l.streamTemp(), "x"), nil, 0644), t)
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+
+	// The log file was renamed as usual.
+	existsWithContent(filename, []byte{}, t)
+	exists(backupFileWithReason(dir, "size"), t)
+}
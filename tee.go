@@ -0,0 +1,101 @@
+package timberjack
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// teeQueueSize is the number of records a TeeWriter holds for each sink that
+// falls behind before dropping records for it. It is a variable so tests can
+// shorten it.
+var teeQueueSize = 1024
+
+// TeeWriter duplicates every write to a Logger and to additional sinks, such
+// as os.Stdout or a network connection, like io.MultiWriter. Unlike
+// io.MultiWriter, the sinks don't hold each other up: each is written by its
+// own goroutine from a queue, so a slow or failing sink neither delays the
+// log file nor the other sinks. Records a sink can't keep up with are
+// dropped for that sink and counted in Dropped. A sink that starts failing
+// is reported as a background error with op "sink", once until it recovers.
+// Rotation only ever affects the log file.
+type TeeWriter struct {
+	l         *Logger
+	sinks     []*teeSink
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// teeSink is a sink of a TeeWriter and the queue of records it is fed from.
+type teeSink struct {
+	w       io.Writer
+	queue   chan []byte
+	done    chan struct{} // closed when the sink's goroutine exits
+	dropped int64         // atomic
+}
+
+// NewTeeWriter returns a TeeWriter writing to l and to sinks.
+func NewTeeWriter(l *Logger, sinks ...io.Writer) *TeeWriter {
+	t := &TeeWriter{l: l}
+	for _, w := range sinks {
+		s := &teeSink{w: w, queue: make(chan []byte, teeQueueSize), done: make(chan struct{})}
+		t.sinks = append(t.sinks, s)
+		go t.feed(s)
+	}
+	return t
+}
+
+// Write queues a copy of p for every sink and writes p to the Logger,
+// returning the Logger's result.
+func (t *TeeWriter) Write(p []byte) (int, error) {
+	if len(t.sinks) > 0 {
+		rec := append([]byte(nil), p...) // the sinks only read it
+		for _, s := range t.sinks {
+			select {
+			case s.queue <- rec:
+			default:
+				atomic.AddInt64(&s.dropped, 1)
+			}
+		}
+	}
+	return t.l.Write(p)
+}
+
+// feed writes the records queued for s until the queue is closed.
+func (t *TeeWriter) feed(s *teeSink) {
+	defer close(s.done)
+	failing := false
+	for rec := range s.queue {
+		_, err := s.w.Write(rec)
+		if err != nil && !failing { // report outages, not every record lost to them
+			t.l.backgroundError("sink", err, "failed to write to sink")
+		}
+		failing = err != nil
+	}
+}
+
+// Dropped returns the number of records dropped for each sink, in the order
+// the sinks were given to NewTeeWriter, because the sink fell behind.
+func (t *TeeWriter) Dropped() []int64 {
+	dropped := make([]int64, len(t.sinks))
+	for i, s := range t.sinks {
+		dropped[i] = atomic.LoadInt64(&s.dropped)
+	}
+	return dropped
+}
+
+// Close closes the Logger, then waits for the records already queued to
+// be written to the sinks. The sinks themselves are left open. Writes must
+// not be made after Close.
+func (t *TeeWriter) Close() error {
+	t.closeOnce.Do(func() {
+		t.closeErr = t.l.Close()
+		for _, s := range t.sinks {
+			close(s.queue)
+		}
+		for _, s := range t.sinks {
+			<-s.done
+		}
+	})
+	return t.closeErr
+}
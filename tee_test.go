@@ -0,0 +1,98 @@
+package timberjack
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// blockedWriter is a sink that doesn't return until released.
+type blockedWriter struct {
+	release chan struct{}
+}
+
+func (w blockedWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("sink down") }
+
+func TestTeeWriter(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestTeeWriter", t)
+	defer os.RemoveAll(dir)
+
+	var errs []string
+	var mu sync.Mutex
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          20, // room for 4 records
+		BackupTimeFormat: backupTimeFormat,
+		OnError: func(op string, err error) {
+			mu.Lock()
+			errs = append(errs, op)
+			mu.Unlock()
+		},
+	}
+
+	defer func(size int) { teeQueueSize = size }(teeQueueSize)
+	teeQueueSize = 4
+
+	blocked := blockedWriter{release: make(chan struct{})}
+	var buf syncBuffer
+	tw := NewTeeWriter(l, blocked, &buf, failingWriter{})
+
+	// A stuck sink neither holds up the file nor the other sinks.
+	const records = 6
+	for i := 1; i <= records; i++ {
+		_, err := tw.Write([]byte("boo!\n"))
+		isNil(err, t)
+		want := strings.Repeat("boo!\n", i)
+		deadline := time.Now().Add(5 * time.Second)
+		for buf.String() != want && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		equals(want, buf.String(), t)
+	}
+	equals(int64(0), tw.Dropped()[1], t)
+	assert(tw.Dropped()[0] >= 1, t, "expected records dropped for the stuck sink, got %v", tw.Dropped())
+
+	close(blocked.release)
+	isNil(tw.Close(), t)
+
+	// Rotation only affects the file: the sinks got everything, the file
+	// only what followed the last rotation.
+	existsWithContent(logFile(dir), []byte("boo!\nboo!\n"), t)
+
+	// The failing sink is reported once, not for every record.
+	mu.Lock()
+	defer mu.Unlock()
+	equals([]string{"sink"}, errs, t)
+}
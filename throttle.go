@@ -0,0 +1,92 @@
+package timberjack
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// throttleChunk is the most a throttled read returns at once, so that pacing
+// stays smooth at low rates.
+const throttleChunk = 32 << 10
+
+// rateLimiter paces reads to a number of bytes per second. Each read reserves
+// the time its bytes take at that rate and waits for the reservation to
+// start, so concurrent readers share the rate.
+type rateLimiter struct {
+	mu   sync.Mutex
+	next time.Time // when the next read may start
+}
+
+// wait blocks until n bytes may be read at rate bytes per second, or ctx is
+// done.
+func (r *rateLimiter) wait(ctx context.Context, n, rate int) error {
+	r.mu.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	delay := r.next.Sub(now)
+	r.next = r.next.Add(time.Duration(n) * time.Second / time.Duration(rate))
+	r.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backgroundCodec returns the codec of background compressions: codec,
+// throttled to CompressionBytesPerSec.
+func (l *Logger) backgroundCodec() Compressor {
+	if l.CompressionBytesPerSec > 0 {
+		return throttledCodec{l.codec(), l}
+	}
+	return l.codec()
+}
+
+// throttledCodec is a codec whose input is read no faster than
+// CompressionBytesPerSec.
+type throttledCodec struct {
+	Compressor
+	l *Logger
+}
+
+func (c throttledCodec) Compress(dst io.Writer, src io.Reader) error {
+	if b, ok := src.(backupReader); ok {
+		b.Reader = throttledReader{b.Reader, c.l}
+		return c.Compressor.Compress(dst, b)
+	}
+	return c.Compressor.Compress(dst, throttledReader{src, c.l})
+}
+
+// throttledReader reads from r no faster than l's CompressionBytesPerSec.
+type throttledReader struct {
+	r io.Reader
+	l *Logger
+}
+
+func (t throttledReader) Read(p []byte) (int, error) {
+	rate := t.l.CompressionBytesPerSec
+	if rate <= 0 {
+		return t.r.Read(p)
+	}
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+	n, err := t.r.Read(p)
+	if n > 0 {
+		if errWait := t.l.compressLimiter.wait(t.l.context(), n, rate); errWait != nil {
+			return n, errWait
+		}
+	}
+	return n, err
+}
@@ -19,16 +19,19 @@
 package timberjack
 
 import (
-	"compress/gzip"
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode"
 )
@@ -37,6 +40,13 @@ const (
 	backupTimeFormat = "2006-01-02T15-04-05.000"
 	compressSuffix   = ".gz"
 	defaultMaxSize   = 100
+
+	// tempSuffix marks a compressed backup or a cross-device copy that is
+	// still being written. It is renamed into place once complete.
+	tempSuffix = ".tmp"
+
+	// defaultRemovalPassInterval is the default for RemovalPassInterval.
+	defaultRemovalPassInterval = time.Second
 )
 
 // ensure we always implement io.WriteCloser
@@ -54,7 +64,8 @@ var _ io.WriteCloser = (*Logger)(nil)
 // Backups use the log file name given to Logger, in the form:
 // `name-timestamp-<reason>.ext` where `name` is the filename without the extension,
 // `timestamp` is the time of rotation formatted as `2006-01-02T15-04-05.000`,
-// `reason` is "size" or "time" (or "manual" for explicit Rotate calls), and `ext` is the original extension.
+// `reason` is "size" or "time" (or "manual" for explicit Rotate calls, "idle" for IdleFinalizeAfter),
+// and `ext` is the original extension.
 // For example, if your Logger.Filename is `/var/log/foo/server.log`, a backup created at 6:30pm on Nov 11 2016
 // due to size would use the filename `/var/log/foo/server-2016-11-04T18-30-00.000-size.log`.
 //
@@ -72,40 +83,163 @@ type Logger struct {
 	// Filename is the file to write logs to.  Backup log files will be retained
 	// in the same directory.  It uses <processname>-timberjack.log in
 	// os.TempDir() if empty.
-	Filename string `json:"filename" yaml:"filename"`
+	Filename string `json:"filename" yaml:"filename" toml:"filename"`
 
 	// MaxSize is the maximum size in megabytes of the log file before it gets
-	// rotated. It defaults to 100 megabytes.
-	MaxSize int `json:"maxsize" yaml:"maxsize"`
+	// rotated. It defaults to 100 megabytes. Set it to Unlimited to disable
+	// size-based rotation, e.g. when rotating purely on time. Because 0 means
+	// the default rather than no limit, ValidateMaxSize reports a zero MaxSize
+	// combined with time-based rotation as ambiguous.
+	MaxSize int `json:"maxsize" yaml:"maxsize" toml:"maxsize"`
 
 	// MaxAge is the maximum number of days to retain old log files based on the
 	// timestamp encoded in their filename.  Note that a day is defined as 24
 	// hours and may not exactly correspond to calendar days due to daylight
 	// savings, leap seconds, etc. The default is not to remove old log files
 	// based on age.
-	MaxAge int `json:"maxage" yaml:"maxage"`
+	MaxAge int `json:"maxage" yaml:"maxage" toml:"maxage"`
 
 	// MaxBackups is the maximum number of old log files to retain.  The default
 	// is to retain all old log files (though MaxAge may still cause them to get
 	// deleted.) MaxBackups counts distinct rotation events (timestamps).
-	MaxBackups int `json:"maxbackups" yaml:"maxbackups"`
+	MaxBackups int `json:"maxbackups" yaml:"maxbackups" toml:"maxbackups"`
+
+	// MaxTotalSize is the maximum combined size in megabytes of all backups.
+	// When it is exceeded, the oldest backups are deleted, even if MaxBackups
+	// and MaxAge would keep them. Compressed backups count with their
+	// compressed size once compressed. Backups matching KeepPatterns count
+	// towards the total but are never deleted. The default of 0 doesn't limit
+	// the total size.
+	MaxTotalSize int `json:"maxtotalsize" yaml:"maxtotalsize" toml:"maxtotalsize"`
+
+	// MinDiskFree is the free space to preserve on the filesystem holding the
+	// backups, as a size ("500MB", "2G") or a percentage of the filesystem
+	// ("10%"). Whenever cleanup runs and less is free, the oldest backups are
+	// deleted until enough space is available or no prunable backups are
+	// left, protecting the host from running out of disk because of logs.
+	// Backups matching KeepPatterns are never deleted. The default of "" disables
+	// the check. Use ValidateMinDiskFree to check the value.
+	MinDiskFree string `json:"mindiskfree" yaml:"mindiskfree" toml:"mindiskfree"`
 
 	// LocalTime determines if the time used for formatting the timestamps in
 	// backup files is the computer's local time.  The default is to use UTC
 	// time.
-	LocalTime bool `json:"localtime" yaml:"localtime"`
+	LocalTime bool `json:"localtime" yaml:"localtime" toml:"localtime"`
+
+	// Location is the time zone in which scheduled rotations (RotateAtMinutes,
+	// RotateAtTimes, RotationSchedule and RotationPeriod) are computed, e.g.
+	// the zone of the team reading the logs. Marks follow the zone's daylight
+	// saving transitions: a minute mark in a repeated hour fires in both
+	// occurrences, and a wall-clock time skipped by a transition fires at the
+	// equivalent time after it. If nil, UTC is used, or the computer's local
+	// time if LocalTime is set. Location doesn't affect backup file names.
+	Location *time.Location `json:"-" yaml:"-" toml:"-"`
 
 	// Compress determines if the rotated log files should be compressed
-	// using gzip. The default is not to perform compression.
-	Compress bool `json:"compress" yaml:"compress"`
+	// using CompressionCodec. The default is not to perform compression.
+	Compress bool `json:"compress" yaml:"compress" toml:"compress"`
+
+	// CompressionCodec selects the compression of backups when Compress is
+	// set: "gzip" (.gz, the default), "zstd" (.zst), which compresses large
+	// files faster, or "lz4" (.lz4), which uses the least CPU at some cost in
+	// compression ratio. Backups compressed with any codec are
+	// recognized, so the codec can be changed at any time. Use
+	// ValidateCompressionCodec to check the value; invalid values fall back
+	// to gzip.
+	CompressionCodec string `json:"compressioncodec" yaml:"compressioncodec" toml:"compressioncodec"`
+
+	// Compressor, if set, compresses backups instead of CompressionCodec,
+	// for codecs not built in, such as snappy or brotli. Backups with its
+	// suffix are managed like those of the built-in codecs, whose suffixes
+	// remain recognized.
+	Compressor Compressor `json:"-" yaml:"-" toml:"-"`
+
+	// CompressConcurrency is the maximum number of backups compressed in
+	// parallel by a cleanup pass, so that a burst of rotations is compressed
+	// quickly without using every CPU. The default of 0, like 1, compresses
+	// one backup at a time.
+	CompressConcurrency int `json:"compressconcurrency" yaml:"compressconcurrency" toml:"compressconcurrency"`
+
+	// StreamCompress, with Compress set, makes rotations compress the log
+	// file straight into its compressed backup and then truncate it, instead
+	// of renaming it and compressing the backup afterwards. The uncompressed
+	// backup never exists, so disk usage doesn't temporarily double, but
+	// writes wait for the compression. If it fails, or the file is smaller
+	// than CompressMinSize, the file is renamed as usual. It is ignored with
+	// RotationTimeout, whose rotations let writes continue to the old file,
+	// and with CompressAfter or UncompressedBackups.
+	StreamCompress bool `json:"streamcompress" yaml:"streamcompress" toml:"streamcompress"`
+
+	// CompressAfter is a grace period during which freshly rotated backups
+	// stay uncompressed, e.g. so that tailers and log collectors can finish
+	// reading them. Backups are compressed once they are older than this,
+	// by age of their rotation time. The default of 0 compresses them
+	// right away.
+	CompressAfter time.Duration `json:"compressafter" yaml:"compressafter" toml:"compressafter"`
+
+	// UncompressedBackups keeps this many of the newest backups uncompressed,
+	// as plain text for quick grepping, when Compress is set; older backups
+	// are compressed as usual. It doesn't change which backups retention
+	// removes. The default of 0 compresses all of them.
+	UncompressedBackups int `json:"uncompressedbackups" yaml:"uncompressedbackups" toml:"uncompressedbackups"`
+
+	// CompressMinSize is the size in bytes below which backups are left
+	// uncompressed, e.g. the many tiny files of frequent time-based
+	// rotation, which compression would barely shrink or even grow. The
+	// default of 0 compresses backups of any size.
+	CompressMinSize int64 `json:"compressminsize" yaml:"compressminsize" toml:"compressminsize"`
+
+	// CompressionBytesPerSec limits how fast backups are read for
+	// compression, shared by all compressions of the Logger, so that
+	// background compression doesn't saturate disk bandwidth and starve the
+	// application. The default of 0 is unlimited.
+	CompressionBytesPerSec int `json:"compressionbytespersec" yaml:"compressionbytespersec" toml:"compressionbytespersec"`
+
+	// CompressionBufferSize is the size in bytes of the buffers backups are
+	// read with for compression. Buffers, like gzip writers, are pooled and
+	// reused across compressions. The default of 0 uses 32 KiB.
+	CompressionBufferSize int `json:"compressionbuffersize" yaml:"compressionbuffersize" toml:"compressionbuffersize"`
+
+	// Checksums makes cleanup passes write a SHA-256 sidecar file next to
+	// every backup, named after it with ".sha256" appended (e.g.
+	// foo-2025-01-02T15-04-05.000-size.log.gz.sha256), in the format of
+	// sha256sum, as integrity evidence for compliance. The sidecar of an
+	// uncompressed backup is replaced once it is compressed, and sidecars
+	// are removed along with their backups. Use VerifyBackups to check them.
+	Checksums bool `json:"checksums" yaml:"checksums" toml:"checksums"`
+
+	// Encrypt encrypts compressed backups at rest with AES-256-GCM, after
+	// compression, producing e.g. foo-2025-01-02T15-04-05.000-size.log.gz.enc.
+	// It requires Compress, and EncryptionKey or KeyProvider. Use
+	// DecryptBackup to read encrypted backups back, and ValidateEncryption
+	// to check the configuration; if no valid key is available, backups are
+	// left uncompressed.
+	Encrypt bool `json:"encrypt" yaml:"encrypt" toml:"encrypt"`
+
+	// EncryptionKey is the 32-byte AES-256 key backups are encrypted with
+	// when Encrypt is set and KeyProvider isn't.
+	EncryptionKey []byte `json:"-" yaml:"-" toml:"-"`
+
+	// KeyProvider, if set, supplies the key of each encrypted backup instead
+	// of EncryptionKey.
+	KeyProvider KeyProvider `json:"-" yaml:"-" toml:"-"`
+
+	// Encrypter, if set, encrypts compressed backups instead of Encrypt, e.g.
+	// to age X25519 or OpenPGP public keys (see the pgp subpackage), so that
+	// backups can be shipped off-host without the host holding a decryption
+	// key. Backups are named with its suffix after the compression suffix
+	// (e.g. .log.gz.gpg). Like Encrypt, it requires Compress.
+	Encrypter Encrypter `json:"-" yaml:"-" toml:"-"`
 
 	// RotationInterval is the maximum duration between log rotations.
 	// If the elapsed time since the last rotation exceeds this interval,
 	// the log file is rotated, even if the file size has not reached MaxSize.
 	// The minimum recommended value is 1 minute. If set to 0, time-based rotation is disabled.
+	// A background timer rotates the file on time even while nothing is
+	// written; an empty file is not rotated, but starts a new interval.
 	//
 	// Example: RotationInterval = time.Hour * 24 will rotate logs daily.
-	RotationInterval time.Duration `json:"rotationinterval" yaml:"rotationinterval"`
+	RotationInterval time.Duration `json:"rotationinterval" yaml:"rotationinterval" toml:"rotationinterval"`
 
 	// BackupTimeFormat defines the layout for the timestamp appended to rotated file names.
 	// While other formats are allowed, it is recommended to follow the standard Go time layout
@@ -120,14 +254,390 @@ type Logger struct {
 	// will generate rotated backup files in the format:
 	// <logfilename>-2006-01-02-15-04-05-<rotationCriterion>-timberjack.log
 	// where `rotationCriterion` could be `time` or `size`.
-	BackupTimeFormat string `json:"backuptimeformat" yaml:"backuptimeformat"`
+	BackupTimeFormat string `json:"backuptimeformat" yaml:"backuptimeformat" toml:"backuptimeformat"`
+
+	// BackupNameFunc, if set, names rotated files instead of the default
+	// <prefix>-<timestamp>-<reason><ext> scheme, e.g. to include the host name
+	// or ISO week or to drop the reason. It receives the log file name's
+	// prefix and extension (for /var/log/app.log, "app" and ".log"), the
+	// rotation time (in UTC, or local time if LocalTime is set) and the
+	// reason, and returns the backup's base name. It must return distinct
+	// names for distinct rotations, or earlier backups are overwritten.
+	//
+	// Backups are recognized for MaxBackups, MaxAge and compression by
+	// BackupNamePattern, which is required with it, and are ordered by
+	// modification time. Invalid names (empty, or containing a path
+	// separator) fall back to the default scheme.
+	BackupNameFunc func(prefix string, t time.Time, reason string, ext string) string `json:"-" yaml:"-" toml:"-"`
+
+	// BackupNamePattern is a glob (see filepath.Match) matching the base
+	// names BackupNameFunc returns, e.g. "app.*.log". Only the files it
+	// matches, optionally followed by a compression suffix, are backups to
+	// retention and compression, so that other files in the directory are
+	// never removed. Without it, backups named by BackupNameFunc aren't
+	// managed at all; use ValidateBackupNamePattern to check it.
+	BackupNamePattern string `json:"-" yaml:"-" toml:"-"`
+
+	// LumberjackCompat names backups like gopkg.in/natefinch/lumberjack does,
+	// <prefix>-<timestamp><ext> without a rotation reason (the timestamp is
+	// always formatted as 2006-01-02T15-04-05.000), and makes retention and
+	// compression recognize such backups, so timberjack can take over from
+	// lumberjack or run side by side with tools that expect its names.
+	// BackupNameFunc takes precedence. See also MigrateBackups.
+	LumberjackCompat bool `json:"lumberjackcompat" yaml:"lumberjackcompat" toml:"lumberjackcompat"`
+
+	// NumberedBackups names backups with the classic logrotate convention
+	// instead: the most recent backup is <filename>.1 (app.log.1, or
+	// app.log.1.gz once compressed), and on every rotation existing backups
+	// are renamed to the next higher number. Use it for log collectors that
+	// only understand this convention. Numbered backups carry no timestamp or
+	// reason; their modification time is used for MaxAge. It takes precedence
+	// over BackupNameFunc and LumberjackCompat.
+	NumberedBackups bool `json:"numberedbackups" yaml:"numberedbackups" toml:"numberedbackups"`
 
 	// RotateAtMinutes defines specific minutes within an hour (0-59) to trigger a rotation.
 	// For example, []int{0} for top of the hour, []int{0, 30} for top and half-past the hour.
-	// Rotations are aligned to the clock minute (second 0).
+	// Rotations are aligned to the clock minute (second 0) in Location.
 	// This operates in addition to RotationInterval and MaxSize.
 	// If multiple rotation conditions are met, the first one encountered typically triggers.
-	RotateAtMinutes []int `json:"rotateAtMinutes" yaml:"rotateAtMinutes"`
+	RotateAtMinutes []int `json:"rotateAtMinutes" yaml:"rotateAtMinutes" toml:"rotateAtMinutes"`
+
+	// RotationSchedule is a cron expression ("minute hour day-of-month month
+	// day-of-week") describing calendar points at which to rotate, e.g.
+	// "0 0 * * *" for midnight every day or "0 */6 * * *" every six hours.
+	// The @hourly, @daily, @weekly, @monthly and @yearly shorthands are also
+	// accepted. Times are evaluated in Location. It operates in addition to
+	// RotationInterval, RotateAtMinutes and MaxSize.
+	// Use ValidateRotationSchedule to check the expression.
+	RotationSchedule string `json:"rotationschedule" yaml:"rotationschedule" toml:"rotationschedule"`
+
+	// RotateAtTimes lists wall-clock times of day ("HH:MM", 24-hour clock) at
+	// which to rotate every day, e.g. []string{"00:00", "06:30", "12:00"}.
+	// Times are evaluated in Location. It complements RotateAtMinutes, which
+	// only fires on minute marks within each hour. Use ValidateRotateAtTimes
+	// to check the values.
+	RotateAtTimes []string `json:"rotateAtTimes" yaml:"rotateAtTimes" toml:"rotateAtTimes"`
+
+	// RotationPeriod rotates on calendar boundaries so that each file covers
+	// one human reporting period: RotationDaily at midnight, RotationWeekly at
+	// Monday 00:00 and RotationMonthly at 00:00 on the first of the month.
+	// Boundaries are evaluated in Location. Unlike RotationInterval, the
+	// first file is cut short at the next boundary rather than running a
+	// full period from startup.
+	RotationPeriod RotationPeriod `json:"rotationperiod" yaml:"rotationperiod" toml:"rotationperiod"`
+
+	// MissedTickPolicy controls what the RotateAtMinutes scheduler does when it
+	// wakes up late and finds that several marks have passed (for example after
+	// a laptop resumes from suspend). The default, MissedTickRotateOnce, performs
+	// a single catch-up rotation instead of one per missed mark. Either way an
+	// EventMissedRotation is published on Events.
+	MissedTickPolicy MissedTickPolicy `json:"missedtickpolicy" yaml:"missedtickpolicy" toml:"missedtickpolicy"`
+
+	// RotationTimeout bounds how long a rotation may block writes waiting for
+	// the filesystem (e.g. a slow NFS mount) to create the log and backup
+	// directories and stat the log file. If the budget is exceeded, writes
+	// continue to go to the old file, Rotate returns ErrRotationPending, an
+	// EventRotationTimeout is published and the rotation is performed by a
+	// later write once the filesystem responds. The rename and the creation
+	// of the new file then happen under the Logger's lock as usual, so the
+	// file is never renamed while it is still being written. The default of 0
+	// means rotations are performed synchronously without a budget.
+	RotationTimeout time.Duration `json:"rotationtimeout" yaml:"rotationtimeout" toml:"rotationtimeout"`
+
+	// BackupDir is the directory rotated backups are moved to. A relative path
+	// is relative to the directory of Filename. It may be on a different
+	// filesystem than Filename; the backup is then copied, fsynced and removed
+	// instead of renamed. The default is to keep backups next to Filename.
+	BackupDir string `json:"backupdir" yaml:"backupdir" toml:"backupdir"`
+
+	// BackupDirLayout, if set, is a time layout for subdirectories of the
+	// backup directory that rotated backups are moved into, e.g.
+	// "2006/01/02" for one directory per day, so that the directory doesn't
+	// accumulate thousands of files. The rotation time is formatted in UTC,
+	// or local time if LocalTime is set. Retention and compression cover the
+	// subdirectories, and subdirectories emptied by retention are removed.
+	// It is ignored with NumberedBackups. Use ValidateBackupDirLayout to
+	// check the value.
+	BackupDirLayout string `json:"backupdirlayout" yaml:"backupdirlayout" toml:"backupdirlayout"`
+
+	// AdoptExisting brings files left behind by a previous logging system under
+	// timberjack's management. When enabled, files in the log directory whose
+	// names match one of AdoptPatterns are treated as backups: they count towards
+	// MaxBackups, are deleted by MaxAge and are compressed when Compress is set.
+	// Since their names carry no timberjack timestamp, the file modification
+	// time is used instead. The active log file is never adopted.
+	AdoptExisting bool `json:"adoptexisting" yaml:"adoptexisting" toml:"adoptexisting"`
+
+	// AdoptPatterns lists the filepath.Match glob patterns (matched against the
+	// base name) of foreign backups to adopt when AdoptExisting is set.
+	// Example: []string{"foo.log.*", "foo-*.txt"}
+	AdoptPatterns []string `json:"adoptpatterns" yaml:"adoptpatterns" toml:"adoptpatterns"`
+
+	// AdoptCompressed lists the suffixes of adopted files that are already
+	// compressed in a format timberjack doesn't write. They are subject to
+	// retention but never compressed again. The default of nil recognizes
+	// ".bz2", ".xz", ".lzma", ".Z", ".zip" and ".br"; files in formats
+	// timberjack writes, such as ".gz", are always recognized.
+	AdoptCompressed []string `json:"adoptcompressed" yaml:"adoptcompressed" toml:"adoptcompressed"`
+
+	// KeepPatterns lists filepath.Match glob patterns (matched against the
+	// base name) of backups that are never deleted by MaxBackups or MaxAge,
+	// e.g. []string{"*-deploy-*.log.gz"} to protect operationally significant
+	// segments. Kept backups don't count towards MaxBackups; they are still
+	// compressed if Compress is set. A pattern also matches the compressed
+	// form of the files it matches. Individual backups can be protected with
+	// Pin.
+	KeepPatterns []string `json:"keeppatterns" yaml:"keeppatterns" toml:"keeppatterns"`
+
+	// PairPolicy decides whether a backup that exists both uncompressed and
+	// compressed, e.g. while it is being compressed, counts once
+	// (PairCountOnce, the default) or twice (PairCountEach) towards
+	// MaxBackups. Either way, the compressed file is preferred: when Compress
+	// is set, a leftover uncompressed file whose compressed form is complete
+	// is removed instead of being compressed again. Use ValidatePairPolicy to
+	// check the value.
+	PairPolicy PairPolicy `json:"pairpolicy" yaml:"pairpolicy" toml:"pairpolicy"`
+
+	// ArchiveAfter bundles backups older than this many days into one
+	// compressed tar archive per ArchivePeriod, e.g. foo-2025-05.tar.gz, and
+	// removes the individual files, drastically reducing the number of files
+	// kept over long retention windows. A period is archived once it has
+	// ended, in the time zone of Location (or LocalTime). Backups are archived
+	// after the other retention options have been applied; kept and pinned
+	// backups are left alone, and so are numbered backups. MaxAge removes an archive once its
+	// whole period has expired. The default of 0 disables archiving.
+	ArchiveAfter int `json:"archiveafter" yaml:"archiveafter" toml:"archiveafter"`
+
+	// ArchivePeriod is the period covered by each archive: RotationDaily,
+	// RotationWeekly (ISO weeks, e.g. foo-2025-W20.tar.gz) or RotationMonthly,
+	// the default, which is also used for any other value.
+	ArchivePeriod RotationPeriod `json:"archiveperiod" yaml:"archiveperiod" toml:"archiveperiod"`
+
+	// IdleFinalizeAfter rotates the log file with reason "idle" once it has
+	// received no writes for this long, so that log collectors can pick up
+	// the tail of an intermittent service's logs promptly instead of waiting
+	// for the next write to trigger a rotation. Empty files are left alone.
+	// The default of 0 disables idle finalization.
+	IdleFinalizeAfter time.Duration `json:"idlefinalizeafter" yaml:"idlefinalizeafter" toml:"idlefinalizeafter"`
+
+	// BufferSize, if greater than zero, buffers writes in memory and writes
+	// them to the file in chunks of up to BufferSize bytes, greatly reducing
+	// system calls for chatty loggers. Buffered records are written every
+	// FlushInterval, before rotations and on Close, or with Flush; a crash
+	// loses those not written yet.
+	BufferSize int `json:"buffersize" yaml:"buffersize" toml:"buffersize"`
+
+	// FlushInterval is how often records buffered by BufferSize are written
+	// to the file. The default of 0 only writes them once the buffer is
+	// full, before rotations, on Close and on Flush.
+	FlushInterval time.Duration `json:"flushinterval" yaml:"flushinterval" toml:"flushinterval"`
+
+	// Preallocate reserves MaxSize bytes of disk space for every new log
+	// file, using fallocate where available (Linux), which reduces
+	// fragmentation and makes a full disk fail the rotation instead of a
+	// write halfway through the file. The file's size is not changed. It is
+	// ignored with an unlimited MaxSize and on other platforms.
+	Preallocate bool `json:"preallocate" yaml:"preallocate" toml:"preallocate"`
+
+	// SyncPolicy decides when the log file is fsynced: never, leaving it to
+	// the operating system (SyncNever, the default), on rotation and Close
+	// (SyncOnRotate), every SyncBytes bytes (SyncEveryBytes), every
+	// SyncInterval (SyncEveryInterval) or after every Write (SyncEveryWrite).
+	// All but SyncNever also sync on rotation and Close. Use
+	// ValidateSyncPolicy to check the configuration, and Sync to fsync on
+	// demand.
+	SyncPolicy SyncPolicy `json:"syncpolicy" yaml:"syncpolicy" toml:"syncpolicy"`
+
+	// SyncBytes is the number of bytes written between fsyncs with
+	// SyncEveryBytes.
+	SyncBytes int64 `json:"syncbytes" yaml:"syncbytes" toml:"syncbytes"`
+
+	// SyncInterval is the time between fsyncs with SyncEveryInterval.
+	SyncInterval time.Duration `json:"syncinterval" yaml:"syncinterval" toml:"syncinterval"`
+
+	// Durable fsyncs the log file's directory after every rotation renames
+	// the file to its backup name and creates the new one, so that a power
+	// loss can't undo the rotation. It costs an extra fsync per rotation
+	// and does nothing on Windows.
+	Durable bool `json:"durable" yaml:"durable" toml:"durable"`
+
+	// DropCaches evicts backups from the page cache once they are rotated
+	// and once they are compressed, using fadvise(DONTNEED), so that
+	// gigabytes of cold log data don't push the application's working set
+	// out of memory. It is ignored on platforms other than 64-bit Linux.
+	DropCaches bool `json:"dropcaches" yaml:"dropcaches" toml:"dropcaches"`
+
+	// AllowOversizeWrites accepts writes larger than MaxSize, e.g. big stack
+	// traces, instead of failing them: the log file is rotated first and
+	// the write goes into a file of its own, which is rotated by the next
+	// write.
+	AllowOversizeWrites bool `json:"allowoversizewrites" yaml:"allowoversizewrites" toml:"allowoversizewrites"`
+
+	// ChunkOversizeWrites accepts writes larger than MaxSize by splitting
+	// them across as many files as needed, filling the log file up to
+	// MaxSize and rotating it, so no data is refused. It takes precedence
+	// over AllowOversizeWrites.
+	ChunkOversizeWrites bool `json:"chunkoversizewrites" yaml:"chunkoversizewrites" toml:"chunkoversizewrites"`
+
+	// ChunkBoundary, if set, is where ChunkOversizeWrites prefers to split
+	// a write, e.g. "\n" to keep lines whole: each chunk ends just after
+	// the last boundary that fits, and the log file is rotated early to
+	// make room for one. Chunks without a boundary within MaxSize are cut
+	// at MaxSize.
+	ChunkBoundary string `json:"chunkboundary" yaml:"chunkboundary" toml:"chunkboundary"`
+
+	// MaxBytesPerSecond, if greater than zero, limits how fast records are
+	// written, so that a misbehaving component can't fill the disk in
+	// seconds. Bursts of up to one second's worth of bytes pass at once.
+	// Records over the budget are delayed or dropped as RateLimitPolicy
+	// says. The default of 0 is unlimited.
+	MaxBytesPerSecond int `json:"maxbytespersecond" yaml:"maxbytespersecond" toml:"maxbytespersecond"`
+
+	// RateLimitPolicy decides what Write does with records over the
+	// MaxBytesPerSecond budget: wait (RateLimitBlock, the default), drop
+	// them (RateLimitDrop) or drop all but every RateLimitSampleEvery-th
+	// (RateLimitSample). Dropped records are counted in Stats.Dropped. Use
+	// ValidateRateLimitPolicy to check the value.
+	RateLimitPolicy RateLimitPolicy `json:"ratelimitpolicy" yaml:"ratelimitpolicy" toml:"ratelimitpolicy"`
+
+	// RateLimitSampleEvery is how many records over budget RateLimitSample
+	// drops for each one it writes. The default of 0 means 100.
+	RateLimitSampleEvery int `json:"ratelimitsampleevery" yaml:"ratelimitsampleevery" toml:"ratelimitsampleevery"`
+
+	// DiskFullPolicy decides what Write does when the disk is full: return
+	// the error (DiskFullFail, the default), retry with backoff
+	// (DiskFullRetry), drop the record (DiskFullDrop) or remove the oldest
+	// backups to make room (DiskFullPrune). It applies to records written
+	// directly and to those queued by AsyncQueueSize. Use
+	// ValidateDiskFullPolicy to check the value.
+	DiskFullPolicy DiskFullPolicy `json:"diskfullpolicy" yaml:"diskfullpolicy" toml:"diskfullpolicy"`
+
+	// OnDiskFull, if set, is called with the error whenever a write finds
+	// the disk full, before DiskFullPolicy is applied, so that services can
+	// degrade gracefully, e.g. by lowering their log level. It is called
+	// with the Logger locked and must not use it.
+	OnDiskFull func(err error) `json:"-" yaml:"-" toml:"-"`
+
+	// Fallback, if set, receives the records the log file can't take, e.g.
+	// because the log volume has disappeared or is read-only, once
+	// DiskFullPolicy has given up, so that they aren't lost. Writes then
+	// succeed. Writes rejected by the options, such as one larger than
+	// MaxSize, still fail. Every write tries the log file first, reopening
+	// it, and switches back as soon as it works again. NewSyslogFallback
+	// returns a writer to the system log. Fallback is called with the
+	// Logger locked and must not use it.
+	Fallback io.Writer `json:"-" yaml:"-" toml:"-"`
+
+	// OnRotate, if set, is called after every rotation with the path the old
+	// file was rotated to and the rotation reason ("size", "time", ...). With
+	// BackupDir, the backup may still be on its way there. It is called with
+	// the Logger locked and must not use it; hand slow work, such as
+	// shipping the backup, to a goroutine.
+	OnRotate func(oldPath string, reason string) `json:"-" yaml:"-" toml:"-"`
+
+	// OnError, if set, is called with every failure of background work,
+	// which is otherwise only reported on stderr and counted in
+	// Stats.BackgroundErrors. op names the work that failed: "compress",
+	// "remove", "archive", "move", "cleanup", "rotate", "write", "flush",
+	// "sync", "checksum", "checkpoint", "recover", "close", "config" or
+	// "sink". It may be called with the Logger locked and must not use it.
+	OnError func(op string, err error) `json:"-" yaml:"-" toml:"-"`
+
+	// Clock, if set, is used instead of the system clock to tell the time
+	// of rotations, backup names, MaxAge and schedules, so that tests can
+	// control it. Timers still run in real time: a fake clock should be
+	// advanced before Rotate or Write is expected to notice a rotation is
+	// due.
+	Clock Clock `json:"-" yaml:"-" toml:"-"`
+
+	// CompressOnClose makes Close rotate the log file with reason "close",
+	// without creating a new one, and then compress every backup left
+	// uncompressed, as CompressPending does, so that batch jobs and CI runs
+	// leave nothing uncompressed behind. Empty files are left alone.
+	CompressOnClose bool `json:"compressonclose" yaml:"compressonclose" toml:"compressonclose"`
+
+	// IntegrityInterval enables a background task that fsyncs the active file
+	// at this interval and records a Checkpoint: the number of durable bytes and
+	// a rolling CRC-32 of those the Logger wrote. After a crash, VerifyCheckpoint uses the last
+	// checkpoint to detect a torn tail and report how much data was lost.
+	// The default of 0 disables periodic syncing.
+	IntegrityInterval time.Duration `json:"integrityinterval" yaml:"integrityinterval" toml:"integrityinterval"`
+
+	// MaxRemovalsPerPass caps the number of backups deleted by a single
+	// cleanup pass. Remaining deletions are spread over subsequent passes,
+	// run every RemovalPassInterval, smoothing the I/O of large prunes.
+	// Progress is reported with EventPruneProgress. The default of 0 removes
+	// everything eligible in one pass.
+	MaxRemovalsPerPass int `json:"maxremovalsperpass" yaml:"maxremovalsperpass" toml:"maxremovalsperpass"`
+
+	// RemovalPassInterval is the delay between cleanup passes while deletions
+	// deferred by MaxRemovalsPerPass remain. It defaults to one second.
+	RemovalPassInterval time.Duration `json:"removalpassinterval" yaml:"removalpassinterval" toml:"removalpassinterval"`
+
+	// CleanupInterval runs retention (MaxBackups, MaxAge, MaxTotalSize,
+	// MinDiskFree) and compression at this interval in the background, in
+	// addition to after every rotation, so that stale backups don't linger
+	// on services that rarely rotate. The janitor starts with the first
+	// Write. The default of 0 cleans up only after rotations.
+	CleanupInterval time.Duration `json:"cleanupinterval" yaml:"cleanupinterval" toml:"cleanupinterval"`
+
+	// EnforceOnOpen makes the first Write or Rotate run a complete cleanup
+	// pass, enforcing retention and compressing backups left uncompressed by
+	// an earlier run, before it proceeds. Without it, that pass runs in the
+	// background and the first writes may briefly coexist with backups that
+	// are due for removal. Note that the first Write blocks for the duration
+	// of the pass.
+	EnforceOnOpen bool `json:"enforceonopen" yaml:"enforceonopen" toml:"enforceonopen"`
+
+	// Context, if set, bounds the lifetime of the Logger's background work.
+	// When it is cancelled, the scheduled rotation, cleanup and integrity
+	// goroutines exit and in-flight compressions are abandoned (their partial
+	// output is removed). Writes are unaffected. Use it to tie the Logger to an
+	// application's root context, e.g. one managed by an errgroup.
+	Context context.Context `json:"-" yaml:"-" toml:"-"`
+
+	// TailBufferSize is the number of most recently written records (one per
+	// Write call) kept in memory and returned by LastN. The default of 0
+	// disables the buffer.
+	TailBufferSize int `json:"tailbuffersize" yaml:"tailbuffersize" toml:"tailbuffersize"`
+
+	// WriteShards, if greater than zero, makes Write copy each record into one
+	// of WriteShards in-memory staging buffers instead of writing it under the
+	// Logger's lock. Whichever writer finds no flush in progress drains the
+	// buffers to the file in the order the records were written, so records
+	// from one goroutine are never reordered. This cuts lock contention when
+	// many goroutines log through one Logger, but Write can then no longer
+	// report I/O errors; they are printed to stderr instead. Close and Rotate
+	// flush staged records first.
+	WriteShards int `json:"writeshards" yaml:"writeshards" toml:"writeshards"`
+
+	// AsyncQueueSize, if greater than zero, makes Write copy each record into
+	// a queue of up to AsyncQueueSize records and return, while a dedicated
+	// goroutine writes them to the file, so that callers aren't stalled by
+	// disk latency spikes. Write can then no longer report I/O errors; they
+	// are printed to stderr instead. Close, Rotate and Flush write the
+	// queued records first. It takes precedence over WriteShards.
+	AsyncQueueSize int `json:"asyncqueuesize" yaml:"asyncqueuesize" toml:"asyncqueuesize"`
+
+	// QueueFullPolicy decides what Write does when the AsyncQueueSize queue
+	// is full: wait for room (QueueBlock, the default), return ErrQueueFull
+	// (QueueError) or drop the record (QueueDrop). Use
+	// ValidateQueueFullPolicy to check the value.
+	QueueFullPolicy QueueFullPolicy `json:"queuefullpolicy" yaml:"queuefullpolicy" toml:"queuefullpolicy"`
+
+	// CoalesceWindow, if greater than zero, merges bursts of small writes,
+	// e.g. from frameworks that call Write per field: a Write starts a
+	// window of this length, and the records written within it go to the
+	// file in a single write, in order and never split. Batches are
+	// written early once they reach CoalesceMaxBytes or would cross a size
+	// rotation. Like with WriteShards, I/O errors are printed to stderr.
+	// AsyncQueueSize and WriteShards take precedence over it.
+	CoalesceWindow time.Duration `json:"coalescewindow" yaml:"coalescewindow" toml:"coalescewindow"`
+
+	// CoalesceMaxBytes is the size at which a CoalesceWindow batch is
+	// written without waiting for the window to end. The default of 0
+	// means 64 KiB.
+	CoalesceMaxBytes int `json:"coalescemaxbytes" yaml:"coalescemaxbytes" toml:"coalescemaxbytes"`
 
 	// Internal fields
 	size             int64     // current size of the log file
@@ -135,18 +645,116 @@ type Logger struct {
 	lastRotationTime time.Time // records the last time a rotation happened (for interval/scheduled).
 	logStartTime     time.Time // start time of the current logging period (used for backup filename timestamp).
 
-	mu sync.Mutex // ensures atomic writes and rotations
+	mu       sync.RWMutex   // ensures atomic writes and rotations; held shared by fast writes
+	retiring sync.WaitGroup // backups being moved into BackupDir (retireBackup)
+
+	closed int32        // 1 once Close has begun (atomic)
+	intake sync.RWMutex // held shared while a staged Write checks closed and stages its record
 
 	// For mill goroutine (backups, compression cleanup)
-	millCh    chan bool // channel to signal the mill goroutine
-	startMill sync.Once // ensures mill goroutine is started only once
+	millCh          chan bool      // channel to signal the mill goroutine
+	startMill       sync.Once      // ensures mill goroutine is started only once
+	millWg          sync.WaitGroup // waits for the mill goroutine to finish
+	millMu          sync.Mutex     // serializes cleanup passes
+	removalsPending int            // deletions deferred by MaxRemovalsPerPass (guarded by millMu)
+	manager         *Manager       // runs cleanup passes instead of the mill goroutine, if set
+	onFallback      bool           // writes are going to Fallback (guarded by mu)
+	rotationPaused  bool           // scheduled rotation is suspended by PauseRotation (guarded by mu)
+	rotationMissed  bool           // a scheduled rotation came due while paused (guarded by mu)
+	compressDue     time.Time      // when the first compression deferred by CompressAfter is due (guarded by millMu)
+	compressLimiter rateLimiter    // paces compressions to CompressionBytesPerSec
+	writeBucket     tokenBucket    // write budget of MaxBytesPerSecond
+	enforceOnce     sync.Once      // runs the EnforceOnOpen cleanup pass once
 
 	// For scheduled rotation goroutine (RotateAtMinutes)
 	startScheduledRotationOnce sync.Once      // ensures scheduled rotation goroutine is started only once
 	scheduledRotationQuitCh    chan struct{}  // channel to signal the scheduled rotation goroutine to stop
 	scheduledRotationWg        sync.WaitGroup // waits for the scheduled rotation goroutine to finish
+	reconfigureMu              sync.Mutex     // serializes Reconfigure calls
+	configWatchQuitCh          chan struct{}  // closed to stop the WatchConfig goroutine
+	signalQuitCh               chan struct{}  // closed to stop the HandleSignals goroutines
+	signalWg                   sync.WaitGroup // waits for the HandleSignals goroutines to finish
 	processedRotateAtMinutes   []int          // internal storage for sorted and validated RotateAtMinutes
 
+	ring *recordRing // in-memory buffer of recent records (TailBufferSize)
+
+	streamID   string    // identifies this Logger's chain of segments (created by streamOnce)
+	streamOnce sync.Once // ensures streamID is created only once
+	segmentSeq int64     // sequence number of the last linked backup
+	lastBackup string    // base name of the last linked backup
+
+	staging     *stager   // staging buffers (WriteShards)
+	stagingOnce sync.Once // ensures staging is created only once
+
+	// For the integrity goroutine (IntegrityInterval)
+	startIntegrityOnce sync.Once     // ensures the integrity goroutine is started only once
+	integrityQuitCh    chan struct{} // closed to stop the integrity goroutine
+	crc                uint32        // running checksum of the active file
+	crcStart           int64         // offset in the active file crc starts at
+	checkpoint         Checkpoint    // last integrity checkpoint
+
+	// For NumberedBackups
+	numberMu  sync.Mutex // serializes shifting numbered backups with the mill's renames and removals
+	numberGen int64      // number of completed shifts
+	millGen   int64      // numberGen when the mill last listed backups (mill goroutine only)
+
+	// For the idle finalization goroutine (IdleFinalizeAfter)
+	startIdleOnce sync.Once     // ensures the idle goroutine is started only once
+	idleQuitCh    chan struct{} // closed to stop the idle goroutine
+	lastWrite     time.Time     // time of the last write
+
+	// For the interval rotation goroutine (RotationInterval)
+	startIntervalOnce sync.Once     // ensures the interval goroutine is started only once
+	intervalQuitCh    chan struct{} // closed to stop the interval goroutine
+
+	// For the write queue (AsyncQueueSize)
+	queue     chan queuedRecord // records waiting to be written
+	queueDone chan struct{}     // closed once the queue's goroutine has exited
+	queueOnce sync.Once         // ensures the queue is created only once
+
+	dropped   *dropCounter // records dropped by QueueDrop
+	dropsOnce sync.Once    // ensures dropped is created only once
+
+	writeCounter    *writeCounter      // Write calls and bytes (created by writeCountsOnce)
+	writeCountsOnce sync.Once          // ensures writeCounter is created only once
+	pendingErrors   []*BackgroundError // background failures not yet returned by Err (guarded by statsMu)
+
+	// For BufferSize and the flush goroutine (FlushInterval)
+	buf            *bufio.Writer // buffers writes to the active file
+	startFlushOnce sync.Once     // ensures the flush goroutine is started only once
+	flushQuitCh    chan struct{} // closed to stop the flush goroutine
+
+	// For CoalesceWindow
+	coalesced     []byte      // records waiting for the window to end
+	coalesceTimer *time.Timer // ends the current window
+
+	// For SyncPolicy and the sync goroutine (SyncEveryInterval)
+	unsynced      int64         // bytes written since the last fsync (SyncEveryBytes)
+	startSyncOnce sync.Once     // ensures the sync goroutine is started only once
+	syncQuitCh    chan struct{} // closed to stop the sync goroutine
+
+	diskFreeDisabled bool // MinDiskFree is invalid or unsupported (guarded by millMu)
+
+	layoutOnce    sync.Once // ensures BackupDirLayout is validated only once
+	layoutInvalid bool      // BackupDirLayout failed validation
+
+	pendingRotation *pendingRotation // rotation waiting for the filesystem after RotationTimeout
+
+	stats        Stats                        // counters returned by Stats
+	links        map[string]SegmentLink       // chain data per backup, keyed by uncompressed base name
+	latency      *latencyRecorder             // Write latencies (created by latencyOnce)
+	latencyOnce  sync.Once                    // ensures latency is created only once
+	compressions map[string]compressionRecord // per-backup compression data, keyed by file name
+	statsMu      sync.Mutex                   // guards stats and compressions
+
+	events   chan Event // lazily created by Events
+	eventsMu sync.Mutex // guards events
+
+	// For the calendar rotation goroutine (RotationSchedule, RotateAtTimes, RotationPeriod)
+	startCalendarOnce sync.Once     // ensures the calendar goroutine is started only once
+	calendarQuitCh    chan struct{} // closed to stop the calendar goroutine
+	schedules         []schedule    // parsed calendar schedules
+
 	// isBackupTimeFormatValidated flag helps prevent repeated validation checks
 	// on supplied format through configuration
 	isBackupTimeFormatValidated bool
@@ -168,8 +776,42 @@ var (
 
 	osRemove = os.Remove
 
+	// osSyncDir exists so it can be mocked out by tests.
+	osSyncDir = syncDir
+
+	// osSyncFile exists so it can be mocked out by tests.
+	osSyncFile = (*os.File).Sync
+
 	// empty BackupTimeFormatField
 	ErrEmptyBackupTimeFormatField = errors.New("empty backupformat field")
+
+	// ErrClosed is returned by Write and Rotate once Close has been called.
+	ErrClosed = errors.New("timberjack: logger closed")
+
+	// ErrRotationPending is returned by Rotate when the filesystem didn't
+	// respond within RotationTimeout: writes continue to the current file,
+	// and a later write performs the rotation.
+	ErrRotationPending = errors.New("timberjack: rotation pending")
+
+	// ErrAmbiguousMaxSize is returned by ValidateMaxSize when MaxSize is zero
+	// (meaning the 100 MB default) while time-based rotation is configured.
+	ErrAmbiguousMaxSize = errors.New("MaxSize 0 means the default of 100 MB, not unlimited; set MaxSize to Unlimited or an explicit size")
+)
+
+// Unlimited, assigned to MaxSize, disables size-based rotation.
+const Unlimited = -1
+
+// MissedTickPolicy determines how missed RotateAtMinutes marks are handled.
+type MissedTickPolicy int
+
+const (
+	// MissedTickRotateOnce rotates once when the scheduler wakes up late,
+	// regardless of how many marks were missed.
+	MissedTickRotateOnce MissedTickPolicy = iota
+
+	// MissedTickSkip does not rotate for marks that were missed; the scheduler
+	// simply waits for the next upcoming mark.
+	MissedTickSkip
 )
 
 // Write implements io.Writer.
@@ -180,24 +822,131 @@ var (
 // using the original filename.
 // If the size of a single write exceeds MaxSize, the write is rejected and an error is returned.
 func (l *Logger) Write(p []byte) (n int, err error) {
+	defer l.writeLatency().record(time.Now())
+	defer func() { l.countWrite(n, err) }()
+	l.enforceOnOpen()
+
+	if l.MaxBytesPerSecond > 0 {
+		if ok, err := l.admitWrite(len(p)); !ok {
+			return len(p), err
+		}
+	}
+
+	if l.AsyncQueueSize > 0 {
+		return l.enqueueWrite(p)
+	}
+	if l.WriteShards > 0 {
+		return l.stageWrite(p)
+	}
+	if l.CoalesceWindow > 0 {
+		return l.coalesceWrite(p)
+	}
+	if n, ok, err := l.writeFast(p); ok {
+		if err == nil || Classify(err) != ErrorDiskFull {
+			return n, l.classifyError(err)
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		n, err = l.handleDiskFull(p, n, err)
+		return n, l.classifyError(err)
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	if l.isClosed() {
+		return 0, ErrClosed
+	}
+	n, err = l.write(p)
+	n, err = l.handleDiskFull(p, n, err)
+	n, err = l.writeFallback(p, n, err)
+	return n, l.classifyError(err)
+}
 
+// is64Bit reports whether the platform guarantees the 64-bit alignment of
+// l.size that atomic access requires.
+const is64Bit = ^uint(0)>>63 == 1
+
+// writeFast writes p holding l.mu only shared, so that concurrent writers
+// don't wait for each other, when the file is open and no rotation is due:
+// the write's length is added to l.size atomically and the write takes the
+// slow path instead if that would exceed MaxSize. Options that keep state
+// for every write or check a schedule on it always take the slow path. ok
+// reports whether p was written.
+func (l *Logger) writeFast(p []byte) (n int, ok bool, err error) {
+	if !is64Bit || l.BufferSize > 0 || l.IntegrityInterval > 0 || l.TailBufferSize > 0 ||
+		l.IdleFinalizeAfter > 0 || l.RotationTimeout > 0 || l.Fallback != nil || l.syncsOnWrite() {
+		return 0, false, nil
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.isClosed() || l.file == nil || len(l.processedRotateAtMinutes) > 0 || len(l.schedules) > 0 {
+		return 0, false, nil
+	}
+	if l.RotationInterval > 0 && l.now().Sub(l.lastRotationTime) >= l.RotationInterval {
+		return 0, false, nil
+	}
+	size := int64(len(p))
+	if atomic.AddInt64(&l.size, size) > l.max() {
+		atomic.AddInt64(&l.size, -size)
+		return 0, false, nil
+	}
+	n, err = l.file.Write(p)
+	if n < len(p) {
+		atomic.AddInt64(&l.size, int64(n-len(p)))
+	}
+	return n, true, err
+}
+
+// write performs a Write. It expects l.mu to be held.
+func (l *Logger) write(p []byte) (n int, err error) {
+	if l.ChunkOversizeWrites && int64(len(p)) > l.max() {
+		return l.writeChunks(p)
+	}
+	if err := l.prepareWrite(int64(len(p))); err != nil {
+		return 0, err
+	}
+
+	// Finally, write the bytes and update size.
+	n, err = l.writeFile(p)
+	l.size += int64(n)
+	l.updateChecksum(p[:n])
+	l.recordTail(p[:n])
+	if err == nil {
+		err = l.syncAfterWrite(n)
+	}
+	return n, err
+}
+
+// prepareWrite opens the file and performs any rotation that is due before
+// writeLen bytes are written to it. It expects l.mu to be held.
+func (l *Logger) prepareWrite(writeLen int64) error {
 	// Ensure the scheduled-rotation goroutine is running (if you've still got one).
 	l.ensureScheduledRotationLoopRunning()
+	l.ensureIntegrityLoopRunning()
+	l.ensureCalendarLoopRunning()
+	l.ensureIdleLoopRunning()
+	l.ensureIntervalLoopRunning()
+	l.ensureFlushLoopRunning()
+	l.ensureSyncLoopRunning()
+	if l.CleanupInterval > 0 {
+		l.startMillLoop()
+	}
 
 	// Anchor all checks to the same instant.
-	now := currentTime().In(l.location())
+	now := l.now().In(l.location())
+	if l.IdleFinalizeAfter > 0 {
+		l.lastWrite = now
+	}
 
-	writeLen := int64(len(p))
-	if writeLen > l.max() {
-		return 0, fmt.Errorf("write length %d exceeds maximum file size %d", writeLen, l.max())
+	if err := l.checkWriteLen(writeLen); err != nil {
+		return err
 	}
 
 	// Open (or create) the file on first write.
 	if l.file == nil {
-		if err = l.openExistingOrNew(len(p)); err != nil {
-			return 0, err
+		if err := l.openExistingOrNew(int(writeLen)); err != nil {
+			return err
 		}
 		if l.lastRotationTime.IsZero() {
 			// Initialize to 'now' so interval/minute checks start from here.
@@ -205,44 +954,65 @@ func (l *Logger) Write(p []byte) (n int, err error) {
 		}
 	}
 
+	// Switch to the new file if a slow rotation (RotationTimeout) has completed.
+	if err := l.finishPendingRotation(); err != nil {
+		return fmt.Errorf("pending rotation failed: %w", err)
+	}
+
 	// 1) Interval-based rotation
 	if l.RotationInterval > 0 && now.Sub(l.lastRotationTime) >= l.RotationInterval {
-		if err := l.rotate("time"); err != nil {
-			return 0, fmt.Errorf("interval rotation failed: %w", err)
+		if rotated, err := l.rotateOnSchedule(); err != nil {
+			return fmt.Errorf("interval rotation failed: %w", err)
+		} else if rotated {
+			l.lastRotationTime = now
 		}
-		l.lastRotationTime = now
 	}
 
 	// 2) Scheduled-minute rotation (RotateAtMinutes)
 	if len(l.processedRotateAtMinutes) > 0 {
 		for _, m := range l.processedRotateAtMinutes {
 			// Build the exact minute-mark timestamp in the current hour.
-			mark := time.Date(now.Year(), now.Month(), now.Day(),
-				now.Hour(), m, 0, 0, l.location())
+			mark := startOfHour(now).Add(time.Duration(m) * time.Minute)
 			// If we've crossed that mark since the last rotation, fire one rotation.
 			if l.lastRotationTime.Before(mark) && (mark.Before(now) || mark.Equal(now)) {
-				if err := l.rotate("time"); err != nil {
-					return 0, fmt.Errorf("scheduled-minute rotation failed: %w", err)
+				if rotated, err := l.rotateOnSchedule(); err != nil {
+					return fmt.Errorf("scheduled-minute rotation failed: %w", err)
+				} else if rotated {
+					// Record the logical mark—so we don’t rerun until next slot.
+					l.lastRotationTime = mark
 				}
-				// Record the logical mark—so we don’t rerun until next slot.
-				l.lastRotationTime = mark
 				break
 			}
 		}
 	}
 
-	// 3) Size-based rotation
-	if l.size+writeLen > l.max() {
+	// 3) Calendar rotation (RotationSchedule, RotateAtTimes, RotationPeriod)
+	if next := l.nextCalendarRotation(l.lastRotationTime); !next.IsZero() && !next.After(now) {
+		if rotated, err := l.rotateOnSchedule(); err != nil {
+			return fmt.Errorf("scheduled rotation failed: %w", err)
+		} else if rotated {
+			l.lastRotationTime = now
+		}
+	}
+
+	// 4) Size-based rotation. An oversize write (AllowOversizeWrites) goes
+	// into a file of its own, so an empty file is not rotated for it.
+	if l.size > 0 && l.size+writeLen > l.max() {
 		if err := l.rotate("size"); err != nil {
-			return 0, fmt.Errorf("size rotation failed: %w", err)
+			return fmt.Errorf("size rotation failed: %w", err)
 		}
 		// Note: we leave lastRotationTime untouched for size rotations.
 	}
+	return nil
+}
 
-	// Finally, write the bytes and update size.
-	n, err = l.file.Write(p)
-	l.size += int64(n)
-	return n, err
+// checkWriteLen rejects a write larger than MaxSize, unless
+// AllowOversizeWrites or ChunkOversizeWrites is set.
+func (l *Logger) checkWriteLen(writeLen int64) error {
+	if writeLen > l.max() && !l.AllowOversizeWrites && !l.ChunkOversizeWrites {
+		return fmt.Errorf("write length %d exceeds maximum file size %d", writeLen, l.max())
+	}
+	return nil
 }
 
 // ValidateBackupTimeFormat checks if the configured BackupTimeFormat is a valid time layout.
@@ -276,8 +1046,32 @@ func (l *Logger) ValidateBackupTimeFormat() error {
 	return nil
 }
 
-// location returns the time.Location (UTC or Local) to use for timestamps in backup filenames.
+// ValidateMaxSize checks that MaxSize is a positive size or Unlimited. A zero
+// MaxSize is only reported, as ErrAmbiguousMaxSize, when time-based rotation
+// is configured too, since such configurations usually expect 0 to disable
+// size-based rotation.
+func (l *Logger) ValidateMaxSize() error {
+	switch {
+	case l.MaxSize < 0 && l.MaxSize != Unlimited:
+		return fmt.Errorf("invalid MaxSize %d: must be positive or Unlimited", l.MaxSize)
+	case l.MaxSize == 0 && l.timeBasedRotation():
+		return ErrAmbiguousMaxSize
+	}
+	return nil
+}
+
+// timeBasedRotation reports whether any kind of time-based rotation is
+// configured.
+func (l *Logger) timeBasedRotation() bool {
+	return l.RotationInterval > 0 || len(l.RotateAtMinutes) > 0 || len(l.RotateAtTimes) > 0 ||
+		l.RotationSchedule != "" || l.RotationPeriod != ""
+}
+
+// location returns the time.Location in which scheduled rotations are computed.
 func (l *Logger) location() *time.Location {
+	if l.Location != nil {
+		return l.Location
+	}
 	if l.LocalTime {
 		return time.Local
 	}
@@ -293,19 +1087,12 @@ func (l *Logger) ensureScheduledRotationLoopRunning() {
 
 	l.startScheduledRotationOnce.Do(func() {
 		// Validate and sort RotateAtMinutes once for efficiency and correctness
-		seenMinutes := make(map[int]bool)
-		for _, m := range l.RotateAtMinutes {
-			if m >= 0 && m <= 59 && !seenMinutes[m] { // Ensure minutes are valid (0-59) and unique
-				l.processedRotateAtMinutes = append(l.processedRotateAtMinutes, m)
-				seenMinutes[m] = true
-			}
-		}
+		l.processedRotateAtMinutes = validRotateAtMinutes(l.RotateAtMinutes)
 		if len(l.processedRotateAtMinutes) == 0 {
 			// Optionally log that no valid minutes were found, preventing goroutine start
 			// fmt.Fprintf(os.Stderr, "timberjack: [%s] No valid minutes specified for RotateAtMinutes.\n", l.Filename)
 			return
 		}
-		sort.Ints(l.processedRotateAtMinutes) // Sort for predictable order in calculating next rotation
 
 		l.scheduledRotationQuitCh = make(chan struct{})
 		l.scheduledRotationWg.Add(1)
@@ -313,6 +1100,21 @@ func (l *Logger) ensureScheduledRotationLoopRunning() {
 	})
 }
 
+// validRotateAtMinutes returns the unique minutes in the range 0-59, sorted
+// for predictable order in calculating the next rotation.
+func validRotateAtMinutes(minutes []int) []int {
+	var valid []int
+	seenMinutes := make(map[int]bool)
+	for _, m := range minutes {
+		if m >= 0 && m <= 59 && !seenMinutes[m] { // Ensure minutes are valid (0-59) and unique
+			valid = append(valid, m)
+			seenMinutes[m] = true
+		}
+	}
+	sort.Ints(valid)
+	return valid
+}
+
 // runScheduledRotations is the main loop for handling rotations at specific minute marks
 // as defined in RotateAtMinutes. It runs in a separate goroutine.
 func (l *Logger) runScheduledRotations() {
@@ -333,33 +1135,13 @@ func (l *Logger) runScheduledRotations() {
 	}
 
 	for {
-		now := currentTime() // Use the mockable currentTime for testability
+		now := l.now() // Use the Logger's Clock for testability
 		nowInLocation := now.In(l.location())
-		nextRotationAbsoluteTime := time.Time{}
-		foundNextSlot := false
-
-	determineNextSlot:
-		// Calculate the next rotation time based on the current time and processedRotateAtMinutes.
-		// Iterate through the current hour, then subsequent hours (up to 24h ahead for robustness
-		// against system sleep or large clock jumps).
-		for hourOffset := 0; hourOffset <= 24; hourOffset++ {
-			// Base time for the hour we are checking (e.g., if now is 10:35, current hour base is 10:00)
-			hourToCheck := time.Date(nowInLocation.Year(), nowInLocation.Month(), nowInLocation.Day(), nowInLocation.Hour(), 0, 0, 0, l.location()).Add(time.Duration(hourOffset) * time.Hour)
-
-			for _, minuteMark := range l.processedRotateAtMinutes { // l.processedRotateAtMinutes is sorted
-				candidateTime := time.Date(hourToCheck.Year(), hourToCheck.Month(), hourToCheck.Day(), hourToCheck.Hour(), minuteMark, 0, 0, l.location())
-
-				if candidateTime.After(now) { // Found the earliest future slot
-					nextRotationAbsoluteTime = candidateTime
-					foundNextSlot = true
-					break determineNextSlot // Exit both loops
-				}
-			}
-		}
+		nextRotationAbsoluteTime, foundNextSlot := l.nextScheduledMark(now)
 
 		if !foundNextSlot {
 			// This should ideally not happen if processedRotateAtMinutes is valid and non-empty.
-			// Could occur if currentTime() is unreliable or jumps massively backward.
+			// Could occur if the Clock is unreliable or jumps massively backward.
 			// Log an error and retry calculation after a fallback delay.
 			fmt.Fprintf(os.Stderr, "timberjack: [%s] Could not determine next scheduled rotation time for %v with marks %v. Retrying calculation in 1 minute.\n", l.Filename, nowInLocation, l.processedRotateAtMinutes)
 			select {
@@ -367,6 +1149,8 @@ func (l *Logger) runScheduledRotations() {
 				continue // Restart the outer loop to recalculate
 			case <-l.scheduledRotationQuitCh: // Exit if Close() was called
 				return
+			case <-l.context().Done(): // Exit if the Logger's context ended
+				return
 			}
 		}
 
@@ -375,41 +1159,197 @@ func (l *Logger) runScheduledRotations() {
 
 		select {
 		case <-timer.C: // Timer fired, it's time for a scheduled rotation
-			l.mu.Lock()
-			// Only rotate if the last rotation time was before this specific scheduled mark.
-			// This prevents redundant rotations if another rotation (e.g., size/interval) happened
-			// very close to, but just before or at, this scheduled time for the same mark.
-			if l.lastRotationTime.Before(nextRotationAbsoluteTime) {
-				if err := l.rotate("time"); err != nil { // Scheduled rotations are "time" based for filename
-					fmt.Fprintf(os.Stderr, "timberjack: [%s] scheduled rotation failed: %v\n", l.Filename, err)
-				} else {
-					l.lastRotationTime = currentTime() // Update lastRotationTime after successful scheduled rotation
-				}
-			}
-			l.mu.Unlock()
+			l.handleScheduledMark(nextRotationAbsoluteTime)
 			// Loop will continue and recalculate the next slot from the new "now"
 
 		case <-l.scheduledRotationQuitCh: // Signal to quit from Close()
-			if !timer.Stop() {
-				// If Stop() returns false, the timer has already fired or been stopped.
-				// If it fired, its channel might have a value, so drain it.
-				select {
-				case <-timer.C:
-				default:
-				}
-			}
+			stopTimer(timer)
 			return // Exit goroutine
+
+		case <-l.context().Done(): // The Logger's context ended
+			stopTimer(timer)
+			return
+		}
+	}
+}
+
+// stopTimer stops timer, draining its channel if it already fired.
+func stopTimer(timer *time.Timer) {
+	if !timer.Stop() {
+		// If Stop() returns false, the timer has already fired or been stopped.
+		// If it fired, its channel might have a value, so drain it.
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+}
+
+// nextScheduledMark returns the earliest RotateAtMinutes mark strictly after now.
+// It searches the current hour and up to 24 hours ahead, for robustness against
+// system sleep or large clock jumps. The boolean is false if no mark was found.
+func (l *Logger) nextScheduledMark(now time.Time) (time.Time, bool) {
+	return nextMinuteMark(l.processedRotateAtMinutes, now.In(l.location()))
+}
+
+// nextMinuteMark returns the earliest mark of the sorted minutes strictly
+// after now, in now's location.
+func nextMinuteMark(minutes []int, now time.Time) (time.Time, bool) {
+	for hourOffset := 0; hourOffset <= 24; hourOffset++ {
+		// Base time for the hour we are checking (e.g., if now is 10:35, current hour base is 10:00)
+		hourToCheck := startOfHour(now).Add(time.Duration(hourOffset) * time.Hour)
+
+		for _, minuteMark := range minutes { // minutes are sorted
+			candidateTime := hourToCheck.Add(time.Duration(minuteMark) * time.Minute)
+			if candidateTime.After(now) { // Found the earliest future slot
+				return candidateTime, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// missedMarks counts the scheduled marks after mark and up to (including) now.
+// A non-zero result means the scheduler woke up too late to honour them.
+func (l *Logger) missedMarks(mark, now time.Time) int {
+	missed := 0
+	for next, ok := l.nextScheduledMark(mark); ok && !next.After(now); next, ok = l.nextScheduledMark(next) {
+		missed++
+	}
+	return missed
+}
+
+// handleScheduledMark performs the rotation for a RotateAtMinutes mark once the
+// scheduler's timer has fired, applying MissedTickPolicy if it woke up late.
+func (l *Logger) handleScheduledMark(mark time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return
+	}
+	if l.rotationPaused {
+		l.rotationMissed = true
+		return
+	}
+
+	now := l.now()
+	if missed := l.missedMarks(mark, now); missed > 0 {
+		l.emit(Event{Type: EventMissedRotation, Time: now, File: l.filename(), Missed: missed})
+		if l.MissedTickPolicy == MissedTickSkip {
+			return
+		}
+	}
+
+	// Only rotate if the last rotation time was before this specific scheduled mark.
+	// This prevents redundant rotations if another rotation (e.g., size/interval) happened
+	// very close to, but just before or at, this scheduled time for the same mark.
+	if l.lastRotationTime.Before(mark) {
+		if err := l.rotate("time"); err != nil { // Scheduled rotations are "time" based for filename
+			l.backgroundError("rotate", err, "scheduled rotation failed")
+		} else {
+			l.lastRotationTime = now // Update lastRotationTime after successful scheduled rotation
 		}
 	}
 }
 
 // Close implements io.Closer, and closes the current logfile.
 // It also signals any running goroutines (like scheduled rotation or mill) to stop.
+//
+// Close waits for Write and Rotate calls in progress to finish; records
+// staged by WriteShards are flushed first. Once Close has begun, Write and
+// Rotate return ErrClosed, and background rotations no longer happen.
+// Calling Close again does nothing and returns nil.
 func (l *Logger) Close() error {
+	l.intake.Lock()
+	first := atomic.CompareAndSwapInt32(&l.closed, 0, 1)
+	l.intake.Unlock()
+	if !first {
+		return nil
+	}
+
+	l.flushStaged()
+	l.closeQueue()
+
 	l.mu.Lock()
-	defer l.mu.Unlock()
+	err := l.shutdown()
+	l.mu.Unlock()
+
+	// Wait for the scheduled rotation and signal goroutines without holding
+	// l.mu, which they may be waiting for.
+	l.scheduledRotationWg.Wait()
+	l.signalWg.Wait()
+
+	if l.CompressOnClose {
+		if errCompress := l.compressOnClose(); err == nil {
+			err = errCompress
+		}
+	}
+	return err
+}
+
+// Shutdown closes the Logger like Close and then waits for its background
+// work to finish: the cleanup pass in progress, and a final one that
+// compresses and prunes the backups left by the last rotations, so that
+// short-lived programs don't leave uncompressed backups behind. If ctx ends
+// first, Shutdown returns its error and the work continues in the
+// background; cancel the Logger's Context to abandon it.
+//
+// Shutdown may be called after Close, and more than once.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		err := l.Close()
+		// Keep the mill from starting from now on, and see whether it ran.
+		l.startMill.Do(func() {})
+		l.millWg.Wait()
+		if l.millCh != nil && !l.CompressOnClose { // CompressOnClose has done its pass
+			if errMill := l.millRunOnce(); err == nil {
+				err = errMill
+			}
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
 
-	// Stop and wait for the scheduled rotation goroutine
+// compressOnClose rotates the closed log file, if it has any data, without
+// creating a new one, and compresses every backup left uncompressed.
+func (l *Logger) compressOnClose() error {
+	l.mu.Lock()
+	info, err := osStat(l.filename())
+	if err == nil && info.Size() > 0 {
+		var seg segment
+		if seg, err = l.newSegment("close", false); err == nil {
+			_ = seg.file.Close()
+			err = osRemove(l.filename())
+			l.retireBackup(seg.backup)
+		}
+	}
+	l.mu.Unlock()
+	l.retiring.Wait()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't rotate log file on close: %w", err)
+	}
+
+	if err := l.millRunOnce(); err != nil {
+		return err
+	}
+	return l.CompressPending()
+}
+
+// shutdown stops the background goroutines and closes the file.
+// It expects l.mu to be held.
+func (l *Logger) shutdown() error {
+	// Let backups being moved into BackupDir arrive; they signal the mill.
+	l.retiring.Wait()
+	l.flushCoalesced()
+
+	// Stop the scheduled rotation goroutine
 	if l.scheduledRotationQuitCh != nil {
 		// Check if quit channel is already closed to prevent panic on double-close
 		alreadyClosed := false
@@ -424,7 +1364,6 @@ func (l *Logger) Close() error {
 		if !alreadyClosed {
 			close(l.scheduledRotationQuitCh)
 		}
-		l.scheduledRotationWg.Wait() // Wait for the goroutine to finish
 	}
 
 	// Stop the mill goroutine. Original timberjack closes millCh.
@@ -437,16 +1376,39 @@ func (l *Logger) Close() error {
 		// A select with a default can also try to send a quit signal if millCh structure is different.
 	}
 
+	l.abandonPendingRotation()
+	l.stopIntegrityLoop()
+	l.stopCalendarLoop()
+	l.stopIdleLoop()
+	l.stopIntervalLoop()
+	l.stopFlushLoop()
+	l.stopSyncLoop()
+	l.stopConfigWatch()
+	l.stopSignalHandlers()
+
 	return l.closeFile() // Call the internal method to close the file descriptor
 }
 
+// isClosed reports whether Close has begun.
+func (l *Logger) isClosed() bool {
+	return atomic.LoadInt32(&l.closed) == 1
+}
+
 // closeFile closes the file if it is open. This is an internal method.
 // It expects l.mu to be held.
 func (l *Logger) closeFile() error {
 	if l.file == nil {
 		return nil
 	}
-	err := l.file.Close()
+	err := l.flushBuffer()
+	l.buf = nil
+	if l.syncsOnRotate() && err == nil {
+		err = osSyncFile(l.file)
+	}
+	l.unsynced = 0
+	if closeErr := l.file.Close(); err == nil {
+		err = closeErr
+	}
 	l.file = nil // Set to nil to indicate it's closed.
 	return err
 }
@@ -457,8 +1419,16 @@ func (l *Logger) closeFile() error {
 // SIGHUP. After rotating, this initiates compression and removal of old log
 // files according to the configuration.
 func (l *Logger) Rotate() error {
+	l.enforceOnOpen()
+	l.flushStaged()
+	l.flushQueue()
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	if l.isClosed() {
+		return ErrClosed
+	}
+	l.flushCoalesced()
 	// Determine reason for manual Rotate to align with test expectations and original behavior:
 	// If an interval rotation is also due at this moment, label it "time".
 	// Otherwise, label it "size" as a general default for manual rotation (tests often expect this).
@@ -466,7 +1436,55 @@ func (l *Logger) Rotate() error {
 	if l.shouldTimeRotate() { // shouldTimeRotate checks RotationInterval based on lastRotationTime
 		reason = "time"
 	}
-	return l.rotate(reason)
+	return l.rotateRequested(reason)
+}
+
+// RotateWithReason is like Rotate, but tags the backup with the given reason
+// instead of "size" or "time", e.g. "deploy" or "sighup". The reason appears
+// in the backup's name and in the EventRotation event. It must be a
+// non-empty string of letters, digits and underscores, since the backup
+// name is split at hyphens and dots.
+func (l *Logger) RotateWithReason(reason string) error {
+	if err := validateReason(reason); err != nil {
+		return err
+	}
+	l.enforceOnOpen()
+	l.flushStaged()
+	l.flushQueue()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isClosed() {
+		return ErrClosed
+	}
+	l.flushCoalesced()
+	return l.rotateRequested(reason)
+}
+
+// rotateRequested performs a rotation requested by Rotate or
+// RotateWithReason, reporting one left pending by RotationTimeout. It
+// expects l.mu to be held.
+func (l *Logger) rotateRequested(reason string) error {
+	if err := l.classifyError(l.rotate(reason)); err != nil {
+		return err
+	}
+	if l.pendingRotation != nil {
+		return ErrRotationPending
+	}
+	return nil
+}
+
+// validateReason checks that reason can be encoded in a backup name.
+func validateReason(reason string) error {
+	if reason == "" {
+		return errors.New("empty rotation reason")
+	}
+	for _, r := range reason {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_') {
+			return fmt.Errorf("invalid rotation reason %q: only letters, digits and underscores are allowed", reason)
+		}
+	}
+	return nil
 }
 
 // rotate closes the current file, moves it aside with a timestamp in the name,
@@ -475,6 +1493,18 @@ func (l *Logger) Rotate() error {
 // It expects l.mu to be held by the caller.
 // Takes an explicit reason for the rotation which is used in the backup filename.
 func (l *Logger) rotate(reason string) error {
+	if l.RotationTimeout > 0 {
+		return l.rotateWithBudget(reason)
+	}
+	return l.rotateNow(reason)
+}
+
+// rotateNow performs a rotation without RotationTimeout's budget. It expects
+// l.mu to be held.
+func (l *Logger) rotateNow(reason string) error {
+	if l.file != nil && !l.streamCompress() && runtime.GOOS != "windows" {
+		return l.swapSegment(reason)
+	}
 	if err := l.closeFile(); err != nil {
 		return err
 	}
@@ -486,61 +1516,146 @@ func (l *Logger) rotate(reason string) error {
 	return nil
 }
 
+// swapSegment rotates by renaming the active file aside and swapping a new
+// file in while the old one is still open, and then closes the old one.
+// Moving the backup into BackupDir, which may mean copying it to another
+// filesystem, and the cleanup that follows happen in the background, off the
+// write path. Windows can't rename open files and streamed rotations need
+// the file closed, so they close it first instead. It expects l.mu to be
+// held.
+func (l *Logger) swapSegment(reason string) error {
+	if err := l.flushBuffer(); err != nil {
+		return err
+	}
+	seg, err := l.newSegment(reason, false)
+	if err != nil {
+		// The file may have been renamed already; stop writing to it.
+		_ = l.closeFile()
+		return err
+	}
+	old := l.file
+	l.file, l.buf = nil, nil
+	l.unsynced = 0
+	l.useSegment(seg)
+	var errClose error
+	if l.syncsOnRotate() {
+		errClose = osSyncFile(old)
+	}
+	if err := old.Close(); errClose == nil {
+		errClose = err
+	}
+
+	if !l.retireBackup(seg.backup) {
+		l.mill()
+	}
+	return errClose
+}
+
+// retireBackup moves a freshly rotated backup into BackupDir in the
+// background and signals the mill once it has arrived, so that a
+// cross-device copy never holds l.mu. It reports whether a move was started;
+// Close waits for it through l.retiring. It expects l.mu to be held.
+func (l *Logger) retireBackup(backup string) bool {
+	if backup == "" || filepath.Dir(backup) == l.archiveDir() {
+		return false
+	}
+	l.retiring.Add(1)
+	go func() {
+		defer l.retiring.Done()
+		l.archiveBackup(backup)
+		l.mill()
+	}()
+	return true
+}
+
 // openNew creates a new log file for writing.
 // If an old log file already exists, it is moved aside by renaming it with a timestamp.
 // This method assumes that l.mu is held and the old file (if any) has already been closed.
 // The reasonForBackup parameter is used in the backup filename.
 func (l *Logger) openNew(reasonForBackup string) error {
+	seg, err := l.newSegment(reasonForBackup, l.streamCompress())
+	if err != nil {
+		return err
+	}
+	l.useSegment(seg)
+	l.retireBackup(seg.backup)
+	return nil
+}
+
+// segment is a freshly created log file, ready to become the active file.
+type segment struct {
+	file      *os.File
+	startTime time.Time // start time of the logging period the file covers
+	backup    string    // path the previous file was renamed to, if any
+	reason    string    // rotation reason encoded in the backup's name
+}
+
+// newSegment moves the existing log file (if any) aside to its backup name and
+// creates a new, empty file in its place. It does not modify the Logger's active
+// file, so it may run without l.mu held once the backup time format is validated.
+// With stream, the existing file is compressed into its backup and then
+// truncated instead, so it must not be written to meanwhile.
+func (l *Logger) newSegment(reasonForBackup string, stream bool) (segment, error) {
 	err := os.MkdirAll(l.dir(), 0755)
 	if err != nil {
-		return fmt.Errorf("can't make directories for new logfile: %s", err)
+		return segment{}, fmt.Errorf("can't make directories for new logfile: %w", err)
+	}
+	if err := os.MkdirAll(l.backupDir(), 0755); err != nil {
+		return segment{}, fmt.Errorf("can't make backup directory: %w", err)
 	}
 
 	name := l.filename()
 	finalMode := os.FileMode(0600)
 	var oldInfo os.FileInfo
+	var startTime time.Time
+	var backup string
 
 	info, err := osStat(name)
 	if err == nil {
 		oldInfo = info
 		finalMode = oldInfo.Mode()
 
-		rotationTimeForBackup := currentTime()
-
-		if !l.isBackupTimeFormatValidated {
-			// a backup format has been supplied.
-			validationErr := l.ValidateBackupTimeFormat()
-			if validationErr != nil {
-				// some validation issue.
-				// backup format is empty or invalid.
-				// use backupformat constant
-				l.BackupTimeFormat = backupTimeFormat
-				fmt.Fprintf(os.Stderr, "timberjack: invalid BackupTimeFormat: %v — falling back to default format: %s\n", validationErr, backupTimeFormat)
+		rotationTimeForBackup := l.now()
+
+		l.validateBackupTimeFormatOnce()
+
+		if l.NumberedBackups {
+			if err := l.shiftNumberedBackups(); err != nil {
+				return segment{}, err
 			}
-			// mark the backup format as validated if there was no error.
-			// this would prevent validation checks in every rotation
-			l.isBackupTimeFormatValidated = true
 		}
-
-		newname := backupName(name, l.LocalTime, reasonForBackup, rotationTimeForBackup, l.BackupTimeFormat)
-		if errRename := osRename(name, newname); errRename != nil {
-			return fmt.Errorf("can't rename log file: %s", errRename)
+		newname := l.uniqueBackupPath(l.backupPath(name, reasonForBackup, rotationTimeForBackup))
+		if stream && info.Size() >= l.CompressMinSize {
+			dst := l.compressedName(newname)
+			if errStream := l.streamBackup(name, dst); errStream == nil {
+				backup = dst
+			} else {
+				fmt.Fprintf(os.Stderr, "timberjack: [%s] failed to compress log file into %s, renaming it instead: %v\n", l.Filename, dst, errStream)
+			}
 		}
-		l.logStartTime = rotationTimeForBackup
+		if backup == "" {
+			if errRename := osRename(name, newname); errRename != nil {
+				return segment{}, fmt.Errorf("can't rename log file: %w", errRename)
+			}
+			backup = newname
+		}
+		startTime = rotationTimeForBackup
 	} else if os.IsNotExist(err) {
-		l.logStartTime = currentTime()
+		startTime = l.now()
 		oldInfo = nil
 	} else {
-		return fmt.Errorf("failed to stat log file %s: %w", name, err)
+		return segment{}, fmt.Errorf("failed to stat log file %s: %w", name, err)
 	}
 
 	// Create and open the new log file at path `name`.
 	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, finalMode)
 	if err != nil {
-		return fmt.Errorf("can't open new logfile %s: %s", name, err)
+		return segment{}, fmt.Errorf("can't open new logfile %s: %w", name, err)
+	}
+	if err := l.preallocate(f); err != nil {
+		f.Close()
+		return segment{}, err
 	}
-	l.file = f
-	l.size = 0
 
 	// Now that the new file `name` is created, if there was an old file, try to chown the new one.
 	if oldInfo != nil {
@@ -548,9 +1663,61 @@ func (l *Logger) openNew(reasonForBackup string) error {
 			fmt.Fprintf(os.Stderr, "timberjack: [%s] failed to chown new log file %s: %v\n", l.Filename, name, errChown)
 		}
 	}
+	if l.Durable {
+		if err := l.syncSegmentDirs(backup); err != nil {
+			f.Close()
+			return segment{}, fmt.Errorf("can't sync directory of new logfile %s: %w", name, err)
+		}
+	}
+	return segment{file: f, startTime: startTime, backup: backup, reason: reasonForBackup}, nil
+}
+
+// syncSegmentDirs fsyncs the directory of the log file, and that of backup
+// if it is elsewhere, so that the rename and creation of a rotation survive
+// a power loss.
+func (l *Logger) syncSegmentDirs(backup string) error {
+	if err := osSyncDir(l.dir()); err != nil {
+		return err
+	}
+	if backup != "" && filepath.Dir(backup) != l.dir() {
+		return osSyncDir(filepath.Dir(backup))
+	}
 	return nil
 }
 
+// useSegment makes seg the active log file. It expects l.mu to be held and
+// the previous file (if any) to be closed.
+func (l *Logger) useSegment(seg segment) {
+	l.file = seg.file
+	l.size = 0
+	l.logStartTime = seg.startTime
+	l.resetChecksum(0)
+	if seg.backup != "" {
+		l.linkSegment(seg.backup, seg.reason)
+		l.dropCache(seg.backup)
+	}
+}
+
+// validateBackupTimeFormatOnce validates BackupTimeFormat the first time a
+// backup name is needed, falling back to the default layout if it is invalid.
+func (l *Logger) validateBackupTimeFormatOnce() {
+	if l.isBackupTimeFormatValidated {
+		return
+	}
+	// a backup format has been supplied.
+	validationErr := l.ValidateBackupTimeFormat()
+	if validationErr != nil {
+		// some validation issue.
+		// backup format is empty or invalid.
+		// use backupformat constant
+		l.BackupTimeFormat = backupTimeFormat
+		fmt.Fprintf(os.Stderr, "timberjack: invalid BackupTimeFormat: %v — falling back to default format: %s\n", validationErr, backupTimeFormat)
+	}
+	// mark the backup format as validated if there was no error.
+	// this would prevent validation checks in every rotation
+	l.isBackupTimeFormatValidated = true
+}
+
 // shouldTimeRotate checks if the time-based rotation interval has elapsed
 // since the last rotation. This is used for RotationInterval logic.
 func (l *Logger) shouldTimeRotate() bool {
@@ -562,7 +1729,7 @@ func (l *Logger) shouldTimeRotate() bool {
 	if l.lastRotationTime.IsZero() {
 		return false
 	}
-	return currentTime().Sub(l.lastRotationTime) >= l.RotationInterval
+	return l.now().Sub(l.lastRotationTime) >= l.RotationInterval
 }
 
 // backupName creates a new backup filename by inserting a timestamp and a rotation reason
@@ -583,6 +1750,60 @@ func backupName(name string, local bool, reason string, t time.Time, fileTimeFor
 	return filepath.Join(dir, fmt.Sprintf("%s-%s-%s%s", prefix, timestamp, reason, ext))
 }
 
+// backupPath returns the path the log file name is renamed to when it is
+// rotated at t for reason, using BackupNameFunc if it is set.
+func (l *Logger) backupPath(name, reason string, t time.Time) string {
+	if l.NumberedBackups {
+		return numberedName(name, 1)
+	}
+	if l.BackupNameFunc != nil {
+		filename := filepath.Base(name)
+		ext := filepath.Ext(filename)
+		loc := time.UTC
+		if l.LocalTime {
+			loc = time.Local
+		}
+		custom := l.BackupNameFunc(filename[:len(filename)-len(ext)], t.In(loc), reason, ext)
+		if custom != "" && custom != filename && !strings.ContainsAny(custom, `/\`) {
+			return filepath.Join(filepath.Dir(name), custom)
+		}
+		fmt.Fprintf(os.Stderr, "timberjack: [%s] BackupNameFunc returned invalid name %q, using the default\n", l.Filename, custom)
+	}
+	if l.LumberjackCompat {
+		return l.lumberjackName(name, t)
+	}
+	return backupName(name, l.LocalTime, reason, t, l.BackupTimeFormat)
+}
+
+// ValidateBackupNamePattern checks that BackupNamePattern is set, and is a
+// valid pattern, when BackupNameFunc is.
+func (l *Logger) ValidateBackupNamePattern() error {
+	if l.BackupNameFunc == nil {
+		return nil
+	}
+	if l.BackupNamePattern == "" {
+		return errors.New("BackupNameFunc requires BackupNamePattern")
+	}
+	if _, err := filepath.Match(l.BackupNamePattern, ""); err != nil {
+		return fmt.Errorf("invalid BackupNamePattern %q: %w", l.BackupNamePattern, err)
+	}
+	return nil
+}
+
+// customBackup reports whether name is a backup named by BackupNameFunc,
+// matched by BackupNamePattern directly or once its compression suffix is
+// stripped.
+func (l *Logger) customBackup(name string) bool {
+	if l.BackupNameFunc == nil || l.BackupNamePattern == "" || name == filepath.Base(l.filename()) {
+		return false
+	}
+	if ok, _ := filepath.Match(l.BackupNamePattern, name); ok {
+		return true
+	}
+	ok, _ := filepath.Match(l.BackupNamePattern, l.trimCompressed(name))
+	return ok
+}
+
 // openExistingOrNew opens the existing logfile if it exists and the current write
 // would not cause it to exceed MaxSize. If the file does not exist, or if writing
 // would exceed MaxSize, the current file is rotated (if it exists) and a new logfile is created.
@@ -599,11 +1820,11 @@ func (l *Logger) openExistingOrNew(writeLen int) error {
 		return l.openNew("initial")
 	}
 	if err != nil {
-		return fmt.Errorf("error getting log file info: %s", err)
+		return fmt.Errorf("error getting log file info: %w", err)
 	}
 
 	// Check if rotation is needed due to size before opening/appending.
-	if info.Size()+int64(writeLen) >= l.max() {
+	if info.Size()+int64(writeLen) >= l.max() && (info.Size() > 0 || int64(writeLen) <= l.max()) {
 		return l.rotate("size") // This rotation is explicitly due to "size"
 	}
 
@@ -615,6 +1836,7 @@ func (l *Logger) openExistingOrNew(writeLen int) error {
 	}
 	l.file = file
 	l.size = info.Size()
+	l.resetChecksum(l.size)
 	// Note: l.logStartTime is NOT updated here if we successfully open an existing file without rotating.
 	// It retains its value from when this current log segment was created (by a previous openNew).
 	// l.lastRotationTime is also NOT updated here; it's handled by rotation trigger logic.
@@ -623,32 +1845,176 @@ func (l *Logger) openExistingOrNew(writeLen int) error {
 
 // filename returns the current log filename, using the configured Filename,
 // or a default based on the process name if Filename is empty.
+// On Windows, paths longer than MAX_PATH are returned in extended-length form.
 func (l *Logger) filename() string {
 	if l.Filename != "" {
-		return l.Filename
+		return normalizePath(l.Filename)
 	}
 	name := filepath.Base(os.Args[0]) + "-timberjack.log"
-	return filepath.Join(os.TempDir(), name)
+	return normalizePath(filepath.Join(os.TempDir(), name))
+}
+
+// removeBackups removes the files planned for removal by plan, passing
+// failures to report, and returns how many were removed. Files already gone
+// count as removed. It expects l.millMu and the numbering lock to be held.
+func (l *Logger) removeBackups(plan millPlan, files []logInfo, report func(name string, err error)) int {
+	removed, pruned := 0, 0
+	for _, f := range files {
+		errRemove := osRemove(filepath.Join(l.backupDir(), f.Name()))
+		if errRemove != nil && !os.IsNotExist(errRemove) { // Report the failure unless the file was already gone
+			report(f.Name(), errRemove)
+			continue
+		}
+		if rule := plan.rules[f.Name()]; rule != "Compress" { // the compressed form lives on
+			l.forgetBackup(f.Name())
+			l.emit(Event{Type: EventRemoval, File: filepath.Join(l.backupDir(), f.Name()), Reason: rule})
+			pruned++
+		}
+		l.removeEmptyLayoutDirs(f.Name())
+		removed++
+	}
+	l.countRemovals(pruned)
+	return removed
 }
 
 // millRunOnce performs one cycle of compression and removal of old log files.
 // If compression is enabled, uncompressed backups are compressed using gzip.
 // Old backup files are deleted to enforce MaxBackups and MaxAge limits.
 func (l *Logger) millRunOnce() error {
-	if l.MaxBackups == 0 && l.MaxAge == 0 && !l.Compress {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+
+	l.repairOrphans()
+
+	if !l.cleanupEnabled() {
 		return nil // Nothing to do if all cleanup options are disabled.
 	}
 
+	// Numbered backups must not be shifted between listing and removal.
+	unlockNumbering := l.lockNumbering()
 	files, err := l.oldLogFiles() // Gets LogInfo structs, sorted newest first by timestamp
 	if err != nil {
+		unlockNumbering()
 		return err
 	}
 
-	var filesToProcess = files  // Start with all found old log files
-	var filesToRemove []logInfo // Accumulates files to be deleted
+	plan := l.planMill(files)
+	finalUniqueRemovals := plan.remove
+	l.compressDue = plan.compressDue
+
+	// Execute removals
+	// Oldest files go first, so a MaxRemovalsPerPass cap defers the newest ones.
+	l.removalsPending = 0
+	if l.MaxRemovalsPerPass > 0 && len(finalUniqueRemovals) > l.MaxRemovalsPerPass {
+		l.removalsPending = len(finalUniqueRemovals) - l.MaxRemovalsPerPass
+		finalUniqueRemovals = finalUniqueRemovals[:l.MaxRemovalsPerPass]
+	}
+	removed := l.removeBackups(plan, finalUniqueRemovals, func(name string, err error) {
+		l.backgroundError("remove", err, "failed to remove old log file %s", name)
+	})
+	unlockNumbering()
+	if l.MaxRemovalsPerPass > 0 && removed > 0 {
+		l.emit(Event{Type: EventPruneProgress, File: l.filename(), Removed: removed, Remaining: l.removalsPending})
+	}
+
+	// Execute archiving and expire old archives
+	l.archiveBackups(plan.archive)
+	l.expireArchives()
+
+	// Execute compressions; failures are reported on stderr
+	if _, stopped := l.compressFiles(plan.compress); stopped != nil {
+		return stopped // the Logger's context ended; leave the rest for later
+	}
+
+	if l.Checksums {
+		l.updateChecksums()
+	}
+	return nil
+}
+
+// compressFiles compresses the backups in files, up to CompressConcurrency
+// at a time, reporting failures on stderr. It returns the first failure, and
+// the Logger's context error if it stopped early because it ended.
+func (l *Logger) compressFiles(files []logInfo) (failed, stopped error) {
+	workers := make(chan struct{}, l.compressConcurrency())
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, f := range files {
+		if stopped = l.context().Err(); stopped != nil {
+			break
+		}
+		workers <- struct{}{}
+		wg.Add(1)
+		go func(f logInfo) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			fn := filepath.Join(l.backupDir(), f.Name())
+			errCompress := l.compressBackup(fn, l.compressedName(fn)) // fn is source, l.compressedName(fn) is dest
+			if errCompress != nil {
+				l.backgroundError("compress", errCompress, "failed to compress log file %s", f.Name())
+				mu.Lock()
+				if failed == nil {
+					failed = errCompress
+				}
+				mu.Unlock()
+			}
+		}(f)
+	}
+	wg.Wait()
+	return failed, stopped
+}
+
+// compressConcurrency returns the number of backups to compress in parallel.
+func (l *Logger) compressConcurrency() int {
+	if l.CompressConcurrency < 1 {
+		return 1
+	}
+	return l.CompressConcurrency
+}
+
+// cleanupEnabled reports whether any option requires cleanup passes.
+func (l *Logger) cleanupEnabled() bool {
+	return l.MaxBackups != 0 || l.MaxAge != 0 || l.MaxTotalSize > 0 || l.MinDiskFree != "" || l.Compress || l.ArchiveAfter > 0 || l.Checksums
+}
+
+// millPlan is the work of a cleanup pass.
+type millPlan struct {
+	remove   []logInfo         // oldest first, without duplicates
+	rules    map[string]string // the option removing each file in remove
+	archive  []logInfo         // to be bundled by ArchiveAfter
+	compress []logInfo
+
+	// compressDue is when the first compression deferred by CompressAfter
+	// becomes due, or zero if none was.
+	compressDue time.Time
+}
+
+// planMill decides which of the backups in files, sorted newest first, are
+// to be removed under the retention options, which are to be archived and
+// which are to be compressed. It expects l.millMu to be held.
+func (l *Logger) planMill(files []logInfo) millPlan {
+	var filesToRemove, filesToCompress []logInfo
+	var compressDue time.Time
+	rules := make(map[string]string)
+
+	// Backups matching KeepPatterns and pinned backups are exempt from pruning.
+	var kept []logInfo
+	files, kept = l.splitKept(files)
+
+	var filesToProcess = files // Start with all found old log files
+	l.sortPairs(filesToProcess)
+
+	// MaxBackups filtering with PairCountEach: keep the MaxBackups newest files
+	if l.MaxBackups > 0 && l.pairPolicy() == PairCountEach && len(filesToProcess) > l.MaxBackups {
+		for _, f := range filesToProcess[l.MaxBackups:] {
+			filesToRemove = append(filesToRemove, f)
+			rules[f.Name()] = "MaxBackups"
+		}
+		filesToProcess = filesToProcess[:l.MaxBackups]
+	}
 
 	// MaxBackups filtering: Keep files belonging to the MaxBackups newest distinct timestamps
-	if l.MaxBackups > 0 {
+	if l.MaxBackups > 0 && l.pairPolicy() == PairCountOnce {
 		uniqueTimestamps := make([]time.Time, 0)
 		timestampMap := make(map[time.Time]bool)
 		for _, f := range filesToProcess { // filesToProcess is sorted newest first
@@ -671,6 +2037,7 @@ func (l *Logger) millRunOnce() error {
 					filteredFiles = append(filteredFiles, f)
 				} else {
 					filesToRemove = append(filesToRemove, f) // Mark for removal
+					rules[f.Name()] = "MaxBackups"
 				}
 			}
 			filesToProcess = filteredFiles // Update filesToProcess for subsequent filters
@@ -681,7 +2048,7 @@ func (l *Logger) millRunOnce() error {
 	// MaxAge filtering (operates on files that passed MaxBackups filter)
 	if l.MaxAge > 0 {
 		diff := time.Duration(int64(24*time.Hour) * int64(l.MaxAge))
-		cutoff := currentTime().Add(-1 * diff)
+		cutoff := l.now().Add(-1 * diff)
 		var filteredFiles []logInfo // Files that pass this MaxAge filter
 		for _, f := range filesToProcess {
 			if f.timestamp.Before(cutoff) {
@@ -695,6 +2062,7 @@ func (l *Logger) millRunOnce() error {
 				}
 				if !isAlreadyMarked {
 					filesToRemove = append(filesToRemove, f) // Mark for removal
+					rules[f.Name()] = "MaxAge"
 				}
 			} else {
 				filteredFiles = append(filteredFiles, f)
@@ -703,11 +2071,55 @@ func (l *Logger) millRunOnce() error {
 		filesToProcess = filteredFiles // Update filesToProcess for compression filter
 	}
 
+	// MaxTotalSize filtering: drop the oldest remaining files until the
+	// backups, including kept ones, fit.
+	if l.MaxTotalSize > 0 {
+		limit := int64(l.MaxTotalSize) * int64(megabyte)
+		var total int64
+		for _, f := range filesToProcess {
+			total += f.Size()
+		}
+		for _, f := range kept {
+			total += f.Size()
+		}
+		n := len(filesToProcess)
+		for n > 0 && total > limit {
+			n-- // filesToProcess is sorted newest first
+			total -= filesToProcess[n].Size()
+			filesToRemove = append(filesToRemove, filesToProcess[n])
+			rules[filesToProcess[n].Name()] = "MaxTotalSize"
+		}
+		filesToProcess = filesToProcess[:n]
+	}
+
+	// MinDiskFree filtering: drop the oldest remaining files until enough
+	// space would be free, counting what the removals above free up.
+	if shortfall := l.diskFreeShortfall(); shortfall > 0 {
+		for _, f := range filesToRemove {
+			shortfall -= f.Size()
+		}
+		n := len(filesToProcess)
+		for n > 0 && shortfall > 0 {
+			n--
+			shortfall -= filesToProcess[n].Size()
+			filesToRemove = append(filesToRemove, filesToProcess[n])
+			rules[filesToProcess[n].Name()] = "MinDiskFree"
+		}
+		filesToProcess = filesToProcess[:n]
+	}
+
+	// ArchiveAfter: bundle the old files that survived retention.
+	var filesToArchive []logInfo
+	filesToProcess, filesToArchive = l.splitArchived(filesToProcess)
+
+	// Kept files are never removed but may still need compressing.
+	filesToProcess = append(filesToProcess, kept...)
+
 	// Compression task identification (operates on files that passed MaxBackups and MaxAge)
-	var filesToCompress []logInfo
 	if l.Compress {
+		plain := l.newestBackups(filesToProcess, l.UncompressedBackups)
 		for _, f := range filesToProcess { // These are files that are meant to be kept (not in filesToRemove yet)
-			if !strings.HasSuffix(f.Name(), compressSuffix) {
+			if !l.isCompressed(f.Name()) && !l.adoptedCompressed(f.Name()) {
 				// Ensure this file isn't ALREADY marked for removal by a previous filter
 				// (e.g. MaxBackups removed it, but it also met MaxAge criteria before this loop)
 				// This check is somewhat redundant if filesToProcess is correctly filtered,
@@ -719,104 +2131,328 @@ func (l *Logger) millRunOnce() error {
 						break
 					}
 				}
-				if !isMarkedForFinalRemoval {
-					filesToCompress = append(filesToCompress, f)
+				if isMarkedForFinalRemoval {
+					continue
+				}
+				// A leftover uncompressed file is redundant once its
+				// compressed form is complete; otherwise compress it again.
+				if c := l.compressedForm(filesToProcess, f.Name()); c != "" && l.completeCompressed(filepath.Join(l.backupDir(), c), f.Size()) {
+					filesToRemove = append(filesToRemove, f)
+					rules[f.Name()] = "Compress"
+					continue
 				}
+				// The UncompressedBackups newest backups stay plain text.
+				if plain[f.Name()] {
+					continue
+				}
+				// So do backups too small to be worth compressing.
+				if f.Size() < l.CompressMinSize {
+					continue
+				}
+				// Leave backups in their CompressAfter grace period alone.
+				if due := f.timestamp.Add(l.CompressAfter); l.CompressAfter > 0 && l.now().Before(due) {
+					if compressDue.IsZero() || due.Before(compressDue) {
+						compressDue = due
+					}
+					continue
+				}
+				filesToCompress = append(filesToCompress, f)
 			}
 		}
 	}
 
-	// Execute removals (ensure unique removals)
-	finalUniqueRemovals := make(map[string]logInfo)
-	for _, f := range filesToRemove {
-		finalUniqueRemovals[f.Name()] = f
+	return millPlan{remove: uniqueOldestFirst(filesToRemove), rules: rules, archive: filesToArchive, compress: filesToCompress, compressDue: compressDue}
+}
+
+// millRun runs in a goroutine to manage post-rotation compression and removal
+// of old log files. It listens on millCh for signals to run millRunOnce, and
+// also runs it every CleanupInterval if that is set.
+// When MaxRemovalsPerPass leaves deletions pending, further passes are run
+// every RemovalPassInterval until the backlog is cleared.
+// The goroutine exits when millCh is closed or the Logger's Context ends.
+func (l *Logger) millRun() {
+	done := l.context().Done()
+	var janitor, deferred <-chan time.Time
+	if l.CleanupInterval > 0 {
+		ticker := time.NewTicker(l.CleanupInterval)
+		defer ticker.Stop()
+		janitor = ticker.C
 	}
-	for _, f := range finalUniqueRemovals {
-		errRemove := osRemove(filepath.Join(l.dir(), f.Name()))
-		if errRemove != nil && !os.IsNotExist(errRemove) { // Log error if removal failed and file wasn't already gone
-			fmt.Fprintf(os.Stderr, "timberjack: [%s] failed to remove old log file %s: %v\n", l.Filename, f.Name(), errRemove)
+	for {
+		select {
+		case _, ok := <-l.millCh:
+			if !ok {
+				return // Loop terminates when millCh is closed
+			}
+		case <-janitor:
+		case <-deferred:
+		case <-done:
+			return
 		}
+		l.millRunReported()
+		for l.pendingRemovals() > 0 {
+			select {
+			case _, ok := <-l.millCh:
+				if !ok {
+					return
+				}
+			case <-done:
+				return
+			case <-time.After(l.removalPassInterval()):
+			}
+			l.millRunReported()
+		}
+		deferred = l.deferredCompression()
 	}
+}
+
+// millRunReported performs a cleanup pass in the mill goroutine, reporting
+// its failure as a background error unless the Logger's context ended.
+func (l *Logger) millRunReported() {
+	if err := l.millRunOnce(); err != nil && l.context().Err() == nil {
+		l.backgroundError("cleanup", err, "cleanup failed")
+	}
+}
+
+// deferredCompression returns a channel that fires when the first
+// compression deferred by CompressAfter in the last pass becomes due, or nil
+// if there is none.
+func (l *Logger) deferredCompression() <-chan time.Time {
+	l.millMu.Lock()
+	due := l.compressDue
+	l.millMu.Unlock()
+	if due.IsZero() {
+		return nil
+	}
+	return time.After(due.Sub(l.now()))
+}
+
+// pendingRemovals returns the number of deletions deferred by the last pass.
+func (l *Logger) pendingRemovals() int {
+	l.millMu.Lock()
+	defer l.millMu.Unlock()
+	return l.removalsPending
+}
 
-	// Execute compressions
-	for _, f := range filesToCompress {
-		fn := filepath.Join(l.dir(), f.Name())
-		errCompress := compressLogFile(fn, fn+compressSuffix) // fn is source, fn+compressSuffix is dest
-		if errCompress != nil {
-			fmt.Fprintf(os.Stderr, "timberjack: [%s] failed to compress log file %s: %v\n", l.Filename, f.Name(), errCompress)
+// enforceOnOpen runs a cleanup pass in the calling goroutine the first time
+// it is called if EnforceOnOpen is set. It must be called without l.mu held.
+func (l *Logger) enforceOnOpen() {
+	if !l.EnforceOnOpen || l.isClosed() {
+		return
+	}
+	l.enforceOnce.Do(func() {
+		if err := l.millRunOnce(); err != nil {
+			l.backgroundError("cleanup", err, "cleanup on open failed")
 		}
+	})
+}
+
+// removalPassInterval returns the delay between capped removal passes.
+func (l *Logger) removalPassInterval() time.Duration {
+	if l.RemovalPassInterval > 0 {
+		return l.RemovalPassInterval
 	}
-	return nil
+	return defaultRemovalPassInterval
 }
 
-// millRun runs in a goroutine to manage post-rotation compression and removal
-// of old log files. It listens on millCh for signals to run millRunOnce.
-func (l *Logger) millRun() {
-	for range l.millCh { // Loop terminates when millCh is closed
-		_ = l.millRunOnce()
+// newestBackups returns the names of the uncompressed forms of the n newest
+// backups in files.
+func (l *Logger) newestBackups(files []logInfo, n int) map[string]bool {
+	newest := make(map[string]bool, n)
+	if n <= 0 {
+		return newest
+	}
+	sorted := append([]logInfo(nil), files...)
+	sort.Stable(byFormatTime(sorted))
+	for _, f := range sorted {
+		if len(newest) == n {
+			break
+		}
+		newest[l.trimCompressed(f.Name())] = true
 	}
+	return newest
 }
 
-// mill performs post-rotation compression and removal of stale log files,
-// starting the mill goroutine if necessary and sending a signal to it.
-func (l *Logger) mill() {
+// uniqueOldestFirst returns files without duplicate names, sorted oldest first.
+func uniqueOldestFirst(files []logInfo) []logInfo {
+	seen := make(map[string]bool, len(files))
+	unique := make([]logInfo, 0, len(files))
+	for _, f := range files {
+		if !seen[f.Name()] {
+			seen[f.Name()] = true
+			unique = append(unique, f)
+		}
+	}
+	sort.Sort(sort.Reverse(byFormatTime(unique)))
+	return unique
+}
+
+// startMillLoop starts the mill goroutine if it isn't running yet.
+func (l *Logger) startMillLoop() {
 	l.startMill.Do(func() {
 		l.millCh = make(chan bool, 1) // Buffered channel of 1
-		go l.millRun()
+		l.millWg.Add(1)
+		go func() {
+			defer l.millWg.Done()
+			l.millRun()
+		}()
 	})
+}
+
+// mill performs post-rotation compression and removal of stale log files,
+// starting the mill goroutine if necessary and sending a signal to it, or
+// queueing the work on the Logger's Manager.
+func (l *Logger) mill() {
+	if l.manager != nil {
+		l.manager.schedule(l)
+		return
+	}
+	l.startMillLoop()
 	select {
 	case l.millCh <- true: // Send signal to run millRunOnce
 	default: // Don't block if channel is full (mill is already busy)
 	}
 }
 
-// oldLogFiles returns the list of backup log files stored in the same
-// directory as the current log file, sorted by their embedded timestamp (newest first).
+// oldLogFiles returns the list of backup log files stored in the backup
+// directory (by default the directory of the current log file), sorted by their embedded timestamp (newest first).
 func (l *Logger) oldLogFiles() ([]logInfo, error) {
-	entries, err := os.ReadDir(l.dir()) // ReadDir is generally preferred over ReadFile for directory listings
+	entries, err := l.backupEntries() // includes BackupDirLayout subdirectories
 	if err != nil {
 		return nil, fmt.Errorf("can't read log file directory: %s", err)
 	}
 	var logFiles []logInfo
+	var numbered []logInfo // numbered backups (NumberedBackups)
+	var numbers []int
 
 	prefix, ext := l.prefixAndExt() // Get prefix like "filename-" and original extension like ".log"
 
 	for _, e := range entries {
-		if e.IsDir() { // Skip directories
+		// Names are matched without the layout subdirectory; info.Name()
+		// keeps it so that the file can be found again.
+		name := filepath.Base(e.rel)
+		info := e.info
+
+		// Classic numbered backups ("filename.log.1", "filename.log.2.gz"), if NumberedBackups is set.
+		if n, ok := l.backupNumber(name); ok {
+			numbered = append(numbered, logInfo{info.ModTime(), info})
+			numbers = append(numbers, n)
 			continue
 		}
-		name := e.Name()
-		info, errInfo := e.Info() // Get FileInfo for modification time and other details
-		if errInfo != nil {
-			// fmt.Fprintf(os.Stderr, "timberjack: failed to get FileInfo for %s: %v\n", name, errInfo)
-			continue // Skip files we can't stat
-		}
-
 		// Attempt to parse timestamp from filename (e.g., from "filename-timestamp-reason.log")
 		if t, errTime := l.timeFromName(name, prefix, ext); errTime == nil {
 			logFiles = append(logFiles, logInfo{t, info})
 			continue
 		}
 		// Attempt to parse timestamp from compressed filename (e.g., from "filename-timestamp-reason.log.gz")
-		if t, errTime := l.timeFromName(name, prefix, ext+compressSuffix); errTime == nil {
+		if t, errTime := l.timeFromName(name, prefix, ext+l.compressedSuffix(name)); l.isCompressed(name) && errTime == nil {
+			logFiles = append(logFiles, logInfo{t, info})
+			continue
+		}
+		// Lumberjack-style backups, if LumberjackCompat is set.
+		if t, ok := l.lumberjackBackupTime(name); ok {
 			logFiles = append(logFiles, logInfo{t, info})
 			continue
 		}
+		// Foreign backups from a previous logging system, if adoption is enabled,
+		// and backups named by BackupNameFunc.
+		if l.adopted(name) || l.customBackup(name) {
+			logFiles = append(logFiles, logInfo{info.ModTime(), info})
+			continue
+		}
 		// Files that don't match the expected backup pattern are ignored.
 	}
+	logFiles = append(logFiles, numberedLogFiles(numbered, numbers)...)
 
 	sort.Sort(byFormatTime(logFiles)) // Sorts newest first based on parsed timestamp
 	return logFiles, nil
 }
 
+// adopted reports whether name is a foreign backup matched by AdoptPatterns.
+// A compressed copy of an adopted file (name plus a compression suffix) is adopted too,
+// so files stay managed after the mill compresses them.
+func (l *Logger) adopted(name string) bool {
+	if !l.AdoptExisting || name == filepath.Base(l.filename()) {
+		return false
+	}
+	for _, pattern := range l.AdoptPatterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, l.trimCompressed(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultAdoptCompressed are the suffixes of adopted files recognized as
+// compressed when AdoptCompressed is nil.
+var defaultAdoptCompressed = []string{".bz2", ".xz", ".lzma", ".Z", ".zip", ".br"}
+
+// adoptedCompressed reports whether name is an adopted file that is already
+// compressed in a foreign format, and so must not be compressed again.
+func (l *Logger) adoptedCompressed(name string) bool {
+	if !l.adopted(name) {
+		return false
+	}
+	suffixes := l.AdoptCompressed
+	if suffixes == nil {
+		suffixes = defaultAdoptCompressed
+	}
+	for _, suffix := range suffixes {
+		if suffix != "" && strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// kept reports whether name matches one of KeepPatterns, directly or once
+// its compression suffix is stripped.
+func (l *Logger) kept(name string) bool {
+	name = filepath.Base(name)
+	for _, pattern := range l.KeepPatterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, l.trimCompressed(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitKept separates the backups matching KeepPatterns and the pinned
+// backups from the rest, preserving their order.
+func (l *Logger) splitKept(files []logInfo) (prunable, kept []logInfo) {
+	for _, f := range files {
+		if l.kept(f.Name()) || l.pinned(filepath.Join(l.backupDir(), f.Name())) {
+			kept = append(kept, f)
+		} else {
+			prunable = append(prunable, f)
+		}
+	}
+	return prunable, kept
+}
+
 // timeFromName extracts the formatted timestamp from the backup filename.
 // It expects filenames like "prefix-YYYY-MM-DDTHH-MM-SS.mmm-reason.ext" or "...ext.gz".
 func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, error) {
+	t, _, err := l.parseBackupName(filename, prefix, ext)
+	return t, err
+}
+
+// parseBackupName splits a backup filename into its timestamp and rotation reason.
+// It expects filenames like "prefix-YYYY-MM-DDTHH-MM-SS.mmm-reason.ext", or
+// "prefix-YYYY-MM-DDTHH-MM-SS.mmm-reason.N.ext" for the Nth backup whose name
+// collided with an existing one.
+func (l *Logger) parseBackupName(filename, prefix, ext string) (time.Time, string, error) {
 	if !strings.HasPrefix(filename, prefix) {
-		return time.Time{}, errors.New("mismatched prefix")
+		return time.Time{}, "", errors.New("mismatched prefix")
 	}
 	if !strings.HasSuffix(filename, ext) {
-		return time.Time{}, errors.New("mismatched extension")
+		return time.Time{}, "", errors.New("mismatched extension")
 	}
 
 	// Remove prefix and suffix to get "YYYY-MM-DDTHH-MM-SS.mmm-reason"
@@ -825,10 +2461,11 @@ func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, error) {
 	// The timestamp is before the last hyphen (which precedes the reason).
 	lastHyphenIdx := strings.LastIndex(trimmed, "-")
 	if lastHyphenIdx == -1 {
-		return time.Time{}, fmt.Errorf("malformed backup filename: missing reason separator in '%s'", trimmed)
+		return time.Time{}, "", fmt.Errorf("malformed backup filename: missing reason separator in '%s'", trimmed)
 	}
 
 	timestampPart := trimmed[:lastHyphenIdx]
+	reason, seq := splitSequence(trimmed[lastHyphenIdx+1:])
 
 	// Determine location (UTC or Local) based on Logger's LocalTime setting for parsing.
 	currentLoc := time.UTC
@@ -840,11 +2477,30 @@ func (l *Logger) timeFromName(filename, prefix, ext string) (time.Time, error) {
 	if layout == "" {
 		layout = backupTimeFormat
 	}
-	return time.ParseInLocation(layout, timestampPart, currentLoc)
+	t, err := time.ParseInLocation(layout, timestampPart, currentLoc)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	// A sequence-numbered backup sorts after the one it collided with.
+	return t.Add(time.Duration(seq)), reason, nil
+}
+
+// backupReason returns the rotation reason encoded in the name of a backup,
+// whether or not it is compressed. It is empty for adopted foreign backups.
+func (l *Logger) backupReason(name string) string {
+	prefix, ext := l.prefixAndExt()
+	_, reason, err := l.parseBackupName(l.trimCompressed(filepath.Base(name)), prefix, ext)
+	if err != nil {
+		return ""
+	}
+	return reason
 }
 
 // max returns the maximum size in bytes of log files before rolling.
 func (l *Logger) max() int64 {
+	if l.MaxSize == Unlimited {
+		return math.MaxInt64
+	}
 	if l.MaxSize == 0 { // If MaxSize is 0, use default.
 		return int64(defaultMaxSize * megabyte)
 	}
@@ -914,50 +2570,89 @@ func truncateFractional(t time.Time, n int) (time.Time, error) {
 // compressLogFile compresses the given source log file (src) to a destination file (dst),
 // removing the source file if compression is successful.
 func compressLogFile(src, dst string) error {
+	return compressLogFileContext(context.Background(), gzipCodec{}, src, dst)
+}
+
+// compressLogFileContext is like compressLogFile, but gives up, removing the
+// partial destination file, once ctx is done.
+func compressLogFileContext(ctx context.Context, c Compressor, src, dst string) error {
+	tmp, srcInfo, err := compressToTemp(ctx, c, src, dst)
+	if err != nil {
+		return err
+	}
+	return finishCompression(src, dst, tmp, srcInfo)
+}
+
+// compressToTemp writes the compressed content of src to a temporary file
+// next to dst, so that an interrupted compression never leaves a truncated
+// dst behind. It returns the temporary's name and src's FileInfo.
+func compressToTemp(ctx context.Context, c Compressor, src, dst string) (string, os.FileInfo, error) {
+	return compressToFile(ctx, c, src, dst, dst+tempSuffix)
+}
+
+// compressToFile is like compressToTemp, with the temporary named tmp.
+func compressToFile(ctx context.Context, c Compressor, src, dst, tmp string) (string, os.FileInfo, error) {
 	srcFile, err := os.Open(src)
 	if err != nil {
-		return fmt.Errorf("failed to open source log file %s for compression: %v", src, err)
+		return "", nil, fmt.Errorf("failed to open source log file %s for compression: %v", src, err)
 	}
 	defer srcFile.Close()
 
 	srcInfo, err := osStat(src) // Get FileInfo of the source to use its mode for the new compressed file
 	if err != nil {
-		return fmt.Errorf("failed to stat source log file %s: %v", src, err)
+		return "", nil, fmt.Errorf("failed to stat source log file %s: %v", src, err)
 	}
 
-	// Create or open the destination file for writing the compressed content
-	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, srcInfo.Mode())
+	dstFile, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, srcInfo.Mode())
 	if err != nil {
-		return fmt.Errorf("failed to open destination compressed log file %s: %v", dst, err)
+		return "", nil, fmt.Errorf("failed to open destination compressed log file %s: %v", dst, err)
 	}
 	// No `defer dstFile.Close()` here, explicit closing in sequence is critical.
 
-	gzWriter := gzip.NewWriter(dstFile)
-
-	// Copy data from source file to gzip writer
-	if _, err = io.Copy(gzWriter, srcFile); err != nil {
-		// Error during copy. Attempt to clean up.
-		_ = gzWriter.Close() // Try to close gzip writer
-		_ = dstFile.Close()  // Try to close destination file
-		_ = osRemove(dst)    // Try to remove potentially partial destination file
-		return fmt.Errorf("failed to copy data to gzip writer for %s: %w", dst, err)
+	// Compress the source into the temporary. The codec finishes its
+	// stream, flushing the compressed data to dstFile, before returning.
+	// The backup's name is that of dst without the codec's suffix, as src
+	// may be the log file itself (StreamCompress).
+	name := strings.TrimSuffix(filepath.Base(dst), c.Suffix())
+	source := backupReader{Reader: contextReader{ctx, srcFile}, name: name, modTime: srcInfo.ModTime()}
+	if err = c.Compress(dstFile, source); err != nil {
+		// Error during compression. Attempt to clean up.
+		_ = dstFile.Close() // Try to close destination file
+		_ = osRemove(tmp)   // Try to remove potentially partial destination file
+		return "", nil, fmt.Errorf("failed to copy data to compressor for %s: %w", dst, err)
 	}
 
-	// IMPORTANT: Close the gzip.Writer first. This flushes the compressed data
-	// to the underlying writer (dstFile's OS buffer).
-	if err = gzWriter.Close(); err != nil {
-		_ = dstFile.Close() // Try to close destination file
-		_ = osRemove(dst)   // Try to remove destination file
-		return fmt.Errorf("failed to close gzip writer for %s: %w", dst, err)
+	// Make the content durable before the temporary is renamed into place,
+	// so that a crash never leaves a truncated backup under its final name.
+	if err = dstFile.Sync(); err != nil {
+		_ = dstFile.Close()
+		_ = osRemove(tmp)
+		return "", nil, fmt.Errorf("failed to sync compressed file %s: %w", dst, err)
 	}
 
 	// IMPORTANT: Now, close the destination file itself. This flushes the OS buffers
 	// to disk, ensuring the file content is complete and persisted.
 	if err = dstFile.Close(); err != nil {
-		// Data is likely written and gzWriter closed successfully, but closing the file descriptor failed.
+		// Data is likely written and the codec finished successfully, but closing the file descriptor failed.
 		// The destination file might still be valid on disk. We typically wouldn't remove dst here
 		// as the data might be recoverable or fully written despite the close error.
-		return fmt.Errorf("failed to close destination compressed file %s: %w", dst, err)
+		return "", nil, fmt.Errorf("failed to close destination compressed file %s: %w", dst, err)
+	}
+	return tmp, srcInfo, nil
+}
+
+// finishCompression moves the complete compressed temporary tmp of src into
+// place at dst and removes src.
+func finishCompression(src, dst, tmp string, srcInfo os.FileInfo) error {
+	// The compressed file is complete; move it into place.
+	if err := os.Rename(tmp, dst); err != nil {
+		_ = osRemove(tmp)
+		return fmt.Errorf("failed to rename compressed file to %s: %w", dst, err)
+	}
+
+	// The source is only removed once the rename is durable too.
+	if err := osSyncDir(filepath.Dir(dst)); err != nil {
+		return fmt.Errorf("failed to sync directory of compressed file %s: %w", dst, err)
 	}
 
 	// If all writes and file/writer closures were successful, now attempt to chown the destination file.
@@ -973,7 +2668,7 @@ func compressLogFile(src, dst string) error {
 	}
 
 	// Finally, after successful compression and closing (and optional chown), remove the original source file.
-	if err = osRemove(src); err != nil {
+	if err := osRemove(src); err != nil {
 		// This is a more significant error if the original isn't removed, as it might be re-processed.
 		return fmt.Errorf("failed to remove original source log file %s after compression: %w", src, err)
 	}
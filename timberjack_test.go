@@ -613,6 +613,8 @@ func TestCompressOnRotate(t *testing.T) {
 
 	existsWithContent(filename, b, t)
 	fileCount(dir, 1, t)
+	info, err := os.Stat(filename)
+	isNil(err, t)
 
 	newFakeTime()
 
@@ -628,9 +630,12 @@ func TestCompressOnRotate(t *testing.T) {
 	<-time.After(300 * time.Millisecond)
 
 	// a compressed version of the log file should now exist and the original
-	// should have been removed.
+	// should have been removed. Its header records the backup's name and
+	// modification time.
 	bc := new(bytes.Buffer)
 	gz := gzip.NewWriter(bc)
+	gz.Name = filepath.Base(backupFileWithReason(dir, "size"))
+	gz.ModTime = info.ModTime()
 	_, err = gz.Write(b)
 	isNil(err, t)
 	err = gz.Close()
@@ -663,6 +668,8 @@ func TestCompressOnResume(t *testing.T) {
 	isNil(err, t)
 	err = os.WriteFile(filename2+compressSuffix, []byte{}, 0644)
 	isNil(err, t)
+	info, err := os.Stat(filename2)
+	isNil(err, t)
 
 	newFakeTime()
 
@@ -680,6 +687,8 @@ func TestCompressOnResume(t *testing.T) {
 	// the log file should now exist and the original should have been removed.
 	bc := new(bytes.Buffer)
 	gz := gzip.NewWriter(bc)
+	gz.Name = filepath.Base(filename2)
+	gz.ModTime = info.ModTime()
 	_, err = gz.Write(b)
 	isNil(err, t)
 	err = gz.Close()
@@ -2512,3 +2521,510 @@ func TestMillGoroutineCleanup(t *testing.T) {
 	// Wait briefly to allow goroutine shutdown
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestAdoptExisting(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestAdoptExisting", t)
+	defer os.RemoveAll(dir)
+
+	filename := logFile(dir)
+
+	// Backups left behind by a previous logging system.
+	old := time.Now().Add(-72 * time.Hour)
+	foreign1 := filepath.Join(dir, "foobar.log.1")
+	foreign2 := filepath.Join(dir, "foobar.log.2")
+	unrelated := filepath.Join(dir, "other.txt")
+	for i, name := range []string{foreign1, foreign2, unrelated} {
+		isNil(os.WriteFile(name, []byte("legacy"), 0644), t)
+		mtime := old.Add(-time.Duration(i) * time.Hour)
+		isNil(os.Chtimes(name, mtime, mtime), t)
+	}
+
+	l := &Logger{
+		Filename:      filename,
+		MaxSize:       10,
+		MaxBackups:    1,
+		Compress:      true,
+		AdoptExisting: true,
+		AdoptPatterns: []string{"foobar.log.*"},
+	}
+	defer l.Close()
+
+	files, err := l.oldLogFiles()
+	isNil(err, t)
+	equals(2, len(files), t)
+	// Sorted newest first by modification time.
+	equals("foobar.log.1", files[0].Name(), t)
+
+	isNil(l.millRunOnce(), t)
+
+	// The newest adopted backup is kept (and compressed), the older one pruned.
+	notExist(foreign2, t)
+	notExist(foreign1, t)
+	exists(foreign1+compressSuffix, t)
+	exists(unrelated, t)
+
+	// The compressed copy is still managed.
+	files, err = l.oldLogFiles()
+	isNil(err, t)
+	equals(1, len(files), t)
+	equals("foobar.log.1"+compressSuffix, files[0].Name(), t)
+}
+
+func TestAdoptExisting_ForeignCompressed(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestAdoptExisting_ForeignCompressed", t)
+	defer os.RemoveAll(dir)
+
+	bz2 := filepath.Join(dir, "foobar.log.1.bz2")
+	xz := filepath.Join(dir, "foobar.log.2.xz")
+	plain := filepath.Join(dir, "foobar.log.3")
+	for _, name := range []string{bz2, xz, plain} {
+		isNil(os.WriteFile(name, []byte("legacy"), 0644), t)
+	}
+
+	l := &Logger{
+		Filename:      logFile(dir),
+		Compress:      true,
+		AdoptExisting: true,
+		AdoptPatterns: []string{"foobar.log.*"},
+	}
+	defer l.Close()
+
+	isNil(l.millRunOnce(), t)
+	existsWithContent(bz2, []byte("legacy"), t)
+	existsWithContent(xz, []byte("legacy"), t)
+	notExist(bz2+compressSuffix, t)
+	notExist(xz+compressSuffix, t)
+	notExist(plain, t)
+	exists(plain+compressSuffix, t)
+
+	// A custom list replaces the default one.
+	l.AdoptCompressed = []string{".xz"}
+	isNil(l.CompressPending(), t)
+	notExist(bz2, t)
+	exists(bz2+compressSuffix, t)
+	existsWithContent(xz, []byte("legacy"), t)
+}
+
+func TestAdoptExisting_Disabled(t *testing.T) {
+	currentTime = fakeTime
+
+	dir := makeTempDir("TestAdoptExisting_Disabled", t)
+	defer os.RemoveAll(dir)
+
+	isNil(os.WriteFile(filepath.Join(dir, "foobar.log.1"), []byte("legacy"), 0644), t)
+
+	l := &Logger{
+		Filename:      logFile(dir),
+		AdoptPatterns: []string{"foobar.log*"},
+	}
+	defer l.Close()
+
+	files, err := l.oldLogFiles()
+	isNil(err, t)
+	equals(0, len(files), t)
+
+	// The active log file itself is never adopted.
+	isNil(os.WriteFile(logFile(dir), []byte("active"), 0644), t)
+	l.AdoptExisting = true
+	files, err = l.oldLogFiles()
+	isNil(err, t)
+	equals(1, len(files), t)
+	equals("foobar.log.1", files[0].Name(), t)
+}
+
+func TestHandleScheduledMark_MissedMarksRotateOnce(t *testing.T) {
+	mark := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	// Woke up 95 minutes late: the 10:30, 11:00 and 11:30 marks were missed.
+	now := mark.Add(95 * time.Minute)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = fakeTime }()
+
+	dir := t.TempDir()
+	l := &Logger{
+		Filename: filepath.Join(dir, "missed.log"),
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("before\n"))
+	isNil(err, t)
+	l.processedRotateAtMinutes = []int{0, 30}
+	l.lastRotationTime = mark.Add(-time.Hour)
+	events := l.Events()
+
+	l.handleScheduledMark(mark)
+
+	e := <-events
+	equals(EventMissedRotation, e.Type, t)
+	equals(3, e.Missed, t)
+	equals(now, l.lastRotationTime, t)
+
+	files, err := os.ReadDir(dir)
+	isNil(err, t)
+	equals(2, len(files), t) // exactly one catch-up rotation
+}
+
+func TestHandleScheduledMark_MissedMarksSkip(t *testing.T) {
+	mark := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	now := mark.Add(45 * time.Minute)
+	currentTime = func() time.Time { return now }
+	defer func() { currentTime = fakeTime }()
+
+	dir := t.TempDir()
+	l := &Logger{
+		Filename:         filepath.Join(dir, "skip.log"),
+		MissedTickPolicy: MissedTickSkip,
+	}
+	defer l.Close()
+	_, err := l.Write([]byte("before\n"))
+	isNil(err, t)
+	l.processedRotateAtMinutes = []int{0, 30}
+	l.lastRotationTime = mark.Add(-time.Hour)
+	events := l.Events()
+
+	l.handleScheduledMark(mark)
+
+	e := <-events
+	equals(1, e.Missed, t)
+
+	files, err := os.ReadDir(dir)
+	isNil(err, t)
+	equals(1, len(files), t) // no rotation
+}
+
+func TestHandleScheduledMark_OnTime(t *testing.T) {
+	mark := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	currentTime = func() time.Time { return mark.Add(time.Second) }
+	defer func() { currentTime = fakeTime }()
+
+	l := &Logger{
+		Filename:                 filepath.Join(t.TempDir(), "ontime.log"),
+		processedRotateAtMinutes: []int{0, 30},
+	}
+	equals(0, l.missedMarks(mark, currentTime()), t)
+	equals(1, l.missedMarks(mark, mark.Add(30*time.Minute)), t)
+}
+
+func TestMaxRemovalsPerPass(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMaxRemovalsPerPass", t)
+	defer os.RemoveAll(dir)
+
+	var backups []string
+	for i := 0; i < 5; i++ {
+		name := backupFileWithReason(dir, "size")
+		isNil(os.WriteFile(name, []byte("data"), 0644), t)
+		backups = append(backups, name)
+		newFakeTime()
+	}
+
+	l := &Logger{
+		Filename:           logFile(dir),
+		MaxBackups:         1,
+		MaxRemovalsPerPass: 2,
+	}
+	defer l.Close()
+	events := l.Events()
+
+	isNil(l.millRunOnce(), t)
+	equals(EventRemoval, (<-events).Type, t)
+	equals(EventRemoval, (<-events).Type, t)
+	e := <-events
+	equals(EventPruneProgress, e.Type, t)
+	equals(2, e.Removed, t)
+	equals(2, e.Remaining, t)
+	// The oldest backups go first.
+	notExist(backups[0], t)
+	notExist(backups[1], t)
+	exists(backups[2], t)
+	fileCount(dir, 3, t)
+
+	isNil(l.millRunOnce(), t)
+	equals(EventRemoval, (<-events).Type, t)
+	equals(EventRemoval, (<-events).Type, t)
+	e = <-events
+	equals(2, e.Removed, t)
+	equals(0, e.Remaining, t)
+	fileCount(dir, 1, t)
+	exists(backups[4], t)
+}
+
+func TestMaxRemovalsPerPass_FollowUpPasses(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMaxRemovalsPerPass_FollowUpPasses", t)
+	defer os.RemoveAll(dir)
+
+	for i := 0; i < 4; i++ {
+		isNil(os.WriteFile(backupFileWithReason(dir, "size"), []byte("data"), 0644), t)
+		newFakeTime()
+	}
+
+	l := &Logger{
+		Filename:            logFile(dir),
+		MaxBackups:          1,
+		MaxRemovalsPerPass:  1,
+		RemovalPassInterval: 5 * time.Millisecond,
+	}
+	defer l.Close()
+
+	// A single trigger keeps running passes until the backlog is cleared.
+	l.mill()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		files, err := os.ReadDir(dir)
+		isNil(err, t)
+		if len(files) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected backlog to be pruned, %d files remain", len(files))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestKeepPatterns(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestKeepPatterns", t)
+	defer os.RemoveAll(dir)
+
+	// Three backups, oldest first; the oldest is a protected "time" segment.
+	protected := backupFileWithReason(dir, "time")
+	isNil(os.WriteFile(protected, []byte("protected"), 0644), t)
+	newFakeTime()
+	older := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(older, []byte("older"), 0644), t)
+	newFakeTime()
+	newest := backupFileWithReason(dir, "size")
+	isNil(os.WriteFile(newest, []byte("newest"), 0644), t)
+
+	l := &Logger{
+		Filename:     logFile(dir),
+		MaxBackups:   1,
+		MaxAge:       1,
+		Compress:     true,
+		KeepPatterns: []string{"*-time.log"},
+	}
+	defer l.Close()
+
+	// Move time on, so MaxAge expires every backup.
+	newFakeTime()
+	isNil(l.millRunOnce(), t)
+
+	notExist(older, t)
+	notExist(newest, t)
+	notExist(protected, t)
+	exists(protected+compressSuffix, t)
+
+	// The compressed file is still protected.
+	isNil(l.millRunOnce(), t)
+	exists(protected+compressSuffix, t)
+	fileCount(dir, 1, t)
+}
+
+func TestMaxSizeUnlimited(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMaxSizeUnlimited", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: Unlimited}
+	defer l.Close()
+
+	// Far beyond the 100 "megabyte" default with megabyte = 1.
+	big := bytes.Repeat([]byte("x"), 1000)
+	for i := 0; i < 3; i++ {
+		n, err := l.Write(big)
+		isNil(err, t)
+		equals(len(big), n, t)
+	}
+	fileCount(dir, 1, t)
+}
+
+func TestValidateMaxSize(t *testing.T) {
+	isNil((&Logger{}).ValidateMaxSize(), t)
+	isNil((&Logger{MaxSize: 10, RotationInterval: time.Hour}).ValidateMaxSize(), t)
+	isNil((&Logger{MaxSize: Unlimited, RotationInterval: time.Hour}).ValidateMaxSize(), t)
+	notNil((&Logger{MaxSize: -5}).ValidateMaxSize(), t)
+
+	for _, l := range []*Logger{
+		{RotationInterval: time.Hour},
+		{RotateAtMinutes: []int{0}},
+		{RotateAtTimes: []string{"00:00"}},
+		{RotationSchedule: "@daily"},
+		{RotationPeriod: RotationWeekly},
+	} {
+		equals(ErrAmbiguousMaxSize, l.ValidateMaxSize(), t)
+	}
+}
+
+func TestRotateWithReason(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestRotateWithReason", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+	events := l.Events()
+
+	b := []byte("boo!")
+	_, err := l.Write(b)
+	isNil(err, t)
+	isNil(l.RotateWithReason("deploy"), t)
+
+	existsWithContent(backupFileWithReason(dir, "deploy"), b, t)
+	e := <-events
+	equals(EventRotation, e.Type, t)
+	equals("deploy", e.Reason, t)
+
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(1, len(backups), t)
+	equals("deploy", backups[0].Reason, t)
+
+	for _, bad := range []string{"", "pre-deploy", "a.b", "a/b"} {
+		notNil(l.RotateWithReason(bad), t)
+	}
+	fileCount(dir, 2, t)
+}
+
+func TestMaxTotalSize(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMaxTotalSize", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxTotalSize: 10, BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	var names []string
+	for _, content := range []string{"one!", "two!", "six!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+		names = append(names, backupFileWithReason(dir, "size"))
+	}
+	<-time.After(10 * time.Millisecond)
+
+	// 12 bytes of backups exceed the 10 byte limit: the oldest goes.
+	notExist(names[0], t)
+	existsWithContent(names[1], []byte("two!"), t)
+	existsWithContent(names[2], []byte("six!"), t)
+}
+
+func TestMinDiskFree(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestMinDiskFree", t)
+	defer os.RemoveAll(dir)
+
+	// Pretend the disk has 100 bytes with 8 free: 10% asks for 2 more.
+	defer func(orig func(string) (uint64, uint64, error)) { diskSpace = orig }(diskSpace)
+	diskSpace = func(string) (uint64, uint64, error) { return 8, 100, nil }
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MinDiskFree: "10%", BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+
+	var names []string
+	for _, content := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+		names = append(names, backupFileWithReason(dir, "size"))
+	}
+	<-time.After(10 * time.Millisecond)
+
+	// Removing the oldest 4 byte backup is enough.
+	notExist(names[0], t)
+	existsWithContent(names[1], []byte("two!"), t)
+
+	for _, v := range []string{"500MB", "2g", "1048576", "15%", "0.5 %"} {
+		isNil((&Logger{MinDiskFree: v}).ValidateMinDiskFree(), t)
+	}
+	for _, v := range []string{"lots", "0", "-5MB", "100%", "10PB"} {
+		notNil((&Logger{MinDiskFree: v}).ValidateMinDiskFree(), t)
+	}
+}
+
+func TestCleanupInterval(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCleanupInterval", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          100,
+		MaxAge:           1,
+		CleanupInterval:  5 * time.Millisecond,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+
+	_, err := l.Write([]byte("boo!"))
+	isNil(err, t)
+	newFakeTime()
+	isNil(l.Rotate(), t)
+	backup := backupFileWithReason(dir, "size")
+	<-time.After(10 * time.Millisecond)
+	existsWithContent(backup, []byte("boo!"), t)
+
+	// Two days pass without any writes: the janitor removes the stale backup.
+	newFakeTime()
+	<-time.After(50 * time.Millisecond)
+	notExist(backup, t)
+}
+
+func TestEnforceOnOpen(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestEnforceOnOpen", t)
+	defer os.RemoveAll(dir)
+
+	// Two backups left behind by an earlier run, neither compressed.
+	oldest := backupFileWithReason(dir, "size")
+	err := os.WriteFile(oldest, []byte("old!"), 0644)
+	isNil(err, t)
+	newFakeTime()
+	newest := backupFileWithReason(dir, "size")
+	err = os.WriteFile(newest, []byte("new!"), 0644)
+	isNil(err, t)
+	newFakeTime()
+
+	l := &Logger{
+		Filename:         logFile(dir),
+		MaxSize:          100,
+		MaxBackups:       1,
+		Compress:         true,
+		EnforceOnOpen:    true,
+		BackupTimeFormat: backupTimeFormat,
+	}
+	defer l.Close()
+
+	_, err = l.Write([]byte("boo!"))
+	isNil(err, t)
+
+	// No waiting: the first Write returns only after the cleanup pass.
+	notExist(oldest, t)
+	notExist(newest, t)
+	exists(newest+compressSuffix, t)
+}
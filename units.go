@@ -0,0 +1,204 @@
+package timberjack
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sizeUnits are the sizes in bytes of one unit of the configuration fields
+// that accept human-readable sizes such as "250MB".
+var sizeUnits = map[string]int64{
+	"maxsize":                1 << 20,
+	"maxtotalsize":           1 << 20,
+	"compressminsize":        1,
+	"compressionbytespersec": 1,
+	"compressionbuffersize":  1,
+	"buffersize":             1,
+	"syncbytes":              1,
+	"maxbytespersecond":      1,
+	"coalescemaxbytes":       1,
+}
+
+// parseSize parses a size with an optional binary unit ("250MB", "1.5G",
+// "64KB") into a number of units of unit bytes. A bare number is already
+// in units.
+func parseSize(s string, unit int64) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	multiplier := float64(unit)
+	for _, u := range diskFreeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v, multiplier = strings.TrimSpace(strings.TrimSuffix(v, u.suffix)), float64(u.multiplier)
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: expected a size like 250MB", s)
+	}
+	units := n * multiplier / float64(unit)
+	if units != math.Trunc(units) {
+		return 0, fmt.Errorf("invalid size %q: not a whole number of %s", s, unitName(unit))
+	}
+	return int64(units), nil
+}
+
+// unitName returns the name of a size unit for error messages.
+func unitName(unit int64) string {
+	if unit == 1<<20 {
+		return "megabytes"
+	}
+	return "bytes"
+}
+
+// humanizedValue converts the string value of the configuration field name
+// of type t to the number encoding/json expects, if the field is a duration
+// ("6h") or a size ("250MB"). ok is false if the field takes strings as they
+// are.
+func humanizedValue(name string, t reflect.Type, s string) (n int64, ok bool, err error) {
+	if t == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, true, err
+		}
+		return int64(d), true, nil
+	}
+	if unit, isSize := sizeUnits[strings.ToLower(name)]; isSize {
+		n, err := parseSize(s, unit)
+		return n, true, err
+	}
+	return 0, false, nil
+}
+
+// decodeHumanized decodes the configuration m into v, a pointer to a struct
+// without custom unmarshalers, after converting the human-readable durations
+// and sizes in it. Keys match the JSON names of v's fields case-insensitively,
+// as with encoding/json.
+func decodeHumanized(m map[string]interface{}, v interface{}) error {
+	t := reflect.TypeOf(v).Elem()
+	for key, value := range m {
+		s, isString := value.(string)
+		if !isString {
+			continue
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !strings.EqualFold(strings.Split(f.Tag.Get("json"), ",")[0], key) {
+				continue
+			}
+			n, ok, err := humanizedValue(key, f.Type, s)
+			if err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			if ok {
+				m[key] = n
+			}
+			break
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// decodeHumanizedJSON decodes the JSON object data into v as
+// decodeHumanized does. A JSON null leaves v unchanged.
+func decodeHumanizedJSON(data []byte, v interface{}) error {
+	var m map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&m); err != nil {
+		return err
+	}
+	if m == nil {
+		return nil
+	}
+	return decodeHumanized(m, v)
+}
+
+// decodeHumanizedMap decodes a configuration decoded by a YAML or TOML
+// library into v as decodeHumanized does.
+func decodeHumanizedMap(data interface{}, v interface{}) error {
+	m := make(map[string]interface{})
+	switch data := data.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		for k, value := range data {
+			m[k] = value
+		}
+	case map[interface{}]interface{}:
+		for k, value := range data {
+			key, ok := k.(string)
+			if !ok {
+				return fmt.Errorf("invalid configuration key %v", k)
+			}
+			m[key] = value
+		}
+	default:
+		return fmt.Errorf("invalid configuration of type %T: expected a mapping", data)
+	}
+	return decodeHumanized(m, v)
+}
+
+// plainLogger is Logger without its unmarshalers.
+type plainLogger Logger
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts durations as strings
+// such as "6h" as well as nanoseconds, and sizes as strings such as "250MB"
+// or "1.5G" as well as numbers in the field's unit, e.g. megabytes for
+// MaxSize.
+func (l *Logger) UnmarshalJSON(data []byte) error {
+	return decodeHumanizedJSON(data, (*plainLogger)(l))
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of gopkg.in/yaml.v2,
+// which gopkg.in/yaml.v3 supports too, accepting durations and sizes as
+// UnmarshalJSON does.
+func (l *Logger) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var m map[string]interface{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	return decodeHumanizedMap(m, (*plainLogger)(l))
+}
+
+// UnmarshalTOML implements the Unmarshaler interface of
+// github.com/BurntSushi/toml, accepting durations and sizes as UnmarshalJSON
+// does.
+func (l *Logger) UnmarshalTOML(data interface{}) error {
+	return decodeHumanizedMap(data, (*plainLogger)(l))
+}
+
+// plainConfig is Config without its unmarshalers.
+type plainConfig Config
+
+// UnmarshalJSON implements json.Unmarshaler, accepting durations and sizes
+// as Logger.UnmarshalJSON does.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	return decodeHumanizedJSON(data, (*plainConfig)(c))
+}
+
+// UnmarshalYAML implements the Unmarshaler interface of gopkg.in/yaml.v2,
+// accepting durations and sizes as Logger.UnmarshalJSON does.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var m map[string]interface{}
+	if err := unmarshal(&m); err != nil {
+		return err
+	}
+	return decodeHumanizedMap(m, (*plainConfig)(c))
+}
+
+// UnmarshalTOML implements the Unmarshaler interface of
+// github.com/BurntSushi/toml, accepting durations and sizes as
+// Logger.UnmarshalJSON does.
+func (c *Config) UnmarshalTOML(data interface{}) error {
+	return decodeHumanizedMap(data, (*plainConfig)(c))
+}
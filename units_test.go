@@ -0,0 +1,53 @@
+package timberjack
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalHumanized(t *testing.T) {
+	var l Logger
+	data := `{
+		"filename": "app.log",
+		"maxsize": "250MB",
+		"MaxTotalSize": "1.5G",
+		"buffersize": "64KB",
+		"compressminsize": 4096,
+		"rotationinterval": "6h",
+		"flushinterval": 1000000000
+	}`
+	isNil(json.Unmarshal([]byte(data), &l), t)
+	equals("app.log", l.Filename, t)
+	equals(250, l.MaxSize, t)
+	equals(1536, l.MaxTotalSize, t)
+	equals(64*1024, l.BufferSize, t)
+	equals(int64(4096), l.CompressMinSize, t)
+	equals(6*time.Hour, l.RotationInterval, t)
+	equals(time.Second, l.FlushInterval, t)
+
+	notNil(json.Unmarshal([]byte(`{"maxsize": "100KB"}`), &l), t)
+	notNil(json.Unmarshal([]byte(`{"maxsize": "lots"}`), &l), t)
+	notNil(json.Unmarshal([]byte(`{"rotationinterval": "daily"}`), &l), t)
+	// Other string fields are left alone.
+	isNil(json.Unmarshal([]byte(`{"mindiskfree": "10%"}`), &l), t)
+	equals("10%", l.MinDiskFree, t)
+}
+
+func TestUnmarshalHumanized_YAMLAndTOML(t *testing.T) {
+	// What gopkg.in/yaml.v2 and github.com/BurntSushi/toml hand over.
+	yaml := func(v interface{}) error {
+		*v.(*map[string]interface{}) = map[string]interface{}{"maxsize": "1GB", "cleanupinterval": "90s", "rotateAtMinutes": []interface{}{0, 30}}
+		return nil
+	}
+	var l Logger
+	isNil(l.UnmarshalYAML(yaml), t)
+	equals(1024, l.MaxSize, t)
+	equals(90*time.Second, l.CleanupInterval, t)
+	equals([]int{0, 30}, l.RotateAtMinutes, t)
+
+	var c Config
+	isNil(c.UnmarshalTOML(map[string]interface{}{"maxsize": "2G", "rotationinterval": "1h", "maxbackups": int64(3)}), t)
+	equals(Config{MaxSize: 2048, RotationInterval: time.Hour, MaxBackups: 3}, c, t)
+	notNil(c.UnmarshalTOML([]interface{}{}), t)
+}
@@ -0,0 +1,113 @@
+package timberjack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// ValidationError lists the problems Validate found in a Logger's
+// configuration.
+type ValidationError struct {
+	Errors []error
+}
+
+// Error implements error.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "timberjack: invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual problems, for errors.Is and errors.As.
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// Validate checks the whole configuration, so that services can fail fast
+// at startup instead of finding out from stderr warnings or failed writes:
+// everything the individual Validate methods check, numeric fields below
+// their minimum, RotateAtMinutes outside 0-59, ChunkBoundary against
+// MaxSize, and whether the backup directory exists or can be created. It
+// returns a *ValidationError listing every problem, or nil. Validate doesn't
+// create anything.
+func (l *Logger) Validate() error {
+	var errs []error
+	check := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	check(l.ValidateMaxSize())
+	if l.BackupTimeFormat != "" {
+		check(l.ValidateBackupTimeFormat())
+	}
+	check(l.ValidateCompressionCodec())
+	check(l.ValidateEncryption())
+	check(l.ValidateMinDiskFree())
+	check(l.ValidateBackupNamePattern())
+	check(l.ValidateBackupDirLayout())
+	check(l.ValidatePairPolicy())
+	check(l.ValidateRotationPeriod())
+	check(l.ValidateRotateAtTimes())
+	check(l.ValidateRotationSchedule())
+	check(l.ValidateQueueFullPolicy())
+	check(l.ValidateSyncPolicy())
+	check(l.ValidateRateLimitPolicy())
+	check(l.ValidateDiskFullPolicy())
+
+	// MaxSize has its own rules in ValidateMaxSize.
+	v := reflect.ValueOf(l).Elem()
+	for _, cf := range configFields() {
+		min, ok := configMinimums[cf.name]
+		if ok && cf.name != "maxsize" && v.FieldByIndex(cf.field.Index).Int() < int64(min) {
+			check(fmt.Errorf("invalid %s %d: must be at least %d", cf.field.Name, v.FieldByIndex(cf.field.Index).Int(), min))
+		}
+	}
+	for _, m := range l.RotateAtMinutes {
+		if m < 0 || m > 59 {
+			check(fmt.Errorf("invalid RotateAtMinutes entry %d: must be between 0 and 59", m))
+		}
+	}
+	if l.ChunkBoundary != "" {
+		if !l.ChunkOversizeWrites {
+			check(fmt.Errorf("ChunkBoundary requires ChunkOversizeWrites"))
+		} else if int64(len(l.ChunkBoundary)) >= l.max() {
+			check(fmt.Errorf("ChunkBoundary of %d bytes doesn't fit in MaxSize", len(l.ChunkBoundary)))
+		}
+	}
+	check(l.validateBackupDir())
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// validateBackupDir checks that the backup directory is a directory, or that
+// its closest existing ancestor is one, so that it can be created.
+func (l *Logger) validateBackupDir() error {
+	dir := l.backupDir()
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("backup directory %s: %s is not a directory", l.backupDir(), dir)
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("backup directory %s: %w", l.backupDir(), err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,47 @@
+package timberjack
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := makeTempDir("TestValidate", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10, BackupDir: filepath.Join(dir, "new", "backups")}
+	isNil(l.Validate(), t)
+
+	l = &Logger{
+		Filename:        logFile(dir),
+		MaxSize:         1,
+		MaxBackups:      -1,
+		RotateAtMinutes: []int{0, 60},
+		SyncPolicy:      "sometimes",
+		ChunkBoundary:   "\n",
+	}
+	err := l.Validate()
+	notNil(err, t)
+	var verr *ValidationError
+	assert(errors.As(err, &verr), t, "expected a *ValidationError, got %T", err)
+	equals(4, len(verr.Errors), t)
+
+	l = &Logger{Filename: logFile(dir), RotationInterval: 1}
+	assert(errors.Is(l.Validate(), ErrAmbiguousMaxSize), t, "expected ErrAmbiguousMaxSize")
+}
+
+func TestValidate_BackupDir(t *testing.T) {
+	dir := makeTempDir("TestValidate_BackupDir", t)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "file")
+	isNil(os.WriteFile(file, []byte("x"), 0644), t)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 10, BackupDir: file}
+	notNil(l.Validate(), t)
+
+	l.BackupDir = filepath.Join(file, "backups")
+	notNil(l.Validate(), t)
+}
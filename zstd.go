@@ -0,0 +1,444 @@
+package timberjack
+
+import (
+	"encoding/binary"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+// This file implements a small Zstandard (RFC 8878) encoder for the "zstd"
+// CompressionCodec. It favors speed and simplicity: matches are found with a
+// single hash probe, literals are stored uncompressed and sequences use the
+// predefined FSE tables. The result is a standard frame that any zstd
+// decoder reads; it compresses repetitive log data well, but less tightly
+// than the reference implementation.
+
+const (
+	zstdMagic      = 0xFD2FB528
+	zstdWindowLog  = 20 // 1 MiB window
+	zstdWindowSize = 1 << zstdWindowLog
+	zstdBlockSize  = 128 << 10 // Block_Maximum_Size
+	zstdHashLog    = 16
+	zstdMinMatch   = 4
+
+	zstdBlockRaw        = 0
+	zstdBlockCompressed = 2
+)
+
+// Predefined FSE distributions (RFC 8878, section 3.1.1.3.2.2).
+var (
+	zstdLLDefaultNorm = []int16{4, 3, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 1, 1,
+		2, 2, 2, 2, 2, 2, 2, 2, 2, 3, 2, 1, 1, 1, 1, 1, -1, -1, -1, -1}
+	zstdMLDefaultNorm = []int16{1, 4, 3, 2, 2, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, -1, -1, -1, -1, -1, -1, -1}
+	zstdOFDefaultNorm = []int16{1, 1, 1, 1, 1, 1, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1,
+		1, 1, 1, 1, 1, 1, 1, 1, -1, -1, -1, -1, -1}
+)
+
+// Literals_Length and Match_Length codes: baselines and extra bits
+// (RFC 8878, section 3.1.1.3.2.1.1).
+var (
+	zstdLLBase = []uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		16, 18, 20, 22, 24, 28, 32, 40, 48, 64, 128, 256, 512, 1024, 2048, 4096,
+		8192, 16384, 32768, 65536}
+	zstdLLBits = []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	zstdMLBase = []uint32{3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18,
+		19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34,
+		35, 37, 39, 41, 43, 47, 51, 59, 67, 83, 99, 131, 259, 515, 1027, 2051,
+		4099, 8195, 16387, 32771, 65539}
+	zstdMLBits = []uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		1, 1, 1, 1, 2, 2, 3, 3, 4, 4, 5, 7, 8, 9, 10, 11,
+		12, 13, 14, 15, 16}
+)
+
+// fseSymbolTransform is the encoding transform of one symbol of an FSE table.
+type fseSymbolTransform struct {
+	deltaNbBits    uint32
+	deltaFindState int32
+}
+
+// fseEncTable is an FSE compression table.
+type fseEncTable struct {
+	tableLog   uint
+	stateTable []uint16
+	symbolTT   []fseSymbolTransform
+}
+
+// fseSpread distributes the symbols of a normalized distribution over a
+// table of 1<<tableLog states, as decoders do.
+func fseSpread(norm []int16, tableLog uint) []uint8 {
+	size := 1 << tableLog
+	mask := size - 1
+	step := size>>1 + size>>3 + 3
+	symbols := make([]uint8, size)
+	high := size - 1
+	for s, n := range norm {
+		if n == -1 {
+			symbols[high] = uint8(s)
+			high--
+		}
+	}
+	pos := 0
+	for s, n := range norm {
+		for i := 0; i < int(n); i++ {
+			symbols[pos] = uint8(s)
+			pos = (pos + step) & mask
+			for pos > high {
+				pos = (pos + step) & mask
+			}
+		}
+	}
+	return symbols
+}
+
+// newFSEEncTable builds the compression table of a normalized distribution.
+func newFSEEncTable(norm []int16, tableLog uint) *fseEncTable {
+	size := 1 << tableLog
+	symbols := fseSpread(norm, tableLog)
+
+	cumul := make([]int, len(norm)+1)
+	for s, n := range norm {
+		if n == -1 {
+			n = 1
+		}
+		cumul[s+1] = cumul[s] + int(n)
+	}
+	t := &fseEncTable{
+		tableLog:   tableLog,
+		stateTable: make([]uint16, size),
+		symbolTT:   make([]fseSymbolTransform, len(norm)),
+	}
+	for u, s := range symbols {
+		t.stateTable[cumul[s]] = uint16(size + u)
+		cumul[s]++
+	}
+
+	total := int32(0)
+	for s, n := range norm {
+		switch n {
+		case 0:
+		case -1, 1:
+			t.symbolTT[s] = fseSymbolTransform{uint32(tableLog<<16) - uint32(size), total - 1}
+			total++
+		default:
+			maxBitsOut := tableLog - uint(bits.Len32(uint32(n-1))-1)
+			minStatePlus := uint32(n) << maxBitsOut
+			t.symbolTT[s] = fseSymbolTransform{uint32(maxBitsOut<<16) - minStatePlus, total - int32(n)}
+			total += int32(n)
+		}
+	}
+	return t
+}
+
+var (
+	zstdTablesOnce           sync.Once
+	zstdLLTable, zstdMLTable *fseEncTable
+	zstdOFTable              *fseEncTable
+)
+
+// zstdTables returns the predefined FSE tables.
+func zstdTables() (ll, ml, of *fseEncTable) {
+	zstdTablesOnce.Do(func() {
+		zstdLLTable = newFSEEncTable(zstdLLDefaultNorm, 6)
+		zstdMLTable = newFSEEncTable(zstdMLDefaultNorm, 6)
+		zstdOFTable = newFSEEncTable(zstdOFDefaultNorm, 5)
+	})
+	return zstdLLTable, zstdMLTable, zstdOFTable
+}
+
+// bitWriter writes a little-endian bit stream, as used by FSE.
+type bitWriter struct {
+	out       []byte
+	container uint64
+	nbits     uint
+}
+
+func (b *bitWriter) addBits(v uint32, n uint) {
+	b.container |= uint64(v&(1<<n-1)) << b.nbits
+	b.nbits += n
+	for b.nbits >= 8 {
+		b.out = append(b.out, byte(b.container))
+		b.container >>= 8
+		b.nbits -= 8
+	}
+}
+
+// close appends the end mark and the last partial byte.
+func (b *bitWriter) close() []byte {
+	b.addBits(1, 1)
+	if b.nbits > 0 {
+		b.out = append(b.out, byte(b.container))
+	}
+	return b.out
+}
+
+// fseState is the state of an FSE encoder.
+type fseState struct {
+	value uint32
+	table *fseEncTable
+}
+
+func (s *fseState) init(t *fseEncTable, symbol uint8) {
+	tt := t.symbolTT[symbol]
+	nbBitsOut := (tt.deltaNbBits + 1<<15) >> 16
+	s.table = t
+	s.value = nbBitsOut<<16 - tt.deltaNbBits
+	s.value = uint32(t.stateTable[int32(s.value>>nbBitsOut)+tt.deltaFindState])
+}
+
+func (s *fseState) encode(b *bitWriter, symbol uint8) {
+	tt := s.table.symbolTT[symbol]
+	nbBitsOut := (s.value + tt.deltaNbBits) >> 16
+	b.addBits(s.value, uint(nbBitsOut))
+	s.value = uint32(s.table.stateTable[int32(s.value>>nbBitsOut)+tt.deltaFindState])
+}
+
+func (s *fseState) flush(b *bitWriter) {
+	b.addBits(s.value, s.table.tableLog)
+}
+
+// zstdSequence is a run of literals followed by a match.
+type zstdSequence struct {
+	litLen, matchLen, offset uint32
+}
+
+// zstdLLCode returns the Literals_Length code of n.
+func zstdLLCode(n uint32) uint8 {
+	if n < 16 {
+		return uint8(n)
+	}
+	c := len(zstdLLBase) - 1
+	for zstdLLBase[c] > n {
+		c--
+	}
+	return uint8(c)
+}
+
+// zstdMLCode returns the Match_Length code of n.
+func zstdMLCode(n uint32) uint8 {
+	if n < 35 {
+		return uint8(n - 3)
+	}
+	c := len(zstdMLBase) - 1
+	for zstdMLBase[c] > n {
+		c--
+	}
+	return uint8(c)
+}
+
+// zstdWriter is an io.WriteCloser that compresses what is written to it
+// into a single Zstandard frame.
+type zstdWriter struct {
+	w       io.Writer
+	buf     []byte // window history followed by pending input
+	pending int    // start of the pending input in buf
+	base    int64  // stream offset of buf[0]
+	table   []int64
+	started bool
+	err     error
+}
+
+// newZstdWriter returns a zstdWriter writing to w.
+func newZstdWriter(w io.Writer) *zstdWriter {
+	return &zstdWriter{w: w, table: make([]int64, 1<<zstdHashLog)}
+}
+
+// Write implements io.Writer.
+func (z *zstdWriter) Write(p []byte) (int, error) {
+	if z.err != nil {
+		return 0, z.err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		room := zstdBlockSize - (len(z.buf) - z.pending)
+		if room > len(p) {
+			room = len(p)
+		}
+		z.buf = append(z.buf, p[:room]...)
+		p = p[room:]
+		if len(z.buf)-z.pending == zstdBlockSize {
+			if z.err = z.writeBlock(false); z.err != nil {
+				return 0, z.err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close writes the last block. It doesn't close the underlying writer.
+func (z *zstdWriter) Close() error {
+	if z.err != nil {
+		return z.err
+	}
+	z.err = z.writeBlock(true)
+	if z.err == nil {
+		z.err = errWriterClosed
+		return nil
+	}
+	return z.err
+}
+
+// writeBlock compresses the pending input into one block.
+func (z *zstdWriter) writeBlock(last bool) error {
+	if !z.started {
+		// Magic, Frame_Header_Descriptor (no checksum, no content size)
+		// and Window_Descriptor.
+		var hdr [6]byte
+		binary.LittleEndian.PutUint32(hdr[:], zstdMagic)
+		hdr[5] = (zstdWindowLog - 10) << 3
+		if _, err := z.w.Write(hdr[:]); err != nil {
+			return err
+		}
+		z.started = true
+	}
+
+	src := z.buf[z.pending:]
+	block := z.compressBlock()
+	blockType := zstdBlockCompressed
+	if block == nil || len(block) >= len(src) {
+		block, blockType = src, zstdBlockRaw
+	}
+	var lastBit uint32
+	if last {
+		lastBit = 1
+	}
+	h := lastBit | uint32(blockType)<<1 | uint32(len(block))<<3
+	if _, err := z.w.Write([]byte{byte(h), byte(h >> 8), byte(h >> 16)}); err != nil {
+		return err
+	}
+	if _, err := z.w.Write(block); err != nil {
+		return err
+	}
+
+	// Keep up to a window of history for the next block, sliding it only
+	// occasionally to limit copying.
+	if drop := len(z.buf) - zstdWindowSize; drop > 0 && len(z.buf) >= 2*zstdWindowSize {
+		z.buf = append(z.buf[:0], z.buf[drop:]...)
+		z.base += int64(drop)
+	}
+	z.pending = len(z.buf)
+	return nil
+}
+
+// matchLen returns the length of the common prefix of a and b, which must
+// not be shorter than b.
+func matchLen(a, b []byte) int {
+	n := 0
+	for len(b)-n >= 8 {
+		if x := binary.LittleEndian.Uint64(a[n:]) ^ binary.LittleEndian.Uint64(b[n:]); x != 0 {
+			return n + bits.TrailingZeros64(x)>>3
+		}
+		n += 8
+	}
+	for n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// zstdHash hashes the 4 bytes at the start of b.
+func zstdHash(b []byte) uint32 {
+	return binary.LittleEndian.Uint32(b) * 2654435761 >> (32 - zstdHashLog)
+}
+
+// compressBlock returns the compressed content of the pending input, or nil
+// if it can't be compressed.
+func (z *zstdWriter) compressBlock() []byte {
+	buf, start := z.buf, z.pending
+	var seqs []zstdSequence
+	var literals []byte
+
+	anchor := start
+	for i := start; i+zstdMinMatch <= len(buf); {
+		h := zstdHash(buf[i:])
+		cand := z.table[h] - z.base
+		z.table[h] = z.base + int64(i) + 1 // 0 means empty
+		cand--
+		if cand < 0 || cand >= int64(i) || int64(i)-cand > zstdWindowSize ||
+			binary.LittleEndian.Uint32(buf[cand:]) != binary.LittleEndian.Uint32(buf[i:]) {
+			i += 1 + (i-anchor)>>6 // skip faster through incompressible data
+			continue
+		}
+		c := int(cand)
+		n := zstdMinMatch + matchLen(buf[c+zstdMinMatch:], buf[i+zstdMinMatch:])
+		for i > anchor && c > 0 && buf[c-1] == buf[i-1] {
+			i, c, n = i-1, c-1, n+1
+		}
+		literals = append(literals, buf[anchor:i]...)
+		seqs = append(seqs, zstdSequence{uint32(i - anchor), uint32(n), uint32(i - c)})
+		i += n
+		anchor = i
+	}
+	if len(seqs) == 0 {
+		return nil
+	}
+	literals = append(literals, buf[anchor:]...)
+
+	// Literals_Section: Raw_Literals_Block.
+	var out []byte
+	switch n := len(literals); {
+	case n < 32:
+		out = append(out, byte(n<<3))
+	case n < 4096:
+		out = append(out, byte(1<<2|n<<4), byte(n>>4))
+	default:
+		out = append(out, byte(3<<2|n<<4), byte(n>>4), byte(n>>12))
+	}
+	out = append(out, literals...)
+
+	// Sequences_Section_Header with predefined modes.
+	switch n := len(seqs); {
+	case n < 128:
+		out = append(out, byte(n))
+	case n < 0x7F00:
+		out = append(out, byte(n>>8+128), byte(n))
+	default:
+		out = append(out, 0xFF, byte(n-0x7F00), byte((n-0x7F00)>>8))
+	}
+	out = append(out, 0)
+
+	return append(out, encodeZstdSequences(seqs)...)
+}
+
+// encodeZstdSequences encodes seqs into an FSE bit stream using the
+// predefined tables. Sequences are written last to first so that the
+// decoder, which reads the stream backwards, gets them in order.
+func encodeZstdSequences(seqs []zstdSequence) []byte {
+	llTable, mlTable, ofTable := zstdTables()
+	n := len(seqs)
+	llCodes := make([]uint8, n)
+	mlCodes := make([]uint8, n)
+	ofCodes := make([]uint8, n)
+	for i, s := range seqs {
+		llCodes[i] = zstdLLCode(s.litLen)
+		mlCodes[i] = zstdMLCode(s.matchLen)
+		ofCodes[i] = uint8(bits.Len32(s.offset+3) - 1)
+	}
+
+	var b bitWriter
+	var ll, ml, of fseState
+	ml.init(mlTable, mlCodes[n-1])
+	of.init(ofTable, ofCodes[n-1])
+	ll.init(llTable, llCodes[n-1])
+	addExtra := func(i int) {
+		s := seqs[i]
+		b.addBits(s.litLen-zstdLLBase[llCodes[i]], uint(zstdLLBits[llCodes[i]]))
+		b.addBits(s.matchLen-zstdMLBase[mlCodes[i]], uint(zstdMLBits[mlCodes[i]]))
+		b.addBits(s.offset+3, uint(ofCodes[i]))
+	}
+	addExtra(n - 1)
+	for i := n - 2; i >= 0; i-- {
+		of.encode(&b, ofCodes[i])
+		ml.encode(&b, mlCodes[i])
+		ll.encode(&b, llCodes[i])
+		addExtra(i)
+	}
+	ml.flush(&b)
+	of.flush(&b)
+	ll.flush(&b)
+	return b.close()
+}
@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package timberjack
+
+import (
+	"bytes"
+	"math/rand"
+	"os/exec"
+	"testing"
+)
+
+// FuzzZstdRoundTrip compresses data, repeated repeat+1 times so that inputs
+// span several blocks, and checks that both the decoder of the tests and,
+// if installed, the reference zstd tool restore it.
+func FuzzZstdRoundTrip(f *testing.F) {
+	random := make([]byte, zstdBlockSize+1000)
+	rand.New(rand.NewSource(1)).Read(random)
+	f.Add([]byte{}, uint8(0))
+	f.Add([]byte("hello hello hello hello world\n"), uint8(3))
+	f.Add(interopSample(), uint8(1))
+	f.Add(random, uint8(0))         // incompressible, more than a block
+	f.Add(random[:997], uint8(200)) // periodic, spanning two blocks
+	f.Add([]byte{0}, uint8(255))
+
+	_, errTool := exec.LookPath("zstd")
+	f.Fuzz(func(t *testing.T, data []byte, repeat uint8) {
+		in := bytes.Repeat(data, int(repeat)+1)
+		var out bytes.Buffer
+		isNil(zstdCodec{}.Compress(&out, bytes.NewReader(in)), t)
+		got, err := decodeZstd(out.Bytes())
+		isNil(err, t)
+		assert(bytes.Equal(in, got), t, "round trip mismatch of %d bytes", len(in))
+		if errTool == nil {
+			checkDecompressedByTool(t, "zstd", out.Bytes(), in)
+		}
+	})
+}
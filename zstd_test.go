@@ -0,0 +1,316 @@
+package timberjack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestZstdRoundTrip(t *testing.T) {
+	var logs bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&logs, "2025-05-%02d 12:%02d:%02d INFO request id=%d path=/api/v1/items/%d status=200\n", i%28+1, i%60, i%60, i*7, i%1000)
+	}
+	random := make([]byte, 300<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	for name, in := range map[string][]byte{
+		"empty":  {},
+		"short":  []byte("hello hello hello hello world\n"),
+		"logs":   logs.Bytes(),
+		"random": random,
+		"zeros":  make([]byte, 3*zstdWindowSize),
+	} {
+		var out bytes.Buffer
+		isNil(zstdCodec{}.Compress(&out, bytes.NewReader(in)), t)
+		got, err := decodeZstd(out.Bytes())
+		isNil(err, t)
+		assert(bytes.Equal(in, got), t, "%s: round trip mismatch", name)
+		if name == "logs" || name == "zeros" {
+			assert(out.Len() < len(in)/4, t, "%s: compressed %d bytes to %d", name, len(in), out.Len())
+		}
+	}
+}
+
+func TestCompressionCodec(t *testing.T) {
+	currentTime = fakeTime
+	megabyte = 1
+
+	dir := makeTempDir("TestCompressionCodec", t)
+	defer os.RemoveAll(dir)
+
+	l := &Logger{Filename: logFile(dir), MaxSize: 100, MaxBackups: 2, Compress: true, CompressionCodec: "zstd", BackupTimeFormat: backupTimeFormat}
+	defer l.Close()
+	isNil(l.ValidateCompressionCodec(), t)
+
+	// A gzip backup from before the codec was changed is still managed.
+	gz := backupFileWithReason(dir, "size") + compressSuffix
+	isNil(os.WriteFile(gz, []byte("old"), 0644), t)
+	newFakeTime()
+
+	var names []string
+	for _, content := range []string{"one!", "two!"} {
+		_, err := l.Write([]byte(content))
+		isNil(err, t)
+		newFakeTime()
+		isNil(l.Rotate(), t)
+		names = append(names, backupFileWithReason(dir, "size"))
+	}
+	isNil(l.millRunOnce(), t)
+
+	notExist(gz, t)
+	for i, content := range []string{"one!", "two!"} {
+		notExist(names[i], t)
+		b, err := os.ReadFile(names[i] + ".zst")
+		isNil(err, t)
+		got, err := decodeZstd(b)
+		isNil(err, t)
+		equals(content, string(got), t)
+	}
+	backups, err := l.Backups()
+	isNil(err, t)
+	equals(2, len(backups), t)
+	assert(backups[0].Compressed, t, "expected a compressed backup")
+	equals("size", backups[0].Reason, t)
+	equals(filepath.Base(names[1])+".zst", backups[0].Name, t)
+
+	l.CompressionCodec = "rar"
+	notNil(l.ValidateCompressionCodec(), t)
+}
+
+// decodeZstd decodes the subset of Zstandard produced by zstdWriter: frames
+// without dictionary, with raw, RLE or compressed blocks whose literals are
+// raw or RLE and whose sequences use the predefined tables.
+func decodeZstd(src []byte) ([]byte, error) {
+	if len(src) < 6 || binary.LittleEndian.Uint32(src) != zstdMagic {
+		return nil, errors.New("bad magic")
+	}
+	if src[4] != 0 {
+		return nil, fmt.Errorf("unsupported frame header descriptor %#x", src[4])
+	}
+	src = src[6:]
+
+	ll := newFSEDecTable(zstdLLDefaultNorm, 6)
+	ml := newFSEDecTable(zstdMLDefaultNorm, 6)
+	of := newFSEDecTable(zstdOFDefaultNorm, 5)
+
+	var out []byte
+	for {
+		if len(src) < 3 {
+			return nil, errors.New("truncated block header")
+		}
+		h := uint32(src[0]) | uint32(src[1])<<8 | uint32(src[2])<<16
+		last, typ, size := h&1 == 1, (h>>1)&3, int(h>>3)
+		src = src[3:]
+		switch typ {
+		case 0:
+			out = append(out, src[:size]...)
+			src = src[size:]
+		case 1:
+			out = append(out, bytes.Repeat(src[:1], size)...)
+			src = src[1:]
+		case 2:
+			var err error
+			if out, err = decodeZstdBlock(out, src[:size], ll, ml, of); err != nil {
+				return nil, err
+			}
+			src = src[size:]
+		default:
+			return nil, errors.New("reserved block type")
+		}
+		if last {
+			return out, nil
+		}
+	}
+}
+
+func decodeZstdBlock(out, b []byte, ll, ml, of *fseDecTable) ([]byte, error) {
+	typ, sizeFormat := b[0]&3, (b[0]>>2)&3
+	var n, hdr int
+	switch sizeFormat {
+	case 0, 2:
+		n, hdr = int(b[0]>>3), 1
+	case 1:
+		n, hdr = int(b[0]>>4)|int(b[1])<<4, 2
+	case 3:
+		n, hdr = int(b[0]>>4)|int(b[1])<<4|int(b[2])<<12, 3
+	}
+	var literals []byte
+	switch typ {
+	case 0:
+		literals = b[hdr : hdr+n]
+		b = b[hdr+n:]
+	case 1:
+		literals = bytes.Repeat(b[hdr:hdr+1], n)
+		b = b[hdr+1:]
+	default:
+		return nil, errors.New("unsupported literals block type")
+	}
+
+	nbSeq := int(b[0])
+	switch {
+	case nbSeq == 0:
+		return append(out, literals...), nil
+	case nbSeq < 128:
+		b = b[1:]
+	case nbSeq < 255:
+		nbSeq, b = (nbSeq-128)<<8|int(b[1]), b[2:]
+	default:
+		nbSeq, b = int(b[1])|int(b[2])<<8+0x7F00, b[3:]
+	}
+	if b[0] != 0 {
+		return nil, errors.New("only predefined sequence modes are supported")
+	}
+	r, err := newBackwardBitReader(b[1:])
+	if err != nil {
+		return nil, err
+	}
+	llState, ofState, mlState := r.read(6), r.read(5), r.read(6)
+	for i := 0; i < nbSeq; i++ {
+		ofCode, mlCode, llCode := of.symbol[ofState], ml.symbol[mlState], ll.symbol[llState]
+		offset := uint32(1)<<ofCode + r.read(uint(ofCode))
+		matchLen := zstdMLBase[mlCode] + r.read(uint(zstdMLBits[mlCode]))
+		litLen := zstdLLBase[llCode] + r.read(uint(zstdLLBits[llCode]))
+		if i < nbSeq-1 {
+			llState = ll.next(llState, r)
+			mlState = ml.next(mlState, r)
+			ofState = of.next(ofState, r)
+		}
+		if offset <= 3 {
+			return nil, errors.New("repeat offsets are not supported")
+		}
+		out = append(out, literals[:litLen]...)
+		literals = literals[litLen:]
+		start := len(out) - int(offset-3)
+		if start < 0 {
+			return nil, errors.New("offset beyond start of data")
+		}
+		for j := 0; j < int(matchLen); j++ {
+			out = append(out, out[start+j])
+		}
+	}
+	return append(out, literals...), nil
+}
+
+// fseDecTable is an FSE decoding table.
+type fseDecTable struct {
+	symbol   []uint8
+	nbBits   []uint8
+	newState []uint32
+}
+
+func newFSEDecTable(norm []int16, tableLog uint) *fseDecTable {
+	size := 1 << tableLog
+	t := &fseDecTable{symbol: fseSpread(norm, tableLog), nbBits: make([]uint8, size), newState: make([]uint32, size)}
+	next := make([]uint32, len(norm))
+	for s, n := range norm {
+		if n == -1 {
+			n = 1
+		}
+		next[s] = uint32(n)
+	}
+	for u, s := range t.symbol {
+		x := next[s]
+		next[s]++
+		nb := tableLog - uint(bits.Len32(x)-1)
+		t.nbBits[u] = uint8(nb)
+		t.newState[u] = x<<nb - uint32(size)
+	}
+	return t
+}
+
+func (t *fseDecTable) next(state uint32, r *backwardBitReader) uint32 {
+	return t.newState[state] + r.read(uint(t.nbBits[state]))
+}
+
+// backwardBitReader reads an FSE bit stream from its end.
+type backwardBitReader struct {
+	b   []byte
+	pos int // number of unread bits
+}
+
+func newBackwardBitReader(b []byte) (*backwardBitReader, error) {
+	if len(b) == 0 || b[len(b)-1] == 0 {
+		return nil, errors.New("missing end mark")
+	}
+	return &backwardBitReader{b, (len(b)-1)*8 + bits.Len8(b[len(b)-1]) - 1}, nil
+}
+
+func (r *backwardBitReader) read(n uint) uint32 {
+	var v uint32
+	for i := 0; i < int(n); i++ {
+		r.pos--
+		v = v<<1 | uint32(r.b[r.pos/8]>>(r.pos%8)&1)
+	}
+	return v
+}
+
+// interopSample is the content of the golden frames in testdata: log lines,
+// spanning more than one block, and some incompressible bytes.
+func interopSample() []byte {
+	var b bytes.Buffer
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&b, "2025-05-%02d 12:%02d:%02d INFO request id=%d path=/api/v1/items/%d status=200\n", i%28+1, i%60, i%60, i*7, i%1000)
+	}
+	random := make([]byte, 2<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	b.Write(random)
+	return b.Bytes()
+}
+
+// checkDecompressedByTool decompresses frame with the reference command
+// line tool, e.g. "zstd", and checks that it yields want. The test is
+// skipped if the tool isn't installed.
+func checkDecompressedByTool(t *testing.T, tool string, frame, want []byte) {
+	t.Helper()
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		t.Skipf("%s not installed", tool)
+	}
+	cmd := exec.Command(path, "-d", "-c")
+	cmd.Stdin = bytes.NewReader(frame)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	got, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%s -d: %v: %s", tool, err, stderr.Bytes())
+	}
+	assert(bytes.Equal(want, got), t, "%s -d: got %d bytes, want %d", tool, len(got), len(want))
+}
+
+// TestZstdGolden checks that the encoder still produces the frame in
+// testdata, which the reference zstd (v1.5.6) decompresses to
+// interopSample, so that backups stay readable without this package.
+func TestZstdGolden(t *testing.T) {
+	golden, err := os.ReadFile(filepath.Join("testdata", "interop.log.zst"))
+	isNil(err, t)
+	var out bytes.Buffer
+	isNil(zstdCodec{}.Compress(&out, bytes.NewReader(interopSample())), t)
+	assert(bytes.Equal(golden, out.Bytes()), t, "the encoder's output changed; check it with zstd -d and update the golden frame")
+}
+
+// TestZstdInterop decompresses frames with the zstd tool, if installed.
+func TestZstdInterop(t *testing.T) {
+	random := make([]byte, 300<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	for name, in := range map[string][]byte{
+		"empty":  {},
+		"short":  []byte("hello hello hello hello world\n"),
+		"sample": interopSample(),
+		"random": random,
+		"zeros":  make([]byte, 3*zstdWindowSize),
+	} {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			isNil(zstdCodec{}.Compress(&out, bytes.NewReader(in)), t)
+			checkDecompressedByTool(t, "zstd", out.Bytes(), in)
+		})
+	}
+}